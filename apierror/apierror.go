@@ -0,0 +1,186 @@
+// Package apierror is the single error type handlers return to clients. An
+// Error carries the HTTP status, a stable machine-readable code, a message
+// (English text, translated when rendered) and optional per-field details.
+// Handlers hand it to Abort; middleware.Errors renders it.
+//
+// The cause of an internal error is kept for the logs and never rendered.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/message"
+)
+
+// Code identifies an error for programs; it never changes once published.
+type Code string
+
+const (
+	CodeInvalidRequest     Code = "invalid_request"     // malformed body, form or parameter
+	CodeValidationFailed   Code = "validation_failed"   // see Fields
+	CodeInvalidID          Code = "invalid_id"          // path id is not an ObjectID
+	CodeInvalidUpload      Code = "invalid_upload"      // rejected file
+	CodeNotFound           Code = "not_found"           // resource does not exist
+	CodeRouteNotFound      Code = "route_not_found"     // no such endpoint
+	CodeConflict           Code = "conflict"            // unique value already taken
+	CodeInsufficientStock  Code = "insufficient_stock"  // a stock movement would go below zero
+	CodeUnauthorized       Code = "unauthorized"        // missing or invalid token
+	CodeInvalidCredentials Code = "invalid_credentials" // wrong email or password
+	CodeForbidden          Code = "forbidden"           // authenticated but not allowed
+	CodeAccountDisabled    Code = "account_disabled"
+	CodeInternal           Code = "internal_error"
+)
+
+// Field rules used next to the validator tags (required, email, min…).
+const (
+	RuleRequired = "required"
+	RuleInvalid  = "invalid"
+	RuleOneOf    = "oneof"
+	RuleUnique   = "unique"
+	RuleExists   = "exists"
+)
+
+// Error is an error meant for the client.
+type Error struct {
+	Status  int
+	Code    Code
+	Message string // English, also the translation key
+	Args    []any
+	Fields  []FieldError
+	// Err is the cause, logged with the request and never rendered.
+	Err error
+}
+
+// FieldError points at one invalid input field.
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+	Args    []any
+}
+
+func (e *Error) Error() string {
+	msg := string(e.Code) + ": " + fmt.Sprintf(e.Message, e.Args...)
+	for _, f := range e.Fields {
+		msg += "; " + f.Field + ": " + fmt.Sprintf(f.Message, f.Args...)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Abort stops the handler chain with err, which middleware.Errors renders.
+// Any error is accepted; those that are not an *Error become a 500.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// New returns an error with a printf-style message.
+func New(status int, code Code, message string, args ...any) *Error {
+	return &Error{Status: status, Code: code, Message: message, Args: args}
+}
+
+// WithField adds a field detail and returns e.
+func (e *Error) WithField(field, rule, message string, args ...any) *Error {
+	e.Fields = append(e.Fields, FieldError{Field: field, Rule: rule, Message: message, Args: args})
+	return e
+}
+
+// Wrap records the cause and returns e.
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+func BadRequest(message string, args ...any) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, message, args...)
+}
+
+// InvalidID is the 400 for a malformed :id path parameter.
+func InvalidID(message string, args ...any) *Error {
+	return New(http.StatusBadRequest, CodeInvalidID, message, args...)
+}
+
+// Validation starts a 400 whose details are added with WithField.
+func Validation() *Error {
+	return New(http.StatusBadRequest, CodeValidationFailed, "some fields are invalid")
+}
+
+// Invalid is a validation error on a single field.
+func Invalid(field, rule, message string, args ...any) *Error {
+	return Validation().WithField(field, rule, message, args...)
+}
+
+// Required is a validation error for a missing field.
+func Required(field string) *Error {
+	return Invalid(field, RuleRequired, "%s is required", field)
+}
+
+func NotFound(message string, args ...any) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message, args...)
+}
+
+// Conflict reports a unique field whose value is already taken.
+func Conflict(field, value string) *Error {
+	return New(http.StatusConflict, CodeConflict, "%s already exists: '%s'", field, value).
+		WithField(field, RuleUnique, "%s is already taken", field)
+}
+
+func Unauthorized(code Code, message string) *Error {
+	return New(http.StatusUnauthorized, code, message)
+}
+
+func Forbidden(code Code, message string) *Error {
+	return New(http.StatusForbidden, code, message)
+}
+
+// Internal hides err behind a generic 500.
+func Internal(err error) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, "internal server error").Wrap(err)
+}
+
+// From returns err as an *Error, turning anything else into Internal(err).
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal(err)
+}
+
+// Envelope is the JSON body of every error response:
+//
+//	{"error": {"code": "validation_failed", "message": "…", "requestId": "…",
+//	           "fields": [{"field": "email", "rule": "email", "message": "…"}]}}
+type Envelope struct {
+	Error Detail `json:"error"`
+}
+
+type Detail struct {
+	Code      Code          `json:"code"`
+	Message   string        `json:"message"`
+	RequestID string        `json:"requestId,omitempty"`
+	Fields    []FieldDetail `json:"fields,omitempty"`
+}
+
+type FieldDetail struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Render renders e in p's language.
+func (e *Error) Render(p *message.Printer, requestID string) Envelope {
+	b := Detail{Code: e.Code, Message: p.Sprintf(e.Message, e.Args...), RequestID: requestID}
+	for _, f := range e.Fields {
+		b.Fields = append(b.Fields, FieldDetail{Field: f.Field, Rule: f.Rule, Message: p.Sprintf(f.Message, f.Args...)})
+	}
+	return Envelope{Error: b}
+}
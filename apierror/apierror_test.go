@@ -0,0 +1,98 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+type signup struct {
+	Email string `json:"email" binding:"required,email"`
+	Items []struct {
+		Quantity int `json:"quantity" binding:"required,min=1"`
+	} `json:"items" binding:"required,min=1,dive"`
+}
+
+func TestBindReportsJSONFieldPaths(t *testing.T) {
+	var body signup
+	err := binding.JSON.BindBody([]byte(`{"email":"nope","items":[{"quantity":-2}]}`), &body)
+
+	e := Bind(err)
+	if e.Status != 400 || e.Code != CodeValidationFailed {
+		t.Fatalf("error = %v", e)
+	}
+	got := map[string]string{}
+	for _, f := range e.Fields {
+		got[f.Field] = f.Rule
+	}
+	if got["email"] != "email" || got["items[0].quantity"] != "min" || len(got) != 2 {
+		t.Fatalf("fields = %+v", e.Fields)
+	}
+}
+
+func TestBindSeparatesSyntaxFromTypeErrors(t *testing.T) {
+	var body signup
+	if e := Bind(json.Unmarshal([]byte(`{"email":`), &body)); e.Code != CodeInvalidRequest || len(e.Fields) != 0 {
+		t.Fatalf("syntax error = %v", e)
+	}
+	if e := Bind(json.Unmarshal([]byte(`{"email":12}`), &body)); e.Code != CodeValidationFailed || e.Fields[0].Field != "email" {
+		t.Fatalf("type error = %v", e)
+	}
+}
+
+func TestRenderTranslatesMessageAndFields(t *testing.T) {
+	e := Conflict("slug", "table-basse")
+	fr := message.NewPrinter(language.French, message.Catalog(frenchCatalog(t,
+		"%s already exists: '%s'", "%s existe déjà : '%s'",
+		"%s is already taken", "%s est déjà utilisé",
+	)))
+
+	got := e.Render(fr, "req-1").Error
+	if got.Code != CodeConflict || got.Message != "slug existe déjà : 'table-basse'" || got.RequestID != "req-1" {
+		t.Fatalf("detail = %+v", got)
+	}
+	if len(got.Fields) != 1 || got.Fields[0].Message != "slug est déjà utilisé" || got.Fields[0].Rule != RuleUnique {
+		t.Fatalf("fields = %+v", got.Fields)
+	}
+
+	en := e.Render(message.NewPrinter(language.English), "").Error
+	if en.Message != "slug already exists: 'table-basse'" {
+		t.Fatalf("english = %+v", en)
+	}
+}
+
+func TestInternalHidesItsCause(t *testing.T) {
+	cause := errors.New("dial tcp 10.0.0.3:27017: connection refused")
+	e := From(cause)
+	if e.Status != 500 || e.Code != CodeInternal || !errors.Is(e, cause) {
+		t.Fatalf("error = %v", e)
+	}
+	if !strings.Contains(e.Error(), "connection refused") {
+		t.Fatalf("Error() drops the cause: %q", e.Error())
+	}
+	if detail := e.Render(message.NewPrinter(language.English), "").Error; strings.Contains(detail.Message, "refused") {
+		t.Fatalf("rendered cause: %+v", detail)
+	}
+
+	wrapped := From(errors.Join(errors.New("context"), NotFound("product not found")))
+	if wrapped.Code != CodeNotFound {
+		t.Fatalf("From unwraps = %v", wrapped)
+	}
+}
+
+func frenchCatalog(t *testing.T, pairs ...string) *catalog.Builder {
+	t.Helper()
+	b := catalog.NewBuilder(catalog.Fallback(language.English))
+	for i := 0; i < len(pairs); i += 2 {
+		if err := b.SetString(language.French, pairs[i], pairs[i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return b
+}
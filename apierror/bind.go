@@ -0,0 +1,94 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// report fields by their JSON name ("fullName"), as clients send them
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// Bind converts the error of c.ShouldBindJSON (or of json.Unmarshal on a
+// multipart "data" field) into a 400: validation_failed with one detail per
+// failed rule, invalid_request when the JSON itself is unreadable. The
+// decoder's message is kept as the cause only.
+func Bind(err error) *Error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return Invalid(typeErr.Field, RuleInvalid, "%s has the wrong type", typeErr.Field).Wrap(err)
+		}
+		return BadRequest("invalid JSON").Wrap(err)
+	}
+
+	e := Validation()
+	for _, fe := range verrs {
+		field := fieldPath(fe.Namespace())
+		message, args := ruleMessage(field, fe)
+		e.WithField(field, fe.Tag(), message, args...)
+	}
+	return e
+}
+
+// fieldPath drops the struct name validator puts first:
+// "CreateQuoteRequestDTO.items[0].quantity" → "items[0].quantity".
+func fieldPath(namespace string) string {
+	if _, rest, ok := strings.Cut(namespace, "."); ok {
+		return rest
+	}
+	return namespace
+}
+
+func ruleMessage(field string, fe validator.FieldError) (string, []any) {
+	countable := false
+	switch fe.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		countable = true
+	}
+	switch fe.Tag() {
+	case "required":
+		return "%s is required", []any{field}
+	case "email":
+		return "%s must be a valid email address", []any{field}
+	case "min":
+		if fe.Kind() == reflect.String {
+			return "%s must be at least %s characters long", []any{field, fe.Param()}
+		}
+		if countable {
+			return "%s must contain at least %s items", []any{field, fe.Param()}
+		}
+		return "%s must be at least %s", []any{field, fe.Param()}
+	case "max":
+		if fe.Kind() == reflect.String {
+			return "%s must be at most %s characters long", []any{field, fe.Param()}
+		}
+		if countable {
+			return "%s must contain at most %s items", []any{field, fe.Param()}
+		}
+		return "%s must be at most %s", []any{field, fe.Param()}
+	case "gt":
+		return "%s must be greater than %s", []any{field, fe.Param()}
+	case "gte":
+		return "%s must be at least %s", []any{field, fe.Param()}
+	case "oneof":
+		return "%s must be one of: %s", []any{field, fe.Param()}
+	default:
+		return "%s is invalid", []any{field}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestLoginRefreshAndAdminAccess(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedAdmin(testAdminEmail, testAdminPassword)
+
+	// wrong password
+	w := e.do(e.jsonRequest(http.MethodPost, "/auth/login", gin.H{"email": testAdminEmail, "password": "nope"}, ""))
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	// unknown user
+	w = e.do(e.jsonRequest(http.MethodPost, "/auth/login", gin.H{"email": "ghost@saho.test", "password": "whatever"}, ""))
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	// malformed body
+	w = e.do(e.jsonRequest(http.MethodPost, "/auth/login", gin.H{"email": "not-an-email"}, ""))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	accessToken, cookie := e.login(testAdminEmail, testAdminPassword)
+	if accessToken == "" {
+		t.Fatal("login returned no access token")
+	}
+	if cookie == nil || cookie.Value == "" || !cookie.HttpOnly {
+		t.Fatalf("login did not set an HttpOnly refresh cookie: %+v", cookie)
+	}
+
+	// a second session opened within the same second gets its own token
+	// (refresh_tokens.tokenHash is unique)
+	_, second := e.login(testAdminEmail, testAdminPassword)
+	if second == nil || second.Value == cookie.Value {
+		t.Fatalf("second login reused the refresh token: %+v", second)
+	}
+
+	// protected routes need a valid bearer token
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/quote-requests", nil, ""))
+	expectStatus(t, w, http.StatusUnauthorized)
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/quote-requests", nil, "garbage"))
+	expectStatus(t, w, http.StatusUnauthorized)
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/quote-requests", nil, accessToken))
+	expectStatus(t, w, http.StatusOK)
+
+	// refresh without cookie
+	w = e.do(httptest.NewRequest(http.MethodPost, "/auth/refresh", nil))
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	// refresh rotates the cookie and issues a working access token
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	req.AddCookie(cookie)
+	w = e.do(req)
+	expectStatus(t, w, http.StatusOK)
+	refreshed := decodeJSON[map[string]string](t, w)["accessToken"]
+	if refreshed == "" {
+		t.Fatal("refresh returned no access token")
+	}
+	rotated := refreshCookie(w)
+	if rotated == nil || rotated.Value == "" || rotated.Value == cookie.Value {
+		t.Fatalf("refresh did not rotate the cookie: %+v", rotated)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/product-requests", nil, refreshed))
+	expectStatus(t, w, http.StatusOK)
+
+	// the old refresh token is revoked after rotation
+	req = httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	req.AddCookie(cookie)
+	w = e.do(req)
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	// the rotated one keeps working
+	req = httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	req.AddCookie(rotated)
+	w = e.do(req)
+	expectStatus(t, w, http.StatusOK)
+}
+
+func TestLoginRejectsDisabledAccount(t *testing.T) {
+	e := newTestEnv(t)
+	user := e.seedAdmin(testAdminEmail, testAdminPassword)
+	if err := e.app.Users.Update(t.Context(), user.ID, bson.M{"isActive": false}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := e.do(e.jsonRequest(http.MethodPost, "/auth/login", gin.H{"email": testAdminEmail, "password": testAdminPassword}, ""))
+	expectStatus(t, w, http.StatusForbidden)
+}
+
+func TestLogoutRevokesTheRefreshToken(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedAdmin(testAdminEmail, testAdminPassword)
+	_, cookie := e.login(testAdminEmail, testAdminPassword)
+	if cookie.Path != "/auth" {
+		t.Fatalf("refresh cookie path = %q, logout would never receive it", cookie.Path)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	req.AddCookie(cookie)
+	w := e.do(req)
+	expectStatus(t, w, http.StatusOK)
+	if cleared := refreshCookie(w); cleared == nil || cleared.MaxAge >= 0 || cleared.Path != cookie.Path {
+		t.Fatalf("logout did not clear the cookie: %+v", cleared)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	req.AddCookie(cookie)
+	expectStatus(t, e.do(req), http.StatusUnauthorized)
+
+	// without a cookie there is nothing to revoke
+	expectStatus(t, e.do(httptest.NewRequest(http.MethodPost, "/auth/logout", nil)), http.StatusOK)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/upload"
+)
+
+type categoriesPage struct {
+	Items []models.Category `json:"items"`
+	Total int64             `json:"total"`
+}
+
+func TestCategoryLifecycle(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/categories",
+		gin.H{"name": "  Tabourets en bois ", "description": "Assises", "isActive": true},
+		[]testFile{{field: "image", name: "cover.png", content: pngBytes(t), mimeType: "image/png"}},
+		token))
+	expectStatus(t, w, http.StatusCreated)
+	id := decodeJSON[map[string]string](t, w)["id"]
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/categories/"+id, nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	cat := decodeJSON[models.Category](t, w)
+	if cat.Name != "Tabourets en bois" || cat.Slug != "tabourets-en-bois" || !cat.IsActive {
+		t.Fatalf("stored category = %+v", cat)
+	}
+	if cat.Image == nil || e.storedImage(*cat.Image) != 3 {
+		t.Fatalf("category image %+v was not uploaded; store has %v", cat.Image, e.storedObjects())
+	}
+	oldImage := *cat.Image
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/categories/slug/tabourets-en-bois", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeJSON[models.Category](t, w); got.Id != cat.Id {
+		t.Fatalf("slug lookup returned %+v", got)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/categories/slug/nope", nil, ""))
+	expectStatus(t, w, http.StatusNotFound)
+	w = e.do(e.jsonRequest(http.MethodGet, "/categories/not-an-id", nil, ""))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	// duplicate slug
+	e.seedCategory("Chaises", "chaises")
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/categories", gin.H{"name": "Chaises"}, nil, token))
+	expectStatus(t, w, http.StatusConflict)
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/categories", gin.H{"name": "   "}, nil, token))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	// the image must fit CATEGORY_IMAGE_MAX_MB and MAX_IMAGE_DIMENSION
+	e.app.Config.Uploads.MaxImageDimension = 2
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/categories", gin.H{"name": "Bancs"},
+		[]testFile{{field: "image", name: "grand.png", content: pngBytes(t), mimeType: "image/png"}}, token))
+	expectStatus(t, w, http.StatusBadRequest)
+	if f := decodeJSON[apierror.Envelope](t, w).Error.Fields; len(f) != 1 || f[0].Field != "image" || f[0].Rule != upload.RuleDimensions ||
+		f[0].Message != "grand.png dépasse 2 × 2 pixels" {
+		t.Fatalf("fields = %+v", f)
+	}
+	e.app.Config.Uploads.MaxImageDimension = 7000
+
+	// listing: sorted by name, q matches the name ignoring case and accents
+	w = e.do(e.jsonRequest(http.MethodGet, "/categories", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeJSON[categoriesPage](t, w); got.Total != 2 || got.Items[0].Name != "Chaises" {
+		t.Fatalf("listing = %+v", got)
+	}
+	for _, q := range []string{"TABOURET", "tabour%C3%A9t", "(x"} {
+		w = e.do(e.jsonRequest(http.MethodGet, "/categories?q="+q, nil, ""))
+		expectStatus(t, w, http.StatusOK)
+		want := int64(1)
+		if q == "(x" {
+			want = 0 // taken literally, not as a regular expression
+		}
+		if got := decodeJSON[categoriesPage](t, w); got.Total != want || (want == 1 && got.Items[0].Id != cat.Id) {
+			t.Fatalf("q=%s listing = %+v", q, got)
+		}
+	}
+
+	// update with a new image replaces the old object
+	w = e.do(e.multipartRequest(http.MethodPatch, "/admin/categories/"+id,
+		gin.H{"name": "Tabourets", "isActive": false},
+		[]testFile{{field: "image", name: "new.png", content: pngBytes(t), mimeType: "image/png"}},
+		token))
+	expectStatus(t, w, http.StatusOK)
+	w = e.do(e.jsonRequest(http.MethodGet, "/categories/"+id, nil, ""))
+	updated := decodeJSON[models.Category](t, w)
+	if updated.Name != "Tabourets" || updated.IsActive || updated.Image.URL == oldImage.URL {
+		t.Fatalf("updated category = %+v", updated)
+	}
+	if e.storedImage(oldImage) != 0 {
+		t.Fatalf("old image %+v still in the store", oldImage)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/categories?isActive=true", nil, ""))
+	if got := decodeJSON[categoriesPage](t, w); got.Total != 1 || got.Items[0].Name != "Chaises" {
+		t.Fatalf("isActive listing = %+v", got)
+	}
+
+	// slug conflicts on update
+	w = e.do(e.multipartRequest(http.MethodPatch, "/admin/categories/"+id, gin.H{"slug": "chaises"}, nil, token))
+	expectStatus(t, w, http.StatusConflict)
+	w = e.do(e.multipartRequest(http.MethodPatch, "/admin/categories/"+id, gin.H{"name": ""}, nil, token))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	// delete removes the document and its image
+	w = e.do(e.jsonRequest(http.MethodDelete, "/admin/categories/"+id, nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if e.storedImage(*updated.Image) != 0 {
+		t.Fatalf("image %+v still in the store after delete", updated.Image)
+	}
+	w = e.do(e.jsonRequest(http.MethodGet, "/categories/"+id, nil, ""))
+	expectStatus(t, w, http.StatusNotFound)
+	w = e.do(e.jsonRequest(http.MethodDelete, "/admin/categories/"+id, nil, token))
+	expectStatus(t, w, http.StatusNotFound)
+}
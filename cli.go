@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/princinho/sahobackend/controllers"
+	"github.com/princinho/sahobackend/migrations"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const usage = `usage:
+  sahobackend                   start the API (runs pending migrations unless MIGRATE_ON_START=false)
+  sahobackend migrate [up]      apply pending migrations and exit
+  sahobackend migrate status    list migrations and when they were applied
+  sahobackend images migrate    encode the renditions of the images saved before they existed
+`
+
+// runMigrateCommand handles "migrate [up|status]".
+func runMigrateCommand(ctx context.Context, db *mongo.Database, args []string, out io.Writer) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "up":
+		ran, err := migrations.Run(ctx, db, migrations.All)
+		for _, m := range ran {
+			fmt.Fprintf(out, "applied %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Fprintln(out, "schema is up to date")
+		}
+		return nil
+	case "status":
+		statuses, err := migrations.List(ctx, db, migrations.All)
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, st := range statuses {
+			applied := "pending"
+			if st.Applied != nil {
+				applied = st.Applied.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", st.Version, st.Name, applied)
+		}
+		_ = w.Flush()
+		return err
+	default:
+		return fmt.Errorf("unknown migrate action %q\n%s", action, usage)
+	}
+}
+
+// runImagesCommand handles "images migrate".
+func runImagesCommand(ctx context.Context, app *controllers.App, args []string, out io.Writer) error {
+	if len(args) != 1 || args[0] != "migrate" {
+		return fmt.Errorf("unknown images action %q\n%s", strings.Join(args, " "), usage)
+	}
+	encoded, failed, err := app.MigrateImages(ctx)
+	fmt.Fprintf(out, "encoded %d images\n", encoded)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d images could not be encoded, see the log", failed)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+// Package config loads the application settings once at startup. Values come
+// from, in increasing priority: the `default` tags below, an optional YAML or
+// TOML file named by CONFIG_FILE, and the environment (a .env file is loaded
+// into it first when present). Keys in the file are the environment variable
+// names, so a setting is spelled the same way everywhere.
+package config
+
+import (
+	"time"
+
+	"github.com/princinho/sahobackend/storage"
+	"github.com/princinho/sahobackend/tracing"
+	"github.com/princinho/sahobackend/upload"
+)
+
+// FileEnv names the optional configuration file.
+const FileEnv = "CONFIG_FILE"
+
+// Config is the effective configuration. Every leaf field carries:
+//   - env:      the variable (and file key) it is read from
+//   - default:  the value used when neither the file nor the env set it
+//   - required: "true" when startup must fail without a value
+//   - secret:   "true" when Redacted must hide it
+type Config struct {
+	Server    Server    `json:"server"`
+	Log       Log       `json:"log"`
+	Tracing   Tracing   `json:"tracing"`
+	Mongo     Mongo     `json:"mongo"`
+	Auth      Auth      `json:"auth"`
+	Admin     Admin     `json:"admin"`
+	Storage   Storage   `json:"storage"`
+	Uploads   Uploads   `json:"uploads"`
+	Query     Query     `json:"query"`
+	Catalog   Catalog   `json:"catalog"`
+	Inventory Inventory `json:"inventory"`
+	Import    Import    `json:"import"`
+	Trash     Trash     `json:"trash"`
+	Metrics   Metrics   `json:"metrics"`
+}
+
+type Server struct {
+	Port           string   `env:"PORT" default:"8080" json:"port"`
+	AllowedOrigins []string `env:"ALLOWED_ORIGINS" json:"allowedOrigins"`
+	MigrateOnStart bool     `env:"MIGRATE_ON_START" default:"true" json:"migrateOnStart"`
+
+	// Timeouts of the http.Server. Reads and writes are generous enough for
+	// multipart uploads of several images.
+	ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" default:"5s" json:"readHeaderTimeout"`
+	ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" default:"30s" json:"readTimeout"`
+	WriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"60s" json:"writeTimeout"`
+	IdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"120s" json:"idleTimeout"`
+	// ShutdownTimeout bounds the drain of in-flight requests on SIGTERM.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" default:"20s" json:"shutdownTimeout"`
+	// ReadinessTimeout bounds each dependency check of GET /readyz.
+	ReadinessTimeout time.Duration `env:"READINESS_TIMEOUT" default:"3s" json:"readinessTimeout"`
+}
+
+// Log selects the verbosity and encoding of the process logs.
+type Log struct {
+	Level  string `env:"LOG_LEVEL" default:"info" json:"level"`
+	Format string `env:"LOG_FORMAT" default:"json" json:"format"`
+}
+
+// Tracing selects where OpenTelemetry spans go. The OTLP exporter also
+// honours the standard OTEL_EXPORTER_OTLP_* variables (headers, timeouts).
+type Tracing struct {
+	Exporter     string  `env:"TRACING_EXPORTER" default:"none" json:"exporter"`
+	OTLPEndpoint string  `env:"TRACING_OTLP_ENDPOINT" json:"otlpEndpoint"`
+	OTLPInsecure bool    `env:"TRACING_OTLP_INSECURE" json:"otlpInsecure"`
+	ServiceName  string  `env:"TRACING_SERVICE_NAME" default:"sahobackend" json:"serviceName"`
+	SampleRatio  float64 `env:"TRACING_SAMPLE_RATIO" default:"1" json:"sampleRatio"`
+}
+
+// Options converts the section into the tracing package's settings.
+func (t Tracing) Options() tracing.Options {
+	return tracing.Options{
+		Exporter:     t.Exporter,
+		OTLPEndpoint: t.OTLPEndpoint,
+		OTLPInsecure: t.OTLPInsecure,
+		ServiceName:  t.ServiceName,
+		SampleRatio:  t.SampleRatio,
+	}
+}
+
+type Mongo struct {
+	URI      string `env:"MONGODB_URI" required:"true" secret:"true" json:"uri"`
+	Database string `env:"DATABASE_NAME" required:"true" json:"database"`
+}
+
+type Auth struct {
+	JWTSecret             string `env:"JWT_SECRET" required:"true" secret:"true" json:"jwtSecret"`
+	JWTRefreshSecret      string `env:"JWT_REFRESH_SECRET" required:"true" secret:"true" json:"jwtRefreshSecret"`
+	AccessTokenTTLMinutes int    `env:"ACCESS_TOKEN_TTL_MINUTES" default:"15" json:"accessTokenTtlMinutes"`
+	RefreshTokenTTLDays   int    `env:"REFRESH_TOKEN_TTL_DAYS" default:"14" json:"refreshTokenTtlDays"`
+	CookieSecure          bool   `env:"COOKIE_SECURE" default:"true" json:"cookieSecure"`
+	CookieDomain          string `env:"COOKIE_DOMAIN" json:"cookieDomain"`
+}
+
+func (a Auth) AccessTTL() time.Duration {
+	return time.Duration(a.AccessTokenTTLMinutes) * time.Minute
+}
+
+func (a Auth) RefreshTTL() time.Duration {
+	return time.Duration(a.RefreshTokenTTLDays) * 24 * time.Hour
+}
+
+// Admin is the account seeded at startup.
+type Admin struct {
+	Email    string `env:"ADMIN_EMAIL" required:"true" json:"email"`
+	Password string `env:"ADMIN_PASSWORD" required:"true" secret:"true" json:"password"`
+}
+
+type Storage struct {
+	Driver string `env:"STORAGE_DRIVER" default:"r2" json:"driver"`
+	R2     R2     `json:"r2"`
+	GCS    GCS    `json:"gcs"`
+	Local  Local  `json:"local"`
+}
+
+type R2 struct {
+	Bucket          string `env:"R2_BUCKET" json:"bucket"`
+	AccessKeyID     string `env:"R2_ACCESS_KEY_ID" json:"accessKeyId"`
+	SecretAccessKey string `env:"R2_SECRET_ACCESS_KEY" secret:"true" json:"secretAccessKey"`
+	Endpoint        string `env:"R2_ENDPOINT" json:"endpoint"`
+	PublicDomain    string `env:"R2_PUBLIC_DOMAIN" json:"publicDomain"`
+}
+
+type GCS struct {
+	Bucket          string `env:"GCS_BUCKET" json:"bucket"`
+	CredentialsFile string `env:"CREDENTIALS_FILE_LOCATION" json:"credentialsFile"`
+}
+
+type Local struct {
+	Dir     string `env:"LOCAL_STORAGE_DIR" default:"uploads" json:"dir"`
+	BaseURL string `env:"LOCAL_STORAGE_BASE_URL" default:"http://localhost:8080/media" json:"baseUrl"`
+}
+
+// Options converts the section into the storage package's settings.
+func (s Storage) Options() storage.Config {
+	return storage.Config{
+		Driver: s.Driver,
+		R2: storage.R2Config{
+			Bucket:          s.R2.Bucket,
+			AccessKeyID:     s.R2.AccessKeyID,
+			SecretAccessKey: s.R2.SecretAccessKey,
+			Endpoint:        s.R2.Endpoint,
+			PublicDomain:    s.R2.PublicDomain,
+		},
+		GCS:   storage.GCSConfig{Bucket: s.GCS.Bucket, CredentialsFile: s.GCS.CredentialsFile},
+		Local: storage.LocalConfig{Dir: s.Local.Dir, BaseURL: s.Local.BaseURL},
+	}
+}
+
+// Uploads bounds what clients may attach, per kind of upload (see Policy).
+// AllowedFileMimeTypes and MaxUploadSizeMB apply to the files attached to
+// product requests; MaxImageDimension to the width and the height of every
+// picture. ImageQuality is the WebP quality of the stored image renditions.
+type Uploads struct {
+	AllowedFileMimeTypes []string `env:"ALLOWED_FILE_MIME_TYPES" default:"application/pdf,image/png,image/jpeg,image/webp" json:"allowedFileMimeTypes"`
+	MaxUploadSizeMB      int      `env:"MAX_UPLOAD_SIZE_MB" default:"5" json:"maxUploadSizeMb"`
+	MaxProductImages     int      `env:"MAX_PROD_IMAGES" default:"4" json:"maxProductImages"`
+	ProductImageMaxMB    int      `env:"PRODUCT_IMAGE_MAX_MB" default:"10" json:"productImageMaxMb"`
+	CategoryImageMaxMB   int      `env:"CATEGORY_IMAGE_MAX_MB" default:"5" json:"categoryImageMaxMb"`
+	QuotePDFMaxMB        int      `env:"QUOTE_PDF_MAX_MB" default:"10" json:"quotePdfMaxMb"`
+	MaxImageDimension    int      `env:"MAX_IMAGE_DIMENSION" default:"7000" json:"maxImageDimension"`
+	ImageQuality         int      `env:"IMAGE_QUALITY" default:"80" json:"imageQuality"`
+}
+
+// Policy is what an upload of kind accepts.
+func (u Uploads) Policy(kind upload.Kind) upload.Policy {
+	images := []string{upload.PNG, upload.JPEG, upload.WebP}
+	p := upload.Policy{MaxWidth: u.MaxImageDimension, MaxHeight: u.MaxImageDimension}
+	switch kind {
+	case upload.ProductImage:
+		p.Types, p.MaxBytes = images, megabytes(u.ProductImageMaxMB)
+	case upload.CategoryImage:
+		p.Types, p.MaxBytes = images, megabytes(u.CategoryImageMaxMB)
+	case upload.QuotePDF:
+		p.Types, p.MaxBytes = []string{upload.PDF}, megabytes(u.QuotePDFMaxMB)
+	case upload.RequestAttachment:
+		p.Types, p.MaxBytes = u.AllowedFileMimeTypes, megabytes(u.MaxUploadSizeMB)
+	}
+	return p
+}
+
+func megabytes(n int) int64 { return int64(n) << 20 }
+
+// Query bounds the page size of list endpoints.
+type Query struct {
+	MaxLimit     int `env:"READ_QUERY_MAX_LIMIT" default:"100" json:"maxLimit"`
+	DefaultLimit int `env:"DEFAULT_READ_QUERY_LIMIT" default:"20" json:"defaultLimit"`
+}
+
+// Catalog shapes the storefront listing. PriceFacetBounds splits prices, in
+// whole francs, into the buckets counted by GET /products: below the first
+// bound, between consecutive bounds, and from the last bound up. ScheduleInterval is how
+// often scheduled products are published and expired ones archived; the
+// shop hides them on time either way. DefaultLocale is the language of the
+// product and category fields themselves; the other languages are stored as
+// translations, and content missing from one falls back to it.
+type Catalog struct {
+	PriceFacetBounds []int64       `env:"PRICE_FACET_BOUNDS" default:"25000,50000,100000,250000,500000" json:"priceFacetBounds"`
+	ScheduleInterval time.Duration `env:"PUBLISH_SCHEDULE_INTERVAL" default:"1m" json:"scheduleInterval"`
+	DefaultLocale    string        `env:"CATALOG_DEFAULT_LOCALE" default:"fr" json:"defaultLocale"`
+}
+
+// Inventory sets when GET /admin/products/low-stock lists a product: at or
+// below LowStockThreshold units (per variant for products with variants).
+type Inventory struct {
+	LowStockThreshold int `env:"LOW_STOCK_THRESHOLD" default:"5" json:"lowStockThreshold"`
+}
+
+// Import bounds POST /admin/products/import. Rows may name their images by
+// a path under ImagesDir; with no ImagesDir, only the images of the uploaded
+// archive are found.
+type Import struct {
+	ImagesDir string `env:"IMPORT_IMAGES_DIR" json:"imagesDir"`
+	MaxRows   int    `env:"IMPORT_MAX_ROWS" default:"1000" json:"maxRows"`
+}
+
+// Trash bounds how long deleted products can be restored. A zero retention
+// keeps them until an admin deletes them permanently.
+type Trash struct {
+	Retention     time.Duration `env:"TRASH_RETENTION" default:"720h" json:"retention"`
+	PurgeInterval time.Duration `env:"TRASH_PURGE_INTERVAL" default:"1h" json:"purgeInterval"`
+}
+
+// Metrics protects GET /metrics. Without a token the endpoint is open and
+// must only be reachable from the scraper's network.
+type Metrics struct {
+	Token string `env:"METRICS_TOKEN" secret:"true" json:"token"`
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func required() map[string]string {
+	return map[string]string{
+		"MONGODB_URI":        "mongodb://localhost:27017",
+		"DATABASE_NAME":      "saho",
+		"JWT_SECRET":         "access",
+		"JWT_REFRESH_SECRET": "refresh",
+		"ADMIN_EMAIL":        "admin@saho.test",
+		"ADMIN_PASSWORD":     "password",
+		"STORAGE_DRIVER":     "local",
+	}
+}
+
+func TestDefaultsParse(t *testing.T) {
+	for _, f := range fields(&Config{}) {
+		if def, ok := f.tag.Lookup("default"); ok {
+			if err := f.set(def); err != nil {
+				t.Errorf("%s default %q: %v", f.env, def, err)
+			}
+		}
+	}
+}
+
+func TestLoadListsEveryMissingSetting(t *testing.T) {
+	_, err := load(env(map[string]string{}))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("load = %v, want *ValidationError", err)
+	}
+	want := []string{
+		"ADMIN_EMAIL", "ADMIN_PASSWORD", "DATABASE_NAME", "JWT_REFRESH_SECRET", "JWT_SECRET", "MONGODB_URI",
+		"R2_ACCESS_KEY_ID", "R2_BUCKET", "R2_ENDPOINT", "R2_SECRET_ACCESS_KEY",
+	}
+	if !reflect.DeepEqual(verr.Missing, want) {
+		t.Fatalf("missing = %v, want %v", verr.Missing, want)
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	vars := required()
+	vars["MAX_PROD_IMAGES"] = "four"
+	vars["COOKIE_SECURE"] = "maybe"
+	vars["DEFAULT_READ_QUERY_LIMIT"] = "500"
+	vars["STORAGE_DRIVER"] = "ftp"
+	vars["LOG_LEVEL"] = "verbose"
+	vars["LOG_FORMAT"] = "xml"
+	vars["TRACING_EXPORTER"] = "zipkin"
+	vars["TRACING_SAMPLE_RATIO"] = "1.5"
+	vars["PRICE_FACET_BOUNDS"] = "5000,1000"
+	vars["LOW_STOCK_THRESHOLD"] = "-1"
+	vars["CATALOG_DEFAULT_LOCALE"] = "de"
+	vars["ALLOWED_FILE_MIME_TYPES"] = "application/pdf,image/gif"
+	vars["IMAGE_QUALITY"] = "0"
+
+	_, err := load(env(vars))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Invalid) != 13 {
+		t.Fatalf("load = %v, want 13 invalid settings", err)
+	}
+}
+
+func TestLoadAppliesDefaultsAndEnv(t *testing.T) {
+	vars := required()
+	vars["ALLOWED_ORIGINS"] = "https://saho.tg, http://localhost:3000,"
+	vars["ACCESS_TOKEN_TTL_MINUTES"] = "30"
+	vars["COOKIE_SECURE"] = "false"
+	vars["PRICE_FACET_BOUNDS"] = "10000, 20000"
+
+	cfg, err := load(env(vars))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.Server.AllowedOrigins, []string{"https://saho.tg", "http://localhost:3000"}) {
+		t.Fatalf("origins = %q", cfg.Server.AllowedOrigins)
+	}
+	if !reflect.DeepEqual(cfg.Catalog.PriceFacetBounds, []int64{10000, 20000}) {
+		t.Fatalf("price bounds = %v", cfg.Catalog.PriceFacetBounds)
+	}
+	if cfg.Auth.AccessTokenTTLMinutes != 30 || cfg.Auth.CookieSecure || cfg.Auth.RefreshTokenTTLDays != 14 {
+		t.Fatalf("auth = %+v", cfg.Auth)
+	}
+	if cfg.Server.Port != "8080" || !cfg.Server.MigrateOnStart || cfg.Query.MaxLimit != 100 || cfg.Uploads.MaxProductImages != 4 || cfg.Uploads.ImageQuality != 80 {
+		t.Fatalf("defaults not applied: %+v", cfg)
+	}
+}
+
+func TestLoadReadsConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"saho.yaml": "DATABASE_NAME: from-file\nMAX_PROD_IMAGES: 6\nALLOWED_ORIGINS:\n  - https://a.test\n  - https://b.test\n",
+		"saho.toml": "DATABASE_NAME = \"from-file\"\nMAX_PROD_IMAGES = 6\nALLOWED_ORIGINS = [\"https://a.test\", \"https://b.test\"]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		vars := required()
+		delete(vars, "DATABASE_NAME")
+		vars["MAX_PROD_IMAGES"] = "8" // the environment wins over the file
+		vars[FileEnv] = path
+
+		cfg, err := load(env(vars))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if cfg.Mongo.Database != "from-file" || cfg.Uploads.MaxProductImages != 8 || len(cfg.Server.AllowedOrigins) != 2 {
+			t.Fatalf("%s: mongo = %+v, uploads = %+v, origins = %v", name, cfg.Mongo, cfg.Uploads, cfg.Server.AllowedOrigins)
+		}
+	}
+
+	vars := required()
+	vars[FileEnv] = filepath.Join(dir, "saho.ini")
+	if _, err := load(env(vars)); err == nil {
+		t.Fatal("load accepted an unsupported file type")
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	vars := required()
+	vars["STORAGE_DRIVER"] = "r2"
+	vars["R2_BUCKET"] = "saho"
+	vars["R2_ACCESS_KEY_ID"] = "key"
+	vars["R2_SECRET_ACCESS_KEY"] = "very-secret"
+	vars["R2_ENDPOINT"] = "https://r2.test"
+	cfg, err := load(env(vars))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	view := cfg.Redacted()
+	r2 := view["storage"].(map[string]any)["r2"].(map[string]any)
+	if r2["secretAccessKey"] != redactedValue || r2["bucket"] != "saho" {
+		t.Fatalf("r2 = %v", r2)
+	}
+	if got := view["mongo"].(map[string]any)["uri"]; got != redactedValue {
+		t.Fatalf("mongo uri = %v", got)
+	}
+	if got := view["auth"].(map[string]any)["cookieDomain"]; got != "" {
+		t.Fatalf("cookieDomain = %v", got)
+	}
+}
@@ -0,0 +1,378 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/princinho/sahobackend/i18n"
+	"github.com/princinho/sahobackend/logging"
+	"github.com/princinho/sahobackend/storage"
+	"github.com/princinho/sahobackend/tracing"
+	"github.com/princinho/sahobackend/upload"
+)
+
+// ValidationError lists every setting that kept the configuration from
+// loading, so a deploy can be fixed in one pass.
+type ValidationError struct {
+	Missing []string
+	Invalid []string
+}
+
+func (e *ValidationError) Error() string {
+	parts := []string{}
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing required settings: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Invalid) > 0 {
+		parts = append(parts, "invalid settings: "+strings.Join(e.Invalid, "; "))
+	}
+	return "config: " + strings.Join(parts, "; ")
+}
+
+// Load reads .env when present, then CONFIG_FILE and the environment.
+func Load() (*Config, error) {
+	_ = godotenv.Load()
+	return load(os.LookupEnv)
+}
+
+// Defaults is the configuration with only the `default` tags applied.
+// Tests start from it and fill in what they need.
+func Defaults() *Config {
+	cfg := &Config{}
+	for _, f := range fields(cfg) {
+		if def, ok := f.tag.Lookup("default"); ok {
+			// defaults are constants; a bad one is caught by TestDefaultsParse
+			_ = f.set(def)
+		}
+	}
+	return cfg
+}
+
+func load(lookup func(string) (string, bool)) (*Config, error) {
+	cfg := Defaults()
+	verr := &ValidationError{}
+
+	file := map[string]string{}
+	if path, ok := lookup(FileEnv); ok && path != "" {
+		var err error
+		if file, err = readFile(path); err != nil {
+			return nil, fmt.Errorf("config: %s: %w", path, err)
+		}
+	}
+
+	for _, f := range fields(cfg) {
+		raw, ok := lookup(f.env)
+		if !ok {
+			raw, ok = file[f.env]
+		}
+		if !ok {
+			continue
+		}
+		if err := f.set(raw); err != nil {
+			verr.Invalid = append(verr.Invalid, fmt.Sprintf("%s: %v", f.env, err))
+		}
+	}
+
+	cfg.validate(verr)
+	if len(verr.Missing) > 0 || len(verr.Invalid) > 0 {
+		return nil, verr
+	}
+	return cfg, nil
+}
+
+// validate checks the required tags plus the rules that depend on other
+// values (the selected storage driver, numeric ranges).
+func (c *Config) validate(verr *ValidationError) {
+	for _, f := range fields(c) {
+		if f.tag.Get("required") == "true" && f.isZero() {
+			verr.Missing = append(verr.Missing, f.env)
+		}
+	}
+
+	switch c.Storage.Driver {
+	case storage.DriverR2:
+		requireAll(verr, map[string]string{
+			"R2_BUCKET":            c.Storage.R2.Bucket,
+			"R2_ACCESS_KEY_ID":     c.Storage.R2.AccessKeyID,
+			"R2_SECRET_ACCESS_KEY": c.Storage.R2.SecretAccessKey,
+			"R2_ENDPOINT":          c.Storage.R2.Endpoint,
+		})
+	case storage.DriverGCS:
+		requireAll(verr, map[string]string{
+			"GCS_BUCKET":                c.Storage.GCS.Bucket,
+			"CREDENTIALS_FILE_LOCATION": c.Storage.GCS.CredentialsFile,
+		})
+	case storage.DriverLocal:
+	default:
+		verr.Invalid = append(verr.Invalid, fmt.Sprintf("STORAGE_DRIVER: %q is not one of %s, %s, %s",
+			c.Storage.Driver, storage.DriverR2, storage.DriverGCS, storage.DriverLocal))
+	}
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		verr.Invalid = append(verr.Invalid, "LOG_LEVEL: "+err.Error())
+	}
+	if f := strings.ToLower(c.Log.Format); f != logging.FormatJSON && f != logging.FormatText {
+		verr.Invalid = append(verr.Invalid, fmt.Sprintf("LOG_FORMAT: %q is not one of %s, %s", c.Log.Format, logging.FormatJSON, logging.FormatText))
+	}
+
+	positive := map[string]int{
+		"ACCESS_TOKEN_TTL_MINUTES": c.Auth.AccessTokenTTLMinutes,
+		"REFRESH_TOKEN_TTL_DAYS":   c.Auth.RefreshTokenTTLDays,
+		"MAX_UPLOAD_SIZE_MB":       c.Uploads.MaxUploadSizeMB,
+		"MAX_PROD_IMAGES":          c.Uploads.MaxProductImages,
+		"PRODUCT_IMAGE_MAX_MB":     c.Uploads.ProductImageMaxMB,
+		"CATEGORY_IMAGE_MAX_MB":    c.Uploads.CategoryImageMaxMB,
+		"QUOTE_PDF_MAX_MB":         c.Uploads.QuotePDFMaxMB,
+		"MAX_IMAGE_DIMENSION":      c.Uploads.MaxImageDimension,
+		"READ_QUERY_MAX_LIMIT":     c.Query.MaxLimit,
+		"DEFAULT_READ_QUERY_LIMIT": c.Query.DefaultLimit,
+		"IMPORT_MAX_ROWS":          c.Import.MaxRows,
+	}
+	for _, name := range sortedKeys(positive) {
+		if positive[name] <= 0 {
+			verr.Invalid = append(verr.Invalid, name+": must be greater than 0")
+		}
+	}
+	if q := c.Uploads.ImageQuality; q < 1 || q > 100 {
+		verr.Invalid = append(verr.Invalid, "IMAGE_QUALITY: must be between 1 and 100")
+	}
+	for _, t := range c.Uploads.AllowedFileMimeTypes {
+		if _, ok := upload.Extensions[t]; !ok {
+			verr.Invalid = append(verr.Invalid, fmt.Sprintf("ALLOWED_FILE_MIME_TYPES: %q is not one of %s, %s, %s, %s",
+				t, upload.PDF, upload.PNG, upload.JPEG, upload.WebP))
+		}
+	}
+	timeouts := map[string]time.Duration{
+		"HTTP_READ_HEADER_TIMEOUT": c.Server.ReadHeaderTimeout,
+		"HTTP_READ_TIMEOUT":        c.Server.ReadTimeout,
+		"HTTP_WRITE_TIMEOUT":       c.Server.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":        c.Server.IdleTimeout,
+		"SHUTDOWN_TIMEOUT":         c.Server.ShutdownTimeout,
+		"READINESS_TIMEOUT":        c.Server.ReadinessTimeout,
+	}
+	for _, name := range sortedKeys(timeouts) {
+		if timeouts[name] <= 0 {
+			verr.Invalid = append(verr.Invalid, name+": must be greater than 0")
+		}
+	}
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		verr.Invalid = append(verr.Invalid, fmt.Sprintf("TRACING_EXPORTER: %q is not one of %s, %s, %s",
+			c.Tracing.Exporter, tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP))
+	}
+	if r := c.Tracing.SampleRatio; r < 0 || r > 1 {
+		verr.Invalid = append(verr.Invalid, "TRACING_SAMPLE_RATIO: must be between 0 and 1")
+	}
+	if c.Query.DefaultLimit > c.Query.MaxLimit {
+		verr.Invalid = append(verr.Invalid, "DEFAULT_READ_QUERY_LIMIT: must not exceed READ_QUERY_MAX_LIMIT")
+	}
+	if c.Inventory.LowStockThreshold < 0 {
+		verr.Invalid = append(verr.Invalid, "LOW_STOCK_THRESHOLD: must not be negative")
+	}
+	for i, bound := range c.Catalog.PriceFacetBounds {
+		if bound <= 0 || (i > 0 && bound <= c.Catalog.PriceFacetBounds[i-1]) {
+			verr.Invalid = append(verr.Invalid, "PRICE_FACET_BOUNDS: must be positive and strictly ascending")
+			break
+		}
+	}
+	if locales := i18n.Locales(); !slices.Contains(locales, c.Catalog.DefaultLocale) {
+		verr.Invalid = append(verr.Invalid, fmt.Sprintf("CATALOG_DEFAULT_LOCALE: %q is not one of %s", c.Catalog.DefaultLocale, strings.Join(locales, ", ")))
+	}
+
+	sort.Strings(verr.Missing)
+}
+
+func requireAll(verr *ValidationError, values map[string]string) {
+	for _, name := range sortedKeys(values) {
+		if values[name] == "" {
+			verr.Missing = append(verr.Missing, name)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readFile parses a flat YAML or TOML document whose keys are environment
+// variable names. Lists become comma-separated values.
+func readFile(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &doc)
+	case ".toml":
+		err = toml.Unmarshal(raw, &doc)
+	default:
+		return nil, fmt.Errorf("unsupported config file type (use .yaml, .yml or .toml)")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string, len(doc))
+	for key, value := range doc {
+		switch v := value.(type) {
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			out[strings.ToUpper(key)] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("%s: nested tables are not supported, use the variable names as keys", key)
+		default:
+			out[strings.ToUpper(key)] = fmt.Sprint(v)
+		}
+	}
+	return out, nil
+}
+
+// ---- reflection over the tagged fields ----------------------------------------
+
+type field struct {
+	env   string
+	path  []string // json names from the root, for Redacted
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+// fields lists every leaf with an env tag, depth first in declaration order.
+func fields(cfg *Config) []field {
+	var out []field
+	var walk func(v reflect.Value, path []string)
+	walk = func(v reflect.Value, path []string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name := strings.Split(sf.Tag.Get("json"), ",")[0]
+			p := append(append([]string{}, path...), name)
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), p)
+				continue
+			}
+			if env := sf.Tag.Get("env"); env != "" {
+				out = append(out, field{env: env, path: p, tag: sf.Tag, value: v.Field(i)})
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), nil)
+	return out
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (f field) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if f.value.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration (e.g. 30s, 2m)", raw)
+		}
+		f.value.SetInt(int64(d))
+		return nil
+	}
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		f.value.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		f.value.SetInt(int64(n))
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		f.value.SetFloat(n)
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		if f.value.Type().Elem().Kind() != reflect.Int64 {
+			f.value.Set(reflect.ValueOf(items))
+			return nil
+		}
+		numbers := make([]int64, len(items))
+		for i, item := range items {
+			n, err := strconv.ParseInt(item, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%q is not an integer", item)
+			}
+			numbers[i] = n
+		}
+		f.value.Set(reflect.ValueOf(numbers))
+	default:
+		return fmt.Errorf("unsupported field kind %s", f.value.Kind())
+	}
+	return nil
+}
+
+func (f field) isZero() bool {
+	if f.value.Kind() == reflect.Slice {
+		return f.value.Len() == 0
+	}
+	return f.value.IsZero()
+}
+
+// redactedValue replaces secrets that are set; an empty secret stays empty
+// so the view still shows what is missing.
+const redactedValue = "[redacted]"
+
+// Redacted is the effective configuration as nested JSON-ready maps, with
+// every secret value hidden. It backs GET /admin/config.
+func (c *Config) Redacted() map[string]any {
+	root := map[string]any{}
+	for _, f := range fields(c) {
+		node := root
+		for _, name := range f.path[:len(f.path)-1] {
+			child, ok := node[name].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				node[name] = child
+			}
+			node = child
+		}
+		var value any = f.value.Interface()
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		if f.tag.Get("secret") == "true" && !f.isZero() {
+			value = redactedValue
+		}
+		node[f.path[len(f.path)-1]] = value
+	}
+	return root
+}
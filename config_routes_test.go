@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAdminConfigIsRedacted(t *testing.T) {
+	e := newTestEnv(t)
+
+	w := e.do(e.jsonRequest(http.MethodGet, "/admin/config", nil, ""))
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/config", nil, e.adminToken()))
+	expectStatus(t, w, http.StatusOK)
+
+	for _, secret := range []string{"test-access-secret", "test-refresh-secret", testAdminPassword, "mongodb://unused"} {
+		if strings.Contains(w.Body.String(), secret) {
+			t.Fatalf("config view leaks %q: %s", secret, w.Body.String())
+		}
+	}
+
+	view := decodeJSON[map[string]map[string]any](t, w)
+	if view["auth"]["jwtSecret"] != "[redacted]" || view["mongo"]["database"] != "saho_test" {
+		t.Fatalf("auth = %v, mongo = %v", view["auth"], view["mongo"])
+	}
+	if view["storage"]["driver"] != "local" || view["query"]["maxLimit"] != float64(100) {
+		t.Fatalf("storage = %v, query = %v", view["storage"], view["query"])
+	}
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/princinho/sahobackend/config"
+	"github.com/princinho/sahobackend/health"
+	"github.com/princinho/sahobackend/logging"
+	"github.com/princinho/sahobackend/metrics"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/storage"
+)
+
+// App carries the dependencies shared by every handler. It is built once in
+// main and its handler methods are registered on the router.
+type App struct {
+	Config *config.Config
+	// Logger is the process logger; handlers log through
+	// logging.FromContext, which adds the request ID and user ID to it.
+	Logger *slog.Logger
+
+	Products        repositories.ProductRepository
+	Categories      repositories.CategoryRepository
+	QuoteRequests   repositories.QuoteRequestRepository
+	ProductRequests repositories.ProductRequestRepository
+	Users           repositories.UserRepository
+	RefreshTokens   repositories.RefreshTokenRepository
+	StockMovements  repositories.StockMovementRepository
+	Revisions       repositories.ProductRevisionRepository
+	ExchangeRates   repositories.ExchangeRateRepository
+	Storage         storage.Storage
+
+	// Metrics counts business events (quote and product requests by status).
+	Metrics *metrics.Metrics
+
+	// Health holds the readiness checks (Mongo, storage).
+	Health *health.Checker
+}
+
+// cleanupURLs deletes stored files the database no longer references. A
+// failure only leaves an orphan object behind, so it is logged rather than
+// returned to the client.
+func (app *App) cleanupURLs(ctx context.Context, urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	if err := storage.DeleteURLs(ctx, app.Storage, urls); err != nil {
+		logging.FromContext(ctx).Warn("storage cleanup failed", "urls", urls, "error", err)
+	}
+}
+
+// cleanupImages is cleanupURLs for every file of the images.
+func (app *App) cleanupImages(ctx context.Context, images []models.Image) {
+	var urls []string
+	for _, img := range images {
+		urls = append(urls, img.URLs()...)
+	}
+	app.cleanupURLs(ctx, urls)
+}
@@ -1,184 +1,193 @@
 package controllers
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/princinho/sahobackend/database"
+	"github.com/princinho/sahobackend/apierror"
 	"github.com/princinho/sahobackend/dto"
 	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
 	"github.com/princinho/sahobackend/utils"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-func Login() gin.HandlerFunc {
+// Unknown email and wrong password get the same answer.
+func errInvalidCredentials() *apierror.Error {
+	return apierror.Unauthorized(apierror.CodeInvalidCredentials, "invalid email or password")
+}
+
+func errAccountDisabled() *apierror.Error {
+	return apierror.Forbidden(apierror.CodeAccountDisabled, "account disabled")
+}
+
+func (app *App) Login() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var dto dto.LoginDTO
 		if err := c.ShouldBindJSON(&dto); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 
-		var user models.User
-		usersCol := database.OpenCollection("users")
-		if err := usersCol.FindOne(c, bson.M{"email": dto.Email}).Decode(&user); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		user, err := app.Users.FindByEmail(c.Request.Context(), dto.Email)
+		if err != nil {
+			if !errors.Is(err, repositories.ErrNotFound) {
+				apierror.Abort(c, apierror.Internal(err))
+				return
+			}
+			apierror.Abort(c, errInvalidCredentials())
 			return
 		}
 
 		if err := utils.CheckPassword(user.PasswordHash, dto.Password); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			apierror.Abort(c, errInvalidCredentials())
 			return
 		}
 
 		if !user.IsActive {
-			c.JSON(http.StatusForbidden, gin.H{"error": "account disabled"})
+			apierror.Abort(c, errAccountDisabled())
 			return
 		}
 
-		accessToken, _ := utils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.Role), utils.AccessTTL())
-		refreshToken, _ := utils.GenerateRefreshToken(user.ID.Hex())
+		auth := app.Config.Auth
+		accessToken, _ := utils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.Role), auth.AccessTTL(), auth.JWTSecret)
+		refreshToken, _ := utils.GenerateRefreshToken(user.ID.Hex(), auth.RefreshTTL(), auth.JWTRefreshSecret)
 
-		refreshTokensCol := database.OpenCollection("refresh_tokens")
-		result, err := refreshTokensCol.InsertOne(c, models.RefreshToken{
+		err = app.RefreshTokens.Insert(c.Request.Context(), &models.RefreshToken{
 			UserID:     user.ID,
 			TokenHash:  refreshToken,
-			ExpiresAt:  time.Now().Add(time.Hour * 24 * 30),
+			ExpiresAt:  time.Now().Add(auth.RefreshTTL()),
 			CreatedAt:  time.Now(),
 			RevokedAt:  nil,
 			ReplacedBy: nil,
 		})
-		if result.InsertedID == nil || err != nil {
-			log.Print("Connection failed ", err.Error())
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed"})
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(fmt.Errorf("store refresh token: %w", err)))
 			return
 		}
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:     "refreshToken",
-			Value:    refreshToken,
-			Path:     "/auth/refresh",
-			MaxAge:   int((7 * 24 * time.Hour).Seconds()),
-			HttpOnly: true,
-			Secure:   true,
-			SameSite: http.SameSiteNoneMode, // for cross-site
-		})
+		app.setRefreshCookie(c, refreshToken)
 		c.JSON(http.StatusOK, gin.H{
 			"access_token": accessToken,
 		})
 	}
 }
-func Refresh() gin.HandlerFunc {
+
+// refreshCookiePath scopes the cookie to the refresh and logout endpoints.
+const refreshCookiePath = "/auth"
+
+func (app *App) setRefreshCookie(c *gin.Context, refreshToken string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "refreshToken",
+		Value:    refreshToken,
+		Path:     refreshCookiePath,
+		Domain:   app.Config.Auth.CookieDomain,
+		MaxAge:   int(app.Config.Auth.RefreshTTL().Seconds()),
+		HttpOnly: true,
+		Secure:   app.Config.Auth.CookieSecure,
+		SameSite: http.SameSiteNoneMode, // for cross-site
+	})
+}
+
+// clearRefreshCookie expires the cookie set by setRefreshCookie; path and
+// domain must match or the browser keeps it.
+func (app *App) clearRefreshCookie(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "refreshToken",
+		Value:    "",
+		Path:     refreshCookiePath,
+		Domain:   app.Config.Auth.CookieDomain,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   app.Config.Auth.CookieSecure,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
+func (app *App) Refresh() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		usersCol := database.OpenCollection("users")
-		refreshCol := database.OpenCollection("refresh_tokens")
 
 		hash, err := c.Cookie("refreshToken")
 		if err != nil || hash == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing refresh token"})
+			apierror.Abort(c, apierror.Unauthorized(apierror.CodeUnauthorized, "missing refresh token"))
 			return
 		}
-		var rt models.RefreshToken
-		err = refreshCol.FindOne(ctx, bson.M{
-			"tokenHash": hash,
-			"revokedAt": bson.M{"$exists": false},
-			"expiresAt": bson.M{"$gt": time.Now().UTC()},
-		}).Decode(&rt)
-
+		rt, err := app.RefreshTokens.FindActive(ctx, hash, time.Now().UTC())
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+			apierror.Abort(c, apierror.Unauthorized(apierror.CodeUnauthorized, "invalid refresh token").Wrap(err))
 			return
 		}
 
-		var user models.User
-		if err := usersCol.FindOne(ctx, bson.M{"_id": rt.UserID}).Decode(&user); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user"})
+		user, err := app.Users.FindByID(ctx, rt.UserID)
+		if err != nil {
+			apierror.Abort(c, apierror.Unauthorized(apierror.CodeUnauthorized, "invalid refresh token").Wrap(err))
 			return
 		}
 		if !user.IsActive {
-			c.JSON(http.StatusForbidden, gin.H{"error": "account disabled"})
+			apierror.Abort(c, errAccountDisabled())
 			return
 		}
 
-		accessTTL := utils.AccessTTL()
-		refreshTTL := utils.RefreshTTL()
+		auth := app.Config.Auth
 
 		// Rotate refresh token
-		newHash, err := utils.GenerateRefreshToken(user.ID.String())
+		newHash, err := utils.GenerateRefreshToken(user.ID.Hex(), auth.RefreshTTL(), auth.JWTRefreshSecret)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rotate refresh token"})
+			apierror.Abort(c, apierror.Internal(fmt.Errorf("rotate refresh token: %w", err)))
 			return
 		}
 
 		now := time.Now().UTC()
 
-		_, err = refreshCol.UpdateByID(ctx, rt.ID, bson.M{
-			"$set": bson.M{
-				"revokedAt":  now,
-				"replacedBy": newHash,
-			},
-		})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke refresh token"})
+		if err := app.RefreshTokens.Revoke(ctx, rt.ID, &newHash); err != nil {
+			apierror.Abort(c, apierror.Internal(fmt.Errorf("revoke refresh token: %w", err)))
 			return
 		}
 
 		// Insert new token
-		_, err = refreshCol.InsertOne(ctx, models.RefreshToken{
+		err = app.RefreshTokens.Insert(ctx, &models.RefreshToken{
 			UserID:    user.ID,
 			TokenHash: newHash,
-			ExpiresAt: now.Add(refreshTTL),
+			ExpiresAt: now.Add(auth.RefreshTTL()),
 			CreatedAt: now,
 		})
-
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store refresh token"})
+			apierror.Abort(c, apierror.Internal(fmt.Errorf("store refresh token: %w", err)))
 			return
 		}
 
-		accessToken, err := utils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.Role), accessTTL)
+		accessToken, err := utils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.Role), auth.AccessTTL(), auth.JWTSecret)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate access token"})
+			apierror.Abort(c, apierror.Internal(fmt.Errorf("generate access token: %w", err)))
 			return
 		}
 
+		// The old cookie was just revoked: hand the rotated token back.
+		app.setRefreshCookie(c, newHash)
+
 		c.JSON(http.StatusOK, gin.H{"accessToken": accessToken})
 	}
 }
 
-func Logout() gin.HandlerFunc {
+func (app *App) Logout() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		refreshCol := database.OpenCollection("refresh_tokens")
 
 		hash, _ := c.Cookie("refreshToken")
-		utils.ClearRefreshCookie(c)
+		app.clearRefreshCookie(c)
 
 		// best effort revoke
 		if hash != "" {
-			now := time.Now().UTC()
-			_, _ = refreshCol.UpdateOne(ctx, bson.M{
-				"tokenHash": hash,
-				"revokedAt": bson.M{"$exists": false},
-			}, bson.M{
-				"$set": bson.M{"revokedAt": now},
-			})
+			_ = app.RefreshTokens.RevokeByHash(ctx, hash)
 		}
 
 		c.JSON(http.StatusOK, gin.H{"ok": true})
 	}
 }
 
-func RevokeAllRefreshTokens(ctx *gin.Context, userID bson.ObjectID) error {
-	refreshCol := database.OpenCollection("refresh_tokens")
-	now := time.Now().UTC()
-	_, err := refreshCol.UpdateMany(ctx.Request.Context(), bson.M{
-		"userId":    userID,
-		"revokedAt": bson.M{"$exists": false},
-	}, bson.M{
-		"$set": bson.M{"revokedAt": now},
-	})
-	return err
+func (app *App) revokeAllRefreshTokens(c *gin.Context, userID bson.ObjectID) error {
+	return app.RefreshTokens.RevokeAllForUser(c.Request.Context(), userID)
 }
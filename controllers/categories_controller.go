@@ -2,46 +2,49 @@ package controllers
 
 import (
 	"encoding/json"
-	"mime/multipart"
+	"errors"
+	"maps"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/princinho/sahobackend/database"
+	"github.com/princinho/sahobackend/apierror"
 	"github.com/princinho/sahobackend/dto"
 	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/storage"
+	"github.com/princinho/sahobackend/upload"
 	"github.com/princinho/sahobackend/utils"
 	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 // ====== AddCategory ========================================================================================================================
 //
 // Accepts a multipart/form-data request:
-//   - "data"  : JSON  { name, slug?, description?, isActive? }
+//   - "data"  : JSON  { name, slug?, description?, isActive?, translations? }
 //   - "image" : file  (optional)
 
-func AddCategory() gin.HandlerFunc {
+func (app *App) AddCategory() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("categories")
 
 		// 1) Parse JSON payload
 		jsonData := c.PostForm("data")
 		if jsonData == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "missing data field"})
+			apierror.Abort(c, apierror.Required("data"))
 			return
 		}
 
 		var body dto.CreateCategoryDTO
 		if err := json.Unmarshal([]byte(jsonData), &body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid data json", "details": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 
 		body.Name = strings.TrimSpace(body.Name)
 		if body.Name == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+			apierror.Abort(c, apierror.Required("name"))
 			return
 		}
 
@@ -50,53 +53,69 @@ func AddCategory() gin.HandlerFunc {
 		if body.Slug == "" {
 			body.Slug = utils.GenerateSlug(body.Name)
 		}
+		if aerr := app.checkTranslationLocales(slices.Collect(maps.Keys(body.Translations))); aerr != nil {
+			apierror.Abort(c, aerr)
+			return
+		}
 
 		// 2) Upload image (optional)
-		var imageUrl string
-		if file, err := c.FormFile("image"); err == nil && file != nil {
-			gcsClient, gcsBucket, gsError := utils.NewCloudClient(c)
-			if gsError != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to connect GS Client", "details": gsError.Error()})
+		var image *models.Image
+		if fh, err := c.FormFile("image"); err == nil && fh != nil {
+			file, aerr := app.checkUpload(upload.CategoryImage, "image", fh)
+			if aerr != nil {
+				apierror.Abort(c, aerr)
+				return
 			}
-			urls, err := utils.UploadImagesToCloudAndGetPublicURLs(
-				ctx, gcsClient, gcsBucket, body.Slug, []*multipart.FileHeader{file},
-			)
+			images, err := storage.UploadImages(ctx, app.Storage, body.Slug, []*upload.File{file}, app.Config.Uploads.ImageQuality)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				apierror.Abort(c, app.uploadError("image", err))
 				return
 			}
-			imageUrl = urls[0]
+			image = &images[0]
 		}
 
 		// 3) Build document
 		doc := models.Category{
-			Name:        body.Name,
-			Slug:        body.Slug,
-			Description: strings.TrimSpace(body.Description),
-			IsActive:    body.IsActive,
-			ImageUrl:    imageUrl,
+			Name:         body.Name,
+			Slug:         body.Slug,
+			Description:  strings.TrimSpace(body.Description),
+			IsActive:     body.IsActive,
+			Image:        image,
+			Translations: categoryTranslations(nil, body.Translations),
 		}
 
-		res, err := col.InsertOne(ctx, doc)
+		err := app.Categories.Insert(ctx, &doc)
 		if err != nil {
-			if utils.IsDuplicateKey(err) {
-				c.JSON(http.StatusConflict, gin.H{"error": "slug already exists: '" + doc.Slug + "'", "field": "slug"})
+			if errors.Is(err, repositories.ErrDuplicateKey) {
+				apierror.Abort(c, apierror.Conflict("slug", doc.Slug))
 				return
 			}
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
 
-		c.JSON(http.StatusCreated, gin.H{"id": res.InsertedID})
+		c.JSON(http.StatusCreated, gin.H{"id": doc.Id})
 	}
 }
 
-func GetCategories() gin.HandlerFunc {
+// ====== GetCategories =========================================================================================================
+//
+// GET /categories lists the categories in the locale of the request; GET
+// /admin/categories lists them as stored, with their translations.
+
+func (app *App) GetCategories() gin.HandlerFunc {
+	return app.listCategories(false)
+}
+
+func (app *App) GetAdminCategories() gin.HandlerFunc {
+	return app.listCategories(true)
+}
+
+func (app *App) listCategories(admin bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("categories")
 
-		maxLimit, defaultLimit := utils.GetDefaultQueryLimits()
+		maxLimit, defaultLimit := app.Config.Query.MaxLimit, app.Config.Query.DefaultLimit
 
 		page := utils.ParseIntDefault(c.Query("page"), 1)
 		limit := utils.ParseIntDefault(c.Query("limit"), defaultLimit)
@@ -108,47 +127,26 @@ func GetCategories() gin.HandlerFunc {
 		}
 		skip := int64((page - 1) * limit)
 
-		q := strings.TrimSpace(c.Query("q"))
-		filter := bson.M{}
-		if q != "" {
-			filter["name"] = bson.M{"$regex": q, "$options": "i"}
+		filter := repositories.CategoryFilter{
+			Name: strings.TrimSpace(c.Query("q")),
 		}
 
 		// Optional isActive filter
 		if b, err := utils.ParseBoolQuery(c.Query("isActive")); err == nil && b != nil {
-			filter["isActive"] = *b
+			filter.IsActive = b
 		}
 
-		opts := options.Find().
-			SetSkip(skip).
-			SetLimit(int64(limit)).
-			SetSort(bson.D{{Key: "name", Value: 1}})
-
-		cursor, err := col.Find(ctx, filter, opts)
+		items, total, err := app.Categories.List(ctx, filter, repositories.Page{Skip: skip, Limit: int64(limit)})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
-		defer cursor.Close(ctx)
 
-		items := make([]models.Category, 0)
-		for cursor.Next(ctx) {
-			var cat models.Category
-			if err := cursor.Decode(&cat); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
+		if !admin {
+			locale := app.contentLocale(c)
+			for i := range items {
+				items[i] = items[i].Localized(locale)
 			}
-			items = append(items, cat)
-		}
-		if err := cursor.Err(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-
-		total, err := col.CountDocuments(ctx, filter)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
@@ -163,76 +161,73 @@ func GetCategories() gin.HandlerFunc {
 // ====== GetCategory ==========================================================================================================================
 // Supports lookup by :id (ObjectID hex) or :slug
 
-func GetCategory() gin.HandlerFunc {
+func (app *App) GetCategory() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("categories")
 
 		idHex := strings.TrimSpace(c.Param("id"))
 		slug := strings.TrimSpace(c.Param("slug"))
 
 		if idHex == "" && slug == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "no id or slug provided"})
+			apierror.Abort(c, apierror.BadRequest("no id or slug provided"))
 			return
 		}
 
-		var filter bson.M
+		var cat *models.Category
+		var err error
 
 		if idHex != "" {
-			id, err := bson.ObjectIDFromHex(idHex)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
+			id, idErr := bson.ObjectIDFromHex(idHex)
+			if idErr != nil {
+				apierror.Abort(c, apierror.InvalidID("invalid category id"))
 				return
 			}
-			filter = bson.M{"_id": id}
+			cat, err = app.Categories.FindByID(ctx, id)
 		} else {
-			filter = bson.M{"slug": slug}
+			cat, err = app.Categories.FindBySlug(ctx, slug)
 		}
-
-		var cat models.Category
-		if err := col.FindOne(ctx, filter).Decode(&cat); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "category not found"))
 			return
 		}
 
-		c.JSON(http.StatusOK, cat)
+		c.JSON(http.StatusOK, cat.Localized(app.contentLocale(c)))
 	}
 }
 
 // ====== UpdateCategory ====================================================================================================================
 //
 // Accepts multipart/form-data:
-//   - "data"  : JSON  { name?, slug?, description?, isActive?, removeImage? }
+//   - "data"  : JSON  { name?, slug?, description?, isActive?, translations?, removeImage? }
 //   - "image" : file  (optional — replaces current image)
 
-func UpdateCategory() gin.HandlerFunc {
+func (app *App) UpdateCategory() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("categories")
 
 		idHex := c.Param("id")
 		id, err := bson.ObjectIDFromHex(idHex)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
+			apierror.Abort(c, apierror.InvalidID("invalid category id"))
 			return
 		}
 
 		dataStr := c.PostForm("data")
 		if dataStr == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "missing data field"})
+			apierror.Abort(c, apierror.Required("data"))
 			return
 		}
 
 		var body dto.UpdateCategoryDTO
 		if err := json.Unmarshal([]byte(dataStr), &body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid data json", "details": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 
 		// 1) Load existing category (need current imageUrl for deletion)
-		var existing models.Category
-		if err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&existing); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		existing, err := app.Categories.FindByID(ctx, id)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "category not found"))
 			return
 		}
 
@@ -241,7 +236,7 @@ func UpdateCategory() gin.HandlerFunc {
 		if body.Name != nil {
 			v := strings.TrimSpace(*body.Name)
 			if v == "" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+				apierror.Abort(c, apierror.Invalid("name", apierror.RuleRequired, "%s cannot be empty", "name"))
 				return
 			}
 			set["name"] = v
@@ -249,7 +244,7 @@ func UpdateCategory() gin.HandlerFunc {
 		if body.Slug != nil {
 			v := strings.TrimSpace(*body.Slug)
 			if v == "" {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "slug cannot be empty"})
+				apierror.Abort(c, apierror.Invalid("slug", apierror.RuleRequired, "%s cannot be empty", "slug"))
 				return
 			}
 			set["slug"] = v
@@ -260,68 +255,63 @@ func UpdateCategory() gin.HandlerFunc {
 		if body.IsActive != nil {
 			set["isActive"] = *body.IsActive
 		}
+		if body.Translations != nil {
+			if aerr := app.checkTranslationLocales(slices.Collect(maps.Keys(body.Translations))); aerr != nil {
+				apierror.Abort(c, aerr)
+				return
+			}
+			set["translations"] = categoryTranslations(existing.Translations, body.Translations)
+		}
 
-		// 2) Determine the slug to use for GCS path (prefer new slug, fall back to current)
+		// 2) Determine the slug to use for the storage path (prefer new slug, fall back to current)
 		uploadSlug := existing.Slug
 		if s, ok := set["slug"]; ok {
 			uploadSlug = s.(string)
 		}
 
 		// 3) Handle image replacement / removal
-		newImageUrl := ""
+		var newImages []models.Image
 		newFile, fileErr := c.FormFile("image")
 		hasNewFile := fileErr == nil && newFile != nil
 
-		var gcsClient *utils.R2Client
-		var gcsBucket string
-
 		if hasNewFile {
-			gcsClient, gcsBucket, err = utils.NewCloudClient(c)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to open GS Client", "details": err.Error()})
+			file, aerr := app.checkUpload(upload.CategoryImage, "image", newFile)
+			if aerr != nil {
+				apierror.Abort(c, aerr)
+				return
 			}
-			urls, err := utils.UploadImagesToCloudAndGetPublicURLs(
-				ctx, gcsClient, gcsBucket, uploadSlug, []*multipart.FileHeader{newFile},
-			)
+			newImages, err = storage.UploadImages(ctx, app.Storage, uploadSlug, []*upload.File{file}, app.Config.Uploads.ImageQuality)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				apierror.Abort(c, app.uploadError("image", err))
 				return
 			}
-			newImageUrl = urls[0]
-			set["imageUrl"] = newImageUrl
+			set["image"] = newImages[0]
 		}
 
 		if len(set) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "no updates provided"})
+			apierror.Abort(c, errNoUpdates())
 			return
 		}
 
-		result, err := col.UpdateByID(ctx, id, bson.M{"$set": set})
+		err = app.Categories.Update(ctx, id, set)
 		if err != nil {
 			// Roll back: delete newly uploaded image (if any)
-			if newImageUrl != "" && gcsClient != nil {
-				if objName, e := utils.ObjectNameFromCloudPublicURL(gcsBucket, newImageUrl); e == nil {
-					_ = utils.DeleteCloudObjects(ctx, gcsClient, gcsBucket, []string{objName})
-				}
+			app.cleanupImages(ctx, newImages)
+			if errors.Is(err, repositories.ErrNotFound) {
+				apierror.Abort(c, apierror.NotFound("category not found"))
+				return
 			}
-			if utils.IsDuplicateKey(err) {
-				c.JSON(http.StatusConflict, gin.H{
-					"error": "slug already exists: '" + *body.Slug + "'", "field": "slug"})
+			if errors.Is(err, repositories.ErrDuplicateKey) {
+				apierror.Abort(c, apierror.Conflict("slug", uploadSlug))
 				return
 			}
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		if result.MatchedCount == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
 
-		// 5) DB OK → delete old image from GCS if replaced or removed
-		if (hasNewFile) && existing.ImageUrl != "" && gcsClient != nil {
-			if objName, e := utils.ObjectNameFromCloudPublicURL(gcsBucket, existing.ImageUrl); e == nil {
-				_ = utils.DeleteCloudObjects(ctx, gcsClient, gcsBucket, []string{objName})
-			}
+		// 5) DB OK → delete old image from storage if replaced
+		if hasNewFile && existing.Image != nil {
+			app.cleanupImages(ctx, []models.Image{*existing.Image})
 		}
 
 		c.JSON(http.StatusOK, gin.H{"ok": true})
@@ -330,44 +320,31 @@ func UpdateCategory() gin.HandlerFunc {
 
 // ====== DeleteCategory ====================================================================================================================
 
-func DeleteCategory() gin.HandlerFunc {
+func (app *App) DeleteCategory() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("categories")
 
 		idHex := c.Param("id")
 		id, err := bson.ObjectIDFromHex(idHex)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
+			apierror.Abort(c, apierror.InvalidID("invalid category id"))
 			return
 		}
 
-		var existing models.Category
-		if err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&existing); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
-			return
-		}
-
-		res, err := col.DeleteOne(ctx, bson.M{"_id": id})
+		existing, err := app.Categories.FindByID(ctx, id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apierror.Abort(c, lookupError(err, "category not found"))
 			return
 		}
-		if res.DeletedCount == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+
+		if err := app.Categories.Delete(ctx, id); err != nil {
+			apierror.Abort(c, lookupError(err, "category not found"))
 			return
 		}
 
-		// Clean up GCS image
-		if existing.ImageUrl != "" {
-			gcsClient, gcsBucket, err := utils.NewCloudClient(c)
-			if err != nil {
-				c.JSON(http.StatusNotFound, gin.H{"error": "failed to open GS Client", "details": err.Error()})
-			}
-			if objName, e := utils.ObjectNameFromCloudPublicURL(gcsBucket, existing.ImageUrl); e == nil {
-				_ = utils.DeleteCloudObjects(ctx, gcsClient, gcsBucket, []string{objName})
-			}
-
+		// Clean up the stored image
+		if existing.Image != nil {
+			app.cleanupImages(ctx, []models.Image{*existing.Image})
 		}
 
 		c.JSON(http.StatusOK, gin.H{"ok": true})
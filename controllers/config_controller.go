@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ====== GetConfig (admin) ===================================================================================================
+// GET /admin/config — the effective configuration with secrets redacted.
+func (app *App) GetConfig() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, app.Config.Redacted())
+	}
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/openapi"
+)
+
+// ====== OpenAPI =============================================================================================================
+// GET /openapi.json — the OpenAPI 3 description of every route.
+func (app *App) OpenAPI() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, openapi.Spec())
+	}
+}
+
+// ====== Docs ================================================================================================================
+// GET /docs — Swagger UI over /openapi.json. The UI assets come from a CDN so
+// nothing has to be vendored or embedded.
+func (app *App) Docs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUI))
+	}
+}
+
+const swaggerUI = `<!doctype html>
+<html lang="fr">
+<head>
+  <meta charset="utf-8">
+  <title>SAHO API</title>
+  <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui", withCredentials: true });
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"slices"
+
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/storage"
+	"github.com/princinho/sahobackend/upload"
+)
+
+// lookupError maps a repository error: ErrNotFound becomes a 404 with
+// message, anything else a 500.
+func lookupError(err error, message string) *apierror.Error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		return apierror.NotFound(message).Wrap(err)
+	}
+	return apierror.Internal(err)
+}
+
+// errInvalidToken matches the auth middleware's answer for a token whose
+// user no longer resolves.
+func errInvalidToken() *apierror.Error {
+	return apierror.Unauthorized(apierror.CodeUnauthorized, "invalid or expired token")
+}
+
+// errNoUpdates answers a PATCH that changes nothing.
+func errNoUpdates() *apierror.Error {
+	return apierror.BadRequest("no updates provided")
+}
+
+// stockError maps the errors of a stock movement.
+func stockError(err error, message string) *apierror.Error {
+	if errors.Is(err, repositories.ErrInsufficientStock) {
+		return apierror.New(http.StatusConflict, apierror.CodeInsufficientStock, "not enough stock").Wrap(err)
+	}
+	return lookupError(err, message)
+}
+
+func tooManyImages(max int) *apierror.Error {
+	return apierror.Invalid("images", "max", "at most %d images are allowed", max)
+}
+
+func invalidStatus(allowed string) *apierror.Error {
+	return apierror.Invalid("status", apierror.RuleOneOf, "%s must be one of: %s", "status", allowed)
+}
+
+// itemField names a field of the i-th quote item.
+func itemField(i int, field string) string {
+	return fmt.Sprintf("items[%d].%s", i, field)
+}
+
+// requiredFields reports every empty value, in field name order, or nil.
+func requiredFields(values map[string]string) *apierror.Error {
+	var e *apierror.Error
+	for _, field := range slices.Sorted(maps.Keys(values)) {
+		if values[field] != "" {
+			continue
+		}
+		if e == nil {
+			e = apierror.Validation()
+		}
+		e.WithField(field, apierror.RuleRequired, "%s is required", field)
+	}
+	return e
+}
+
+// checkUpload checks a file against the upload policy of kind. A rejected
+// file is a 400 invalid_upload detailing why under field.
+func (app *App) checkUpload(kind upload.Kind, field string, fh *multipart.FileHeader) (*upload.File, *apierror.Error) {
+	files, err := app.checkFiles(kind, []string{field}, []*multipart.FileHeader{fh})
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// checkUploads is checkUpload for the files of a multi-file field. Every
+// rejected file is reported, under field[i].
+func (app *App) checkUploads(kind upload.Kind, field string, fhs []*multipart.FileHeader) ([]*upload.File, *apierror.Error) {
+	fields := make([]string, len(fhs))
+	for i := range fhs {
+		fields[i] = fmt.Sprintf("%s[%d]", field, i)
+	}
+	return app.checkFiles(kind, fields, fhs)
+}
+
+func (app *App) checkFiles(kind upload.Kind, fields []string, fhs []*multipart.FileHeader) ([]*upload.File, *apierror.Error) {
+	policy := app.Config.Uploads.Policy(kind)
+	files := make([]*upload.File, 0, len(fhs))
+	var rejected *apierror.Error
+	for i, fh := range fhs {
+		f, err := policy.Open(fh)
+		var rejection *upload.Error
+		switch {
+		case errors.As(err, &rejection):
+			if rejected == nil {
+				rejected = apierror.New(http.StatusBadRequest, apierror.CodeInvalidUpload, "some files were rejected")
+			}
+			rejected.WithField(fields[i], rejection.Rule, rejection.Message, rejection.Args...)
+		case err != nil:
+			return nil, apierror.Internal(err)
+		default:
+			files = append(files, f)
+		}
+	}
+	if rejected != nil {
+		return nil, rejected
+	}
+	return files, nil
+}
+
+// uploadError maps a storage failure to the client error for the form field;
+// backend failures stay internal.
+func (app *App) uploadError(field string, err error) *apierror.Error {
+	if errors.Is(err, storage.ErrNoImages) {
+		return apierror.Required(field)
+	}
+	return apierror.Internal(err)
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/money"
+	"github.com/princinho/sahobackend/repositories"
+)
+
+// ====== GetExchangeRates ======================================================================================================
+//
+// GET /exchange-rates — the currencies prices can be shown in, with what one
+// unit is worth in XOF.
+
+func (app *App) GetExchangeRates() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rates, err := app.ExchangeRates.List(c.Request.Context())
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"base": money.Base, "items": rates})
+	}
+}
+
+// ====== SetExchangeRate (admin) ===============================================================================================
+//
+// PUT /admin/exchange-rates/:currency — creates or replaces the rate of a
+// currency. Quotes already made keep the rate they were priced with.
+
+func (app *App) SetExchangeRate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currency, aerr := rateCurrency(c.Param("currency"))
+		if aerr != nil {
+			apierror.Abort(c, aerr)
+			return
+		}
+		var body dto.SetExchangeRateDTO
+		if err := c.ShouldBindJSON(&body); err != nil {
+			apierror.Abort(c, apierror.Bind(err))
+			return
+		}
+
+		_, email := signedInAuthor(c)
+		rate := models.ExchangeRate{Currency: currency, Rate: body.Rate, UpdatedAt: time.Now().UTC(), UpdatedBy: email}
+		if err := app.ExchangeRates.Upsert(c.Request.Context(), &rate); err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+		c.JSON(http.StatusOK, rate)
+	}
+}
+
+// ====== DeleteExchangeRate (admin) ============================================================================================
+
+func (app *App) DeleteExchangeRate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currency, aerr := rateCurrency(c.Param("currency"))
+		if aerr != nil {
+			apierror.Abort(c, aerr)
+			return
+		}
+		if err := app.ExchangeRates.Delete(c.Request.Context(), currency); err != nil {
+			apierror.Abort(c, lookupError(err, "exchange rate not found"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+// rateCurrency validates the currency of a rate: a supported one other than
+// the base, which is worth 1 by definition.
+func rateCurrency(raw string) (string, *apierror.Error) {
+	currency, err := money.Normalize(raw)
+	if err != nil {
+		return "", unsupportedCurrency()
+	}
+	if currency == money.Base {
+		return "", apierror.Invalid("currency", apierror.RuleInvalid, "%s has no exchange rate, prices are stored in it", money.Base)
+	}
+	return currency, nil
+}
+
+func unsupportedCurrency() *apierror.Error {
+	return apierror.Invalid("currency", apierror.RuleOneOf, "%s must be one of: %s", "currency", strings.Join(money.Codes(), ", "))
+}
+
+// pricing converts the stored XOF amounts to the currency a request asked
+// for. The zero value keeps them in XOF.
+type pricing struct {
+	currency string
+	// rate is what one unit of currency is worth in XOF; 0 for XOF.
+	rate float64
+}
+
+// pricing resolves a ?currency= value; empty means XOF. A currency without
+// a rate cannot be priced and is rejected like an unknown one.
+func (app *App) pricing(ctx context.Context, raw string) (pricing, *apierror.Error) {
+	if strings.TrimSpace(raw) == "" {
+		return pricing{currency: money.Base}, nil
+	}
+	currency, err := money.Normalize(raw)
+	if err != nil {
+		return pricing{}, unsupportedCurrency()
+	}
+	if currency == money.Base {
+		return pricing{currency: money.Base}, nil
+	}
+	rate, err := app.ExchangeRates.FindByCurrency(ctx, currency)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return pricing{}, apierror.Invalid("currency", apierror.RuleInvalid, "no exchange rate for %s", currency).Wrap(err)
+	}
+	if err != nil {
+		return pricing{}, apierror.Internal(err)
+	}
+	return pricing{currency: currency, rate: rate.Rate}, nil
+}
+
+// amount converts an amount of XOF.
+func (p pricing) amount(xof int64) int64 {
+	if p.rate == 0 {
+		return xof
+	}
+	// the currency and the rate were checked when the pricing was resolved
+	v, _ := money.FromBase(xof, p.currency, p.rate)
+	return v
+}
+
+// base converts an amount of the pricing currency to XOF.
+func (p pricing) base(amount int64) int64 {
+	if p.rate == 0 {
+		return amount
+	}
+	v, _ := money.ToBase(amount, p.currency, p.rate)
+	return v
+}
+
+// product converts the prices of p, its variants' included.
+func (p pricing) product(product *models.Product) {
+	product.Price = p.amount(product.Price)
+	product.Currency = p.currency
+	for i, v := range product.Variants {
+		if v.Price != nil {
+			price := p.amount(*v.Price)
+			product.Variants[i].Price = &price
+		}
+	}
+}
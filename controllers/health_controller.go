@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ====== Healthz =============================================================================================================
+// GET /healthz — liveness: the process is up and serving HTTP. It never
+// touches a dependency, so a database outage does not get the pod restarted.
+func (app *App) Healthz() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
+
+// ====== Readyz ==============================================================================================================
+// GET /readyz — readiness: every dependency answered in time. 503 with the
+// per-dependency status otherwise, or while the server drains on shutdown.
+func (app *App) Readyz() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := app.Health.Check(c.Request.Context())
+		status := http.StatusOK
+		if !report.OK() {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	}
+}
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/i18n"
+	"github.com/princinho/sahobackend/logging"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ====== UpdateProductImages (admin) ===========================================================================================
+//
+// PATCH /admin/products/:id/images
+// Body: application/json
+//
+//	{
+//	  "images": [                                              // every image of the product, in the new order
+//	    { "url": "https://...", "isPrimary": true, "alt": { "fr": "Chaise en teck", "en": "Teak chair" } },
+//	    { "url": "https://..." }                               // alt omitted: unchanged
+//	  ]
+//	}
+//
+// Reorders the gallery, picks the primary image and edits alt texts without
+// uploading the images again.
+
+func (app *App) UpdateProductImages() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		id, err := bson.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidID("invalid product id"))
+			return
+		}
+
+		var body dto.UpdateProductImagesDTO
+		if err := c.ShouldBindJSON(&body); err != nil {
+			apierror.Abort(c, apierror.Bind(err))
+			return
+		}
+
+		before, err := app.Products.FindByID(ctx, id)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+		images, aerr := galleryImages(before, body.Images)
+		if aerr != nil {
+			apierror.Abort(c, aerr)
+			return
+		}
+		if err := app.Products.Update(ctx, id, bson.M{"images": images}); err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+		app.recordRevision(c, models.ProductRevisionUpdate, before, id)
+
+		product, err := app.Products.FindByID(ctx, id)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+		c.JSON(http.StatusOK, product)
+	}
+}
+
+// galleryImages applies changes, which must name every image of product
+// once, to its images: their order, primary flag and alt texts.
+func galleryImages(product *models.Product, changes []dto.ProductImageDTO) ([]models.Image, *apierror.Error) {
+	verr := apierror.Validation()
+	locales := i18n.Locales()
+	primaries := 0
+	for _, change := range changes {
+		if change.IsPrimary {
+			primaries++
+		}
+	}
+	if primaries > 1 {
+		verr = verr.WithField("images", apierror.RuleInvalid, "only one image can be primary")
+	}
+
+	images := make([]models.Image, 0, len(changes))
+	for i, change := range changes {
+		field := fmt.Sprintf("images[%d]", i)
+		img, ok := product.Image(change.URL)
+		if !ok {
+			verr = verr.WithField(field+".url", apierror.RuleExists, "%s is not an image of the product", change.URL)
+			continue
+		}
+		if slices.Contains(models.ImageURLs(images), img.URL) {
+			verr = verr.WithField(field+".url", apierror.RuleUnique, "%s is listed more than once", change.URL)
+			continue
+		}
+		if primaries > 0 {
+			img.IsPrimary = change.IsPrimary
+		}
+		if change.Alt != nil {
+			img.Alt = nil
+			for _, locale := range slices.Sorted(maps.Keys(change.Alt)) {
+				if !slices.Contains(locales, locale) {
+					verr = verr.WithField(field+".alt."+locale, apierror.RuleOneOf, "%s must be one of: %s", "alt", strings.Join(locales, ", "))
+					continue
+				}
+				if text := strings.TrimSpace(change.Alt[locale]); text != "" {
+					if img.Alt == nil {
+						img.Alt = map[string]string{}
+					}
+					img.Alt[locale] = text
+				}
+			}
+		}
+		images = append(images, img)
+	}
+	if len(verr.Fields) > 0 {
+		return nil, verr
+	}
+	if len(images) != len(product.Images) {
+		return nil, apierror.Invalid("images", apierror.RuleRequired, "%s must list every image of the product", "images")
+	}
+	return models.ArrangeImages(images), nil
+}
+
+// MigrateImages encodes the renditions of the product and category images
+// saved before renditions existed and deletes their originals once the new
+// images are saved. An image whose original cannot be read or decoded is
+// logged, counted as failed and left as it is, so a rerun retries it.
+// "sahobackend images migrate" runs it.
+func (app *App) MigrateImages(ctx context.Context) (encoded, failed int, err error) {
+	log := logging.FromContext(ctx)
+
+	// encode returns the image with its renditions, or false when they
+	// could not be encoded.
+	encode := func(slug string, legacy models.Image) (models.Image, bool) {
+		img, err := storage.EncodeLegacyImage(ctx, app.Storage, slug, legacy, app.Config.Uploads.ImageQuality)
+		if err != nil {
+			log.Warn("image migration failed", "url", legacy.URL, "error", err)
+			failed++
+			return legacy, false
+		}
+		return img, true
+	}
+
+	products, err := app.Products.WithLegacyImages(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("list products: %w", err)
+	}
+	for _, p := range products {
+		images := slices.Clone(p.Images)
+		renamed := map[string]string{}
+		var originals, added []models.Image
+		for i, legacy := range images {
+			if legacy.Processed() {
+				continue
+			}
+			img, ok := encode(p.Slug, legacy)
+			if !ok {
+				continue
+			}
+			images[i] = img
+			originals = append(originals, legacy)
+			added = append(added, img)
+			renamed[legacy.URL] = img.URL
+		}
+		if len(added) == 0 {
+			continue
+		}
+		if err := app.Products.Update(ctx, p.Id, bson.M{"images": images}); err != nil {
+			app.cleanupImages(ctx, added)
+			return encoded, failed, fmt.Errorf("save images of product %s: %w", p.Id.Hex(), err)
+		}
+		// the originals stay until the variants point to the new images
+		if len(p.Variants) > 0 {
+			if err := app.Products.RenameVariantImages(ctx, p.Id, renamed); err != nil {
+				return encoded, failed, fmt.Errorf("save variant images of product %s: %w", p.Id.Hex(), err)
+			}
+		}
+		app.cleanupImages(ctx, originals)
+		encoded += len(added)
+	}
+
+	categories, err := app.Categories.WithLegacyImage(ctx)
+	if err != nil {
+		return encoded, failed, fmt.Errorf("list categories: %w", err)
+	}
+	for _, cat := range categories {
+		legacy := *cat.Image
+		img, ok := encode(cat.Slug, legacy)
+		if !ok {
+			continue
+		}
+		if err := app.Categories.Update(ctx, cat.Id, bson.M{"image": img}); err != nil {
+			app.cleanupImages(ctx, []models.Image{img})
+			return encoded, failed, fmt.Errorf("save image of category %s: %w", cat.Id.Hex(), err)
+		}
+		app.cleanupImages(ctx, []models.Image{legacy})
+		encoded++
+	}
+	return encoded, failed, nil
+}
@@ -0,0 +1,641 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/i18n"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/money"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/spreadsheet"
+	"github.com/princinho/sahobackend/storage"
+	"github.com/princinho/sahobackend/upload"
+	"github.com/princinho/sahobackend/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// productColumns are the columns of the catalog spreadsheet, in the order
+// of the export. Lists (categories, materials, colors, images) are separated
+// by "|"; categories are given by slug.
+var productColumns = []string{
+	"slug", "name", "price", "quantity", "categories", "materials", "colors",
+	"description", "descriptionFull", "dimensions", "weight", "isTrending", "isDisabled", "images",
+}
+
+// requiredColumns must be in the header of an import: every row needs them.
+var requiredColumns = []string{"name", "price", "categories"}
+
+// listSeparator splits the list cells of the spreadsheet.
+const listSeparator = "|"
+
+// ====== ExportProducts (admin) ================================================================================================
+//
+// GET /admin/products/export?format=csv|xlsx — every product but the trash,
+// one per row, in the columns POST /admin/products/import reads back.
+// Variants are not exported.
+
+func (app *App) ExportProducts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", spreadsheet.CSV)))
+		if _, ok := spreadsheet.ContentTypes[format]; !ok {
+			apierror.Abort(c, apierror.Invalid("format", apierror.RuleOneOf, "%s must be one of: %s", "format", "csv, xlsx"))
+			return
+		}
+
+		products, _, err := app.Products.List(ctx, repositories.ProductFilter{}, repositories.Page{})
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+		categories, _, err := app.Categories.List(ctx, repositories.CategoryFilter{}, repositories.Page{})
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+		categorySlugs := make(map[bson.ObjectID]string, len(categories))
+		for _, cat := range categories {
+			categorySlugs[cat.Id] = cat.Slug
+		}
+
+		rows := make([][]string, 0, len(products)+1)
+		rows = append(rows, productColumns)
+		for _, p := range products {
+			var slugs []string
+			for _, id := range p.CategoryIds {
+				if slug, ok := categorySlugs[id]; ok {
+					slugs = append(slugs, slug)
+				}
+			}
+			rows = append(rows, []string{
+				p.Slug, p.Name, strconv.FormatInt(p.Price, 10), strconv.Itoa(p.Quantity),
+				strings.Join(slugs, listSeparator), strings.Join(p.Materials, listSeparator), strings.Join(p.Colors, listSeparator),
+				p.Description, p.DescriptionFull, p.Dimensions, p.Weight,
+				strconv.FormatBool(p.IsTrending), strconv.FormatBool(p.IsDisabled), strings.Join(models.ImageURLs(p.Images), listSeparator),
+			})
+		}
+
+		var buf bytes.Buffer
+		if err := spreadsheet.Write(format, &buf, rows); err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="produits-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+		c.Data(http.StatusOK, spreadsheet.ContentTypes[format], buf.Bytes())
+	}
+}
+
+// ====== ImportProducts (admin) ================================================================================================
+//
+// POST /admin/products/import?dryRun=true
+// Body: multipart/form-data
+//
+//	file     the catalog (.csv or .xlsx) with the columns of the export
+//	archive  optional .zip of the images the rows name
+//
+// Each row is validated like POST /admin/products/add, then upserted by slug
+// (derived from the name when the cell is empty). An image cell lists the
+// current URLs to keep and the files to upload, found in the archive first,
+// then under IMPORT_IMAGES_DIR. Rows with errors are skipped and reported;
+// with dryRun nothing is written.
+
+func (app *App) ImportProducts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dryRun, err := utils.ParseBoolQuery(c.Query("dryRun"))
+		if err != nil {
+			apierror.Abort(c, apierror.Invalid("dryRun", apierror.RuleInvalid, "%s is invalid", "dryRun"))
+			return
+		}
+
+		rows, apiErr := app.readImportFile(c)
+		if apiErr != nil {
+			apierror.Abort(c, apiErr)
+			return
+		}
+		columns := map[string]int{}
+		for i, header := range rows[0] {
+			for _, name := range productColumns {
+				if strings.EqualFold(strings.TrimSpace(header), name) {
+					columns[name] = i
+				}
+			}
+		}
+		for _, name := range requiredColumns {
+			if _, ok := columns[name]; !ok {
+				apierror.Abort(c, apierror.Invalid("file", apierror.RuleRequired, "column %s is missing", name))
+				return
+			}
+		}
+
+		images, apiErr := app.openImportImages(c)
+		if apiErr != nil {
+			apierror.Abort(c, apiErr)
+			return
+		}
+		defer images.Close()
+
+		imp := &productImport{
+			app:        app,
+			c:          c,
+			dryRun:     dryRun != nil && *dryRun,
+			columns:    columns,
+			images:     images,
+			categories: map[string]*bson.ObjectID{},
+			seen:       map[string]int{},
+		}
+		report := dto.ProductImportReportDTO{DryRun: imp.dryRun, Rows: []dto.ProductImportRowDTO{}}
+		printer := i18n.Printer(i18n.Match(c.GetHeader("Accept-Language")))
+
+		for i, row := range rows[1:] {
+			if !slices.ContainsFunc(row, func(cell string) bool { return strings.TrimSpace(cell) != "" }) {
+				continue
+			}
+			result := dto.ProductImportRowDTO{Row: i + 2}
+			slug, action, rowErr, err := imp.importRow(i+2, row)
+			if err != nil {
+				// the rows before this one are saved; importing again is safe
+				apierror.Abort(c, apierror.Internal(err))
+				return
+			}
+			result.Slug = slug
+			switch {
+			case rowErr != nil:
+				result.Action = dto.ImportActionError
+				result.Errors = rowErr.Render(printer, "").Error.Fields
+				report.Failed++
+			case action == dto.ImportActionCreate:
+				result.Action = action
+				report.Created++
+			default:
+				result.Action = action
+				report.Updated++
+			}
+			report.Rows = append(report.Rows, result)
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// readImportFile reads the "file" part of an import, at least a header and
+// one row and at most IMPORT_MAX_ROWS rows.
+func (app *App) readImportFile(c *gin.Context) ([][]string, *apierror.Error) {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return nil, apierror.Required("file")
+	}
+	format, err := spreadsheet.FormatOf(fh.Filename)
+	if err != nil {
+		return nil, apierror.New(http.StatusBadRequest, apierror.CodeInvalidUpload, "file type not allowed (allowed: %s)", "csv, xlsx").
+			WithField("file", apierror.RuleInvalid, "file type not allowed (allowed: %s)", "csv, xlsx").Wrap(err)
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, apierror.Internal(err)
+	}
+	defer f.Close()
+	rows, err := spreadsheet.Read(format, f)
+	if err != nil {
+		return nil, apierror.New(http.StatusBadRequest, apierror.CodeInvalidUpload, "the file cannot be read as %s", format).
+			WithField("file", apierror.RuleInvalid, "the file cannot be read as %s", format).Wrap(err)
+	}
+
+	if len(rows) < 2 {
+		return nil, apierror.Invalid("file", apierror.RuleRequired, "the file has no product rows")
+	}
+	if maxRows := app.Config.Import.MaxRows; len(rows)-1 > maxRows {
+		return nil, apierror.Invalid("file", "max", "at most %d rows can be imported at once", maxRows)
+	}
+	return rows, nil
+}
+
+// productImport carries what the rows of an import share.
+type productImport struct {
+	app     *App
+	c       *gin.Context
+	dryRun  bool
+	columns map[string]int
+	images  *importImages
+	// categories caches the slug lookups; nil marks an unknown slug
+	categories map[string]*bson.ObjectID
+	// seen maps each slug to the line that used it first
+	seen map[string]int
+}
+
+// importRow validates and saves one row. It returns the row's slug and its
+// action, or the row's errors; err is a backend failure that stops the
+// import.
+func (imp *productImport) importRow(line int, row []string) (string, string, *apierror.Error, error) {
+	ctx := imp.c.Request.Context()
+	rowErr := apierror.Validation()
+
+	body := dto.CreateProductDTO{
+		Name:            imp.cell(row, "name"),
+		Materials:       imp.list(row, "materials"),
+		Colors:          imp.list(row, "colors"),
+		Description:     imp.cell(row, "description"),
+		DescriptionFull: imp.cell(row, "descriptionFull"),
+		Dimensions:      imp.cell(row, "dimensions"),
+		Weight:          imp.cell(row, "weight"),
+	}
+	if raw := imp.number(row, "price"); raw != "" {
+		// French spreadsheets write 52000,0; prices are whole francs
+		price, err := strconv.ParseFloat(strings.Replace(raw, ",", ".", 1), 64)
+		switch {
+		case err != nil:
+			rowErr.WithField("price", apierror.RuleInvalid, "%s must be a positive number", "price")
+		case price != math.Trunc(price):
+			rowErr.WithField("price", apierror.RuleInvalid, "%s must be a whole number", "price")
+		default:
+			body.Price = int64(price)
+		}
+	}
+	if raw := imp.number(row, "quantity"); raw != "" {
+		quantity, err := strconv.Atoi(raw)
+		if err != nil {
+			rowErr.WithField("quantity", apierror.RuleInvalid, "%s must be a positive number", "quantity")
+		}
+		body.Quantity = quantity
+	}
+	for _, flag := range []struct {
+		name  string
+		value *bool
+	}{{"isTrending", &body.IsTrending}, {"isDisabled", &body.IsDisabled}} {
+		b, err := utils.ParseBoolQuery(imp.cell(row, flag.name))
+		if err != nil {
+			rowErr.WithField(flag.name, apierror.RuleInvalid, "%s must be true or false", flag.name)
+		} else if b != nil {
+			*flag.value = *b
+		}
+	}
+
+	slug := imp.cell(row, "slug")
+	if slug == "" {
+		slug = body.Name
+	}
+	body.Slug = utils.GenerateSlug(slug)
+	if first, ok := imp.seen[body.Slug]; ok && body.Slug != "" {
+		rowErr.WithField("slug", apierror.RuleUnique, "slug %s already appears on row %d", body.Slug, first)
+	} else {
+		imp.seen[body.Slug] = line
+	}
+
+	for _, catSlug := range imp.list(row, "categories") {
+		id, err := imp.category(catSlug)
+		if err != nil {
+			return body.Slug, "", nil, err
+		}
+		if id == nil {
+			rowErr.WithField("categories", apierror.RuleExists, "category %s does not exist", catSlug)
+			continue
+		}
+		body.CategoryIds = append(body.CategoryIds, id.Hex())
+	}
+
+	if err := binding.Validator.ValidateStruct(&body); err != nil {
+		for _, f := range apierror.Bind(err).Fields {
+			if f.Field == "categoryIds" {
+				f.Field = "categories"
+			}
+			// a cell that did not parse is reported once
+			if !slices.ContainsFunc(rowErr.Fields, func(e apierror.FieldError) bool { return e.Field == f.Field }) {
+				rowErr.Fields = append(rowErr.Fields, f)
+			}
+		}
+	}
+
+	var existing *models.Product
+	if body.Slug != "" {
+		var err error
+		existing, err = imp.app.Products.FindBySlug(ctx, body.Slug)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return body.Slug, "", nil, err
+		}
+	}
+
+	stockDelta := body.Quantity
+	if existing != nil {
+		stockDelta = 0
+		if _, ok := imp.columns["quantity"]; ok {
+			stockDelta = body.Quantity - existing.Quantity
+		}
+		if stockDelta != 0 && len(existing.Variants) > 0 {
+			rowErr.WithField("quantity", apierror.RuleInvalid, "the stock of product %s is held by its variants", existing.Id.Hex())
+		}
+	}
+
+	imageRefs, uploads, err := imp.resolveImages(row, existing, rowErr)
+	if err != nil {
+		return body.Slug, "", nil, err
+	}
+
+	action := dto.ImportActionCreate
+	if existing != nil {
+		action = dto.ImportActionUpdate
+	}
+	if len(rowErr.Fields) > 0 {
+		return body.Slug, "", rowErr, nil
+	}
+	if imp.dryRun {
+		return body.Slug, action, nil, nil
+	}
+
+	// upload the new images, then save the row
+	images := make([]models.Image, len(imageRefs))
+	for i, ref := range imageRefs {
+		if existing != nil {
+			images[i], _ = existing.Image(ref)
+		}
+	}
+	var uploaded []models.Image
+	for i, file := range uploads {
+		stored, err := storage.UploadImages(ctx, imp.app.Storage, body.Slug, []*upload.File{file}, imp.app.Config.Uploads.ImageQuality)
+		if err != nil {
+			imp.app.cleanupImages(ctx, uploaded)
+			return body.Slug, "", nil, err
+		}
+		images[i] = stored[0]
+		uploaded = append(uploaded, stored[0])
+	}
+	// the cell gives the order; kept images keep their alt text and flag
+	images = models.ArrangeImages(images)
+	categoryIds, _ := utils.StringsToObjectIDs(body.CategoryIds)
+
+	if existing == nil {
+		product := models.Product{
+			Name:            body.Name,
+			Slug:            body.Slug,
+			Price:           body.Price,
+			Currency:        money.Base,
+			Quantity:        body.Quantity,
+			Images:          images,
+			CategoryIds:     categoryIds,
+			Materials:       body.Materials,
+			Colors:          body.Colors,
+			Description:     body.Description,
+			DescriptionFull: body.DescriptionFull,
+			Dimensions:      body.Dimensions,
+			Weight:          body.Weight,
+			IsTrending:      body.IsTrending,
+			IsDisabled:      body.IsDisabled,
+			Status:          models.ProductStatusPublished,
+		}
+		if err := imp.app.Products.Insert(ctx, &product); err != nil {
+			imp.app.cleanupImages(ctx, uploaded)
+			if errors.Is(err, repositories.ErrDuplicateKey) {
+				// the slug belongs to a product in the trash
+				return body.Slug, "", apierror.Invalid("slug", apierror.RuleUnique, "%s is already taken", "slug"), nil
+			}
+			return body.Slug, "", nil, err
+		}
+		imp.app.recordInitialStock(imp.c, product.Id, nil, product.Quantity)
+		imp.app.recordRevision(imp.c, models.ProductRevisionCreate, nil, product.Id)
+		return body.Slug, action, nil, nil
+	}
+
+	set := bson.M{"name": body.Name, "price": body.Price, "categoryIds": categoryIds}
+	for column, value := range map[string]any{
+		"materials": body.Materials, "colors": body.Colors, "description": body.Description,
+		"descriptionFull": body.DescriptionFull, "dimensions": body.Dimensions, "weight": body.Weight,
+		"isTrending": body.IsTrending, "isDisabled": body.IsDisabled,
+	} {
+		if _, ok := imp.columns[column]; ok {
+			set[column] = value
+		}
+	}
+	var removed []models.Image
+	if len(images) > 0 {
+		set["images"] = images
+		for _, img := range existing.Images {
+			if !slices.Contains(imageRefs, img.URL) {
+				removed = append(removed, img)
+			}
+		}
+	}
+	// the stock moves first, so a short stock leaves the row unsaved
+	var movement *models.StockMovement
+	if stockDelta != 0 {
+		movement = newStockMovement(imp.c, existing.Id, nil, models.StockMovementAdjustment, stockDelta, "import")
+		if err := imp.app.moveStock(ctx, movement); err != nil {
+			imp.app.cleanupImages(ctx, uploaded)
+			return body.Slug, "", nil, err
+		}
+	}
+	if err := imp.app.Products.Update(ctx, existing.Id, set); err != nil {
+		imp.app.undoStock(imp.c, movement)
+		imp.app.cleanupImages(ctx, uploaded)
+		return body.Slug, "", nil, err
+	}
+	imp.app.cleanupImages(ctx, imp.app.dropVariantImages(ctx, existing, removed))
+	imp.app.recordRevision(imp.c, models.ProductRevisionUpdate, existing, existing.Id)
+	return body.Slug, action, nil, nil
+}
+
+// resolveImages reads the images cell of a row: the product's current URLs
+// are kept, anything else is a file to upload. It returns the cell's
+// entries and, by position, the files to upload, checked by the product
+// image policy; problems with the files are added to rowErr.
+func (imp *productImport) resolveImages(row []string, existing *models.Product, rowErr *apierror.Error) ([]string, map[int]*upload.File, error) {
+	refs := imp.list(row, "images")
+	if maxImages := imp.app.Config.Uploads.MaxProductImages; len(refs) > maxImages {
+		rowErr.WithField("images", "max", "at most %d images are allowed", maxImages)
+		return nil, nil, nil
+	}
+	if len(refs) == 0 && existing == nil {
+		rowErr.WithField("images", apierror.RuleRequired, "%s is required", "images")
+		return nil, nil, nil
+	}
+
+	uploads := map[int]*upload.File{}
+	for i, ref := range refs {
+		if existing != nil {
+			if _, ok := existing.Image(ref); ok {
+				continue
+			}
+		}
+		file, err := imp.images.Read(ref)
+		var rejection *upload.Error
+		switch {
+		case errors.Is(err, errImageNotFound):
+			rowErr.WithField("images", apierror.RuleExists, "image %s was not found", ref)
+		case errors.As(err, &rejection):
+			rowErr.WithField("images", rejection.Rule, rejection.Message, rejection.Args...)
+		case err != nil:
+			return nil, nil, err
+		default:
+			uploads[i] = file
+		}
+	}
+	return refs, uploads, nil
+}
+
+func (imp *productImport) cell(row []string, column string) string {
+	i, ok := imp.columns[column]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// number reads a numeric cell without its thousands separators: spaces,
+// including the no-break ones spreadsheets put in French numbers.
+func (imp *productImport) number(row []string, column string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\u202f' {
+			return -1
+		}
+		return r
+	}, imp.cell(row, column))
+}
+
+func (imp *productImport) list(row []string, column string) []string {
+	var values []string
+	for _, v := range strings.Split(imp.cell(row, column), listSeparator) {
+		if v = strings.TrimSpace(v); v != "" && !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// category resolves a category slug; nil means no such category.
+func (imp *productImport) category(slug string) (*bson.ObjectID, error) {
+	if id, ok := imp.categories[slug]; ok {
+		return id, nil
+	}
+	cat, err := imp.app.Categories.FindBySlug(imp.c.Request.Context(), slug)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+	var id *bson.ObjectID
+	if cat != nil {
+		id = &cat.Id
+	}
+	imp.categories[slug] = id
+	return id, nil
+}
+
+var errImageNotFound = errors.New("image not found")
+
+// importImages finds the files named by the images cells of an import: in
+// the uploaded archive first, then under IMPORT_IMAGES_DIR.
+type importImages struct {
+	archiveFile multipart.File
+	// archive indexes the archive entries by path and by base name
+	archive map[string]*zip.File
+	dir     *os.Root
+	policy  upload.Policy
+}
+
+// openImportImages opens the optional "archive" part and the images
+// directory of an import.
+func (app *App) openImportImages(c *gin.Context) (*importImages, *apierror.Error) {
+	images := &importImages{
+		archive: map[string]*zip.File{},
+		policy:  app.Config.Uploads.Policy(upload.ProductImage),
+	}
+
+	if fh, err := c.FormFile("archive"); err == nil {
+		f, err := fh.Open()
+		if err != nil {
+			return nil, apierror.Internal(err)
+		}
+		images.archiveFile = f
+		zr, err := zip.NewReader(f, fh.Size)
+		if err != nil {
+			images.Close()
+			return nil, apierror.New(http.StatusBadRequest, apierror.CodeInvalidUpload, "the archive is not a valid zip file").
+				WithField("archive", apierror.RuleInvalid, "the archive is not a valid zip file").Wrap(err)
+		}
+		for _, entry := range zr.File {
+			if entry.FileInfo().IsDir() {
+				continue
+			}
+			name := cleanImageRef(entry.Name)
+			images.archive[name] = entry
+			if _, taken := images.archive[path.Base(name)]; !taken {
+				images.archive[path.Base(name)] = entry
+			}
+		}
+	}
+
+	if dir := app.Config.Import.ImagesDir; dir != "" {
+		root, err := os.OpenRoot(dir)
+		if err != nil {
+			images.Close()
+			return nil, apierror.Internal(fmt.Errorf("open import images dir: %w", err))
+		}
+		images.dir = root
+	}
+	return images, nil
+}
+
+// Read returns the image ref names, checked by the product image policy.
+func (imgs *importImages) Read(ref string) (*upload.File, error) {
+	name := cleanImageRef(ref)
+
+	var r io.ReadCloser
+	if entry, ok := imgs.archive[name]; ok {
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s in the archive: %w", ref, err)
+		}
+		r = rc
+	} else if imgs.dir != nil && !strings.Contains(ref, "://") {
+		// os.Root refuses paths escaping the directory
+		f, err := imgs.dir.Open(filepath.FromSlash(name))
+		if err != nil {
+			return nil, errImageNotFound
+		}
+		if info, err := f.Stat(); err != nil || info.IsDir() {
+			f.Close()
+			return nil, errImageNotFound
+		}
+		r = f
+	} else {
+		return nil, errImageNotFound
+	}
+	defer r.Close()
+
+	data, err := imgs.policy.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", ref, err)
+	}
+	return imgs.policy.Check(ref, data)
+}
+
+func (imgs *importImages) Close() {
+	if imgs.archiveFile != nil {
+		imgs.archiveFile.Close()
+	}
+	if imgs.dir != nil {
+		imgs.dir.Close()
+	}
+}
+
+// cleanImageRef turns a file name of an images cell into a relative,
+// slash-separated path.
+func cleanImageRef(ref string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(ref, `\`, "/")), "/")
+}
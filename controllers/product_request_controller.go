@@ -3,17 +3,18 @@ package controllers
 import (
 	"encoding/json"
 	"net/http"
-	"regexp"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/princinho/sahobackend/database"
+	"github.com/princinho/sahobackend/apierror"
 	"github.com/princinho/sahobackend/dto"
 	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/storage"
+	"github.com/princinho/sahobackend/upload"
 	"github.com/princinho/sahobackend/utils"
 	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 // ====== CreateProductRequest (public — no auth) ================================================================
@@ -21,19 +22,19 @@ import (
 // multipart/form-data:
 //   - data: JSON string (CreateProductRequestDTO)
 //   - image: optional file (jpg/png/webp/jpeg) or even pdf
-func CreateProductRequest(ImageOrPdfValidator *utils.FileValidator) gin.HandlerFunc {
+func (app *App) CreateProductRequest() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
 
 		dataStr := c.PostForm("data")
 		if dataStr == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "missing data field"})
+			apierror.Abort(c, apierror.Required("data"))
 			return
 		}
 
 		var body dto.CreateProductRequestDTO
 		if err := json.Unmarshal([]byte(dataStr), &body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid data json", "details": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 
@@ -41,8 +42,10 @@ func CreateProductRequest(ImageOrPdfValidator *utils.FileValidator) gin.HandlerF
 		body.Email = strings.TrimSpace(body.Email)
 		body.Description = strings.TrimSpace(body.Description)
 
-		if body.FullName == "" || body.Email == "" || body.Description == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "fullName, email and description are required"})
+		if missing := requiredFields(map[string]string{
+			"fullName": body.FullName, "email": body.Email, "description": body.Description,
+		}); missing != nil {
+			apierror.Abort(c, missing)
 			return
 		}
 		if body.Quantity <= 0 {
@@ -74,31 +77,26 @@ func CreateProductRequest(ImageOrPdfValidator *utils.FileValidator) gin.HandlerF
 		}
 
 		// Optional reference image/file
-		file, errFile := c.FormFile("image")
-		if errFile == nil && file != nil {
-			if _, err := ImageOrPdfValidator.ValidateFile(file); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		fh, errFile := c.FormFile("image")
+		if errFile == nil && fh != nil {
+			file, aerr := app.checkUpload(upload.RequestAttachment, "image", fh)
+			if aerr != nil {
+				apierror.Abort(c, aerr)
 				return
 			}
-			gcsClient, bucket, err := utils.NewCloudClient(c)
+			att, err := storage.UploadProductRequestFile(ctx, app.Storage, req.Id.Hex(), file)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create GCS client"})
-				return
-			}
-			att, err := utils.UploadProductRequestFileToGCS(ctx, gcsClient, bucket, req.Id.Hex(), file)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				apierror.Abort(c, app.uploadError("image", err))
 				return
 			}
 			req.ReferenceImage = att
 		}
 
-		col := database.OpenCollection("product_requests")
-		_, err := col.InsertOne(ctx, req)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err := app.ProductRequests.Insert(ctx, &req); err != nil {
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
+		app.Metrics.ProductRequestStatus(string(req.Status))
 
 		c.JSON(http.StatusCreated, gin.H{
 			"id":      req.Id,
@@ -109,12 +107,11 @@ func CreateProductRequest(ImageOrPdfValidator *utils.FileValidator) gin.HandlerF
 
 // ====== GetProductRequests (admin) ==========================================================================================
 // GET /admin/product-requests?page=1&limit=20&status=NEW&email=a@b.com&q=...
-func GetProductRequests() gin.HandlerFunc {
+func (app *App) GetProductRequests() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("product_requests")
 
-		maxLimit, defaultLimit := utils.GetDefaultQueryLimits()
+		maxLimit, defaultLimit := app.Config.Query.MaxLimit, app.Config.Query.DefaultLimit
 
 		page := utils.ParseIntDefault(c.Query("page"), 1)
 		limit := utils.ParseIntDefault(c.Query("limit"), defaultLimit)
@@ -126,53 +123,15 @@ func GetProductRequests() gin.HandlerFunc {
 		}
 		skip := int64((page - 1) * limit)
 
-		filter := bson.M{}
-
-		if status := strings.TrimSpace(c.Query("status")); status != "" {
-			filter["status"] = status
-		}
-		if email := strings.TrimSpace(c.Query("email")); email != "" {
-			filter["email"] = email
-		}
-		if q := strings.TrimSpace(c.Query("q")); q != "" {
-			escaped := regexp.QuoteMeta(q)
-			filter["$or"] = []bson.M{
-				{"fullName": bson.M{"$regex": escaped, "$options": "i"}},
-				{"email": bson.M{"$regex": escaped, "$options": "i"}},
-				{"company": bson.M{"$regex": escaped, "$options": "i"}},
-				{"description": bson.M{"$regex": escaped, "$options": "i"}},
-			}
-		}
-
-		opts := options.Find().
-			SetSkip(skip).
-			SetLimit(int64(limit)).
-			SetSort(bson.D{{Key: "createdAt", Value: -1}})
-
-		cursor, err := col.Find(ctx, filter, opts)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		defer cursor.Close(ctx)
-
-		items := make([]models.ProductRequest, 0)
-		for cursor.Next(ctx) {
-			var r models.ProductRequest
-			if err := cursor.Decode(&r); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-			items = append(items, r)
-		}
-		if err := cursor.Err(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+		filter := repositories.ProductRequestFilter{
+			Status: strings.TrimSpace(c.Query("status")),
+			Email:  strings.TrimSpace(c.Query("email")),
+			Q:      strings.TrimSpace(c.Query("q")),
 		}
 
-		total, err := col.CountDocuments(ctx, filter)
+		items, total, err := app.ProductRequests.List(ctx, filter, repositories.Page{Skip: skip, Limit: int64(limit)})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
 
@@ -187,20 +146,19 @@ func GetProductRequests() gin.HandlerFunc {
 
 // ====== GetProductRequest (admin) ============================================================================================
 // GET /admin/product-requests/:id
-func GetProductRequest() gin.HandlerFunc {
+func (app *App) GetProductRequest() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("product_requests")
 
 		id, err := bson.ObjectIDFromHex(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product request id"})
+			apierror.Abort(c, apierror.InvalidID("invalid product request id"))
 			return
 		}
 
-		var req models.ProductRequest
-		if err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&req); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "product request not found"})
+		req, err := app.ProductRequests.FindByID(ctx, id)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product request not found"))
 			return
 		}
 
@@ -211,20 +169,19 @@ func GetProductRequest() gin.HandlerFunc {
 // ====== UpdateProductRequestStatus (admin) ==========================================================================
 // PATCH /admin/product-requests/:id/status
 // Body: { "status": "IN_PROGRESS" }
-func UpdateProductRequestStatus() gin.HandlerFunc {
+func (app *App) UpdateProductRequestStatus() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("product_requests")
 
 		id, err := bson.ObjectIDFromHex(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product request id"})
+			apierror.Abort(c, apierror.InvalidID("invalid product request id"))
 			return
 		}
 
 		var body dto.UpdateProductRequestStatusDTO
 		if err := c.ShouldBindJSON(&body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 
@@ -236,10 +193,7 @@ func UpdateProductRequestStatus() gin.HandlerFunc {
 			string(models.ProductRequestStatusClosed):     true,
 		}
 		if !allowed[body.Status] {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid status value",
-				"allowed": []string{"NEW", "IN_PROGRESS", "ANSWERED", "REJECTED", "CLOSED"},
-			})
+			apierror.Abort(c, invalidStatus("NEW, IN_PROGRESS, ANSWERED, REJECTED, CLOSED"))
 			return
 		}
 
@@ -253,15 +207,11 @@ func UpdateProductRequestStatus() gin.HandlerFunc {
 			set["answeredAt"] = now
 		}
 
-		res, err := col.UpdateByID(ctx, id, bson.M{"$set": set})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		if res.MatchedCount == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "product request not found"})
+		if err := app.ProductRequests.Update(ctx, id, set); err != nil {
+			apierror.Abort(c, lookupError(err, "product request not found"))
 			return
 		}
+		app.Metrics.ProductRequestStatus(body.Status)
 
 		c.JSON(http.StatusOK, gin.H{"ok": true})
 	}
@@ -272,32 +222,31 @@ func UpdateProductRequestStatus() gin.HandlerFunc {
 // multipart/form-data:
 //   - data: { "content": "..." }
 //   - file: optional attachment (pdf/image)
-func AddProductRequestNote() gin.HandlerFunc {
+func (app *App) AddProductRequestNote() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("product_requests")
 
 		reqID, err := bson.ObjectIDFromHex(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product request id"})
+			apierror.Abort(c, apierror.InvalidID("invalid product request id"))
 			return
 		}
 
 		dataStr := c.PostForm("data")
 		if dataStr == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "missing data field"})
+			apierror.Abort(c, apierror.Required("data"))
 			return
 		}
 
 		var body dto.AddAdminNoteDTO
 		if err := json.Unmarshal([]byte(dataStr), &body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid data json", "details": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 
 		body.Content = strings.TrimSpace(body.Content)
 		if body.Content == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "content is required"})
+			apierror.Abort(c, apierror.Required("content"))
 			return
 		}
 
@@ -316,51 +265,25 @@ func AddProductRequestNote() gin.HandlerFunc {
 		// optional attachment
 		fh, ferr := c.FormFile("file")
 		if ferr == nil && fh != nil {
-			gcsClient, bucket, err := utils.NewCloudClient(c)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create GCS client"})
+			file, aerr := app.checkUpload(upload.RequestAttachment, "file", fh)
+			if aerr != nil {
+				apierror.Abort(c, aerr)
 				return
 			}
-			att, err := utils.UploadProductRequestFileToGCS(ctx, gcsClient, bucket, reqID.Hex(), fh)
+			att, err := storage.UploadProductRequestFile(ctx, app.Storage, reqID.Hex(), file)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				apierror.Abort(c, app.uploadError("file", err))
 				return
 			}
 			note.Attachment = att
 		}
 
 		// auto-advance NEW -> IN_PROGRESS if adding first note
-		res, err := col.UpdateOne(ctx,
-			bson.M{"_id": reqID, "status": models.ProductRequestStatusNew},
-			bson.M{
-				"$push": bson.M{"notes": note},
-				"$set": bson.M{
-					"status":    models.ProductRequestStatusInProgress,
-					"updatedAt": time.Now().UTC(),
-				},
-			},
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err := app.ProductRequests.AddNote(ctx, reqID, note); err != nil {
+			apierror.Abort(c, lookupError(err, "product request not found"))
 			return
 		}
 
-		// if not NEW, just push note + updatedAt
-		if res.MatchedCount == 0 {
-			res2, err := col.UpdateByID(ctx, reqID, bson.M{
-				"$push": bson.M{"notes": note},
-				"$set":  bson.M{"updatedAt": time.Now().UTC()},
-			})
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-			if res2.MatchedCount == 0 {
-				c.JSON(http.StatusNotFound, gin.H{"error": "product request not found"})
-				return
-			}
-		}
-
 		c.JSON(http.StatusCreated, note)
 	}
 }
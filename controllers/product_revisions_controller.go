@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/logging"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// rollbackFields are the fields a rollback restores. The images are left
+// out because the ones a change removed are deleted from storage, and the
+// variants because their quantities follow the stock ledger.
+var rollbackFields = []string{
+	"name", "slug", "price", "categoryIds", "materials", "colors", "description", "descriptionFull",
+	"dimensions", "weight", "isTrending", "isDisabled", "similarProductsIds", "status", "publishAt", "unpublishAt", "translations",
+}
+
+// revisionInsertAttempts bounds the retries of a revision whose version was
+// taken by a concurrent change of the same product.
+const revisionInsertAttempts = 3
+
+// ====== GetProductRevisions (admin) ===========================================================================================
+//
+// GET /admin/products/:id/revisions?page=&limit= — the history of a product,
+// newest first. It outlives the product, trashed or deleted.
+
+func (app *App) GetProductRevisions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		maxLimit, defaultLimit := app.Config.Query.MaxLimit, app.Config.Query.DefaultLimit
+
+		id, err := bson.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidID("invalid product id"))
+			return
+		}
+
+		page := utils.ParseIntDefault(c.Query("page"), 1)
+		limit := utils.ParseIntDefault(c.Query("limit"), defaultLimit)
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > maxLimit {
+			limit = defaultLimit
+		}
+		skip := int64((page - 1) * limit)
+
+		items, total, err := app.Revisions.List(ctx, id, repositories.Page{Skip: skip, Limit: int64(limit)})
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": items,
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// ====== GetProductRevisionDiff (admin) ========================================================================================
+//
+// GET /admin/products/:id/revisions/diff?from=2&to=5 — the fields that differ
+// between two revisions, with their value in each. from may be after to.
+
+func (app *App) GetProductRevisionDiff() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		id, err := bson.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidID("invalid product id"))
+			return
+		}
+
+		var versions [2]int
+		verr := apierror.Validation()
+		for i, name := range []string{"from", "to"} {
+			raw := strings.TrimSpace(c.Query(name))
+			if raw == "" {
+				verr = verr.WithField(name, apierror.RuleRequired, "%s is required", name)
+				continue
+			}
+			v, err := strconv.Atoi(raw)
+			if err != nil || v < 1 {
+				verr = verr.WithField(name, "min", "%s must be at least %s", name, "1")
+				continue
+			}
+			versions[i] = v
+		}
+		if len(verr.Fields) > 0 {
+			apierror.Abort(c, verr)
+			return
+		}
+
+		var snapshots [2]map[string]json.RawMessage
+		for i, version := range versions {
+			rev, err := app.Revisions.FindByVersion(ctx, id, version)
+			if err != nil {
+				apierror.Abort(c, revisionError(err, version))
+				return
+			}
+			if snapshots[i], err = revisionFields(&rev.Snapshot); err != nil {
+				apierror.Abort(c, apierror.Internal(err))
+				return
+			}
+		}
+
+		diff := dto.ProductRevisionDiffDTO{ProductID: id, From: versions[0], To: versions[1], Changes: []dto.ProductFieldChangeDTO{}}
+		for _, field := range changedFields(snapshots[0], snapshots[1]) {
+			change := dto.ProductFieldChangeDTO{Field: field}
+			_ = json.Unmarshal(orNull(snapshots[0][field]), &change.From)
+			_ = json.Unmarshal(orNull(snapshots[1][field]), &change.To)
+			diff.Changes = append(diff.Changes, change)
+		}
+		c.JSON(http.StatusOK, diff)
+	}
+}
+
+// ====== RollbackProduct (admin) ===============================================================================================
+//
+// POST /admin/products/:id/revisions/:version/rollback — restores the
+// rollbackFields of a revision and records the result as a new revision.
+
+func (app *App) RollbackProduct() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		id, err := bson.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidID("invalid product id"))
+			return
+		}
+		version, err := strconv.Atoi(c.Param("version"))
+		if err != nil || version < 1 {
+			apierror.Abort(c, apierror.Invalid("version", "min", "%s must be at least %s", "version", "1"))
+			return
+		}
+
+		product, err := app.Products.FindByID(ctx, id)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+		rev, err := app.Revisions.FindByVersion(ctx, id, version)
+		if err != nil {
+			apierror.Abort(c, revisionError(err, version))
+			return
+		}
+
+		set, err := rollbackSet(&rev.Snapshot)
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+		if err := app.Products.Update(ctx, id, set); err != nil {
+			if errors.Is(err, repositories.ErrDuplicateKey) {
+				apierror.Abort(c, apierror.Conflict("slug", rev.Snapshot.Slug).Wrap(err))
+				return
+			}
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+		if _, err := app.addRevision(c, models.ProductRevisionRollback, product, id, version); err != nil {
+			logging.FromContext(ctx).Warn("product revision not recorded", "productId", id.Hex(), "error", err)
+		}
+
+		restored, err := app.Products.FindByID(ctx, id)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+		c.JSON(http.StatusOK, restored)
+	}
+}
+
+func revisionError(err error, version int) *apierror.Error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		return apierror.NotFound("revision %d not found", version).Wrap(err)
+	}
+	return apierror.Internal(err)
+}
+
+// rollbackSet is the $set restoring the rollbackFields of a snapshot; the
+// fields the snapshot lacks are cleared.
+func rollbackSet(snapshot *models.Product) (bson.M, error) {
+	raw, err := bson.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	set := bson.M{}
+	for _, field := range rollbackFields {
+		set[field] = doc[field]
+	}
+	return set, nil
+}
+
+// recordRevision appends the product as now stored to its history. before
+// is the product loaded ahead of the change, nil for a creation. The change
+// is already saved, so a failure is logged instead of failing the request.
+func (app *App) recordRevision(c *gin.Context, action models.ProductRevisionAction, before *models.Product, productID bson.ObjectID) {
+	if _, err := app.addRevision(c, action, before, productID, 0); err != nil {
+		ctx := c.Request.Context()
+		logging.FromContext(ctx).Warn("product revision not recorded", "productId", productID.Hex(), "error", err)
+	}
+}
+
+// addRevision records the revision of a change made by the signed-in admin.
+// A change that leaves the compared fields as they were (a stock update)
+// records nothing and returns nil.
+func (app *App) addRevision(c *gin.Context, action models.ProductRevisionAction, before *models.Product, productID bson.ObjectID, restoredVersion int) (*models.ProductRevision, error) {
+	ctx := c.Request.Context()
+	product, err := app.Products.FindByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("load product: %w", err)
+	}
+
+	snapshot := revisionSnapshot(*product)
+	after, err := revisionFields(&snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var previous map[string]json.RawMessage
+	if before != nil {
+		b := revisionSnapshot(*before)
+		if previous, err = revisionFields(&b); err != nil {
+			return nil, err
+		}
+	}
+	changed := changedFields(previous, after)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	authorID, authorEmail := signedInAuthor(c)
+	rev := &models.ProductRevision{
+		ProductID:       productID,
+		Action:          action,
+		ChangedFields:   changed,
+		RestoredVersion: restoredVersion,
+		Snapshot:        snapshot,
+		AuthorID:        authorID,
+		AuthorEmail:     authorEmail,
+		CreatedAt:       time.Now().UTC(),
+	}
+	for range revisionInsertAttempts {
+		rev.Version = 1
+		latest, err := app.Revisions.Latest(ctx, productID)
+		if err == nil {
+			rev.Version = latest.Version + 1
+		} else if !errors.Is(err, repositories.ErrNotFound) {
+			return nil, fmt.Errorf("load latest revision: %w", err)
+		}
+		err = app.Revisions.Insert(ctx, rev)
+		if !errors.Is(err, repositories.ErrDuplicateKey) {
+			return rev, err
+		}
+	}
+	return nil, fmt.Errorf("revision %d: %w", rev.Version, repositories.ErrDuplicateKey)
+}
+
+// revisionSnapshot copies a product as its revisions store it: without its
+// stock and trash date.
+func revisionSnapshot(p models.Product) models.Product {
+	p.Quantity = 0
+	p.DeletedAt = nil
+	p.Variants = slices.Clone(p.Variants)
+	for i := range p.Variants {
+		p.Variants[i].Quantity = 0
+	}
+	return p
+}
+
+// revisionFields encodes the fields compared between revisions, keyed by
+// their name in the product JSON.
+func revisionFields(snapshot *models.Product) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"id", "quantity", "deletedAt"} {
+		delete(fields, name)
+	}
+	return fields, nil
+}
+
+// changedFields lists, in name order, the fields whose encoding differs; a
+// missing field equals null. Without before, it lists the fields of after
+// that are not empty.
+func changedFields(before, after map[string]json.RawMessage) []string {
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name, value := range after {
+		if before == nil && isEmptyJSON(value) {
+			continue
+		}
+		names[name] = true
+	}
+
+	changed := []string{}
+	for name := range names {
+		if !bytes.Equal(orNull(before[name]), orNull(after[name])) {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+func orNull(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
+
+func isEmptyJSON(value json.RawMessage) bool {
+	switch string(value) {
+	case "null", `""`, "0", "false", "[]", "{}":
+		return true
+	}
+	return false
+}
+
+// signedInAuthor returns the id and email the auth middleware set for the
+// signed-in admin.
+func signedInAuthor(c *gin.Context) (bson.ObjectID, string) {
+	authorIDStr, _ := c.Get("userID")
+	authorEmail, _ := c.Get("email")
+	authorID, _ := bson.ObjectIDFromHex(fmt.Sprint(authorIDStr))
+	email, _ := authorEmail.(string)
+	return authorID, email
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var productStatuses = map[models.ProductStatus]bool{
+	models.ProductStatusDraft:     true,
+	models.ProductStatusScheduled: true,
+	models.ProductStatusPublished: true,
+	models.ProductStatusArchived:  true,
+}
+
+const productStatusList = "DRAFT, SCHEDULED, PUBLISHED, ARCHIVED"
+
+// productStatus validates the lifecycle fields of a product. An empty status
+// publishes the product, at publishAt when that is still to come.
+func productStatus(raw string, publishAt, unpublishAt *time.Time, now time.Time) (models.ProductStatus, *apierror.Error) {
+	status := models.ProductStatus(strings.ToUpper(strings.TrimSpace(raw)))
+	if status == "" {
+		status = models.ProductStatusPublished
+		if publishAt != nil && publishAt.After(now) {
+			status = models.ProductStatusScheduled
+		}
+	}
+	if !productStatuses[status] {
+		return "", invalidStatus(productStatusList)
+	}
+	if status == models.ProductStatusScheduled && publishAt == nil {
+		return "", apierror.Required("publishAt")
+	}
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return "", apierror.Invalid("unpublishAt", apierror.RuleInvalid, "%s must be after %s", "unpublishAt", "publishAt")
+	}
+	return status, nil
+}
+
+// ====== UpdateProductStatus (admin) ===========================================================================================
+//
+// PATCH /admin/products/:id/status
+// Body: application/json
+//
+//	{
+//	  "status": "SCHEDULED",                   // DRAFT | SCHEDULED | PUBLISHED | ARCHIVED
+//	  "publishAt": "2026-11-01T08:00:00Z",     // required for SCHEDULED
+//	  "unpublishAt": "2026-12-31T23:00:00Z"    // optional, archives the product
+//	}
+//
+// Only published products, and scheduled ones past their publishAt, are on
+// sale; the scheduler then moves them along (see ApplyProductSchedule).
+
+func (app *App) UpdateProductStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		id, err := bson.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidID("invalid product id"))
+			return
+		}
+
+		var body dto.UpdateProductStatusDTO
+		if err := c.ShouldBindJSON(&body); err != nil {
+			apierror.Abort(c, apierror.Bind(err))
+			return
+		}
+		status, statusErr := productStatus(body.Status, body.PublishAt, body.UnpublishAt, time.Now().UTC())
+		if statusErr != nil {
+			apierror.Abort(c, statusErr)
+			return
+		}
+
+		before, err := app.Products.FindByID(ctx, id)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+		set := bson.M{"status": status, "publishAt": body.PublishAt, "unpublishAt": body.UnpublishAt}
+		if err := app.Products.Update(ctx, id, set); err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+		app.recordRevision(c, models.ProductRevisionUpdate, before, id)
+
+		product, err := app.Products.FindByID(ctx, id)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+		c.JSON(http.StatusOK, product)
+	}
+}
+
+// ApplyProductSchedule publishes and archives the products whose dates have
+// passed, so their stored status catches up with what the shop shows. main
+// runs it every Catalog.ScheduleInterval.
+func (app *App) ApplyProductSchedule(ctx context.Context, now time.Time) (published, archived int64, err error) {
+	published, archived, err = app.Products.ApplySchedule(ctx, now)
+	if err != nil {
+		return published, archived, fmt.Errorf("apply product schedule: %w", err)
+	}
+	return published, archived, nil
+}
@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/logging"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ====== AddProductVariant (admin) =============================================================================================
+//
+// POST /admin/products/:id/variants
+// Body: application/json
+//
+//	{
+//	  "sku": "CH-LOME-TECK",
+//	  "options": [{ "name": "Finition", "value": "Teck" }],
+//	  "price": 135000,                    // optional, overrides the product price
+//	  "quantity": 4,
+//	  "imageUrls": ["https://..."]        // optional, among the product images
+//	}
+//
+// The first variant takes over the quantity of the product.
+
+func (app *App) AddProductVariant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		id, err := bson.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidID("invalid product id"))
+			return
+		}
+
+		var body dto.CreateVariantDTO
+		if err := c.ShouldBindJSON(&body); err != nil {
+			apierror.Abort(c, apierror.Bind(err))
+			return
+		}
+
+		product, err := app.Products.FindByID(ctx, id)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+
+		variant := models.ProductVariant{
+			Id:        bson.NewObjectID(),
+			SKU:       strings.TrimSpace(body.SKU),
+			Options:   variantOptions(body.Options),
+			Price:     body.Price,
+			Quantity:  body.Quantity,
+			ImageUrls: body.ImageUrls,
+		}
+		if apiErr := checkVariant(product, variant); apiErr != nil {
+			apierror.Abort(c, apiErr)
+			return
+		}
+
+		// the first variant takes over the stock held by the product, which
+		// is no longer counted once it has variants
+		var takeOut *models.StockMovement
+		if len(product.Variants) == 0 && product.Quantity > 0 {
+			takeOut = newStockMovement(c, product.Id, nil, models.StockMovementAdjustment, -product.Quantity, "moved to a variant")
+			if err := app.moveStock(ctx, takeOut); err != nil {
+				apierror.Abort(c, stockError(err, "product not found"))
+				return
+			}
+		}
+
+		if err := app.Products.AddVariant(ctx, product.Id, variant); err != nil {
+			app.undoStock(c, takeOut)
+			apierror.Abort(c, variantError(err, variant.SKU))
+			return
+		}
+		app.recordInitialStock(c, product.Id, &variant.Id, variant.Quantity)
+		if takeOut != nil {
+			moveIn := newStockMovement(c, product.Id, &variant.Id, models.StockMovementAdjustment, -takeOut.Delta, "moved from the product")
+			if err := app.moveStock(ctx, moveIn); err != nil {
+				logging.FromContext(ctx).Error("product stock not moved to the variant",
+					"productId", product.Id.Hex(), "variantId", variant.Id.Hex(), "error", err)
+				app.undoStock(c, takeOut)
+			} else {
+				variant.Quantity = moveIn.QuantityAfter
+			}
+		}
+		app.recordRevision(c, models.ProductRevisionUpdate, product, product.Id)
+
+		c.JSON(http.StatusCreated, variant)
+	}
+}
+
+// ====== UpdateProductVariant (admin) ==========================================================================================
+//
+// PATCH /admin/products/:id/variants/:variantId
+// Body: the fields of POST /admin/products/:id/variants to change. A price of
+// 0 drops the override.
+
+func (app *App) UpdateProductVariant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		product, variant, ok := app.loadVariant(c)
+		if !ok {
+			return
+		}
+
+		var body dto.UpdateVariantDTO
+		if err := c.ShouldBindJSON(&body); err != nil {
+			apierror.Abort(c, apierror.Bind(err))
+			return
+		}
+
+		updated := *variant
+		changed := false
+		if body.SKU != nil {
+			updated.SKU = strings.TrimSpace(*body.SKU)
+			changed = true
+		}
+		if body.Options != nil {
+			updated.Options = variantOptions(*body.Options)
+			changed = true
+		}
+		if body.Price != nil {
+			updated.Price = body.Price
+			if *body.Price == 0 {
+				updated.Price = nil
+			}
+			changed = true
+		}
+		// the quantity goes through the stock ledger, as an adjustment
+		stockDelta := 0
+		if body.Quantity != nil {
+			stockDelta = *body.Quantity - variant.Quantity
+			changed = true
+		}
+		if body.ImageUrls != nil {
+			updated.ImageUrls = *body.ImageUrls
+			changed = true
+		}
+		if !changed {
+			apierror.Abort(c, errNoUpdates())
+			return
+		}
+
+		if apiErr := checkVariant(product, updated); apiErr != nil {
+			apierror.Abort(c, apiErr)
+			return
+		}
+
+		// the stock moves first, so a short stock fails the whole update
+		var movement *models.StockMovement
+		if stockDelta != 0 {
+			movement = newStockMovement(c, product.Id, &updated.Id, models.StockMovementAdjustment, stockDelta, "variant update")
+			if err := app.moveStock(ctx, movement); err != nil {
+				apierror.Abort(c, stockError(err, "variant not found"))
+				return
+			}
+		}
+		if updated.SKU != variant.SKU || !slices.Equal(updated.Options, variant.Options) ||
+			!equalPrice(updated.Price, variant.Price) || !slices.Equal(updated.ImageUrls, variant.ImageUrls) {
+			if err := app.Products.UpdateVariant(ctx, product.Id, updated); err != nil {
+				app.undoStock(c, movement)
+				apierror.Abort(c, variantError(err, updated.SKU))
+				return
+			}
+		}
+		app.recordRevision(c, models.ProductRevisionUpdate, product, product.Id)
+
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+// ====== DeleteProductVariant (admin) ==========================================================================================
+//
+// DELETE /admin/products/:id/variants/:variantId — quote requests keep their
+// snapshot of the variant, and its remaining stock leaves through an
+// adjustment.
+
+func (app *App) DeleteProductVariant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		product, variant, ok := app.loadVariant(c)
+		if !ok {
+			return
+		}
+
+		// the stock the variant held leaves the ledger with it
+		var movement *models.StockMovement
+		if variant.Quantity > 0 {
+			movement = newStockMovement(c, product.Id, &variant.Id, models.StockMovementAdjustment, -variant.Quantity, "variant deleted")
+			if err := app.moveStock(ctx, movement); err != nil {
+				apierror.Abort(c, stockError(err, "variant not found"))
+				return
+			}
+		}
+
+		if err := app.Products.DeleteVariant(ctx, product.Id, variant.Id); err != nil {
+			app.undoStock(c, movement)
+			apierror.Abort(c, lookupError(err, "variant not found"))
+			return
+		}
+		app.recordRevision(c, models.ProductRevisionUpdate, product, product.Id)
+
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+// loadVariant resolves the :id and :variantId path parameters, aborting the
+// request when either is invalid or missing. The variant points into the
+// returned product's Variants.
+func (app *App) loadVariant(c *gin.Context) (*models.Product, *models.ProductVariant, bool) {
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidID("invalid product id"))
+		return nil, nil, false
+	}
+	variantID, err := bson.ObjectIDFromHex(c.Param("variantId"))
+	if err != nil {
+		apierror.Abort(c, apierror.InvalidID("invalid variant id"))
+		return nil, nil, false
+	}
+
+	product, err := app.Products.FindByID(c.Request.Context(), id)
+	if err != nil {
+		apierror.Abort(c, lookupError(err, "product not found"))
+		return nil, nil, false
+	}
+	variant, ok := product.Variant(variantID)
+	if !ok {
+		apierror.Abort(c, apierror.NotFound("variant not found"))
+		return nil, nil, false
+	}
+	return product, variant, true
+}
+
+// variantError maps the errors of a variant write. sku names the SKU to
+// blame when another product already uses it.
+func variantError(err error, sku string) *apierror.Error {
+	if errors.Is(err, repositories.ErrDuplicateKey) {
+		return apierror.Conflict("sku", sku).Wrap(err)
+	}
+	return lookupError(err, "variant not found")
+}
+
+func variantOptions(in []dto.VariantOptionDTO) []models.VariantOption {
+	out := make([]models.VariantOption, len(in))
+	for i, o := range in {
+		out[i] = models.VariantOption{Name: strings.TrimSpace(o.Name), Value: strings.TrimSpace(o.Value)}
+	}
+	return out
+}
+
+// checkVariant validates v against its product: the SKU and the option
+// values must tell it apart from the other variants, and its images must be
+// among the product's.
+func checkVariant(product *models.Product, v models.ProductVariant) *apierror.Error {
+	if v.SKU == "" {
+		return apierror.Invalid("sku", apierror.RuleRequired, "%s cannot be empty", "sku")
+	}
+
+	names := make([]string, 0, len(v.Options))
+	for _, o := range v.Options {
+		if o.Name == "" || o.Value == "" {
+			return apierror.Invalid("options", apierror.RuleRequired, "%s cannot be empty", "options")
+		}
+		name := strings.ToLower(o.Name)
+		if slices.Contains(names, name) {
+			return apierror.Invalid("options", apierror.RuleUnique, "option %s is repeated", o.Name)
+		}
+		names = append(names, name)
+	}
+
+	for _, other := range product.Variants {
+		if other.Id == v.Id {
+			continue
+		}
+		if strings.EqualFold(other.SKU, v.SKU) {
+			return apierror.Conflict("sku", v.SKU)
+		}
+		if sameOptions(other.Options, v.Options) {
+			return apierror.Invalid("options", apierror.RuleUnique, "a variant with these options already exists")
+		}
+	}
+
+	for _, url := range v.ImageUrls {
+		if _, ok := product.Image(url); !ok {
+			return apierror.Invalid("imageUrls", apierror.RuleExists, "%s is not an image of the product", url)
+		}
+	}
+	return nil
+}
+
+// sameOptions compares option sets regardless of order and case.
+func sameOptions(a, b []models.VariantOption) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		if !slices.ContainsFunc(b, func(y models.VariantOption) bool {
+			return strings.EqualFold(x.Name, y.Name) && strings.EqualFold(x.Value, y.Value)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalPrice(a, b *int64) bool {
+	return a == b || (a != nil && b != nil && *a == *b)
+}
+
+// dropVariantImages removes deleted product images from the variants and
+// returns the images whose files can go. The product is already saved, so
+// a failure is logged rather than returned, and the files stay for the
+// variants that still show them.
+func (app *App) dropVariantImages(ctx context.Context, product *models.Product, removed []models.Image) []models.Image {
+	if len(removed) == 0 || len(product.Variants) == 0 {
+		return removed
+	}
+	if err := app.Products.RenameVariantImages(ctx, product.Id, withoutImages(removed)); err != nil {
+		logging.FromContext(ctx).Error("variant images not updated", "product", product.Id.Hex(), "urls", models.ImageURLs(removed), "error", err)
+		return nil
+	}
+	return removed
+}
+
+// withoutImages maps the URLs of removed product images to "", so
+// RenameVariantImages drops them from the variants.
+func withoutImages(removed []models.Image) map[string]string {
+	renamed := make(map[string]string, len(removed))
+	for _, img := range removed {
+		renamed[img.URL] = ""
+	}
+	return renamed
+}
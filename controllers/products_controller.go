@@ -1,39 +1,53 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"maps"
 	"mime/multipart"
 	"net/http"
-	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/gin-gonic/gin"
-	"github.com/princinho/sahobackend/database"
+	"github.com/princinho/sahobackend/apierror"
 	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/logging"
 	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/money"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/search"
+	"github.com/princinho/sahobackend/storage"
+	"github.com/princinho/sahobackend/upload"
 	"github.com/princinho/sahobackend/utils"
 	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
-	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-type App struct {
-	GCSClient   *storage.Client
-	GCSBucket   string
-	ProductsCol *mongo.Collection
+func (app *App) GetProducts() gin.HandlerFunc {
+	return app.listProducts(false)
 }
 
-func GetProducts() gin.HandlerFunc {
+// ====== GetAdminProducts (admin) ==============================================================================================
+//
+// GET /admin/products — GET /products with the products that are not on sale
+// (drafts, scheduled, archived), filterable by ?status=.
+
+func (app *App) GetAdminProducts() gin.HandlerFunc {
+	return app.listProducts(true)
+}
+
+// listProducts serves GET /products, which only lists the products on sale,
+// and GET /admin/products, which lists every live product.
+func (app *App) listProducts(admin bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
 
 		categorySlug := strings.TrimSpace(c.Query("category"))
-		maxLimit, defaultLimit := utils.GetDefaultQueryLimits()
+		maxLimit, defaultLimit := app.Config.Query.MaxLimit, app.Config.Query.DefaultLimit
 
 		page := utils.ParseIntDefault(c.Query("page"), 1)
 		limit := utils.ParseIntDefault(c.Query("limit"), defaultLimit)
@@ -47,85 +61,133 @@ func GetProducts() gin.HandlerFunc {
 
 		// Optional sorting
 		sortParam := strings.TrimSpace(c.Query("sort"))
-		sortDoc := bson.D{{Key: "name", Value: 1}}
-		switch sortParam {
-		case "price_asc":
-			sortDoc = bson.D{{Key: "price", Value: 1}}
-		case "price_desc":
-			sortDoc = bson.D{{Key: "price", Value: -1}}
-		case "stock_asc":
-			sortDoc = bson.D{{Key: "createdAt", Value: 1}}
-		case "stock_desc":
-			sortDoc = bson.D{{Key: "createdAt", Value: -1}}
-		case "":
-			sortDoc = bson.D{{Key: "name", Value: 1}}
-		}
-
-		productsCol := database.OpenCollection("products")
-		categoriesCol := database.OpenCollection("categories")
-
-		// Build filter
-		filter := bson.M{}
-
-		if categorySlug != "" {
-			var cat models.Category
-			if err := categoriesCol.FindOne(ctx, bson.M{"slug": categorySlug}).Decode(&cat); err != nil {
-				// slug not found => return empty list (or 404; your choice)
-				c.JSON(http.StatusOK, gin.H{
-					"items": []models.Product{},
-					"page":  page,
-					"limit": limit,
-					"total": 0,
-				})
-				return
-			}
 
-			filter["categoryIds"] = bson.M{"$in": bson.A{cat.Id}}
+		// Optional text search, ranked by relevance unless sorted otherwise
+		q := strings.TrimSpace(c.Query("q"))
+		if len([]rune(q)) > search.MaxQueryLength {
+			apierror.Abort(c, apierror.Invalid("q", "max", "%s must be at most %s characters long", "q", strconv.Itoa(search.MaxQueryLength)))
+			return
 		}
-		if b, err := utils.ParseBoolQuery(c.Query("isTrending")); err == nil && b != nil {
-			filter["isTrending"] = *b
+		highlight, _ := utils.ParseBoolQuery(c.Query("highlight"))
+
+		// Prices, price filters and facets are in ?currency=, XOF by default
+		prices, aerr := app.pricing(ctx, c.Query("currency"))
+		if aerr != nil {
+			apierror.Abort(c, aerr)
+			return
 		}
-		if b, err := utils.ParseBoolQuery(c.Query("isDisabled")); err == nil && b != nil {
-			filter["isDisabled"] = *b
+
+		// The storefront gets the content in the locale of the request; the
+		// admin listing keeps the translations to edit them
+		locale := ""
+		if !admin {
+			locale = app.contentLocale(c)
 		}
-		findOpts := options.Find().
-			SetSkip(skip).
-			SetLimit(int64(limit)).
-			SetSort(sortDoc)
 
-		cursor, err := productsCol.Find(ctx, filter, findOpts)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+		// Build filter; multi-value params take comma-separated or repeated values
+		filter := repositories.ProductFilter{
+			Sort:      repositories.ProductSort(sortParam),
+			Materials: queryValues(c, "materials"),
+			Colors:    queryValues(c, "colors"),
 		}
-		defer cursor.Close(ctx)
 
-		products := make([]models.Product, 0)
-		for cursor.Next(ctx) {
-			var p models.Product
-			if err := cursor.Decode(&p); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if slugs := queryValues(c, "category"); len(slugs) > 0 {
+			for _, slug := range slugs {
+				cat, err := app.Categories.FindBySlug(ctx, slug)
+				if errors.Is(err, repositories.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					apierror.Abort(c, apierror.Internal(err))
+					return
+				}
+				filter.CategoryIDs = append(filter.CategoryIDs, cat.Id)
+			}
+			if len(filter.CategoryIDs) == 0 {
+				// unknown slugs match no product (but the facets are still counted)
+				filter.CategoryIDs = []bson.ObjectID{bson.NilObjectID}
+			}
+		}
+		for _, price := range []struct {
+			name  string
+			bound **int64
+		}{{"minPrice", &filter.MinPrice}, {"maxPrice", &filter.MaxPrice}} {
+			if raw := strings.TrimSpace(c.Query(price.name)); raw != "" {
+				v, err := strconv.ParseInt(raw, 10, 64)
+				if err != nil || v < 0 {
+					apierror.Abort(c, apierror.Invalid(price.name, apierror.RuleInvalid, "%s must be a positive number", price.name))
+					return
+				}
+				v = prices.base(v)
+				*price.bound = &v
+			}
+		}
+		if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+			apierror.Abort(c, apierror.Invalid("minPrice", apierror.RuleInvalid, "%s must not exceed %s", "minPrice", "maxPrice"))
+			return
+		}
+		if b, err := utils.ParseBoolQuery(c.Query("isTrending")); err == nil && b != nil {
+			filter.IsTrending = b
+		}
+		if b, err := utils.ParseBoolQuery(c.Query("isDisabled")); err == nil && b != nil {
+			filter.IsDisabled = b
+		}
+		if !admin {
+			now := time.Now().UTC()
+			filter.VisibleAt = &now
+		} else if raw := strings.TrimSpace(c.Query("status")); raw != "" {
+			filter.Status = models.ProductStatus(strings.ToUpper(raw))
+			if !productStatuses[filter.Status] {
+				apierror.Abort(c, invalidStatus(productStatusList))
 				return
 			}
-			products = append(products, p)
 		}
-		if err := cursor.Err(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+		// Items and total count for pagination UI
+		var items any
+		var total int64
+		var err error
+		if q != "" {
+			var hits []repositories.ProductHit
+			hits, total, err = app.Products.Search(ctx, q, filter, repositories.Page{Skip: skip, Limit: int64(limit)})
+			for i := range hits {
+				if !admin {
+					hits[i].Product = hits[i].Product.Localized(locale)
+				}
+				prices.product(&hits[i].Product)
+			}
+			items = searchHits(hits, q, highlight != nil && *highlight)
+		} else {
+			var products []models.Product
+			products, total, err = app.Products.List(ctx, filter, repositories.Page{Skip: skip, Limit: int64(limit)})
+			for i := range products {
+				if !admin {
+					products[i] = products[i].Localized(locale)
+				}
+				prices.product(&products[i])
+			}
+			items = products
+		}
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
 
-		// Total count for pagination UI
-		total, err := productsCol.CountDocuments(ctx, filter)
+		facets, err := app.productFacets(ctx, q, filter, prices, locale)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"items": products,
-			"page":  page,
-			"limit": limit,
-			"total": total,
+			"items":  items,
+			"facets": facets,
+			"q":      q,
+			"page":   page,
+			"limit":  limit,
+			"total":  total,
+			// the currency of every price in the response
+			"currency": prices.currency,
 			// helpful for debugging on frontend:
 			"category": categorySlug,
 			"sort":     sortParam,
@@ -134,55 +196,264 @@ func GetProducts() gin.HandlerFunc {
 	}
 }
 
-func AddProduct() gin.HandlerFunc {
+// queryValues collects a multi-value query parameter given as
+// "?colors=red,blue" or "?colors=red&colors=blue".
+func queryValues(c *gin.Context, name string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(name) {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" && !slices.Contains(values, v) {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// productFacets counts the listing per filter value for the storefront
+// sidebar. Category counts are resolved to the active categories, named in
+// locale.
+func (app *App) productFacets(ctx context.Context, q string, filter repositories.ProductFilter, prices pricing, locale string) (dto.ProductFacetsDTO, error) {
+	facets, err := app.Products.Facets(ctx, q, filter, app.Config.Catalog.PriceFacetBounds)
+	if err != nil {
+		return dto.ProductFacetsDTO{}, fmt.Errorf("count facets: %w", err)
+	}
+
+	out := dto.ProductFacetsDTO{
+		Categories: []dto.CategoryFacetDTO{},
+		Materials:  make([]dto.FacetValueDTO, 0, len(facets.Materials)),
+		Colors:     make([]dto.FacetValueDTO, 0, len(facets.Colors)),
+		Prices:     make([]dto.PriceBucketDTO, 0, len(facets.Prices)),
+	}
+	for _, v := range facets.Materials {
+		out.Materials = append(out.Materials, dto.FacetValueDTO{Value: v.Value, Count: v.Count})
+	}
+	for _, v := range facets.Colors {
+		out.Colors = append(out.Colors, dto.FacetValueDTO{Value: v.Value, Count: v.Count})
+	}
+	for _, b := range facets.Prices {
+		bucket := dto.PriceBucketDTO{Min: prices.amount(b.Min), Count: b.Count}
+		if b.Max != nil {
+			max := prices.amount(*b.Max)
+			bucket.Max = &max
+		}
+		out.Prices = append(out.Prices, bucket)
+	}
+
+	if len(facets.Categories) > 0 {
+		ids := make([]bson.ObjectID, len(facets.Categories))
+		for i, cc := range facets.Categories {
+			ids[i] = cc.ID
+		}
+		categories, err := app.Categories.FindByIDs(ctx, ids)
+		if err != nil {
+			return out, fmt.Errorf("load facet categories: %w", err)
+		}
+		byID := make(map[bson.ObjectID]models.Category, len(categories))
+		for _, cat := range categories {
+			byID[cat.Id] = cat
+		}
+		for _, cc := range facets.Categories {
+			if cat, ok := byID[cc.ID]; ok && cat.IsActive {
+				cat = cat.Localized(locale)
+				out.Categories = append(out.Categories, dto.CategoryFacetDTO{Id: cat.Id, Name: cat.Name, Slug: cat.Slug, Count: cc.Count})
+			}
+		}
+	}
+	return out, nil
+}
+
+// snippetContext is the number of words kept around a highlighted match.
+const snippetContext = 8
+
+func searchHits(hits []repositories.ProductHit, q string, highlight bool) []dto.ProductSearchHitDTO {
+	terms := search.Terms(q)
+	out := make([]dto.ProductSearchHitDTO, 0, len(hits))
+	for _, h := range hits {
+		hit := dto.ProductSearchHitDTO{Product: h.Product, Score: h.Score}
+		if highlight {
+			hit.Highlights = map[string]string{}
+			for field, text := range map[string]string{
+				"name":            h.Name,
+				"materials":       strings.Join(h.Materials, ", "),
+				"colors":          strings.Join(h.Colors, ", "),
+				"description":     h.Description,
+				"descriptionFull": h.DescriptionFull,
+			} {
+				if snippet := search.Highlight(text, terms, snippetContext); snippet != "" {
+					hit.Highlights[field] = snippet
+				}
+			}
+		}
+		out = append(out, hit)
+	}
+	return out
+}
+
+// ====== GetProduct ============================================================================================================================
+// Supports lookup by :id (ObjectID hex) or :slug. Disabled products are not
+// public and answer 404, like missing ones.
+
+func (app *App) GetProduct() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		collection := database.OpenCollection("products")
+		ctx := c.Request.Context()
+
+		idHex := strings.TrimSpace(c.Param("id"))
+		slug := strings.TrimSpace(c.Param("slug"))
+
+		if idHex == "" && slug == "" {
+			apierror.Abort(c, apierror.BadRequest("no id or slug provided"))
+			return
+		}
+
+		var product *models.Product
+		var err error
+
+		if idHex != "" {
+			id, idErr := bson.ObjectIDFromHex(idHex)
+			if idErr != nil {
+				apierror.Abort(c, apierror.InvalidID("invalid product id"))
+				return
+			}
+			product, err = app.Products.FindByID(ctx, id)
+		} else {
+			product, err = app.Products.FindBySlug(ctx, slug)
+		}
+		if err == nil && (product.IsDisabled || !product.IsPublished(time.Now().UTC())) {
+			err = repositories.ErrNotFound
+		}
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+
+		prices, aerr := app.pricing(ctx, c.Query("currency"))
+		if aerr != nil {
+			apierror.Abort(c, aerr)
+			return
+		}
+
+		detail, err := app.productDetail(ctx, product, prices, app.contentLocale(c))
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, detail)
+	}
+}
+
+// productDetail resolves the categories and similar products a product
+// references, all in locale. Inactive categories and hidden products are
+// left out; the summaries keep the order of the product's id lists.
+func (app *App) productDetail(ctx context.Context, product *models.Product, prices pricing, locale string) (dto.ProductDetailDTO, error) {
+	prices.product(product)
+	detail := dto.ProductDetailDTO{
+		Product:         product.Localized(locale),
+		Categories:      []dto.CategorySummaryDTO{},
+		SimilarProducts: []dto.ProductSummaryDTO{},
+	}
+
+	if len(product.CategoryIds) > 0 {
+		categories, err := app.Categories.FindByIDs(ctx, product.CategoryIds)
+		if err != nil {
+			return detail, fmt.Errorf("load categories: %w", err)
+		}
+		byID := make(map[bson.ObjectID]models.Category, len(categories))
+		for _, cat := range categories {
+			byID[cat.Id] = cat
+		}
+		for _, id := range product.CategoryIds {
+			if cat, ok := byID[id]; ok && cat.IsActive {
+				cat = cat.Localized(locale)
+				detail.Categories = append(detail.Categories, dto.CategorySummaryDTO{
+					Id: cat.Id, Name: cat.Name, Slug: cat.Slug, Image: cat.Image,
+				})
+			}
+		}
+	}
 
-		GCSClient, GSBucket, err := utils.NewCloudClient(c)
+	if len(product.SimilarProductsIds) > 0 {
+		now := time.Now().UTC()
+		similar, err := app.Products.FindByIDs(ctx, product.SimilarProductsIds)
 		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to create GCS client"})
+			return detail, fmt.Errorf("load similar products: %w", err)
 		}
+		byID := make(map[bson.ObjectID]models.Product, len(similar))
+		for _, p := range similar {
+			byID[p.Id] = p
+		}
+		for _, id := range product.SimilarProductsIds {
+			p, ok := byID[id]
+			if !ok || p.IsDisabled || !p.IsPublished(now) || p.Id == product.Id {
+				continue
+			}
+			p = p.Localized(locale)
+			summary := dto.ProductSummaryDTO{Id: p.Id, Name: p.Name, Slug: p.Slug, Price: prices.amount(p.Price)}
+			summary.Image = p.PrimaryImage()
+			detail.SimilarProducts = append(detail.SimilarProducts, summary)
+		}
+	}
+
+	return detail, nil
+}
+
+func (app *App) AddProduct() gin.HandlerFunc {
+	return func(c *gin.Context) {
 		jsonData := c.PostForm("data")
 		if jsonData == "" {
-			c.JSON(400, gin.H{"error": "missing data"})
+			apierror.Abort(c, apierror.Required("data"))
 			return
 		}
 
 		var dto dto.CreateProductDTO
 		if err := json.Unmarshal([]byte(jsonData), &dto); err != nil {
-			c.JSON(400, gin.H{"error": "invalid data json"})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 		dto.Slug = utils.GenerateSlug(dto.Name)
 		form, err := c.MultipartForm()
 		if err != nil {
-			c.JSON(400, gin.H{"error": "invalid multipart form"})
+			apierror.Abort(c, apierror.BadRequest("invalid multipart form").Wrap(err))
 			return
 		}
-		files := form.File["images"]
-
-		imageUrls, err := utils.UploadImagesToCloudAndGetPublicURLs(
-			c.Request.Context(),
-			GCSClient,
-			GSBucket,
-			dto.Slug,
-			files,
-		)
-		if err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
+		if len(form.File["images"]) > app.Config.Uploads.MaxProductImages {
+			apierror.Abort(c, tooManyImages(app.Config.Uploads.MaxProductImages))
+			return
+		}
+		files, aerr := app.checkUploads(upload.ProductImage, "images", form.File["images"])
+		if aerr != nil {
+			apierror.Abort(c, aerr)
 			return
 		}
 		categoryIdBsons, err := utils.StringsToObjectIDs(dto.CategoryIds)
 		if err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Invalid("categoryIds", apierror.RuleInvalid, "invalid category id").Wrap(err))
+			return
+		}
+		status, statusErr := productStatus(dto.Status, dto.PublishAt, dto.UnpublishAt, time.Now().UTC())
+		if statusErr != nil {
+			apierror.Abort(c, statusErr)
+			return
+		}
+		if aerr := app.checkTranslationLocales(slices.Collect(maps.Keys(dto.Translations))); aerr != nil {
+			apierror.Abort(c, aerr)
+			return
+		}
+
+		images, err := storage.UploadImages(c.Request.Context(), app.Storage, dto.Slug, files, app.Config.Uploads.ImageQuality)
+		if err != nil {
+			apierror.Abort(c, app.uploadError("images", err))
+			return
 		}
-		// Insert product with imageUrls
+		// Insert product with its images
 		product := models.Product{
 			Name:            dto.Name,
 			Slug:            dto.Slug,
 			Price:           dto.Price,
+			Currency:        money.Base,
 			Quantity:        dto.Quantity,
-			ImageUrls:       imageUrls,
+			Images:          models.ArrangeImages(images),
 			CategoryIds:     categoryIdBsons,
 			Materials:       dto.Materials,
 			Colors:          dto.Colors,
@@ -192,102 +463,113 @@ func AddProduct() gin.HandlerFunc {
 			Weight:          dto.Weight,
 			IsTrending:      dto.IsTrending,
 			IsDisabled:      dto.IsDisabled,
+			Status:          status,
+			PublishAt:       dto.PublishAt,
+			UnpublishAt:     dto.UnpublishAt,
+			Translations:    productTranslations(nil, dto.Translations),
 		}
 
-		_, err = collection.InsertOne(c.Request.Context(), product)
+		err = app.Products.Insert(c.Request.Context(), &product)
 		if err != nil {
-			if utils.IsDuplicateKey(err) {
-				c.JSON(409, gin.H{
-					"error": "slug already exists: '" + dto.Slug + "'",
-					"field": "slug",
-				})
+			app.cleanupImages(c.Request.Context(), images)
+			if errors.Is(err, repositories.ErrDuplicateKey) {
+				apierror.Abort(c, apierror.Conflict("slug", dto.Slug))
 				return
 			}
-			c.JSON(500, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
+		app.recordInitialStock(c, product.Id, nil, product.Quantity)
+		app.recordRevision(c, models.ProductRevisionCreate, nil, product.Id)
 
 		c.JSON(201, product)
 	}
 
 }
 
-func UpdateProduct() gin.HandlerFunc {
+func (app *App) UpdateProduct() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// parse id
 		idHex := c.Param("id")
 		prodID, err := bson.ObjectIDFromHex(idHex)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product id"})
+			apierror.Abort(c, apierror.InvalidID("invalid product id"))
 			return
 		}
-		collection := database.OpenCollection("products")
-		GCSClient, bucket, err := utils.NewCloudClient(c)
-
 		dataStr := c.PostForm("data")
 		if dataStr == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "missing data"})
+			apierror.Abort(c, apierror.Required("data"))
 			return
 		}
 
 		var dto dto.UpdateProductDTO
 		if err := json.Unmarshal([]byte(dataStr), &dto); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid data json", "details": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
-		log.Println("Init Ok")
 
 		ctx := c.Request.Context()
 
-		// 1) Load product (need current imageUrls)
-		var product models.Product
-		if err := collection.FindOne(ctx, bson.M{"_id": prodID}).Decode(&product); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+		// 1) Load product (need current images)
+		product, err := app.Products.FindByID(ctx, prodID)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+
+		// 2) The quantity goes through the stock ledger, as an adjustment
+		stockDelta := 0
+		if dto.Quantity != nil {
+			if *dto.Quantity < 0 {
+				apierror.Abort(c, apierror.Invalid("quantity", "min", "%s must be at least %s", "quantity", "0"))
+				return
+			}
+			stockDelta = *dto.Quantity - product.Quantity
+			if stockDelta != 0 && len(product.Variants) > 0 {
+				apierror.Abort(c, apierror.Invalid("quantity", apierror.RuleInvalid, "the stock of product %s is held by its variants", product.Id.Hex()))
+				return
+			}
+		}
+
+		if aerr := app.checkTranslationLocales(slices.Collect(maps.Keys(dto.Translations))); aerr != nil {
+			apierror.Abort(c, aerr)
 			return
 		}
 
-		// 2) Filter out imageUrls that don't belong to product
-		imagesToDelete := utils.IntersectStrings(dto.RemovedImagesUrls, product.ImageUrls)
+		// 3) Filter out removed images that don't belong to product
+		var imagesToDelete []models.Image
+		for _, url := range dto.RemovedImagesUrls {
+			if img, ok := product.Image(url); ok && !slices.Contains(models.ImageURLs(imagesToDelete), url) {
+				imagesToDelete = append(imagesToDelete, img)
+			}
+		}
 
-		// 5) Collect new image files
-		var newFiles []*multipart.FileHeader
+		// 5) Collect and check new image files
+		var newFileHeaders []*multipart.FileHeader
 		if form, err := c.MultipartForm(); err == nil && form != nil {
-			newFiles = form.File["images"]
+			newFileHeaders = form.File["images"]
 		}
-		maxProdImages, err := strconv.Atoi(os.Getenv("MAX_PROD_IMAGES"))
-		if err != nil {
-			maxProdImages = 4
-		}
-		totalImageCount := len(product.ImageUrls) - len(imagesToDelete) + len(newFiles)
+		maxProdImages := app.Config.Uploads.MaxProductImages
+		totalImageCount := len(product.Images) - len(imagesToDelete) + len(newFileHeaders)
 		if totalImageCount > maxProdImages {
-			c.JSON(400, gin.H{"error": fmt.Sprintf("Max %v images", maxProdImages)})
+			apierror.Abort(c, tooManyImages(maxProdImages))
+			return
+		}
+		newFiles, aerr := app.checkUploads(upload.ProductImage, "images", newFileHeaders)
+		if aerr != nil {
+			apierror.Abort(c, aerr)
 			return
 		}
-		log.Println("Parsing Images OK")
 		// 6) Upload new images (if any)
-		newObjectNames := []string{} // for cleanup if DB update fails
-		var imageUrls []string
+		var newImages []models.Image // for cleanup if DB update fails
 		if len(newFiles) > 0 {
-			urls, err := utils.UploadImagesToCloudAndGetPublicURLs(
-				c.Request.Context(),
-				GCSClient,
-				bucket,
-				product.Slug,
-				newFiles,
-			)
-			imageUrls = urls
+			newImages, err = storage.UploadImages(c.Request.Context(), app.Storage, product.Slug, newFiles, app.Config.Uploads.ImageQuality)
 			if err != nil {
-				c.JSON(400, gin.H{"image-upload-error": err.Error()})
+				apierror.Abort(c, app.uploadError("images", err))
 				return
 			}
 		}
 
-		for _, imageUrl := range imageUrls {
-			objName, _ := utils.ObjectNameFromCloudPublicURL(bucket, imageUrl)
-			newObjectNames = append(newObjectNames, objName)
-		}
-		log.Println("Images Uploading Ok")
-		update := bson.M{}
 		set := bson.M{}
 
 		if dto.Name != nil {
@@ -296,9 +578,6 @@ func UpdateProduct() gin.HandlerFunc {
 		if dto.Price != nil {
 			set["price"] = *dto.Price
 		}
-		if dto.Quantity != nil {
-			set["quantity"] = *dto.Quantity
-		}
 		if dto.Slug != nil {
 			set["slug"] = *dto.Slug
 		}
@@ -326,51 +605,196 @@ func UpdateProduct() gin.HandlerFunc {
 		if dto.IsDisabled != nil {
 			set["isDisabled"] = *dto.IsDisabled
 		}
+		if dto.Translations != nil {
+			set["translations"] = productTranslations(product.Translations, dto.Translations)
+		}
 		if dto.CategoryIds != nil {
-			set["categoryIds"] = *dto.CategoryIds
+			categoryIds, err := utils.StringsToObjectIDs(*dto.CategoryIds)
+			if err != nil {
+				apierror.Abort(c, apierror.Invalid("categoryIds", apierror.RuleInvalid, "invalid category id").Wrap(err))
+				return
+			}
+			set["categoryIds"] = categoryIds
 		}
 
-		mergedImageUrls := utils.MergeImageUrlsArrays(product.ImageUrls, imagesToDelete, imageUrls)
-		if len(imagesToDelete) > 0 || len(imageUrls) > 0 {
-			set["imageUrls"] = mergedImageUrls
+		if len(imagesToDelete) > 0 || len(newImages) > 0 {
+			removed := models.ImageURLs(imagesToDelete)
+			kept := slices.DeleteFunc(slices.Clone(product.Images), func(img models.Image) bool {
+				return slices.Contains(removed, img.URL)
+			})
+			set["images"] = models.ArrangeImages(append(kept, newImages...))
+		}
+		if len(set) == 0 && stockDelta == 0 {
+			apierror.Abort(c, errNoUpdates())
+			return
 		}
 
+		// 4) Record the new quantity first: a short stock fails the whole
+		// update before anything else is written
+		var movement *models.StockMovement
+		if stockDelta != 0 {
+			movement = newStockMovement(c, prodID, nil, models.StockMovementAdjustment, stockDelta, "product update")
+			if err := app.moveStock(ctx, movement); err != nil {
+				app.cleanupImages(ctx, newImages)
+				apierror.Abort(c, stockError(err, "product not found"))
+				return
+			}
+		}
+
+		// 5) Then update the other fields
 		if len(set) > 0 {
-			update["$set"] = set
+			err = app.Products.Update(ctx, prodID, set)
 		}
 
-		if len(update) == 0 {
-			c.JSON(400, gin.H{"error": "no updates provided"})
+		if err != nil {
+			// undo the stock move and delete new images from storage
+			app.undoStock(c, movement)
+			app.cleanupImages(ctx, newImages)
+			if errors.Is(err, repositories.ErrDuplicateKey) && dto.Slug != nil {
+				apierror.Abort(c, apierror.Conflict("slug", *dto.Slug).Wrap(err))
+				return
+			}
+			apierror.Abort(c, lookupError(err, "product not found"))
 			return
 		}
 
-		// 4) Update DB first
-		_, err = collection.UpdateByID(ctx, prodID, update)
+		// 6) DB update went fine. Drop the removed images from the variants,
+		// then from storage
+		app.cleanupImages(ctx, app.dropVariantImages(ctx, product, imagesToDelete))
+		app.recordRevision(c, models.ProductRevisionUpdate, product, prodID)
+
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+// ====== DeleteProduct (admin) =================================================================================================
+//
+// DELETE /admin/products/:id                 → moves the product to the trash
+// DELETE /admin/products/:id?permanent=true  → deletes it now, with its images
+//
+// Trashed products disappear from the shop and can be restored until the
+// trash retention elapses. Quote requests keep their item snapshots either way.
 
+func (app *App) DeleteProduct() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		id, err := bson.ObjectIDFromHex(c.Param("id"))
 		if err != nil {
-			// 5) Delete new images from GCS
-			if len(newObjectNames) > 0 {
-				_ = utils.DeleteCloudObjects(ctx, GCSClient, bucket, newObjectNames)
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db update failed", "details": err.Error()})
+			apierror.Abort(c, apierror.InvalidID("invalid product id"))
 			return
 		}
-		log.Println("Db Update Ok")
 
-		// 5) DB update went fine. Delete old images from GCS
-		if len(imagesToDelete) > 0 {
-			objectNames := make([]string, 0, len(imagesToDelete))
-			for _, imageUrl := range imagesToDelete {
-				obj, err := utils.ObjectNameFromCloudPublicURL(bucket, imageUrl)
-				if err == nil {
-					objectNames = append(objectNames, obj)
-				}
-			}
-			_ = utils.DeleteCloudObjects(ctx, GCSClient, bucket, objectNames)
+		permanent, err := utils.ParseBoolQuery(c.Query("permanent"))
+		if err != nil {
+			apierror.Abort(c, apierror.Invalid("permanent", apierror.RuleInvalid, "%s is invalid", "permanent"))
+			return
+		}
+
+		if permanent != nil && *permanent {
+			err = app.purgeProduct(ctx, id)
+		} else {
+			err = app.Products.Trash(ctx, id, time.Now().UTC())
+		}
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+// ====== GetTrashedProducts (admin) ============================================================================================
+//
+// GET /admin/products/trash — paginated and sorted (?sort=) like GET /products.
+
+func (app *App) GetTrashedProducts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		maxLimit, defaultLimit := app.Config.Query.MaxLimit, app.Config.Query.DefaultLimit
+
+		page := utils.ParseIntDefault(c.Query("page"), 1)
+		limit := utils.ParseIntDefault(c.Query("limit"), defaultLimit)
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > maxLimit {
+			limit = defaultLimit
+		}
+		skip := int64((page - 1) * limit)
+
+		filter := repositories.ProductFilter{Trashed: true, Sort: repositories.ProductSort(strings.TrimSpace(c.Query("sort")))}
+		items, total, err := app.Products.List(ctx, filter, repositories.Page{Skip: skip, Limit: int64(limit)})
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
 		}
 
-		log.Println("Deleting images Ok")
+		c.JSON(http.StatusOK, gin.H{
+			"items": items,
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// ====== RestoreProduct (admin) ================================================================================================
+//
+// POST /admin/products/:id/restore
+
+func (app *App) RestoreProduct() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := bson.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidID("invalid product id"))
+			return
+		}
+
+		if err := app.Products.Restore(c.Request.Context(), id); err != nil {
+			apierror.Abort(c, lookupError(err, "product not found in the trash"))
+			return
+		}
 
 		c.JSON(http.StatusOK, gin.H{"ok": true})
 	}
 }
+
+// PurgeTrash permanently deletes the products trashed longer than the
+// configured retention and returns how many were deleted. main runs it
+// every Trash.PurgeInterval.
+func (app *App) PurgeTrash(ctx context.Context, now time.Time) (int, error) {
+	retention := app.Config.Trash.Retention
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	expired, err := app.Products.TrashedBefore(ctx, now.Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("list expired trash: %w", err)
+	}
+
+	purged := 0
+	for _, p := range expired {
+		if err := app.purgeProduct(ctx, p.Id); err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return purged, fmt.Errorf("purge product %s: %w", p.Id.Hex(), err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// purgeProduct deletes a product and its images. Losing the similar-products
+// cleanup only leaves ids that product pages already skip, so it is logged.
+func (app *App) purgeProduct(ctx context.Context, id bson.ObjectID) error {
+	deleted, err := app.Products.Delete(ctx, id)
+	if deleted == nil {
+		return err
+	}
+	app.cleanupImages(ctx, deleted.Images)
+	if err != nil {
+		logging.FromContext(ctx).Warn("similar products cleanup failed", "productId", id.Hex(), "error", err)
+	}
+	return nil
+}
@@ -7,12 +7,14 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/princinho/sahobackend/database"
+	"github.com/princinho/sahobackend/apierror"
 	"github.com/princinho/sahobackend/dto"
 	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/storage"
+	"github.com/princinho/sahobackend/upload"
 	"github.com/princinho/sahobackend/utils"
 	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 // ====== CreateQuoteRequest (public — no auth) ======================================================================
@@ -30,115 +32,132 @@ import (
 //	  "message": "Je souhaite un devis.", // optional
 //	  "items": [
 //	    { "productId": "665f...", "quantity": 2 },
+//	    { "productId": "665f...", "variantId": "6660...", "quantity": 1 }, // required for products with variants
 //	    { "productId": "665f...", "quantity": 1 }
-//	  ]
+//	  ],
+//	  "currency": "EUR"                   // optional, XOF by default
 //	}
+//
+// The unit prices are converted to the currency and the quote keeps the
+// exchange rate they were converted with.
 
-func CreateQuoteRequest() gin.HandlerFunc {
+func (app *App) CreateQuoteRequest() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
 
 		var body dto.CreateQuoteRequestDTO
 		if err := c.ShouldBindJSON(&body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 
-		// 1) Convert all productId strings to ObjectIDs and build a quantity map
-		productsCol := database.OpenCollection("products")
-		productIDs := make([]bson.ObjectID, 0, len(body.Items))
-		idToQuantity := make(map[bson.ObjectID]int, len(body.Items))
+		prices, aerr := app.pricing(ctx, body.Currency)
+		if aerr != nil {
+			apierror.Abort(c, aerr)
+			return
+		}
 
-		for _, itemDTO := range body.Items {
+		// 1) Convert all productId strings to ObjectIDs
+		productIDs := make([]bson.ObjectID, 0, len(body.Items))
+		for i, itemDTO := range body.Items {
 			prodID, err := bson.ObjectIDFromHex(itemDTO.ProductID)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error":     "invalid productId",
-					"productId": itemDTO.ProductID,
-				})
+				apierror.Abort(c, apierror.Invalid(itemField(i, "productId"), apierror.RuleInvalid, "invalid product id %s", itemDTO.ProductID))
 				return
 			}
 			productIDs = append(productIDs, prodID)
-			idToQuantity[prodID] = itemDTO.Quantity
 		}
 
 		// 2) Fetch all products in a single DB round-trip
-		prodCursor, err := productsCol.Find(ctx, bson.M{"_id": bson.M{"$in": productIDs}})
+		products, err := app.Products.FindByIDs(ctx, productIDs)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
-		defer prodCursor.Close(ctx)
 
-		productMap := make(map[bson.ObjectID]models.Product, len(productIDs))
-		for prodCursor.Next(ctx) {
-			var p models.Product
-			if err := prodCursor.Decode(&p); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
+		productMap := make(map[bson.ObjectID]models.Product, len(products))
+		for _, p := range products {
 			productMap[p.Id] = p
 		}
-		if err := prodCursor.Err(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
 
-		// 3) Build enriched items, verifying every requested product was found
+		// 3) Build enriched items, verifying every requested product (and
+		// variant) was found. A product sold in variants is quoted per variant.
+		// Products the shop does not show are treated as missing, so their
+		// name and price never leak.
+		now := time.Now().UTC()
 		items := make([]models.QuoteRequestItem, 0, len(productIDs))
-		for _, prodID := range productIDs {
+		for i, prodID := range productIDs {
 			product, found := productMap[prodID]
-			if !found {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error":     "product not found",
-					"productId": prodID.Hex(),
-				})
+			if !found || product.IsDisabled || !product.IsPublished(now) {
+				apierror.Abort(c, apierror.Invalid(itemField(i, "productId"), apierror.RuleExists, "product %s does not exist", prodID.Hex()))
 				return
 			}
-			items = append(items, models.QuoteRequestItem{
+			item := models.QuoteRequestItem{
 				ProductID:   prodID,
-				Quantity:    idToQuantity[prodID],
+				Quantity:    body.Items[i].Quantity,
 				ProductName: product.Name,
 				ProductSlug: product.Slug,
-				UnitPrice:   product.Price,
-			})
-		}
+				UnitPrice:   prices.amount(product.Price),
+			}
 
-		now := time.Now().UTC()
-		quote := models.QuoteRequest{
-			FullName:  strings.TrimSpace(body.FullName),
-			Email:     strings.TrimSpace(body.Email),
-			Phone:     strings.TrimSpace(body.Phone),
-			Country:   strings.TrimSpace(body.Country),
-			City:      strings.TrimSpace(body.City),
-			Address:   strings.TrimSpace(body.Address),
-			Message:   strings.TrimSpace(body.Message),
-			Items:     items,
-			Status:    models.QuoteStatusNew,
-			Notes:     []models.QuoteAdminNote{},
-			CreatedAt: now,
-			UpdatedAt: now,
+			variantHex := strings.TrimSpace(body.Items[i].VariantID)
+			switch {
+			case variantHex != "":
+				variantID, err := bson.ObjectIDFromHex(variantHex)
+				if err != nil {
+					apierror.Abort(c, apierror.Invalid(itemField(i, "variantId"), apierror.RuleInvalid, "invalid variant id %s", variantHex))
+					return
+				}
+				variant, ok := product.Variant(variantID)
+				if !ok {
+					apierror.Abort(c, apierror.Invalid(itemField(i, "variantId"), apierror.RuleExists, "variant %s does not exist", variantHex))
+					return
+				}
+				item.VariantID = &variant.Id
+				item.VariantSKU = variant.SKU
+				item.VariantLabel = variant.Label()
+				item.UnitPrice = prices.amount(product.PriceOf(variant))
+			case len(product.Variants) > 0:
+				apierror.Abort(c, apierror.Invalid(itemField(i, "variantId"), apierror.RuleRequired, "product %s requires a variant", prodID.Hex()))
+				return
+			}
+			items = append(items, item)
 		}
 
-		col := database.OpenCollection("quote_requests")
-		res, err := col.InsertOne(ctx, quote)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		quote := models.QuoteRequest{
+			FullName:     strings.TrimSpace(body.FullName),
+			Email:        strings.TrimSpace(body.Email),
+			Phone:        strings.TrimSpace(body.Phone),
+			Country:      strings.TrimSpace(body.Country),
+			City:         strings.TrimSpace(body.City),
+			Address:      strings.TrimSpace(body.Address),
+			Message:      strings.TrimSpace(body.Message),
+			Items:        items,
+			Currency:     prices.currency,
+			ExchangeRate: prices.rate,
+			Status:       models.QuoteStatusNew,
+			Notes:        []models.QuoteAdminNote{},
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+
+		if err := app.QuoteRequests.Insert(ctx, &quote); err != nil {
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
+		app.Metrics.QuoteRequestStatus(string(quote.Status))
 
 		c.JSON(http.StatusCreated, gin.H{
-			"id":      res.InsertedID,
+			"id":      quote.ID,
 			"message": "Your quote request has been submitted. We will get back to you shortly.",
 		})
 	}
 }
 
-func GetQuoteRequests() gin.HandlerFunc {
+func (app *App) GetQuoteRequests() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("quote_requests")
-		maxLimit, defaultLimit := utils.GetDefaultQueryLimits()
+		maxLimit, defaultLimit := app.Config.Query.MaxLimit, app.Config.Query.DefaultLimit
 
 		page := utils.ParseIntDefault(c.Query("page"), 1)
 		limit := utils.ParseIntDefault(c.Query("limit"), defaultLimit)
@@ -150,40 +169,14 @@ func GetQuoteRequests() gin.HandlerFunc {
 		}
 		skip := int64((page - 1) * limit)
 
-		filter := bson.M{}
-		if status := strings.TrimSpace(c.Query("status")); status != "" {
-			filter["status"] = status
-		}
-
-		opts := options.Find().
-			SetSkip(skip).
-			SetLimit(int64(limit)).
-			SetSort(bson.D{{Key: "createdAt", Value: -1}}) // newest first
-
-		cursor, err := col.Find(ctx, filter, opts)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		defer cursor.Close(ctx)
-
-		items := make([]models.QuoteRequest, 0)
-		for cursor.Next(ctx) {
-			var q models.QuoteRequest
-			if err := cursor.Decode(&q); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-			items = append(items, q)
-		}
-		if err := cursor.Err(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+		filter := repositories.QuoteRequestFilter{
+			Status: strings.TrimSpace(c.Query("status")),
 		}
 
-		total, err := col.CountDocuments(ctx, filter)
+		// newest first
+		items, total, err := app.QuoteRequests.List(ctx, filter, repositories.Page{Skip: skip, Limit: int64(limit)})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
 
@@ -196,20 +189,19 @@ func GetQuoteRequests() gin.HandlerFunc {
 	}
 }
 
-func GetQuoteRequest() gin.HandlerFunc {
+func (app *App) GetQuoteRequest() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("quote_requests")
 
 		id, err := bson.ObjectIDFromHex(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quote request id"})
+			apierror.Abort(c, apierror.InvalidID("invalid quote request id"))
 			return
 		}
 
-		var quote models.QuoteRequest
-		if err := col.FindOne(ctx, bson.M{"_id": id}).Decode(&quote); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "quote request not found"})
+		quote, err := app.QuoteRequests.FindByID(ctx, id)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "quote request not found"))
 			return
 		}
 
@@ -221,20 +213,19 @@ func GetQuoteRequest() gin.HandlerFunc {
 //
 // PATCH /admin/quote-requests/:id/status
 // Body: { "status": "IN_PROGRESS" }
-func UpdateQuoteStatus() gin.HandlerFunc {
+func (app *App) UpdateQuoteStatus() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("quote_requests")
 
 		id, err := bson.ObjectIDFromHex(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quote request id"})
+			apierror.Abort(c, apierror.InvalidID("invalid quote request id"))
 			return
 		}
 
 		var body dto.UpdateQuoteStatusDTO
 		if err := c.ShouldBindJSON(&body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 
@@ -247,10 +238,7 @@ func UpdateQuoteStatus() gin.HandlerFunc {
 			string(models.QuoteStatusClosed):     true,
 		}
 		if !allowed[body.Status] {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "invalid status value",
-				"allowed": []string{"NEW", "IN_PROGRESS", "QUOTED", "REJECTED", "CLOSED"},
-			})
+			apierror.Abort(c, invalidStatus("NEW, IN_PROGRESS, QUOTED, REJECTED, CLOSED"))
 			return
 		}
 
@@ -265,15 +253,11 @@ func UpdateQuoteStatus() gin.HandlerFunc {
 			set["quotedAt"] = now
 		}
 
-		res, err := col.UpdateByID(ctx, id, bson.M{"$set": set})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		if res.MatchedCount == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "quote request not found"})
+		if err := app.QuoteRequests.Update(ctx, id, set); err != nil {
+			apierror.Abort(c, lookupError(err, "quote request not found"))
 			return
 		}
+		app.Metrics.QuoteRequestStatus(body.Status)
 
 		c.JSON(http.StatusOK, gin.H{"ok": true})
 	}
@@ -289,32 +273,31 @@ func UpdateQuoteStatus() gin.HandlerFunc {
 //
 // The logged-in admin's ID and email are expected to be set on the gin context
 // by your auth middleware, e.g. c.Set("userID", "...") / c.Set("email", "...").
-func AddQuoteNote() gin.HandlerFunc {
+func (app *App) AddQuoteNote() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		col := database.OpenCollection("quote_requests")
 
 		quoteID, err := bson.ObjectIDFromHex(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quote request id"})
+			apierror.Abort(c, apierror.InvalidID("invalid quote request id"))
 			return
 		}
 
 		// Parse JSON payload from the "data" form field
 		dataStr := c.PostForm("data")
 		if dataStr == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "missing data field"})
+			apierror.Abort(c, apierror.Required("data"))
 			return
 		}
 
 		var body dto.AddAdminNoteDTO
 		if err := json.Unmarshal([]byte(dataStr), &body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid data json", "details": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 		body.Content = strings.TrimSpace(body.Content)
 		if body.Content == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "content is required"})
+			apierror.Abort(c, apierror.Required("content"))
 			return
 		}
 
@@ -335,62 +318,26 @@ func AddQuoteNote() gin.HandlerFunc {
 		// Optional PDF attachment
 		pdfFile, pdfErr := c.FormFile("pdf")
 		if pdfErr == nil && pdfFile != nil {
-			gcsClient, GCSBucket, err := utils.NewCloudClient(c)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create GCS client"})
+			pdf, aerr := app.checkUpload(upload.QuotePDF, "pdf", pdfFile)
+			if aerr != nil {
+				apierror.Abort(c, aerr)
 				return
 			}
-
-			attachment, err := utils.UploadQuotePDFToCloud(ctx, gcsClient, GCSBucket, quoteID.Hex(), pdfFile)
+			attachment, err := storage.UploadQuotePDF(ctx, app.Storage, quoteID.Hex(), pdf)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				apierror.Abort(c, app.uploadError("pdf", err))
 				return
 			}
 			note.QuotePDF = attachment
 		}
 
-		// Push note into the notes array and update status + updatedAt
-		update := bson.M{
-			"$push": bson.M{"notes": note},
-			"$set": bson.M{
-				"updatedAt": time.Now().UTC(),
-				// Automatically move status to IN_PROGRESS when first note is added
-				// (only if still NEW — won't overwrite a more advanced status)
-			},
-		}
-
-		res, err := col.UpdateOne(ctx,
-			bson.M{
-				"_id":    quoteID,
-				"status": models.QuoteStatusNew, // only auto-advance from NEW
-			},
-			bson.M{
-				"$push": bson.M{"notes": note},
-				"$set": bson.M{
-					"status":    models.QuoteStatusInProgress,
-					"updatedAt": time.Now().UTC(),
-				},
-			},
-		)
-
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		// Push note into the notes array; the repository moves status to
+		// IN_PROGRESS when the quote is still NEW.
+		if err := app.QuoteRequests.AddNote(ctx, quoteID, note); err != nil {
+			apierror.Abort(c, lookupError(err, "quote request not found"))
 			return
 		}
 
-		// If the quote wasn't NEW, just push the note without changing status
-		if res.MatchedCount == 0 {
-			res2, err := col.UpdateByID(ctx, quoteID, update)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-			if res2.MatchedCount == 0 {
-				c.JSON(http.StatusNotFound, gin.H{"error": "quote request not found"})
-				return
-			}
-		}
-
 		c.JSON(http.StatusCreated, note)
 	}
 }
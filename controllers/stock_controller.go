@@ -0,0 +1,275 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/logging"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ====== AddStockMovement (admin) ==============================================================================================
+//
+// POST /admin/products/:id/stock-movements
+// Body: application/json
+//
+//	{
+//	  "type": "RECEIPT",            // RECEIPT | RESERVATION | RELEASE | ADJUSTMENT
+//	  "quantity": 10,               // units; signed for an ADJUSTMENT
+//	  "variantId": "6660...",       // required for products with variants
+//	  "reason": "Livraison atelier" // optional
+//	}
+
+func (app *App) AddStockMovement() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		id, err := bson.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			apierror.Abort(c, apierror.InvalidID("invalid product id"))
+			return
+		}
+
+		var body dto.CreateStockMovementDTO
+		if err := c.ShouldBindJSON(&body); err != nil {
+			apierror.Abort(c, apierror.Bind(err))
+			return
+		}
+
+		movementType := models.StockMovementType(strings.ToUpper(strings.TrimSpace(body.Type)))
+		delta := body.Quantity
+		switch movementType {
+		case models.StockMovementReceipt, models.StockMovementRelease, models.StockMovementReservation:
+			if body.Quantity <= 0 {
+				apierror.Abort(c, apierror.Invalid("quantity", "gt", "%s must be greater than %s", "quantity", "0"))
+				return
+			}
+			if movementType == models.StockMovementReservation {
+				delta = -body.Quantity
+			}
+		case models.StockMovementAdjustment:
+		default:
+			// INITIAL entries are only written when a product or variant is created
+			apierror.Abort(c, apierror.Invalid("type", apierror.RuleOneOf, "%s must be one of: %s", "type", "RECEIPT, RESERVATION, RELEASE, ADJUSTMENT"))
+			return
+		}
+
+		product, err := app.Products.FindByID(ctx, id)
+		if err != nil {
+			apierror.Abort(c, lookupError(err, "product not found"))
+			return
+		}
+
+		var variantID *bson.ObjectID
+		if hex := strings.TrimSpace(body.VariantID); hex != "" {
+			vid, err := bson.ObjectIDFromHex(hex)
+			if err != nil {
+				apierror.Abort(c, apierror.Invalid("variantId", apierror.RuleInvalid, "invalid variant id %s", hex))
+				return
+			}
+			if _, ok := product.Variant(vid); !ok {
+				apierror.Abort(c, apierror.Invalid("variantId", apierror.RuleExists, "variant %s does not exist", hex))
+				return
+			}
+			variantID = &vid
+		} else if len(product.Variants) > 0 {
+			// the stock of such a product is held by its variants
+			apierror.Abort(c, apierror.Invalid("variantId", apierror.RuleRequired, "product %s requires a variant", product.Id.Hex()))
+			return
+		}
+
+		movement := newStockMovement(c, product.Id, variantID, movementType, delta, strings.TrimSpace(body.Reason))
+		if err := app.moveStock(ctx, movement); err != nil {
+			apierror.Abort(c, stockError(err, "product not found"))
+			return
+		}
+
+		c.JSON(http.StatusCreated, movement)
+	}
+}
+
+// ====== GetStockMovements (admin) =============================================================================================
+//
+// GET /admin/stock-movements?productId=&variantId=&type=&page=&limit= — the
+// ledger, newest first.
+
+func (app *App) GetStockMovements() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		maxLimit, defaultLimit := app.Config.Query.MaxLimit, app.Config.Query.DefaultLimit
+
+		page := utils.ParseIntDefault(c.Query("page"), 1)
+		limit := utils.ParseIntDefault(c.Query("limit"), defaultLimit)
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > maxLimit {
+			limit = defaultLimit
+		}
+		skip := int64((page - 1) * limit)
+
+		filter := repositories.StockMovementFilter{
+			Type: models.StockMovementType(strings.ToUpper(strings.TrimSpace(c.Query("type")))),
+		}
+		for _, param := range []struct {
+			name string
+			id   **bson.ObjectID
+		}{{"productId", &filter.ProductID}, {"variantId", &filter.VariantID}} {
+			if hex := strings.TrimSpace(c.Query(param.name)); hex != "" {
+				id, err := bson.ObjectIDFromHex(hex)
+				if err != nil {
+					apierror.Abort(c, apierror.Invalid(param.name, apierror.RuleInvalid, "%s is invalid", param.name))
+					return
+				}
+				*param.id = &id
+			}
+		}
+
+		items, total, err := app.StockMovements.List(ctx, filter, repositories.Page{Skip: skip, Limit: int64(limit)})
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": items,
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// ====== GetLowStockProducts (admin) ===========================================================================================
+//
+// GET /admin/products/low-stock?threshold=&page=&limit= — products with at
+// most threshold units left (LOW_STOCK_THRESHOLD by default), lowest first.
+// For products with variants, each variant is checked.
+
+func (app *App) GetLowStockProducts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		maxLimit, defaultLimit := app.Config.Query.MaxLimit, app.Config.Query.DefaultLimit
+
+		page := utils.ParseIntDefault(c.Query("page"), 1)
+		limit := utils.ParseIntDefault(c.Query("limit"), defaultLimit)
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > maxLimit {
+			limit = defaultLimit
+		}
+		skip := int64((page - 1) * limit)
+
+		threshold := app.Config.Inventory.LowStockThreshold
+		if raw := strings.TrimSpace(c.Query("threshold")); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				apierror.Abort(c, apierror.Invalid("threshold", apierror.RuleInvalid, "%s must be a positive number", "threshold"))
+				return
+			}
+			threshold = n
+		}
+
+		products, total, err := app.Products.LowStock(ctx, threshold, repositories.Page{Skip: skip, Limit: int64(limit)})
+		if err != nil {
+			apierror.Abort(c, apierror.Internal(err))
+			return
+		}
+
+		items := make([]dto.LowStockProductDTO, 0, len(products))
+		for _, p := range products {
+			item := dto.LowStockProductDTO{Id: p.Id, Name: p.Name, Slug: p.Slug, Quantity: p.Quantity, IsDisabled: p.IsDisabled}
+			for _, v := range p.Variants {
+				if v.Quantity <= threshold {
+					item.Variants = append(item.Variants, dto.LowStockVariantDTO{Id: v.Id, SKU: v.SKU, Label: v.Label(), Quantity: v.Quantity})
+				}
+			}
+			items = append(items, item)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items":     items,
+			"threshold": threshold,
+			"page":      page,
+			"limit":     limit,
+			"total":     total,
+		})
+	}
+}
+
+// newStockMovement prepares a movement authored by the signed-in admin.
+func newStockMovement(c *gin.Context, productID bson.ObjectID, variantID *bson.ObjectID, t models.StockMovementType, delta int, reason string) *models.StockMovement {
+	authorID, email := signedInAuthor(c)
+	return &models.StockMovement{
+		ProductID:   productID,
+		VariantID:   variantID,
+		Type:        t,
+		Delta:       delta,
+		Reason:      reason,
+		AuthorID:    authorID,
+		AuthorEmail: email,
+	}
+}
+
+// moveStock applies a movement to the stock, then appends it to the ledger.
+// If the ledger entry cannot be written the stock change is reverted, so
+// the quantity always matches the movements.
+func (app *App) moveStock(ctx context.Context, movement *models.StockMovement) error {
+	after, err := app.Products.AdjustStock(ctx, movement.ProductID, movement.VariantID, movement.Delta)
+	if err != nil {
+		return err
+	}
+	movement.QuantityAfter = after
+	movement.CreatedAt = time.Now().UTC()
+
+	if err := app.StockMovements.Insert(ctx, movement); err != nil {
+		if _, revertErr := app.Products.AdjustStock(ctx, movement.ProductID, movement.VariantID, -movement.Delta); revertErr != nil {
+			logging.FromContext(ctx).Error("stock changed without a ledger entry",
+				"productId", movement.ProductID.Hex(), "delta", movement.Delta, "error", revertErr)
+		}
+		return fmt.Errorf("record stock movement: %w", err)
+	}
+	return nil
+}
+
+// undoStock reverts a movement applied before a write that then failed,
+// with the opposite adjustment so the ledger still adds up. A nil movement
+// is a no-op.
+func (app *App) undoStock(c *gin.Context, movement *models.StockMovement) {
+	if movement == nil {
+		return
+	}
+	ctx := c.Request.Context()
+	undo := newStockMovement(c, movement.ProductID, movement.VariantID, models.StockMovementAdjustment, -movement.Delta, movement.Reason+" (reverted)")
+	if err := app.moveStock(ctx, undo); err != nil {
+		logging.FromContext(ctx).Error("stock move not reverted",
+			"productId", movement.ProductID.Hex(), "delta", movement.Delta, "error", err)
+	}
+}
+
+// recordInitialStock writes the INITIAL entry of a product or variant just
+// created with quantity units. The stock itself is already stored, so a
+// failure is logged instead of failing the creation.
+func (app *App) recordInitialStock(c *gin.Context, productID bson.ObjectID, variantID *bson.ObjectID, quantity int) {
+	if quantity == 0 {
+		return
+	}
+	ctx := c.Request.Context()
+	movement := newStockMovement(c, productID, variantID, models.StockMovementInitial, quantity, "")
+	movement.QuantityAfter = quantity
+	movement.CreatedAt = time.Now().UTC()
+	if err := app.StockMovements.Insert(ctx, movement); err != nil {
+		logging.FromContext(ctx).Warn("initial stock movement not recorded", "productId", productID.Hex(), "error", err)
+	}
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/i18n"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/utils"
+)
+
+// Types of GET /admin/translations/missing items.
+const (
+	translationTypeProduct  = "product"
+	translationTypeCategory = "category"
+)
+
+// ====== GetMissingTranslations (admin) ========================================================================================
+//
+// GET /admin/translations/missing?locale=en&type=product&page=&limit= — the
+// live products and the categories with a name or description not yet
+// translated, one item per locale. Without ?locale=, every locale but the
+// default one is checked.
+
+func (app *App) GetMissingTranslations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		maxLimit, defaultLimit := app.Config.Query.MaxLimit, app.Config.Query.DefaultLimit
+
+		page := utils.ParseIntDefault(c.Query("page"), 1)
+		limit := utils.ParseIntDefault(c.Query("limit"), defaultLimit)
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > maxLimit {
+			limit = defaultLimit
+		}
+
+		locales := app.translationLocales()
+		if raw := strings.ToLower(strings.TrimSpace(c.Query("locale"))); raw != "" {
+			if !slices.Contains(locales, raw) {
+				apierror.Abort(c, apierror.Invalid("locale", apierror.RuleOneOf, "%s must be one of: %s", "locale", strings.Join(locales, ", ")))
+				return
+			}
+			locales = []string{raw}
+		}
+		kind := strings.ToLower(strings.TrimSpace(c.Query("type")))
+		if kind != "" && kind != translationTypeProduct && kind != translationTypeCategory {
+			apierror.Abort(c, apierror.Invalid("type", apierror.RuleOneOf, "%s must be one of: %s", "type",
+				translationTypeProduct+", "+translationTypeCategory))
+			return
+		}
+
+		// The result is the missing products then categories of each locale
+		// in turn. Each list is queried for the part of the page it holds
+		// (skip and limit relative to where it starts) or only counted.
+		skip, left := int64((page-1)*limit), int64(limit)
+		var total int64
+		window := func() repositories.Page { return repositories.Page{Skip: max(skip-total, 0), Limit: left} }
+		items := []dto.MissingTranslationDTO{}
+		for _, locale := range locales {
+			if kind != translationTypeCategory {
+				filter := repositories.ProductFilter{MissingTranslation: locale}
+				var products []models.Product
+				var n int64
+				var err error
+				if left > 0 {
+					products, n, err = app.Products.List(ctx, filter, window())
+				} else {
+					n, err = app.Products.Count(ctx, filter)
+				}
+				if err != nil {
+					apierror.Abort(c, apierror.Internal(err))
+					return
+				}
+				for _, p := range products {
+					items = append(items, dto.MissingTranslationDTO{
+						Type: translationTypeProduct, Id: p.Id, Name: p.Name, Slug: p.Slug, Locale: locale, Fields: p.MissingTranslations(locale),
+					})
+				}
+				total, left = total+n, left-int64(len(products))
+			}
+			if kind != translationTypeProduct {
+				filter := repositories.CategoryFilter{MissingTranslation: locale}
+				var categories []models.Category
+				var n int64
+				var err error
+				if left > 0 {
+					categories, n, err = app.Categories.List(ctx, filter, window())
+				} else {
+					n, err = app.Categories.Count(ctx, filter)
+				}
+				if err != nil {
+					apierror.Abort(c, apierror.Internal(err))
+					return
+				}
+				for _, cat := range categories {
+					items = append(items, dto.MissingTranslationDTO{
+						Type: translationTypeCategory, Id: cat.Id, Name: cat.Name, Slug: cat.Slug, Locale: locale, Fields: cat.MissingTranslations(locale),
+					})
+				}
+				total, left = total+n, left-int64(len(categories))
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": items,
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		})
+	}
+}
+
+// contentLocale picks the language of the catalog content of a public
+// response from ?lang=, then Accept-Language, then the default locale.
+func (app *App) contentLocale(c *gin.Context) string {
+	locale := i18n.ContentLocale(c.Query("lang"), c.GetHeader("Accept-Language"), app.Config.Catalog.DefaultLocale)
+	c.Header("Content-Language", locale)
+	// added to the Vary: Origin of the CORS middleware, not in its place
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	return locale
+}
+
+// translationLocales are the locales a translation can be given for: every
+// supported one but the default, which is the content of the fields.
+func (app *App) translationLocales() []string {
+	return slices.DeleteFunc(i18n.Locales(), func(l string) bool { return l == app.Config.Catalog.DefaultLocale })
+}
+
+// checkTranslationLocales reports the keys of a translations map that are
+// not translationLocales.
+func (app *App) checkTranslationLocales(locales []string) *apierror.Error {
+	allowed := app.translationLocales()
+	verr := apierror.Validation()
+	for _, locale := range locales {
+		if !slices.Contains(allowed, locale) {
+			verr = verr.WithField("translations."+locale, apierror.RuleOneOf, "%s must be one of: %s", "translations", strings.Join(allowed, ", "))
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// productTranslations applies changes to the current translations: each
+// locale given is replaced, or removed when its fields are all empty. It
+// returns nil when no translation is left.
+func productTranslations(current map[string]models.ProductTranslation, changes map[string]dto.ProductTranslationDTO) map[string]models.ProductTranslation {
+	out := maps.Clone(current)
+	for _, locale := range slices.Sorted(maps.Keys(changes)) {
+		t := models.ProductTranslation{
+			Name:            strings.TrimSpace(changes[locale].Name),
+			Description:     strings.TrimSpace(changes[locale].Description),
+			DescriptionFull: strings.TrimSpace(changes[locale].DescriptionFull),
+		}
+		out = setTranslation(out, locale, t, t == models.ProductTranslation{})
+	}
+	return out
+}
+
+// categoryTranslations is productTranslations for categories.
+func categoryTranslations(current map[string]models.CategoryTranslation, changes map[string]dto.CategoryTranslationDTO) map[string]models.CategoryTranslation {
+	out := maps.Clone(current)
+	for _, locale := range slices.Sorted(maps.Keys(changes)) {
+		t := models.CategoryTranslation{
+			Name:        strings.TrimSpace(changes[locale].Name),
+			Description: strings.TrimSpace(changes[locale].Description),
+		}
+		out = setTranslation(out, locale, t, t == models.CategoryTranslation{})
+	}
+	return out
+}
+
+func setTranslation[T any](m map[string]T, locale string, t T, empty bool) map[string]T {
+	if empty {
+		delete(m, locale)
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	}
+	if m == nil {
+		m = map[string]T{}
+	}
+	m[locale] = t
+	return m
+}
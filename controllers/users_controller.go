@@ -1,14 +1,16 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/princinho/sahobackend/database"
+	"github.com/princinho/sahobackend/apierror"
 	"github.com/princinho/sahobackend/dto"
 	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
 	"github.com/princinho/sahobackend/utils"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
@@ -22,25 +24,24 @@ func requireAdmin(c *gin.Context) bool {
 }
 
 // POST /admin/users
-func CreateUser() gin.HandlerFunc {
+func (app *App) CreateUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if !requireAdmin(c) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Only Admins can open accounts"})
+			apierror.Abort(c, apierror.Forbidden(apierror.CodeForbidden, "only admins can open accounts"))
+			return
 		}
 
 		var body dto.RegisterUserDTO
 		if err := c.ShouldBindJSON(&body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 
 		email := strings.ToLower(strings.TrimSpace(body.Email))
 
-		usersCol := database.OpenCollection("users")
-
 		hash, err := utils.HashPassword(body.Password)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
 
@@ -55,8 +56,12 @@ func CreateUser() gin.HandlerFunc {
 			UpdatedAt:    now,
 		}
 
-		if _, err := usersCol.InsertOne(c.Request.Context(), user); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err := app.Users.Insert(c.Request.Context(), &user); err != nil {
+			if errors.Is(err, repositories.ErrDuplicateKey) {
+				apierror.Abort(c, apierror.Conflict("email", email))
+				return
+			}
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
 
@@ -72,59 +77,56 @@ func CreateUser() gin.HandlerFunc {
 }
 
 // POST /admin/users/me/password
-func ChangeMyPassword() gin.HandlerFunc {
+func (app *App) ChangeMyPassword() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var body dto.ChangeMyPasswordDTO
 		if err := c.ShouldBindJSON(&body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Bind(err))
 			return
 		}
 
 		userIDStr, ok := c.Get("userID")
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+			apierror.Abort(c, errInvalidToken())
 			return
 		}
 
 		userID, err := bson.ObjectIDFromHex(userIDStr.(string))
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid auth context"})
+			apierror.Abort(c, errInvalidToken())
 			return
 		}
 
-		usersCol := database.OpenCollection("users")
-
-		var user models.User
-		if err := usersCol.FindOne(c.Request.Context(), bson.M{"_id": userID}).Decode(&user); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user"})
+		user, err := app.Users.FindByID(c.Request.Context(), userID)
+		if err != nil {
+			apierror.Abort(c, errInvalidToken().Wrap(err))
 			return
 		}
 
 		if err := utils.CheckPassword(user.PasswordHash, body.CurrentPassword); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "current password is incorrect"})
+			apierror.Abort(c, apierror.Unauthorized(apierror.CodeInvalidCredentials, "current password is incorrect").
+				WithField("currentPassword", apierror.RuleInvalid, "current password is incorrect"))
 			return
 		}
 
 		newHash, err := utils.HashPassword(body.NewPassword)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
 
 		now := time.Now().UTC()
-		_, err = usersCol.UpdateByID(c.Request.Context(), userID, bson.M{
-			"$set": bson.M{
-				"passwordHash": newHash,
-				"updatedAt":    now,
-			},
+		err = app.Users.Update(c.Request.Context(), userID, bson.M{
+			"passwordHash": newHash,
+			"updatedAt":    now,
 		})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apierror.Abort(c, apierror.Internal(err))
 			return
 		}
 
-		_ = RevokeAllRefreshTokens(c, userID)
-		utils.ClearRefreshCookie(c)
+		_ = app.revokeAllRefreshTokens(c, userID)
+		app.clearRefreshCookie(c)
 
 		c.JSON(http.StatusOK, gin.H{"ok": true})
 	}
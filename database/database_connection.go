@@ -3,45 +3,81 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 
-	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
 )
 
-var dbClient *mongo.Client
-
-func Connect() *mongo.Client {
+// Connect dials MongoDB once and pings the primary. The returned client is
+// safe for concurrent use and must be shared by the whole process. Every
+// command is reported to monitors (metrics, tracing) in order.
+func Connect(ctx context.Context, uri string, monitors ...*event.CommandMonitor) (*mongo.Client, error) {
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
-	}
-	uri := os.Getenv("MONGODB_URI")
 	opts := options.Client().ApplyURI(uri).SetServerAPIOptions(serverAPI)
+	if len(monitors) > 0 {
+		opts.SetMonitor(chainMonitors(monitors))
+	}
 	client, err := mongo.Connect(opts)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("mongo connect: %w", err)
 	}
-	// defer func() {
-	// 	if err = client.Disconnect(context.TODO()); err != nil {
-	// 		panic(err)
-	// 	}
-	// }()
 	// Send a ping to confirm a successful connection
-	if err := client.Ping(context.TODO(), readpref.Primary()); err != nil {
-		panic(err)
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, fmt.Errorf("mongo ping: %w", err)
+	}
+	slog.Info("mongo connected")
+	return client, nil
+}
+
+// Database returns the application database on client.
+func Database(client *mongo.Client, name string) *mongo.Database {
+	return client.Database(name)
+}
+
+// chainMonitors fans the driver's single monitor out to several.
+func chainMonitors(monitors []*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, evt)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, evt)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, evt)
+				}
+			}
+		},
 	}
-	fmt.Println("Pinged your deployment. You successfully connected to MongoDB!")
-	return client
 }
 
-func OpenCollection(collectionName string) *mongo.Collection {
-	dbClient = Connect()
-	databaseName := os.Getenv("DATABASE_NAME")
-	fmt.Println("DATABASE_NAME: ", databaseName)
-	collection := dbClient.Database(databaseName).Collection(collectionName)
-	return collection
+// CommandCollection reads the collection a command targets: the value of its
+// first field (find, insert, update, aggregate…), or the "collection" field
+// of getMore. Commands such as hello or ping have none.
+func CommandCollection(name string, cmd bson.Raw) string {
+	if name == "getMore" {
+		coll, _ := cmd.Lookup("collection").StringValueOK()
+		return coll
+	}
+	elems, err := cmd.Elements()
+	if err != nil || len(elems) == 0 {
+		return ""
+	}
+	coll, _ := elems[0].Value().StringValueOK()
+	return coll
 }
@@ -6,6 +6,8 @@ type CreateCategoryDTO struct {
 	Slug        string `json:"slug"` // auto-generated from Name if empty
 	Description string `json:"description"`
 	IsActive    bool   `json:"isActive"`
+	// Translations holds the content in the other locales, keyed by locale.
+	Translations map[string]CategoryTranslationDTO `json:"translations"`
 }
 
 // UpdateCategoryDTO — all fields are optional pointers
@@ -14,4 +16,7 @@ type UpdateCategoryDTO struct {
 	Slug        *string `json:"slug"`
 	Description *string `json:"description"`
 	IsActive    *bool   `json:"isActive"`
+	// Translations replaces the translation of each locale it lists; an
+	// empty one removes it.
+	Translations map[string]CategoryTranslationDTO `json:"translations"`
 }
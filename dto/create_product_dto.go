@@ -1,9 +1,12 @@
 package dto
 
+import "time"
+
 type CreateProductDTO struct {
-	Name            string   `json:"name" binding:"required,min=3"`
-	Price           float64  `json:"price" binding:"required,gt=0"`
-	Quantity        int      `json:"quantity" binding:"required,gte=0"`
+	Name string `json:"name" binding:"required,min=3"`
+	// Price is in XOF.
+	Price           int64    `json:"price" binding:"required,gt=0"`
+	Quantity        int      `json:"quantity" binding:"gte=0"`
 	Slug            string   `json:"slug" binding:"required"`
 	CategoryIds     []string `json:"categoryIds" binding:"required,min=1"`
 	Materials       []string `json:"materials"`
@@ -14,10 +17,17 @@ type CreateProductDTO struct {
 	Weight          string   `json:"weight"`
 	IsTrending      bool     `json:"isTrending"`
 	IsDisabled      bool     `json:"isDisabled"`
+	// Status defaults to SCHEDULED when PublishAt is in the future and to
+	// PUBLISHED otherwise.
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt"`
+	// Translations holds the content in the other locales, keyed by locale.
+	Translations map[string]ProductTranslationDTO `json:"translations"`
 }
 type UpdateProductDTO struct {
 	Name              *string   `json:"name,omitempty"`
-	Price             *float64  `json:"price,omitempty"`
+	Price             *int64    `json:"price,omitempty"`
 	Quantity          *int      `json:"quantity,omitempty"`
 	Slug              *string   `json:"slug,omitempty"`
 	Description       *string   `json:"description,omitempty"`
@@ -30,4 +40,15 @@ type UpdateProductDTO struct {
 	IsDisabled        *bool     `json:"isDisabled,omitempty"`
 	CategoryIds       *[]string `json:"categoryIds" binding:"required,min=1"`
 	RemovedImagesUrls []string  `json:"removedImagesUrls,omitempty"`
+	// Translations replaces the translation of each locale it lists; an
+	// empty one removes it. The other locales are kept.
+	Translations map[string]ProductTranslationDTO `json:"translations,omitempty"`
+}
+
+// UpdateProductStatusDTO replaces the status and both dates of a product; a
+// date left out is cleared.
+type UpdateProductStatusDTO struct {
+	Status      string     `json:"status" binding:"required"`
+	PublishAt   *time.Time `json:"publishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt"`
 }
@@ -0,0 +1,6 @@
+package dto
+
+// SetExchangeRateDTO is what one unit of the currency is worth in XOF.
+type SetExchangeRateDTO struct {
+	Rate float64 `json:"rate" binding:"required,gt=0"`
+}
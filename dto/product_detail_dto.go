@@ -0,0 +1,68 @@
+package dto
+
+import (
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ProductDetailDTO is the public product page: the product plus the
+// categories and similar products it references, resolved in one response.
+type ProductDetailDTO struct {
+	models.Product
+	Categories      []CategorySummaryDTO `json:"categories"`
+	SimilarProducts []ProductSummaryDTO  `json:"similarProducts"`
+}
+
+type CategorySummaryDTO struct {
+	Id    bson.ObjectID `json:"id"`
+	Name  string        `json:"name"`
+	Slug  string        `json:"slug"`
+	Image *models.Image `json:"image,omitempty"`
+}
+
+// ProductSummaryDTO is enough to render a product card.
+type ProductSummaryDTO struct {
+	Id    bson.ObjectID `json:"id"`
+	Name  string        `json:"name"`
+	Slug  string        `json:"slug"`
+	Price int64         `json:"price"`
+	Image *models.Image `json:"image,omitempty"` // the primary one of the product
+}
+
+// ProductSearchHitDTO is a GET /products item when ?q= is set: the product,
+// its relevance and, with ?highlight=true, an HTML snippet per matching
+// field with the matched words in <mark>.
+type ProductSearchHitDTO struct {
+	models.Product
+	Score      float64           `json:"score,omitempty"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// ProductFacetsDTO counts a GET /products listing per filter value. Each
+// facet applies every filter but its own.
+type ProductFacetsDTO struct {
+	Categories []CategoryFacetDTO `json:"categories"`
+	Materials  []FacetValueDTO    `json:"materials"`
+	Colors     []FacetValueDTO    `json:"colors"`
+	Prices     []PriceBucketDTO   `json:"prices"`
+}
+
+type CategoryFacetDTO struct {
+	Id    bson.ObjectID `json:"id"`
+	Name  string        `json:"name"`
+	Slug  string        `json:"slug"`
+	Count int64         `json:"count"`
+}
+
+type FacetValueDTO struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// PriceBucketDTO counts prices from Min (inclusive) to Max (exclusive); the
+// last bucket is open-ended. The bounds are in the listing currency.
+type PriceBucketDTO struct {
+	Min   int64  `json:"min"`
+	Max   *int64 `json:"max,omitempty"`
+	Count int64  `json:"count"`
+}
@@ -0,0 +1,16 @@
+package dto
+
+// ProductImageDTO is one image of a product gallery, named by its URL.
+type ProductImageDTO struct {
+	URL string `json:"url" binding:"required"`
+	// Alt replaces the alt text of the image, by locale, when given; an
+	// empty text removes the locale.
+	Alt       map[string]string `json:"alt,omitempty" binding:"omitempty,dive,max=250"`
+	IsPrimary bool              `json:"isPrimary"`
+}
+
+// UpdateProductImagesDTO lists every image of a product in its new order.
+// When none is flagged primary, the primary image stays the same.
+type UpdateProductImagesDTO struct {
+	Images []ProductImageDTO `json:"images" binding:"required,min=1,dive"`
+}
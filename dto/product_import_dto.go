@@ -0,0 +1,29 @@
+package dto
+
+import "github.com/princinho/sahobackend/apierror"
+
+// Actions of an imported row.
+const (
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+	ImportActionError  = "error"
+)
+
+// ProductImportReportDTO is the response of POST /admin/products/import.
+// In a dry run, Created and Updated count what the import would do.
+type ProductImportReportDTO struct {
+	DryRun  bool                  `json:"dryRun"`
+	Created int                   `json:"created"`
+	Updated int                   `json:"updated"`
+	Failed  int                   `json:"failed"`
+	Rows    []ProductImportRowDTO `json:"rows"`
+}
+
+// ProductImportRowDTO reports one data row; Row is its line in the file,
+// the header being line 1, and Action one of the ImportAction values.
+type ProductImportRowDTO struct {
+	Row    int                    `json:"row"`
+	Slug   string                 `json:"slug,omitempty"`
+	Action string                 `json:"action"`
+	Errors []apierror.FieldDetail `json:"errors,omitempty"`
+}
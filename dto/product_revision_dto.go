@@ -0,0 +1,21 @@
+package dto
+
+import "go.mongodb.org/mongo-driver/v2/bson"
+
+// ProductRevisionDiffDTO is the response of GET
+// /admin/products/:id/revisions/diff: the fields that differ between two
+// revisions, in field name order.
+type ProductRevisionDiffDTO struct {
+	ProductID bson.ObjectID           `json:"productId"`
+	From      int                     `json:"from"`
+	To        int                     `json:"to"`
+	Changes   []ProductFieldChangeDTO `json:"changes"`
+}
+
+// ProductFieldChangeDTO gives a field's value in each revision, as in the
+// product JSON; a field missing from a revision is null.
+type ProductFieldChangeDTO struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+type VariantOptionDTO struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Value string `json:"value" binding:"required,max=100"`
+}
+
+type CreateVariantDTO struct {
+	SKU     string             `json:"sku" binding:"required,max=64"`
+	Options []VariantOptionDTO `json:"options" binding:"required,min=1,dive"`
+	// Price overrides the product price when set, in XOF.
+	Price    *int64 `json:"price" binding:"omitempty,gt=0"`
+	Quantity int    `json:"quantity" binding:"gte=0"`
+	// ImageUrls picks among the product images.
+	ImageUrls []string `json:"imageUrls"`
+}
+
+// UpdateVariantDTO changes the given fields; a price of 0 drops the override.
+type UpdateVariantDTO struct {
+	SKU       *string             `json:"sku,omitempty" binding:"omitempty,min=1,max=64"`
+	Options   *[]VariantOptionDTO `json:"options,omitempty" binding:"omitempty,min=1,dive"`
+	Price     *int64              `json:"price,omitempty" binding:"omitempty,gte=0"`
+	Quantity  *int                `json:"quantity,omitempty" binding:"omitempty,gte=0"`
+	ImageUrls *[]string           `json:"imageUrls,omitempty"`
+}
@@ -5,6 +5,7 @@ type CreateQuoteNoteDTO struct {
 }
 type QuoteRequestItemDTO struct {
 	ProductID string `json:"productId" binding:"required"`
+	VariantID string `json:"variantId"`
 	Quantity  int    `json:"quantity"  binding:"required,min=1"`
 }
 
@@ -19,6 +20,8 @@ type CreateQuoteRequestDTO struct {
 
 	Message string                `json:"message"`
 	Items   []QuoteRequestItemDTO `json:"items" binding:"required,min=1,dive"`
+	// Currency prices the items; XOF when empty.
+	Currency string `json:"currency"`
 }
 
 type UpdateQuoteStatusDTO struct {
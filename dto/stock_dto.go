@@ -0,0 +1,31 @@
+package dto
+
+import "go.mongodb.org/mongo-driver/v2/bson"
+
+// CreateStockMovementDTO records a movement of a product's stock, or of one
+// of its variants. Quantity counts the units received, reserved or
+// released; for an ADJUSTMENT it is the signed correction.
+type CreateStockMovementDTO struct {
+	Type      string `json:"type" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required"`
+	VariantID string `json:"variantId"`
+	Reason    string `json:"reason" binding:"max=500"`
+}
+
+// LowStockProductDTO is a GET /admin/products/low-stock item. Variants only
+// lists the variants at or below the threshold.
+type LowStockProductDTO struct {
+	Id         bson.ObjectID        `json:"id"`
+	Name       string               `json:"name"`
+	Slug       string               `json:"slug"`
+	Quantity   int                  `json:"quantity"`
+	IsDisabled bool                 `json:"isDisabled"`
+	Variants   []LowStockVariantDTO `json:"variants,omitempty"`
+}
+
+type LowStockVariantDTO struct {
+	Id       bson.ObjectID `json:"id"`
+	SKU      string        `json:"sku"`
+	Label    string        `json:"label"`
+	Quantity int           `json:"quantity"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import "go.mongodb.org/mongo-driver/v2/bson"
+
+// ProductTranslationDTO is the content of a product in one locale; empty
+// fields fall back to the default locale.
+type ProductTranslationDTO struct {
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	DescriptionFull string `json:"descriptionFull"`
+}
+
+// CategoryTranslationDTO is the content of a category in one locale.
+type CategoryTranslationDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// MissingTranslationDTO is a product or category with fields set in the
+// default locale but not translated in Locale.
+type MissingTranslationDTO struct {
+	Type   string        `json:"type"`
+	Id     bson.ObjectID `json:"id"`
+	Name   string        `json:"name"`
+	Slug   string        `json:"slug"`
+	Locale string        `json:"locale"`
+	Fields []string      `json:"fields"`
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/middleware"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+)
+
+// failingQuotes fails every List with an error clients must never see.
+type failingQuotes struct {
+	repositories.QuoteRequestRepository
+}
+
+func (failingQuotes) List(context.Context, repositories.QuoteRequestFilter, repositories.Page) ([]models.QuoteRequest, int64, error) {
+	return nil, 0, errors.New("mongo: connection refused to db-internal:27017")
+}
+
+func TestErrorsAreLocalizedFromAcceptLanguage(t *testing.T) {
+	e := newTestEnv(t)
+
+	cases := []struct {
+		acceptLanguage, language, message string
+	}{
+		{"", "fr", "catégorie introuvable"},
+		{"fr-FR,fr;q=0.9", "fr", "catégorie introuvable"},
+		{"en-US,en;q=0.9", "en", "category not found"},
+		{"de-DE", "fr", "catégorie introuvable"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/categories/000000000000000000000000", nil)
+		if tc.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		req.Header.Set(middleware.RequestIDHeader, "req-404")
+		req.Header.Set("Origin", "http://localhost:3000")
+		w := e.do(req)
+		expectStatus(t, w, http.StatusNotFound)
+
+		if got := w.Header().Get("Content-Language"); got != tc.language {
+			t.Fatalf("%q: Content-Language = %q", tc.acceptLanguage, got)
+		}
+		// the CORS Vary: Origin is kept, and Accept-Language is listed once
+		if got := w.Header().Values("Vary"); !slices.Equal(got, []string{"Origin", "Accept-Language"}) {
+			t.Fatalf("%q: Vary = %q", tc.acceptLanguage, got)
+		}
+		body := decodeJSON[apierror.Envelope](t, w)
+		if body.Error.Code != apierror.CodeNotFound || body.Error.Message != tc.message || body.Error.RequestID != "req-404" {
+			t.Fatalf("%q: error = %+v", tc.acceptLanguage, body.Error)
+		}
+	}
+}
+
+func TestValidationErrorsListEveryField(t *testing.T) {
+	e := newTestEnv(t)
+
+	req := e.jsonRequest(http.MethodPost, "/quote-requests", map[string]any{
+		"email": "not-an-email",
+		"items": []map[string]any{{"productId": "665f00000000000000000000", "quantity": -1}},
+	}, "")
+	req.Header.Set("Accept-Language", "en")
+	w := e.do(req)
+	expectStatus(t, w, http.StatusBadRequest)
+
+	body := decodeJSON[apierror.Envelope](t, w)
+	if body.Error.Code != apierror.CodeValidationFailed {
+		t.Fatalf("code = %q", body.Error.Code)
+	}
+	fields := map[string]apierror.FieldDetail{}
+	for _, f := range body.Error.Fields {
+		fields[f.Field] = f
+	}
+	if f := fields["fullName"]; f.Rule != apierror.RuleRequired || f.Message != "fullName is required" {
+		t.Fatalf("fullName = %+v", f)
+	}
+	if f := fields["email"]; f.Rule != "email" {
+		t.Fatalf("email = %+v", f)
+	}
+	if f := fields["items[0].quantity"]; f.Rule != "min" || f.Message != "items[0].quantity must be at least 1" {
+		t.Fatalf("fields = %+v", body.Error.Fields)
+	}
+}
+
+func TestInternalErrorsAreLoggedNotLeaked(t *testing.T) {
+	e := newTestEnv(t)
+	e.app.QuoteRequests = failingQuotes{e.app.QuoteRequests}
+	token := e.adminToken()
+	e.logs.Reset()
+
+	w := e.do(e.jsonRequest(http.MethodGet, "/admin/quote-requests", nil, token))
+	expectStatus(t, w, http.StatusInternalServerError)
+
+	if strings.Contains(w.Body.String(), "db-internal") {
+		t.Fatalf("body leaks the cause: %s", w.Body.String())
+	}
+	body := decodeJSON[apierror.Envelope](t, w)
+	if body.Error.Code != apierror.CodeInternal || body.Error.RequestID == "" {
+		t.Fatalf("error = %+v", body.Error)
+	}
+	if !strings.Contains(e.logs.String(), "db-internal") {
+		t.Fatalf("cause not logged: %s", e.logs.String())
+	}
+}
+
+func TestUnknownRoutesUseTheEnvelope(t *testing.T) {
+	e := newTestEnv(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	req.Header.Set("Accept-Language", "en")
+	w := e.do(req)
+	expectStatus(t, w, http.StatusNotFound)
+
+	body := decodeJSON[apierror.Envelope](t, w)
+	if body.Error.Code != apierror.CodeRouteNotFound || body.Error.Message != "route not found" {
+		t.Fatalf("error = %+v", body.Error)
+	}
+}
+
+func TestAuthErrorsCarryTheirCode(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedAdmin("boss@saho.test", "correct-horse")
+
+	w := e.do(e.jsonRequest(http.MethodPost, "/auth/login", map[string]string{
+		"email": "boss@saho.test", "password": "wrong",
+	}, ""))
+	expectStatus(t, w, http.StatusUnauthorized)
+	if body := decodeJSON[apierror.Envelope](t, w); body.Error.Code != apierror.CodeInvalidCredentials {
+		t.Fatalf("login error = %+v", body.Error)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/config", nil, ""))
+	expectStatus(t, w, http.StatusUnauthorized)
+	if body := decodeJSON[apierror.Envelope](t, w); body.Error.Code != apierror.CodeUnauthorized || body.Error.Message != "token manquant" {
+		t.Fatalf("missing token error = %+v", body.Error)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestAdminManagesExchangeRates(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPut, "/admin/exchange-rates/EUR", gin.H{"rate": 655.957}, "")), http.StatusUnauthorized)
+	for path, body := range map[string]gin.H{
+		"/admin/exchange-rates/XOF": {"rate": 1},
+		"/admin/exchange-rates/BTC": {"rate": 1},
+		"/admin/exchange-rates/USD": {"rate": 0},
+	} {
+		expectStatus(t, e.do(e.jsonRequest(http.MethodPut, path, body, token)), http.StatusBadRequest)
+	}
+
+	w := e.do(e.jsonRequest(http.MethodPut, "/admin/exchange-rates/eur", gin.H{"rate": 650}, token))
+	expectStatus(t, w, http.StatusOK)
+	created := decodeJSON[models.ExchangeRate](t, w)
+	w = e.do(e.jsonRequest(http.MethodPut, "/admin/exchange-rates/EUR", gin.H{"rate": 655.957}, token))
+	expectStatus(t, w, http.StatusOK)
+	if updated := decodeJSON[models.ExchangeRate](t, w); updated.ID != created.ID || updated.Currency != "EUR" ||
+		updated.Rate != 655.957 || updated.UpdatedBy != testAdminEmail {
+		t.Fatalf("updated rate = %+v, created %+v", updated, created)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/exchange-rates", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeJSON[struct {
+		Base  string                `json:"base"`
+		Items []models.ExchangeRate `json:"items"`
+	}](t, w); got.Base != "XOF" || len(got.Items) != 1 || got.Items[0].Rate != 655.957 {
+		t.Fatalf("rates = %+v", got)
+	}
+
+	expectStatus(t, e.do(e.jsonRequest(http.MethodDelete, "/admin/exchange-rates/EUR", nil, token)), http.StatusOK)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodDelete, "/admin/exchange-rates/EUR", nil, token)), http.StatusNotFound)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/products?currency=EUR", nil, "")), http.StatusBadRequest)
+}
+
+func TestPricesAreConvertedToTheRequestedCurrency(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPut, "/admin/exchange-rates/EUR", gin.H{"rate": 655.957}, token)), http.StatusOK)
+
+	teak := int64(200000)
+	bench := e.seedProduct(models.Product{Name: "Banc", Price: 90000})
+	chair := e.seedProduct(models.Product{Name: "Chaise", Price: 150000, Variants: []models.ProductVariant{
+		{Id: bson.NewObjectID(), SKU: "CH-TECK", Options: []models.VariantOption{{Name: "Finition", Value: "Teck"}}, Price: &teak},
+	}})
+
+	// 150 000 XOF = 228,67 EUR; the bounds are euro cents
+	w := e.do(e.jsonRequest(http.MethodGet, "/products?currency=eur&minPrice=22000", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	page := decodeJSON[struct {
+		Items    []models.Product `json:"items"`
+		Currency string           `json:"currency"`
+	}](t, w)
+	if page.Currency != "EUR" || len(page.Items) != 1 || page.Items[0].Price != 22867 ||
+		page.Items[0].Currency != "EUR" || *page.Items[0].Variants[0].Price != 30490 {
+		t.Fatalf("page = %+v", page)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/products", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	if items := decodeJSON[productsPage](t, w).Items; items[0].Price != 90000 || items[0].Currency != "XOF" {
+		t.Fatalf("base prices = %+v", items)
+	}
+	for _, query := range []string{"currency=USD", "currency=euro"} {
+		expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/products?"+query, nil, "")), http.StatusBadRequest)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/products/slug/banc?currency=EUR", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	if detail := decodeJSON[dto.ProductDetailDTO](t, w); detail.Price != 13720 || detail.Currency != "EUR" {
+		t.Fatalf("detail = %+v", detail)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodPost, "/quote-requests", gin.H{
+		"fullName": "Jean Dupont", "email": "jean@example.com", "currency": "EUR",
+		"items": []gin.H{
+			{"productId": bench.Id.Hex(), "quantity": 2},
+			{"productId": chair.Id.Hex(), "variantId": chair.Variants[0].Id.Hex(), "quantity": 1},
+		},
+	}, ""))
+	expectStatus(t, w, http.StatusCreated)
+	id := decodeJSON[map[string]string](t, w)["id"]
+
+	// a later rate leaves the quote as priced
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPut, "/admin/exchange-rates/EUR", gin.H{"rate": 700}, token)), http.StatusOK)
+	quote, err := e.app.QuoteRequests.FindByID(t.Context(), mustObjectID(t, id))
+	if err != nil || quote.Currency != "EUR" || quote.ExchangeRate != 655.957 ||
+		quote.Items[0].UnitPrice != 13720 || quote.Items[1].UnitPrice != 30490 {
+		t.Fatalf("quote = %+v, %v", quote, err)
+	}
+}
@@ -0,0 +1,110 @@
+// Package health runs the readiness checks behind GET /readyz.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports whether one dependency can serve traffic.
+type Check func(ctx context.Context) error
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// Result is the outcome of one dependency check. Errors are logged, not
+// returned, so the public endpoint does not leak hosts or credentials.
+type Result struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// OK reports whether the process should receive traffic.
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Checker holds the named dependency checks of the process.
+type Checker struct {
+	timeout  time.Duration
+	mu       sync.Mutex
+	names    []string
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+// NewChecker bounds every check by timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, checks: map[string]Check{}}
+}
+
+// Add registers (or replaces) the check for name.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Drain makes every later report unavailable so load balancers stop routing
+// to the process while it shuts down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Check runs every check concurrently.
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	names := append([]string{}, c.names...)
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.Unlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(names))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(ctx)
+			res := Result{Status: StatusOK, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				slog.Warn("readiness check failed", "check", name, "error", err)
+				res.Status = StatusUnavailable
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = res
+			if err != nil {
+				report.Status = StatusUnavailable
+			}
+		}(name, checks[name])
+	}
+	wg.Wait()
+
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
@@ -0,0 +1,45 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckBoundsSlowDependencies(t *testing.T) {
+	c := NewChecker(20 * time.Millisecond)
+	c.Add("fast", func(context.Context) error { return nil })
+	c.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	report := c.Check(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("check took %s", elapsed)
+	}
+	if report.OK() || report.Status != StatusUnavailable {
+		t.Fatalf("status = %q", report.Status)
+	}
+	if report.Checks["fast"].Status != StatusOK || report.Checks["slow"].Status != StatusUnavailable {
+		t.Fatalf("checks = %+v", report.Checks)
+	}
+}
+
+func TestAddReplacesAndDrainWins(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.Add("db", func(context.Context) error { return errors.New("down") })
+	c.Add("db", func(context.Context) error { return nil })
+
+	report := c.Check(context.Background())
+	if !report.OK() || len(report.Checks) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	c.Drain()
+	if report := c.Check(context.Background()); report.OK() || report.Status != StatusDraining {
+		t.Fatalf("status while draining = %q", report.Status)
+	}
+}
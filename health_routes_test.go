@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/princinho/sahobackend/health"
+)
+
+func TestHealthz(t *testing.T) {
+	e := newTestEnv(t)
+	e.app.Health.Add("mongo", func(context.Context) error { return errors.New("down") })
+
+	// liveness ignores dependencies
+	w := e.do(httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	expectStatus(t, w, http.StatusOK)
+}
+
+func TestReadyzReportsEachDependency(t *testing.T) {
+	e := newTestEnv(t)
+	var mongoDown atomic.Bool
+	e.app.Health.Add("mongo", func(context.Context) error {
+		if mongoDown.Load() {
+			return errors.New("server selection timeout: mongo-0.internal:27017")
+		}
+		return nil
+	})
+
+	w := e.do(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	expectStatus(t, w, http.StatusOK)
+	report := decodeJSON[health.Report](t, w)
+	if report.Status != health.StatusOK || report.Checks["mongo"].Status != health.StatusOK || report.Checks["storage"].Status != health.StatusOK {
+		t.Fatalf("report = %+v", report)
+	}
+
+	mongoDown.Store(true)
+	w = e.do(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	expectStatus(t, w, http.StatusServiceUnavailable)
+	report = decodeJSON[health.Report](t, w)
+	if report.Checks["mongo"].Status != health.StatusUnavailable || report.Checks["storage"].Status != health.StatusOK {
+		t.Fatalf("report = %+v", report)
+	}
+	if got := w.Body.String(); strings.Contains(got, "mongo-0.internal") {
+		t.Fatalf("readiness leaks the dependency error: %s", got)
+	}
+
+	mongoDown.Store(false)
+	e.app.Health.Drain()
+	w = e.do(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	expectStatus(t, w, http.StatusServiceUnavailable)
+	if got := decodeJSON[health.Report](t, w).Status; got != health.StatusDraining {
+		t.Fatalf("status while draining = %q", got)
+	}
+}
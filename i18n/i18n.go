@@ -0,0 +1,79 @@
+// Package i18n picks the language of a response and translates the messages
+// the API writes for people (error messages for now). Messages are keyed by
+// their English text, so English needs no catalog entry and an untranslated
+// message still reads correctly.
+package i18n
+
+import (
+	"slices"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// Supported lists the response languages, the default first: the shop's
+// customers are French speakers.
+var Supported = []language.Tag{language.French, language.English}
+
+var (
+	matcher = language.NewMatcher(Supported)
+	cat     = newCatalog()
+)
+
+func newCatalog() *catalog.Builder {
+	b := catalog.NewBuilder(catalog.Fallback(language.English))
+	for key, msg := range french {
+		if err := b.SetString(language.French, key, msg); err != nil {
+			panic("i18n: " + key + ": " + err.Error())
+		}
+	}
+	return b
+}
+
+// Match returns the supported language that best fits an Accept-Language
+// header; French when the header is missing or names nothing supported.
+func Match(acceptLanguage string) language.Tag {
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, i, _ := matcher.Match(tags...)
+	return Supported[i]
+}
+
+// Locales are the codes of the Supported languages: the languages the
+// catalog content (product and category names, descriptions) is written in.
+func Locales() []string {
+	codes := make([]string, len(Supported))
+	for i, tag := range Supported {
+		base, _ := tag.Base()
+		codes[i] = base.String()
+	}
+	return codes
+}
+
+// ContentLocale picks the language of catalog content: lang when it is one
+// of the Locales, else the best match for an Accept-Language header, else
+// fallback.
+func ContentLocale(lang, acceptLanguage, fallback string) string {
+	locales := Locales()
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if slices.Contains(locales, lang) {
+		return lang
+	}
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	if _, i, confidence := matcher.Match(tags...); confidence != language.No {
+		return locales[i]
+	}
+	return fallback
+}
+
+// Printer formats and translates messages for tag.
+func Printer(tag language.Tag) *message.Printer {
+	return message.NewPrinter(tag, message.Catalog(cat))
+}
+
+// Translated reports whether key has a French translation.
+func Translated(key string) bool {
+	_, ok := french[key]
+	return ok
+}
@@ -0,0 +1,130 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestMatchDefaultsToFrench(t *testing.T) {
+	cases := map[string]language.Tag{
+		"":                       language.French,
+		"fr-FR,fr;q=0.9":         language.French,
+		"en-US,en;q=0.9":         language.English,
+		"de-DE,en;q=0.5":         language.English,
+		"de-DE":                  language.French,
+		"not a header;;;":        language.French,
+		"en;q=0.2,fr-CA;q=0.8,*": language.French,
+	}
+	for header, want := range cases {
+		if got := Match(header); got != want {
+			t.Errorf("Match(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestContentLocaleFallsBack(t *testing.T) {
+	cases := []struct {
+		lang, header, want string
+	}{
+		{"en", "fr-FR", "en"},
+		{" FR ", "en-US", "fr"},
+		{"de", "en-US,en;q=0.9", "en"},
+		{"", "de-DE", "en"},
+		{"", "", "en"},
+		{"", "fr-CA", "fr"},
+	}
+	for _, tc := range cases {
+		if got := ContentLocale(tc.lang, tc.header, "en"); got != tc.want {
+			t.Errorf("ContentLocale(%q, %q) = %q, want %q", tc.lang, tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestPrinterTranslatesWithArguments(t *testing.T) {
+	if got := Printer(language.French).Sprintf("at most %d images are allowed", 5); got != "5 images au maximum" {
+		t.Fatalf("fr = %q", got)
+	}
+	if got := Printer(language.English).Sprintf("at most %d images are allowed", 5); got != "at most 5 images are allowed" {
+		t.Fatalf("en = %q", got)
+	}
+}
+
+// messageArg is the position of the message in each function building an
+// API error.
+var messageArg = map[string]int{
+	"New": 2, "WithField": 2, "Invalid": 2,
+	"BadRequest": 0, "InvalidID": 0, "NotFound": 0,
+	"Unauthorized": 1, "Forbidden": 1, "lookupError": 1,
+}
+
+// TestEveryErrorMessageIsTranslated reads the packages that build errors and
+// fails on any literal message missing from the French catalog.
+func TestEveryErrorMessageIsTranslated(t *testing.T) {
+	fset := token.NewFileSet()
+	var files []string
+	for _, dir := range []string{"../apierror", "../controllers", "../middleware"} {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+
+	seen := 0
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check := func(n ast.Node, lit ast.Expr) {
+			s, ok := lit.(*ast.BasicLit)
+			if !ok || s.Kind != token.STRING {
+				return
+			}
+			key, _ := strconv.Unquote(s.Value)
+			seen++
+			if !Translated(key) {
+				t.Errorf("%s: %q has no French translation", fset.Position(n.Pos()), key)
+			}
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				var name string
+				switch fn := n.Fun.(type) {
+				case *ast.Ident:
+					name = fn.Name
+				case *ast.SelectorExpr:
+					name = fn.Sel.Name
+				}
+				if i, ok := messageArg[name]; ok && i < len(n.Args) {
+					check(n, n.Args[i])
+				}
+			case *ast.FuncDecl:
+				// Bind's rule messages are returned, not passed
+				if n.Name.Name == "ruleMessage" {
+					ast.Inspect(n, func(m ast.Node) bool {
+						if ret, ok := m.(*ast.ReturnStmt); ok && len(ret.Results) > 0 {
+							check(ret, ret.Results[0])
+						}
+						return true
+					})
+				}
+			}
+			return true
+		})
+	}
+	if seen < 20 {
+		t.Fatalf("only %d messages found; did the constructors change?", seen)
+	}
+}
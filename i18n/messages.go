@@ -0,0 +1,106 @@
+package i18n
+
+// french translates the English messages; the verbs must match the key's.
+var french = map[string]string{
+	// generic
+	"internal server error":   "erreur interne du serveur",
+	"route not found":         "route introuvable",
+	"invalid JSON":            "JSON invalide",
+	"invalid multipart form":  "formulaire multipart invalide",
+	"some fields are invalid": "certains champs sont invalides",
+	"no updates provided":     "aucune modification fournie",
+	"no id or slug provided":  "aucun identifiant ni slug fourni",
+
+	// fields
+	"%s is required":                         "%s est obligatoire",
+	"%s cannot be empty":                     "%s ne peut pas être vide",
+	"%s is invalid":                          "%s est invalide",
+	"%s has the wrong type":                  "%s n'a pas le bon type",
+	"%s must be a valid email address":       "%s doit être une adresse email valide",
+	"%s must be at least %s characters long": "%s doit contenir au moins %s caractères",
+	"%s must be at most %s characters long":  "%s doit contenir au plus %s caractères",
+	"%s must contain at least %s items":      "%s doit contenir au moins %s éléments",
+	"%s must contain at most %s items":       "%s doit contenir au plus %s éléments",
+	"%s must be at least %s":                 "%s doit être supérieur ou égal à %s",
+	"%s must be at most %s":                  "%s doit être inférieur ou égal à %s",
+	"%s must be greater than %s":             "%s doit être supérieur à %s",
+	"%s must be one of: %s":                  "%s doit valoir l'une des valeurs : %s",
+	"%s already exists: '%s'":                "%s existe déjà : '%s'",
+	"%s is already taken":                    "%s est déjà utilisé",
+	"%s must be a positive number":           "%s doit être un nombre positif",
+	"%s must not exceed %s":                  "%s ne doit pas dépasser %s",
+	"%s must be after %s":                    "%s doit être postérieur à %s",
+	"%s must be true or false":               "%s doit valoir true ou false",
+	"%s must be a whole number":              "%s doit être un nombre entier",
+
+	// resources
+	"invalid product id":             "identifiant de produit invalide",
+	"invalid product id %s":          "identifiant de produit invalide : %s",
+	"product %s does not exist":      "le produit %s n'existe pas",
+	"product not found":              "produit introuvable",
+	"product not found in the trash": "produit introuvable dans la corbeille",
+	"invalid category id":            "identifiant de catégorie invalide",
+	"category not found":             "catégorie introuvable",
+	"invalid quote request id":       "identifiant de demande de devis invalide",
+	"quote request not found":        "demande de devis introuvable",
+	"invalid product request id":     "identifiant de demande de produit invalide",
+	"product request not found":      "demande de produit introuvable",
+	"only admins can open accounts":  "seuls les administrateurs peuvent créer des comptes",
+
+	// variants
+	"invalid variant id":                          "identifiant de variante invalide",
+	"invalid variant id %s":                       "identifiant de variante invalide : %s",
+	"variant not found":                           "variante introuvable",
+	"variant %s does not exist":                   "la variante %s n'existe pas",
+	"product %s requires a variant":               "le produit %s nécessite une variante",
+	"option %s is repeated":                       "l'option %s est répétée",
+	"a variant with these options already exists": "une variante avec ces options existe déjà",
+	"%s is not an image of the product":           "%s n'est pas une image du produit",
+
+	// product images
+	"only one image can be primary":           "une seule image peut être principale",
+	"%s is listed more than once":             "%s est listée plusieurs fois",
+	"%s must list every image of the product": "%s doit lister toutes les images du produit",
+
+	// revisions
+	"revision %d not found": "révision %d introuvable",
+
+	// currencies
+	"exchange rate not found":                          "taux de change introuvable",
+	"no exchange rate for %s":                          "aucun taux de change pour %s",
+	"%s has no exchange rate, prices are stored in it": "%s n'a pas de taux de change, les prix y sont enregistrés",
+
+	// stock
+	"not enough stock": "stock insuffisant",
+	"the stock of product %s is held by its variants": "le stock du produit %s est porté par ses variantes",
+
+	// import
+	"column %s is missing":                    "la colonne %s est manquante",
+	"the file cannot be read as %s":           "le fichier ne peut pas être lu en %s",
+	"the file has no product rows":            "le fichier ne contient aucune ligne de produit",
+	"at most %d rows can be imported at once": "%d lignes au maximum par import",
+	"slug %s already appears on row %d":       "le slug %s figure déjà à la ligne %d",
+	"category %s does not exist":              "la catégorie %s n'existe pas",
+	"image %s was not found":                  "image %s introuvable",
+	"the archive is not a valid zip file":     "l'archive n'est pas un fichier zip valide",
+
+	// uploads
+	"at most %d images are allowed":                  "%d images au maximum",
+	"file type not allowed (allowed: %s)":            "type de fichier non autorisé (acceptés : %s)",
+	"some files were rejected":                       "certains fichiers ont été refusés",
+	"%s is not an accepted file type (accepted: %s)": "%s n'est pas d'un type accepté (acceptés : %s)",
+	"%s is larger than %d MB":                        "%s dépasse %d Mo",
+	"%s is larger than %d × %d pixels":               "%s dépasse %d × %d pixels",
+	"%s has more than %d megapixels":                 "%s dépasse %d mégapixels",
+	"%s is damaged or incomplete":                    "%s est endommagé ou incomplet",
+
+	// auth
+	"missing token":                 "token manquant",
+	"invalid or expired token":      "token invalide ou expiré",
+	"missing refresh token":         "refresh token manquant",
+	"invalid refresh token":         "refresh token invalide",
+	"invalid email or password":     "email ou mot de passe incorrect",
+	"current password is incorrect": "le mot de passe actuel est incorrect",
+	"account disabled":              "compte désactivé",
+	"invalid metrics token":         "token de métriques invalide",
+}
@@ -0,0 +1,139 @@
+// Package imaging turns uploaded product and category pictures into the
+// renditions the storefront downloads: decoded, scaled down, turned upright
+// according to their EXIF orientation and re-encoded as lossy WebP.
+// Re-encoding drops every metadata block of the original (EXIF, GPS
+// position, ICC).
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // decoders
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // the WebP decoder
+)
+
+// Errors caused by the uploaded file itself.
+var (
+	ErrFormat   = errors.New("not a PNG, JPEG or WebP image")
+	ErrTooLarge = errors.New("image has too many pixels")
+)
+
+// MaxPixels bounds the decoded size of an upload (width × height), so a
+// small file cannot claim gigabytes of memory.
+const MaxPixels = 50_000_000
+
+// Names of the renditions.
+const (
+	Thumbnail = "thumbnail"
+	Card      = "card"
+	Full      = "full"
+)
+
+// Rendition is a size uploads are re-encoded to: the picture is scaled down
+// to fit in a MaxSize × MaxSize box, never up.
+type Rendition struct {
+	Name    string
+	MaxSize int
+}
+
+// Renditions is the fixed set of sizes, largest first: each one is scaled
+// from the previous, which is faster than scaling every one from the
+// original and looks the same.
+var Renditions = []Rendition{
+	{Name: Full, MaxSize: 1600},
+	{Name: Card, MaxSize: 600},
+	{Name: Thumbnail, MaxSize: 200},
+}
+
+// ContentType and Extension of the encoded renditions.
+const (
+	ContentType = "image/webp"
+	Extension   = ".webp"
+)
+
+// Encoded is one rendition of an upload.
+type Encoded struct {
+	Rendition
+	Width, Height int
+	Data          []byte
+}
+
+// Process decodes an upload and encodes every rendition, upright, in
+// Renditions order. quality (1 to 100) is that of the lossy WebP encoding;
+// lossless renditions of a photo weigh several times its JPEG. The decoded
+// original is only kept until the first rendition is scaled from it.
+func Process(data []byte, quality int) ([]Encoded, error) {
+	img, orientation, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	// the boxes are square, so scaling before turning the picture upright
+	// gives the same sizes and rotates far fewer pixels
+	scaled := orient(fit(img, Renditions[0].MaxSize), orientation)
+
+	out := make([]Encoded, 0, len(Renditions))
+	for _, r := range Renditions {
+		scaled = fit(scaled, r.MaxSize)
+		b := scaled.Bounds()
+		out = append(out, Encoded{Rendition: r, Width: b.Dx(), Height: b.Dy(), Data: encodeWebP(scaled, quality)})
+	}
+	return out, nil
+}
+
+// Validate checks that data is a whole PNG, JPEG or WebP picture of at most
+// MaxPixels. The decoded picture is dropped at once: Process decodes it
+// again when the upload is stored, so a request never holds more than one
+// decoded picture.
+func Validate(data []byte) error {
+	_, _, err := decode(data)
+	return err
+}
+
+// decode decodes a PNG, JPEG or WebP picture and reads the EXIF orientation
+// of a JPEG (1 for the other formats).
+func decode(data []byte) (image.Image, int, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, ErrFormat
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, 0, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	return img, orientation, nil
+}
+
+// fit scales img down to fit in a size × size box, keeping its aspect
+// ratio. A picture that already fits is only converted to NRGBA.
+func fit(img image.Image, size int) *image.NRGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		if n, ok := img.(*image.NRGBA); ok && b.Min == (image.Point{}) {
+			return n
+		}
+		dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+		return dst
+	}
+	if w >= h {
+		w, h = size, max(1, h*size/w)
+	} else {
+		w, h = max(1, w*size/h), size
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
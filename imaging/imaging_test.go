@@ -0,0 +1,154 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// halves is a w × h picture, red on its left half and blue on its right one.
+func halves(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// withOrientation is a JPEG of img carrying an EXIF Orientation tag.
+func withOrientation(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, buf.Bytes()[2:]...)
+}
+
+func isRed(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > 0xC000 && b < 0x4000
+}
+
+func TestProcessTurnsJPEGsUpright(t *testing.T) {
+	cases := []struct {
+		orientation uint16
+		w, h        int
+		red         image.Point // a pixel of the red half once upright
+	}{
+		{1, 64, 32, image.Pt(4, 16)},
+		{3, 64, 32, image.Pt(60, 16)},
+		{6, 32, 64, image.Pt(16, 4)},
+		{8, 32, 64, image.Pt(16, 60)},
+	}
+	for _, tc := range cases {
+		out, err := Process(withOrientation(t, halves(64, 32), tc.orientation), 90)
+		if err != nil {
+			t.Fatalf("orientation %d: %v", tc.orientation, err)
+		}
+		img, err := webp.Decode(bytes.NewReader(out[0].Data))
+		if err != nil {
+			t.Fatalf("orientation %d: %v", tc.orientation, err)
+		}
+		if b := img.Bounds(); b.Dx() != tc.w || b.Dy() != tc.h {
+			t.Errorf("orientation %d: size %v", tc.orientation, b)
+		}
+		if !isRed(img.At(tc.red.X, tc.red.Y)) {
+			t.Errorf("orientation %d: %v is %v", tc.orientation, tc.red, img.At(tc.red.X, tc.red.Y))
+		}
+	}
+}
+
+func TestProcessEncodesEveryRendition(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, halves(2000, 1000)); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Process(buf.Bytes(), 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]image.Point{Full: {1600, 800}, Card: {600, 300}, Thumbnail: {200, 100}}
+	if len(out) != len(want) {
+		t.Fatalf("%d renditions", len(out))
+	}
+	for _, r := range out {
+		img, err := webp.Decode(bytes.NewReader(r.Data))
+		if err != nil {
+			t.Fatalf("%s: %v", r.Name, err)
+		}
+		if got := img.Bounds().Size(); got != want[r.Name] || r.Width != got.X || r.Height != got.Y {
+			t.Errorf("%s: %v, recorded %dx%d", r.Name, got, r.Width, r.Height)
+		}
+	}
+
+	// small pictures are not scaled up
+	buf.Reset()
+	if err := png.Encode(&buf, halves(120, 80)); err != nil {
+		t.Fatal(err)
+	}
+	out, err = Process(buf.Bytes(), 80)
+	if err != nil || out[0].Width != 120 || out[2].Height != 80 {
+		t.Fatalf("small picture: %+v, %v", out, err)
+	}
+}
+
+// Half transparent pixels keep their color: the encoder is given straight alpha.
+func TestProcessKeepsTranslucentColors(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []byte{255, 0, 0, 128})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Process(buf.Bytes(), 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := webp.Decode(bytes.NewReader(out[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.NRGBAModel.Convert(got.At(16, 16)).(color.NRGBA); c.R < 230 || c.A < 110 || c.A > 145 {
+		t.Fatalf("pixel = %+v, want translucent red", c)
+	}
+}
+
+func TestValidateRejectsOtherFiles(t *testing.T) {
+	if err := Validate([]byte("%PDF-1.4")); !errors.Is(err, ErrFormat) {
+		t.Fatalf("pdf: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 10000, 6000))); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(buf.Bytes()); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("60 Mpx: %v", err)
+	}
+}
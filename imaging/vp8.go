@@ -0,0 +1,779 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// This file encodes the lossy renditions as a VP8 key frame (RFC 6386).
+// It keeps to a subset of the format every decoder reads: one quantizer,
+// no segments, one token partition and whole-macroblock prediction (16×16
+// luma, 8×8 chroma) chosen by distortion. The token probabilities are
+// fitted to each frame.
+
+// Prediction modes of whole macroblocks, numbered as the 4×4 modes they
+// stand for in the contexts of the format.
+const (
+	predDC = iota
+	predTM
+	predVE
+	predHE
+	nPredMB
+)
+
+// Rounding of the quantizer, in 1/256 of a step: below one half, so small
+// coefficients fall to zero. The values are libwebp's.
+const (
+	dcBias = 96
+	acBias = 110
+)
+
+// maxLevel bounds a quantized coefficient, as libwebp does.
+const maxLevel = 2047
+
+// vp8Quant are the DC and AC steps of one kind of block.
+type vp8Quant [2]int32
+
+// vp8Macroblock is what the bitstream records of a macroblock: its modes
+// and quantized coefficients in coding (zigzag) order. Blocks 0 to 15 are
+// the luma ones, 16 to 19 Cb, 20 to 23 Cr and 24 the Y2 block holding the
+// luma DCs.
+type vp8Macroblock struct {
+	yMode, uvMode uint8
+	skip          bool
+	coeffs        [25][16]int16
+}
+
+// vp8Encoder holds a frame being encoded. The source planes are padded to
+// whole macroblocks. The reconstructed planes are what a decoder gets
+// before its loop filter, which is what it predicts from, so the encoder
+// predicts from them too.
+type vp8Encoder struct {
+	width, height int
+	mbw, mbh      int
+	qIndex        int
+	filterLevel   int
+	y1, y2, uv    vp8Quant
+
+	yStride, uvStride int
+	srcY, srcU, srcV  []uint8
+	recY, recU, recV  []uint8
+
+	mbs []vp8Macroblock
+}
+
+// encodeVP8 encodes the planes of a width × height frame, padded to whole
+// macroblocks, and returns the VP8 bitstream.
+func encodeVP8(width, height int, y, u, v []uint8, quality int) []byte {
+	e := &vp8Encoder{
+		width: width, height: height,
+		mbw: (width + 15) / 16, mbh: (height + 15) / 16,
+		srcY: y, srcU: u, srcV: v,
+	}
+	e.yStride, e.uvStride = 16*e.mbw, 8*e.mbw
+	e.recY = make([]uint8, len(y))
+	e.recU = make([]uint8, len(u))
+	e.recV = make([]uint8, len(v))
+	e.setQuality(quality)
+
+	e.mbs = make([]vp8Macroblock, e.mbw*e.mbh)
+	for mby := range e.mbh {
+		for mbx := range e.mbw {
+			e.macroblock(mbx, mby, &e.mbs[mby*e.mbw+mbx])
+		}
+	}
+	return e.bitstream()
+}
+
+// setQuality maps quality (1 to 100) to a quantizer index the way libwebp
+// does, and picks a loop filter level that grows with the index.
+func (e *vp8Encoder) setQuality(quality int) {
+	c := float64(min(max(quality, 1), 100)) / 100
+	linear := 2*c - 1
+	if c < 0.75 {
+		linear = c * 2 / 3
+	}
+	q := min(max(int(math.Round(127*(1-math.Cbrt(linear)))), 0), 127)
+	e.qIndex = q
+	e.filterLevel = min(q/3+4, 63)
+
+	e.y1 = vp8Quant{int32(dcSteps[q]), int32(acSteps[q])}
+	e.y2 = vp8Quant{2 * int32(dcSteps[q]), max(int32(acSteps[q])*155/100, 8)}
+	e.uv = vp8Quant{int32(dcSteps[min(q, 117)]), int32(acSteps[q])}
+}
+
+// macroblock picks the modes of a macroblock, quantizes its residuals and
+// reconstructs it as the decoder will.
+func (e *vp8Encoder) macroblock(mbx, mby int, mb *vp8Macroblock) {
+	src := crop(e.srcY, e.yStride, 16, mbx, mby)
+	top, left, corner := edges(e.recY, e.yStride, 16, mbx, mby)
+	var pred [nPredMB][]uint8
+	for mode := range uint8(nPredMB) {
+		pred[mode] = predict(16, mode, top, left, corner, mbx, mby)
+	}
+	mb.yMode = closest(pred[:], src)
+	rec := e.luma(mb, src, pred[mb.yMode])
+	paste(e.recY, e.yStride, 16, mbx, mby, rec)
+
+	srcU := crop(e.srcU, e.uvStride, 8, mbx, mby)
+	srcV := crop(e.srcV, e.uvStride, 8, mbx, mby)
+	topU, leftU, cornerU := edges(e.recU, e.uvStride, 8, mbx, mby)
+	topV, leftV, cornerV := edges(e.recV, e.uvStride, 8, mbx, mby)
+	var predUV [nPredMB][]uint8
+	for mode := range uint8(nPredMB) {
+		// both planes side by side, so one distortion covers them
+		predUV[mode] = append(predict(8, mode, topU, leftU, cornerU, mbx, mby),
+			predict(8, mode, topV, leftV, cornerV, mbx, mby)...)
+	}
+	mb.uvMode = closest(predUV[:], append(srcU, srcV...))
+	paste(e.recU, e.uvStride, 8, mbx, mby, e.chroma(mb, 16, srcU, predUV[mb.uvMode][:64]))
+	paste(e.recV, e.uvStride, 8, mbx, mby, e.chroma(mb, 20, srcV, predUV[mb.uvMode][64:]))
+
+	mb.skip = true
+	for i := range mb.coeffs {
+		if mb.coeffs[i] != [16]int16{} {
+			mb.skip = false
+			break
+		}
+	}
+}
+
+// luma codes the 16 luma blocks, their DCs through the Y2 block, and
+// returns the reconstructed macroblock.
+func (e *vp8Encoder) luma(mb *vp8Macroblock, src, pred []uint8) []uint8 {
+	var coeffs [16][16]int32
+	var dcs [16]int32
+	for n := range 16 {
+		off := n/4*4*16 + n%4*4
+		coeffs[n] = fdct(src[off:], pred[off:], 16)
+		dcs[n] = coeffs[n][0]
+	}
+
+	var y2 [16]int16
+	wht := fwht(&dcs)
+	for k, z := range zigzag {
+		level := quantize(wht[z], e.y2[min(z, 1)], bias(z))
+		mb.coeffs[24][k] = level
+		y2[z] = int16(int32(level) * e.y2[min(z, 1)])
+	}
+	dc := iwht(&y2)
+
+	rec := append([]uint8(nil), pred...)
+	for n := range 16 {
+		var block [16]int16
+		block[0] = dc[n]
+		for k, z := range zigzag[1:] {
+			level := quantize(coeffs[n][z], e.y1[1], acBias)
+			mb.coeffs[n][k+1] = level
+			block[z] = int16(int32(level) * e.y1[1])
+		}
+		idct(rec[n/4*4*16+n%4*4:], 16, &block)
+	}
+	return rec
+}
+
+// chroma codes the four blocks of an 8×8 chroma plane, from block first of
+// the macroblock, and returns the reconstructed plane.
+func (e *vp8Encoder) chroma(mb *vp8Macroblock, first int, src, pred []uint8) []uint8 {
+	rec := append([]uint8(nil), pred...)
+	for n := range 4 {
+		off := n/2*4*8 + n%2*4
+		coeffs := fdct(src[off:], pred[off:], 8)
+		var block [16]int16
+		for k, z := range zigzag {
+			level := quantize(coeffs[z], e.uv[min(z, 1)], bias(z))
+			mb.coeffs[first+n][k] = level
+			block[z] = int16(int32(level) * e.uv[min(z, 1)])
+		}
+		idct(rec[off:], 8, &block)
+	}
+	return rec
+}
+
+func bias(z uint8) int32 {
+	if z == 0 {
+		return dcBias
+	}
+	return acBias
+}
+
+// quantize divides a coefficient by step, rounding towards zero by bias.
+func quantize(c, step, bias int32) int16 {
+	level := (abs32(c)*256 + step*bias) / (step * 256)
+	level = min(level, maxLevel)
+	if c < 0 {
+		return int16(-level)
+	}
+	return int16(level)
+}
+
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// crop copies the size × size block of macroblock (mbx, mby) out of plane.
+func crop(plane []uint8, stride, size, mbx, mby int) []uint8 {
+	out := make([]uint8, size*size)
+	for j := range size {
+		copy(out[j*size:(j+1)*size], plane[(mby*size+j)*stride+mbx*size:])
+	}
+	return out
+}
+
+// paste writes a size × size block back to macroblock (mbx, mby) of plane.
+func paste(plane []uint8, stride, size, mbx, mby int, block []uint8) {
+	for j := range size {
+		copy(plane[(mby*size+j)*stride+mbx*size:], block[j*size:(j+1)*size])
+	}
+}
+
+// edges gathers the pixels a macroblock is predicted from, as the decoder
+// sees them: 127 above the frame, 129 left of it, and a corner of 127 on
+// the first row and of 129 down the first column.
+func edges(plane []uint8, stride, size, mbx, mby int) (top, left []uint8, corner uint8) {
+	top, left = make([]uint8, size), make([]uint8, size)
+	x0, y0 := mbx*size, mby*size
+	switch {
+	case mby == 0:
+		corner = 127
+		for i := range top {
+			top[i] = 127
+		}
+	case mbx == 0:
+		corner = 129
+		copy(top, plane[(y0-1)*stride+x0:])
+	default:
+		corner = plane[(y0-1)*stride+x0-1]
+		copy(top, plane[(y0-1)*stride+x0:])
+	}
+	for j := range left {
+		if mbx == 0 {
+			left[j] = 129
+		} else {
+			left[j] = plane[(y0+j)*stride+x0-1]
+		}
+	}
+	return top, left, corner
+}
+
+// predict returns the size × size prediction of mode. DC averages the
+// edges inside the frame only, and is 128 in the first macroblock.
+func predict(size int, mode uint8, top, left []uint8, corner uint8, mbx, mby int) []uint8 {
+	dst := make([]uint8, size*size)
+	switch mode {
+	case predDC:
+		sum, n := 0, 0
+		if mby > 0 {
+			for _, p := range top {
+				sum += int(p)
+			}
+			n += size
+		}
+		if mbx > 0 {
+			for _, p := range left {
+				sum += int(p)
+			}
+			n += size
+		}
+		dc := uint8(128)
+		if n > 0 {
+			dc = uint8((sum + n/2) / n)
+		}
+		for i := range dst {
+			dst[i] = dc
+		}
+	case predTM:
+		for j := range size {
+			for i := range size {
+				dst[j*size+i] = clip8(int32(left[j]) + int32(top[i]) - int32(corner))
+			}
+		}
+	case predVE:
+		for j := range size {
+			copy(dst[j*size:], top)
+		}
+	case predHE:
+		for j := range size {
+			for i := range size {
+				dst[j*size+i] = left[j]
+			}
+		}
+	}
+	return dst
+}
+
+// closest returns the index of the prediction with the least squared
+// error against src.
+func closest(preds [][]uint8, src []uint8) uint8 {
+	best, bestSSE := 0, math.MaxInt
+	for i, pred := range preds {
+		sse := 0
+		for k, p := range pred {
+			d := int(src[k]) - int(p)
+			sse += d * d
+		}
+		if sse < bestSSE {
+			best, bestSSE = i, sse
+		}
+	}
+	return uint8(best)
+}
+
+func clip8(v int32) uint8 {
+	return uint8(min(max(v, 0), 255))
+}
+
+// fdct returns the DCT of the 4×4 block src − pred, as libwebp computes
+// it.
+func fdct(src, pred []uint8, stride int) [16]int32 {
+	var tmp, out [16]int32
+	for i := range 4 {
+		s, p := src[i*stride:], pred[i*stride:]
+		d0 := int32(s[0]) - int32(p[0])
+		d1 := int32(s[1]) - int32(p[1])
+		d2 := int32(s[2]) - int32(p[2])
+		d3 := int32(s[3]) - int32(p[3])
+		a0, a1, a2, a3 := d0+d3, d1+d2, d1-d2, d0-d3
+		tmp[0+i*4] = (a0 + a1) * 8
+		tmp[1+i*4] = (a2*2217 + a3*5352 + 1812) >> 9
+		tmp[2+i*4] = (a0 - a1) * 8
+		tmp[3+i*4] = (a3*2217 - a2*5352 + 937) >> 9
+	}
+	for i := range 4 {
+		a0, a1 := tmp[0+i]+tmp[12+i], tmp[4+i]+tmp[8+i]
+		a2, a3 := tmp[4+i]-tmp[8+i], tmp[0+i]-tmp[12+i]
+		out[0+i] = (a0 + a1 + 7) >> 4
+		out[4+i] = ((a2*2217 + a3*5352 + 12000) >> 16)
+		if a3 != 0 {
+			out[4+i]++
+		}
+		out[8+i] = (a0 - a1 + 7) >> 4
+		out[12+i] = (a3*2217 - a2*5352 + 51000) >> 16
+	}
+	return out
+}
+
+// idct adds the inverse DCT of coeffs to the 4×4 block at dst, exactly as
+// the decoder does.
+func idct(dst []uint8, stride int, coeffs *[16]int16) {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2)
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2)
+	)
+	var m [4][4]int32
+	for i := range 4 {
+		a := int32(coeffs[i]) + int32(coeffs[8+i])
+		b := int32(coeffs[i]) - int32(coeffs[8+i])
+		c := (int32(coeffs[4+i])*c2)>>16 - (int32(coeffs[12+i])*c1)>>16
+		d := (int32(coeffs[4+i])*c1)>>16 + (int32(coeffs[12+i])*c2)>>16
+		m[i] = [4]int32{a + d, b + c, b - c, a - d}
+	}
+	for j := range 4 {
+		dc := m[0][j] + 4
+		a, b := dc+m[2][j], dc-m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		row := dst[j*stride:]
+		row[0] = clip8(int32(row[0]) + (a+d)>>3)
+		row[1] = clip8(int32(row[1]) + (b+c)>>3)
+		row[2] = clip8(int32(row[2]) + (b-c)>>3)
+		row[3] = clip8(int32(row[3]) + (a-d)>>3)
+	}
+}
+
+// fwht returns the Walsh-Hadamard transform of the 16 luma DCs, as libwebp
+// computes it.
+func fwht(dcs *[16]int32) [16]int32 {
+	var tmp, out [16]int32
+	for i := range 4 {
+		d := dcs[i*4:]
+		a0, a1 := d[0]+d[2], d[1]+d[3]
+		a2, a3 := d[1]-d[3], d[0]-d[2]
+		tmp[0+i*4], tmp[1+i*4] = a0+a1, a3+a2
+		tmp[2+i*4], tmp[3+i*4] = a3-a2, a0-a1
+	}
+	for i := range 4 {
+		a0, a1 := tmp[0+i]+tmp[8+i], tmp[4+i]+tmp[12+i]
+		a2, a3 := tmp[4+i]-tmp[12+i], tmp[0+i]-tmp[8+i]
+		out[0+i] = (a0 + a1) >> 1
+		out[4+i] = (a3 + a2) >> 1
+		out[8+i] = (a3 - a2) >> 1
+		out[12+i] = (a0 - a1) >> 1
+	}
+	return out
+}
+
+// iwht returns the 16 luma DCs of the dequantized Y2 block, exactly as the
+// decoder computes them.
+func iwht(coeffs *[16]int16) [16]int16 {
+	var m [16]int32
+	for i := range 4 {
+		a0 := int32(coeffs[0+i]) + int32(coeffs[12+i])
+		a1 := int32(coeffs[4+i]) + int32(coeffs[8+i])
+		a2 := int32(coeffs[4+i]) - int32(coeffs[8+i])
+		a3 := int32(coeffs[0+i]) - int32(coeffs[12+i])
+		m[0+i], m[8+i] = a0+a1, a0-a1
+		m[4+i], m[12+i] = a3+a2, a3-a2
+	}
+	var out [16]int16
+	for i := range 4 {
+		dc := m[0+i*4] + 3
+		a0, a1 := dc+m[3+i*4], m[1+i*4]+m[2+i*4]
+		a2, a3 := m[1+i*4]-m[2+i*4], dc-m[3+i*4]
+		out[i*4+0] = int16((a0 + a1) >> 3)
+		out[i*4+1] = int16((a3 + a2) >> 3)
+		out[i*4+2] = int16((a0 - a1) >> 3)
+		out[i*4+3] = int16((a3 - a2) >> 3)
+	}
+	return out
+}
+
+// bitstream writes the frame: its header, the first partition (settings,
+// token probabilities and modes) and the token partition.
+func (e *vp8Encoder) bitstream() []byte {
+	// a first pass counts the tokens, to fit the probabilities to them
+	probs := defaultTokenProb
+	var stats tokenStats
+	e.residuals(&tokenCoder{probs: &probs, stats: &stats})
+	updated := fitTokenProbs(&probs, &stats)
+
+	fp := newBoolEncoder()
+	fp.putLiteral(0, 2) // color space and clamping
+	fp.putLiteral(0, 1) // no segments
+	fp.putLiteral(0, 1) // normal loop filter
+	fp.putLiteral(uint32(e.filterLevel), 6)
+	fp.putLiteral(0, 3) // sharpness
+	fp.putLiteral(0, 1) // no filter deltas
+	fp.putLiteral(0, 2) // one token partition
+	fp.putLiteral(uint32(e.qIndex), 7)
+	fp.putLiteral(0, 5) // no quantizer deltas
+	fp.putLiteral(0, 1) // refresh_entropy_probs, moot for a still image
+	for i := range probs {
+		for j := range probs[i] {
+			for k := range probs[i][j] {
+				for l, p := range probs[i][j][k] {
+					fp.putBit(updated[i][j][k][l], tokenProbUpdateProb[i][j][k][l])
+					if updated[i][j][k][l] {
+						fp.putLiteral(uint32(p), 8)
+					}
+				}
+			}
+		}
+	}
+
+	skipped := 0
+	for i := range e.mbs {
+		if e.mbs[i].skip {
+			skipped++
+		}
+	}
+	skipProb := uint8(min(max((len(e.mbs)-skipped)*256/len(e.mbs), 1), 255))
+	fp.putLiteral(1, 1)
+	fp.putLiteral(uint32(skipProb), 8)
+	for i := range e.mbs {
+		mb := &e.mbs[i]
+		fp.putBit(mb.skip, skipProb)
+		fp.putBit(true, 145) // a 16×16 luma mode
+		switch mb.yMode {
+		case predDC:
+			fp.putBit(false, 156)
+			fp.putBit(false, 163)
+		case predVE:
+			fp.putBit(false, 156)
+			fp.putBit(true, 163)
+		case predHE:
+			fp.putBit(true, 156)
+			fp.putBit(false, 128)
+		case predTM:
+			fp.putBit(true, 156)
+			fp.putBit(true, 128)
+		}
+		fp.putBit(mb.uvMode != predDC, 142)
+		if mb.uvMode != predDC {
+			fp.putBit(mb.uvMode != predVE, 114)
+			if mb.uvMode != predVE {
+				fp.putBit(mb.uvMode == predTM, 183)
+			}
+		}
+	}
+	first := fp.flush()
+
+	tp := newBoolEncoder()
+	e.residuals(&tokenCoder{probs: &probs, enc: tp})
+	tokens := tp.flush()
+
+	out := make([]byte, 10, 10+len(first)+len(tokens))
+	// key frame, version 0, shown, then the size of the first partition
+	tag := uint32(len(first))<<5 | 1<<4
+	out[0], out[1], out[2] = byte(tag), byte(tag>>8), byte(tag>>16)
+	out[3], out[4], out[5] = 0x9d, 0x01, 0x2a
+	binary.LittleEndian.PutUint16(out[6:], uint16(e.width))
+	binary.LittleEndian.PutUint16(out[8:], uint16(e.height))
+	out = append(out, first...)
+	return append(out, tokens...)
+}
+
+// nzContext records which blocks along an edge of a macroblock have
+// coefficients, the context of the first token of the neighbouring blocks.
+type nzContext struct {
+	y    [4]uint8
+	u, v [2]uint8
+	y2   uint8
+}
+
+// residuals codes the coefficients of every macroblock in decoding order.
+func (e *vp8Encoder) residuals(t *tokenCoder) {
+	above := make([]nzContext, e.mbw)
+	for mby := range e.mbh {
+		var left nzContext
+		for mbx := range e.mbw {
+			mb, top := &e.mbs[mby*e.mbw+mbx], &above[mbx]
+			if mb.skip {
+				left, *top = nzContext{}, nzContext{}
+				continue
+			}
+			nz := t.block(planeY2, left.y2+top.y2, &mb.coeffs[24], 0)
+			left.y2, top.y2 = nz, nz
+			for y := range 4 {
+				for x := range 4 {
+					nz := t.block(planeY1WithY2, left.y[y]+top.y[x], &mb.coeffs[y*4+x], 1)
+					left.y[y], top.y[x] = nz, nz
+				}
+			}
+			for y := range 2 {
+				for x := range 2 {
+					nz := t.block(planeUV, left.u[y]+top.u[x], &mb.coeffs[16+y*2+x], 0)
+					left.u[y], top.u[x] = nz, nz
+				}
+			}
+			for y := range 2 {
+				for x := range 2 {
+					nz := t.block(planeUV, left.v[y]+top.v[x], &mb.coeffs[20+y*2+x], 0)
+					left.v[y], top.v[x] = nz, nz
+				}
+			}
+		}
+	}
+}
+
+// tokenStats counts, per token probability, the zeros and ones it coded.
+type tokenStats [nPlane][nBand][nContext][nProb][2]uint32
+
+// tokenCoder writes the tokens of the coefficients with probs, or only
+// counts them in stats while enc is nil.
+type tokenCoder struct {
+	probs *[nPlane][nBand][nContext][nProb]uint8
+	enc   *boolEncoder
+	stats *tokenStats
+}
+
+func (t *tokenCoder) branch(plane int, band uint8, ctx uint8, i int, bit bool) {
+	if t.enc == nil {
+		if bit {
+			t.stats[plane][band][ctx][i][1]++
+		} else {
+			t.stats[plane][band][ctx][i][0]++
+		}
+		return
+	}
+	t.enc.putBit(bit, t.probs[plane][band][ctx][i])
+}
+
+// fixed writes a bit whose probability the format does not let change.
+func (t *tokenCoder) fixed(bit bool, prob uint8) {
+	if t.enc != nil {
+		t.enc.putBit(bit, prob)
+	}
+}
+
+// block codes the coefficients of a block from position first, and returns
+// 1 if any of them is not zero.
+func (t *tokenCoder) block(plane int, ctx uint8, coeffs *[16]int16, first int) uint8 {
+	last := -1
+	for i := 15; i >= first; i-- {
+		if coeffs[i] != 0 {
+			last = i
+			break
+		}
+	}
+	band := bands[first]
+	if last < 0 {
+		t.branch(plane, band, ctx, 0, false)
+		return 0
+	}
+	t.branch(plane, band, ctx, 0, true)
+	for i := first; i <= last; i++ {
+		level := coeffs[i]
+		if level == 0 {
+			t.branch(plane, band, ctx, 1, false)
+			band, ctx = bands[i+1], 0
+			continue
+		}
+		t.branch(plane, band, ctx, 1, true)
+		v := int32(level)
+		if v < 0 {
+			v = -v
+		}
+		t.value(plane, band, ctx, v)
+		t.fixed(level < 0, 128)
+		band, ctx = bands[i+1], 2
+		if v == 1 {
+			ctx = 1
+		}
+		if i < 15 {
+			t.branch(plane, band, ctx, 0, i < last)
+		}
+	}
+	return 1
+}
+
+// value codes the magnitude v (1 to 2048) of a coefficient.
+func (t *tokenCoder) value(plane int, band, ctx uint8, v int32) {
+	if v == 1 {
+		t.branch(plane, band, ctx, 2, false)
+		return
+	}
+	t.branch(plane, band, ctx, 2, true)
+	switch {
+	case v <= 4:
+		t.branch(plane, band, ctx, 3, false)
+		t.branch(plane, band, ctx, 4, v != 2)
+		if v != 2 {
+			t.branch(plane, band, ctx, 5, v == 4)
+		}
+	case v <= 10:
+		t.branch(plane, band, ctx, 3, true)
+		t.branch(plane, band, ctx, 6, false)
+		t.branch(plane, band, ctx, 7, v > 6)
+		if v <= 6 {
+			t.fixed(v == 6, 159)
+		} else {
+			t.fixed((v-7)&2 != 0, 165)
+			t.fixed((v-7)&1 != 0, 145)
+		}
+	default:
+		t.branch(plane, band, ctx, 3, true)
+		t.branch(plane, band, ctx, 6, true)
+		cat := 0
+		for cat < 3 && v >= 3+(8<<(cat+1)) {
+			cat++
+		}
+		t.branch(plane, band, ctx, 8, cat >= 2)
+		t.branch(plane, band, ctx, 9+cat>>1, cat&1 != 0)
+		extra, tab := v-(3+8<<cat), cat3456[cat][:]
+		n := 0
+		for tab[n] != 0 {
+			n++
+		}
+		for i := range n {
+			t.fixed(extra>>(n-1-i)&1 != 0, tab[i])
+		}
+	}
+}
+
+// fitTokenProbs replaces the probabilities whose update, given what the
+// frame codes with them, saves more bits than it costs, and reports which.
+func fitTokenProbs(probs *[nPlane][nBand][nContext][nProb]uint8, stats *tokenStats) (updated [nPlane][nBand][nContext][nProb]bool) {
+	for i := range probs {
+		for j := range probs[i] {
+			for k := range probs[i][j] {
+				for l, old := range probs[i][j][k] {
+					n := stats[i][j][k][l]
+					if n[0]+n[1] == 0 {
+						continue
+					}
+					p := uint8(min(max((n[0]*256+(n[0]+n[1])/2)/(n[0]+n[1]), 1), 255))
+					up := tokenProbUpdateProb[i][j][k][l]
+					cost := 8 + bitCost(0, 1, up) - bitCost(1, 0, up)
+					if bitCost(n[0], n[1], old)-bitCost(n[0], n[1], p) > cost {
+						probs[i][j][k][l] = p
+						updated[i][j][k][l] = true
+					}
+				}
+			}
+		}
+	}
+	return updated
+}
+
+// bitCost is the number of bits zeros and ones take, coded with prob.
+func bitCost(zeros, ones uint32, prob uint8) float64 {
+	p := float64(prob) / 256
+	return -float64(zeros)*math.Log2(p) - float64(ones)*math.Log2(1-p)
+}
+
+// boolEncoder is the arithmetic coder of the partitions (RFC 6386 section
+// 7.3).
+type boolEncoder struct {
+	buf      []byte
+	rng      uint32
+	bottom   uint32
+	bitCount int
+}
+
+func newBoolEncoder() *boolEncoder {
+	return &boolEncoder{rng: 255, bitCount: 24}
+}
+
+// putBit codes bit, where prob/256 is the probability that it is false.
+func (e *boolEncoder) putBit(bit bool, prob uint8) {
+	split := 1 + (e.rng-1)*uint32(prob)>>8
+	if bit {
+		e.bottom += split
+		e.rng -= split
+	} else {
+		e.rng = split
+	}
+	for e.rng < 128 {
+		e.rng <<= 1
+		if e.bottom&(1<<31) != 0 {
+			e.carry()
+		}
+		e.bottom <<= 1
+		e.bitCount--
+		if e.bitCount == 0 {
+			e.buf = append(e.buf, byte(e.bottom>>24))
+			e.bottom &= 1<<24 - 1
+			e.bitCount = 8
+		}
+	}
+}
+
+// putLiteral codes the n low bits of v, most significant first.
+func (e *boolEncoder) putLiteral(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		e.putBit(v>>i&1 != 0, 128)
+	}
+}
+
+// carry adds one to the bytes already written.
+func (e *boolEncoder) carry() {
+	i := len(e.buf) - 1
+	for ; i >= 0 && e.buf[i] == 255; i-- {
+		e.buf[i] = 0
+	}
+	if i >= 0 {
+		e.buf[i]++
+	}
+}
+
+// flush writes out what is left of the coded value and returns the
+// partition.
+func (e *boolEncoder) flush() []byte {
+	c, v := e.bitCount, e.bottom
+	if v&(1<<(32-c)) != 0 {
+		e.carry()
+	}
+	v <<= c & 7
+	for c >>= 3; c > 0; c-- {
+		v <<= 8
+	}
+	for range 4 {
+		e.buf = append(e.buf, byte(v>>24))
+		v <<= 8
+	}
+	return e.buf
+}
@@ -0,0 +1,381 @@
+package imaging
+
+import (
+	"container/heap"
+	"math/bits"
+)
+
+// This file compresses the alpha plane of a translucent rendition as the
+// lossless (VP8L) bitstream an ALPH chunk holds: the alpha values are the
+// green channel of an image without transforms or color cache, coded as
+// literals or as copies of the pixels on the left and above, which is
+// where the long runs of an alpha plane come from.
+
+// Sizes of the alphabets of the five prefix codes: green with the copy
+// lengths, red, blue, alpha and the copy distances.
+const (
+	nLiteral     = 256
+	nLengthCodes = 24
+	nDistCodes   = 40
+)
+
+// Copies shorter than minCopy cost more than the literals they replace.
+// The format cannot express copies longer than maxCopy.
+const (
+	minCopy = 3
+	maxCopy = 4096
+)
+
+// Distance codes of the neighbours a copy starts from: the 2-D map of the
+// format puts the pixel above first and the one on the left second.
+const (
+	distUp   = 1
+	distLeft = 2
+)
+
+// Code length limits of the prefix codes and of the code that codes their
+// lengths.
+const (
+	maxCodeLength       = 15
+	maxCodeLengthLength = 7
+)
+
+// codeLengthOrder is the order the code length code lengths are written in.
+var codeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// alphaSymbol is a literal alpha value or a copy of length pixels.
+type alphaSymbol struct {
+	literal  uint8
+	length   int
+	distCode int
+}
+
+// encodeAlphaPlane returns the ALPH payload, after its header byte, of the
+// w × h alpha values in a.
+func encodeAlphaPlane(a []uint8, w, h int) []byte {
+	symbols := alphaSymbols(a, w)
+
+	green := make([]int, nLiteral+nLengthCodes)
+	dist := make([]int, nDistCodes)
+	for _, s := range symbols {
+		if s.length == 0 {
+			green[s.literal]++
+			continue
+		}
+		code, _, _ := prefixCode(s.length)
+		green[nLiteral+code]++
+		code, _, _ = prefixCode(s.distCode)
+		dist[code]++
+	}
+
+	var bw bitWriter
+	bw.write(0, 1) // no transform
+	bw.write(0, 1) // no color cache
+	bw.write(0, 1) // a single group of prefix codes
+	greenCode := bw.prefixCode(green)
+	bw.prefixCode([]int{1}) // red, blue and alpha are always 0
+	bw.prefixCode([]int{1})
+	bw.prefixCode([]int{1})
+	distCode := bw.prefixCode(dist)
+
+	for _, s := range symbols {
+		if s.length == 0 {
+			greenCode.write(&bw, int(s.literal))
+			continue
+		}
+		code, extra, n := prefixCode(s.length)
+		greenCode.write(&bw, nLiteral+code)
+		bw.write(extra, n)
+		code, extra, n = prefixCode(s.distCode)
+		distCode.write(&bw, code)
+		bw.write(extra, n)
+	}
+	return bw.flush()
+}
+
+// alphaSymbols codes a greedily: each pixel starts the longest copy of its
+// left or upper neighbours, or is a literal when no copy is long enough.
+func alphaSymbols(a []uint8, w int) []alphaSymbol {
+	var symbols []alphaSymbol
+	for i := 0; i < len(a); {
+		end := min(len(a), i+maxCopy)
+		left, up := 0, 0
+		if i > 0 {
+			for i+left < end && a[i+left] == a[i-1] {
+				left++
+			}
+		}
+		if i >= w {
+			for i+up < end && a[i+up] == a[i+up-w] {
+				up++
+			}
+		}
+		switch {
+		case max(left, up) < minCopy:
+			symbols = append(symbols, alphaSymbol{literal: a[i]})
+			i++
+		case up >= left:
+			symbols = append(symbols, alphaSymbol{length: up, distCode: distUp})
+			i += up
+		default:
+			symbols = append(symbols, alphaSymbol{length: left, distCode: distLeft})
+			i += left
+		}
+	}
+	return symbols
+}
+
+// prefixCode splits a copy length or distance code into the symbol coding
+// its magnitude and the extra bits that follow it.
+func prefixCode(v int) (code int, extra uint32, n int) {
+	v--
+	if v < 4 {
+		return v, 0, 0
+	}
+	hb := bits.Len(uint(v)) - 1
+	second := v >> (hb - 1) & 1
+	n = hb - 1
+	return 2*hb + second, uint32(v & (1<<n - 1)), n
+}
+
+// huffmanCode is a canonical prefix code. A code of a single symbol takes
+// no bits.
+type huffmanCode struct {
+	lengths []uint8
+	codes   []uint32 // bit reversed, as the writer emits the low bit first
+	single  bool
+}
+
+func newHuffmanCode(lengths []uint8) huffmanCode {
+	h := huffmanCode{lengths: lengths, codes: make([]uint32, len(lengths))}
+	var count [maxCodeLength + 1]int
+	used := 0
+	for _, l := range lengths {
+		count[l]++
+		if l > 0 {
+			used++
+		}
+	}
+	h.single = used == 1
+	count[0] = 0
+	var next [maxCodeLength + 2]uint32
+	for l := 1; l <= maxCodeLength; l++ {
+		next[l+1] = (next[l] + uint32(count[l])) << 1
+	}
+	for s, l := range lengths {
+		if l > 0 {
+			h.codes[s] = bits.Reverse32(next[l]) >> (32 - l)
+			next[l]++
+		}
+	}
+	return h
+}
+
+func (h huffmanCode) write(bw *bitWriter, symbol int) {
+	if !h.single {
+		bw.write(h.codes[symbol], int(h.lengths[symbol]))
+	}
+}
+
+// prefixCode writes the code fitted to counts and returns it: a simple
+// code when a single symbol is used, otherwise code lengths that are
+// themselves prefix coded.
+func (bw *bitWriter) prefixCode(counts []int) huffmanCode {
+	lengths := huffmanLengths(counts, maxCodeLength)
+	var used []int
+	for s, l := range lengths {
+		if l > 0 {
+			used = append(used, s)
+		}
+	}
+	if len(used) == 0 || len(used) == 1 && used[0] < 256 {
+		symbol := 0
+		if len(used) == 1 {
+			symbol = used[0]
+		}
+		bw.write(1, 1) // simple code
+		bw.write(0, 1) // of one symbol
+		if symbol < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(symbol), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(symbol), 8)
+		}
+		return newHuffmanCode(lengths)
+	}
+
+	runs := codeLengthRuns(lengths)
+	var counts19 [19]int
+	for _, r := range runs {
+		counts19[r.symbol]++
+	}
+	lengths19 := huffmanLengths(counts19[:], maxCodeLengthLength)
+	n := 4
+	for i, s := range codeLengthOrder {
+		if lengths19[s] > 0 {
+			n = max(n, i+1)
+		}
+	}
+	bw.write(0, 1) // normal code
+	bw.write(uint32(n-4), 4)
+	for _, s := range codeLengthOrder[:n] {
+		bw.write(uint32(lengths19[s]), 3)
+	}
+	bw.write(0, 1) // every symbol has a length
+	lengthCode := newHuffmanCode(lengths19)
+	for _, r := range runs {
+		lengthCode.write(bw, r.symbol)
+		bw.write(r.extra, r.n)
+	}
+	return newHuffmanCode(lengths)
+}
+
+// codeLengthRun is a code length, or a run of them, as the code length
+// code writes it: 16 repeats the previous length, 17 and 18 are zeros.
+type codeLengthRun struct {
+	symbol int
+	extra  uint32
+	n      int
+}
+
+func codeLengthRuns(lengths []uint8) []codeLengthRun {
+	var runs []codeLengthRun
+	for i := 0; i < len(lengths); {
+		l := lengths[i]
+		j := i + 1
+		for j < len(lengths) && lengths[j] == l {
+			j++
+		}
+		run := j - i
+		i = j
+		if l == 0 {
+			for run >= 11 {
+				k := min(run, 138)
+				runs = append(runs, codeLengthRun{18, uint32(k - 11), 7})
+				run -= k
+			}
+			if run >= 3 {
+				runs = append(runs, codeLengthRun{17, uint32(run - 3), 3})
+				run = 0
+			}
+		} else {
+			runs = append(runs, codeLengthRun{symbol: int(l)})
+			run--
+			for run >= 3 {
+				k := min(run, 6)
+				runs = append(runs, codeLengthRun{16, uint32(k - 3), 2})
+				run -= k
+			}
+		}
+		for range run {
+			runs = append(runs, codeLengthRun{symbol: int(l)})
+		}
+	}
+	return runs
+}
+
+// huffmanLengths returns the code lengths of a Huffman code for counts,
+// at most limit bits long: when the tree is too deep, the rarest symbols
+// are counted as more frequent until it fits. Unused symbols get no code.
+func huffmanLengths(counts []int, limit int) []uint8 {
+	lengths := make([]uint8, len(counts))
+	var used []int
+	for s, c := range counts {
+		if c > 0 {
+			used = append(used, s)
+		}
+	}
+	if len(used) == 1 {
+		lengths[used[0]] = 1
+	}
+	if len(used) < 2 {
+		return lengths
+	}
+	weights := make([]int, len(counts))
+	copy(weights, counts)
+	for floor := 1; ; floor *= 2 {
+		for _, s := range used {
+			weights[s] = max(weights[s], floor)
+		}
+		if depthsFit(weights, used, lengths, limit) {
+			return lengths
+		}
+	}
+}
+
+// depthsFit builds the Huffman tree of the used symbols' weights, stores
+// their depths in lengths and reports whether none is deeper than limit.
+func depthsFit(weights []int, used []int, lengths []uint8, limit int) bool {
+	// nodes below len(weights) are the symbols, the rest are branches
+	parent := make([]int, len(weights), len(weights)+len(used))
+	q := make(huffmanQueue, 0, len(used))
+	for _, s := range used {
+		q = append(q, huffmanNode{weights[s], s})
+	}
+	heap.Init(&q)
+	for q.Len() > 1 {
+		a := heap.Pop(&q).(huffmanNode)
+		b := heap.Pop(&q).(huffmanNode)
+		id := len(parent)
+		parent = append(parent, -1)
+		parent[a.id], parent[b.id] = id, id
+		heap.Push(&q, huffmanNode{a.weight + b.weight, id})
+	}
+	// branches come after their children, so the root is last
+	depth := make([]int, len(parent))
+	for id := len(parent) - 2; id >= len(weights); id-- {
+		depth[id] = depth[parent[id]] + 1
+	}
+	for _, s := range used {
+		d := depth[parent[s]] + 1
+		if d > limit {
+			return false
+		}
+		lengths[s] = uint8(d)
+	}
+	return true
+}
+
+type huffmanNode struct{ weight, id int }
+
+// huffmanQueue is a min-heap of tree nodes; ties go to the lower id so
+// the tree does not depend on the heap's internal order.
+type huffmanQueue []huffmanNode
+
+func (q huffmanQueue) Len() int { return len(q) }
+func (q huffmanQueue) Less(i, j int) bool {
+	return q[i].weight < q[j].weight || q[i].weight == q[j].weight && q[i].id < q[j].id
+}
+func (q huffmanQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *huffmanQueue) Push(x any)   { *q = append(*q, x.(huffmanNode)) }
+func (q *huffmanQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// bitWriter packs values low bit first, as the lossless format reads them.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nBits int
+}
+
+func (bw *bitWriter) write(v uint32, n int) {
+	bw.acc |= uint64(v) << bw.nBits
+	bw.nBits += n
+	for bw.nBits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nBits -= 8
+	}
+}
+
+func (bw *bitWriter) flush() []byte {
+	if bw.nBits > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+	}
+	return bw.buf
+}
@@ -0,0 +1,100 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// encodeWebP encodes img as a lossy WebP file. Translucent pictures keep
+// their alpha plane in an ALPH chunk, compressed losslessly.
+func encodeWebP(img *image.NRGBA, quality int) []byte {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	y, u, v := yuvPlanes(img)
+	frame := encodeVP8(w, h, y, u, v, quality)
+
+	out := []byte("RIFF\x00\x00\x00\x00WEBP")
+	if !img.Opaque() {
+		alpha := encodeAlphaPlane(alphaPlane(img), w, h)
+		header := make([]byte, 10)
+		header[0] = 0x10 // alpha
+		putUint24(header[4:], uint32(w-1))
+		putUint24(header[7:], uint32(h-1))
+		out = appendChunk(out, "VP8X", header)
+		// no filtering or pre-processing, lossless compression
+		out = appendChunk(out, "ALPH", append([]byte{0x01}, alpha...))
+	}
+	out = appendChunk(out, "VP8 ", frame)
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+func appendChunk(out []byte, fourCC string, data []byte) []byte {
+	out = append(out, fourCC...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(data)))
+	out = append(out, data...)
+	if len(data)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+// alphaPlane returns the alpha values of img, row by row.
+func alphaPlane(img *image.NRGBA) []uint8 {
+	b := img.Bounds()
+	a := make([]uint8, 0, b.Dx()*b.Dy())
+	for y := range b.Dy() {
+		row := img.Pix[y*img.Stride:]
+		for x := range b.Dx() {
+			a = append(a, row[4*x+3])
+		}
+	}
+	return a
+}
+
+// yuvPlanes converts img to the planes of a VP8 frame, with the BT.601
+// coefficients of libwebp: a luma plane and chroma planes at half
+// resolution, padded to whole macroblocks by repeating the last row and
+// column.
+func yuvPlanes(img *image.NRGBA) (y, u, v []uint8) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	mbw, mbh := (w+15)/16, (h+15)/16
+	yStride, uvStride := 16*mbw, 8*mbw
+	y = make([]uint8, yStride*16*mbh)
+	u = make([]uint8, uvStride*8*mbh)
+	v = make([]uint8, uvStride*8*mbh)
+
+	at := func(px, py int) (r, g, b int) {
+		i := min(py, h-1)*img.Stride + 4*min(px, w-1)
+		p := img.Pix[i : i+3 : i+3]
+		return int(p[0]), int(p[1]), int(p[2])
+	}
+	for py := range 16 * mbh {
+		for px := range yStride {
+			r, g, b := at(px, py)
+			y[py*yStride+px] = uint8((16839*r + 33059*g + 6420*b + 1<<15 + 16<<16) >> 16)
+		}
+	}
+	for py := range 8 * mbh {
+		for px := range uvStride {
+			var r, g, b int
+			for _, d := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				pr, pg, pb := at(2*px+d[0], 2*py+d[1])
+				r, g, b = r+pr, g+pg, b+pb
+			}
+			u[py*uvStride+px] = clipUV(-9719*r - 19081*g + 28800*b)
+			v[py*uvStride+px] = clipUV(28800*r - 24116*g - 4684*b)
+		}
+	}
+	return y, u, v
+}
+
+// clipUV scales a chroma value computed on the sum of four pixels.
+func clipUV(uv int) uint8 {
+	return uint8(min(max((uv+1<<17+128<<18)>>18, 0), 255))
+}
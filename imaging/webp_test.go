@@ -0,0 +1,78 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// gradient is a w × h picture mixing smooth ramps and a sharp edge, with
+// the given alpha function.
+func gradient(w, h int, alpha func(x, y int) uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			c := color.NRGBA{R: uint8(x * 255 / w), G: uint8(y * 255 / h), B: 200, A: alpha(x, y)}
+			if x > w/3 && y > h/3 {
+				c.B = 40
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestEncodeWebPRoundTrips(t *testing.T) {
+	for _, size := range []image.Point{{1, 1}, {7, 5}, {37, 21}, {130, 66}} {
+		opaque := func(x, y int) uint8 { return 255 }
+		fading := func(x, y int) uint8 { return uint8((x + y) * 255 / (size.X + size.Y)) }
+		for _, alpha := range []func(x, y int) uint8{opaque, fading} {
+			src := gradient(size.X, size.Y, alpha)
+			data := encodeWebP(src, 90)
+			got, err := webp.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%v: %v", size, err)
+			}
+			if got.Bounds().Size() != size {
+				t.Fatalf("%v: decoded %v", size, got.Bounds().Size())
+			}
+			// x/image converts to RGB with JFIF rather than BT.601 ranges,
+			// so compare the luma the frame was given
+			var luma []uint8
+			var stride int
+			switch got := got.(type) {
+			case *image.YCbCr:
+				luma, stride = got.Y, got.YStride
+			case *image.NYCbCrA:
+				luma, stride = got.Y, got.YStride
+				for y := range size.Y {
+					for x := range size.X {
+						if a, want := got.A[y*got.AStride+x], src.NRGBAAt(x, y).A; a != want {
+							t.Fatalf("%v: alpha at (%d, %d) = %d, want %d", size, x, y, a, want)
+						}
+					}
+				}
+			default:
+				t.Fatalf("%v: decoded a %T", size, got)
+			}
+			if _, ok := got.(*image.NYCbCrA); ok == src.Opaque() {
+				t.Errorf("%v: alpha plane kept = %v", size, ok)
+			}
+			want, _, _ := yuvPlanes(src)
+			wantStride := 16 * ((size.X + 15) / 16)
+			var diff int
+			for y := range size.Y {
+				for x := range size.X {
+					d := int(luma[y*stride+x]) - int(want[y*wantStride+x])
+					diff += max(d, -d)
+				}
+			}
+			if mean := float64(diff) / float64(size.X*size.Y); mean > 2 {
+				t.Errorf("%v: mean luma difference %.1f", size, mean)
+			}
+		}
+	}
+}
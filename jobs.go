@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// every runs job at each tick of interval until ctx is done. A failing run
+// is logged and retried at the next tick; a non-positive interval disables
+// the job.
+func every(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	if interval <= 0 {
+		slog.Info("background job disabled", "job", name)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := job(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("background job failed", "job", name, "error", err)
+			}
+		}
+	}
+}
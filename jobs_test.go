@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEveryRepeatsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		every(ctx, "test", time.Millisecond, func(context.Context) error {
+			// a failing run does not stop the job
+			if runs.Add(1) == 3 {
+				cancel()
+			}
+			return errors.New("flaky")
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("every did not stop after cancel")
+	}
+	if runs.Load() < 3 {
+		t.Fatalf("runs = %d", runs.Load())
+	}
+}
+
+func TestEveryWithoutIntervalReturns(t *testing.T) {
+	every(context.Background(), "disabled", 0, func(context.Context) error {
+		t.Fatal("disabled job ran")
+		return nil
+	})
+}
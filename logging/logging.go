@@ -0,0 +1,72 @@
+// Package logging builds the process logger and carries a request-scoped
+// child of it through context.Context, so every line a handler writes has
+// the request ID (and the admin user ID once authenticated) attached.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Attribute keys shared by the middleware and the handlers.
+const (
+	KeyRequestID = "requestId"
+	KeyUserID    = "userId"
+	KeyTraceID   = "traceId"
+)
+
+// New returns a logger writing to w at level ("debug", "info", "warn",
+// "error") in format ("json" or "text").
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (use %s or %s)", format, FormatJSON, FormatText)
+	}
+}
+
+// ParseLevel accepts the slog level names, case-insensitively.
+func ParseLevel(level string) (slog.Level, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (use debug, info, warn or error)", level)
+	}
+	return lvl, nil
+}
+
+type ctxKey struct{}
+
+// NewContext returns ctx carrying l.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx, or slog.Default() outside a
+// request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// With returns ctx whose logger also carries args.
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
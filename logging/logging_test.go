@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestNewFiltersByLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, "WARN", FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("dropped")
+	l.Warn("kept", "n", 1)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("want exactly one JSON line, got %q: %v", buf.String(), err)
+	}
+	if line["msg"] != "kept" || line["level"] != "WARN" {
+		t.Fatalf("line = %v", line)
+	}
+}
+
+func TestNewRejectsUnknownSettings(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "verbose", FormatJSON); err == nil {
+		t.Fatal("want an error for an unknown level")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Fatal("want an error for an unknown format")
+	}
+}
+
+func TestContextCarriesAttributes(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Fatal("want the default logger outside a request")
+	}
+
+	var buf bytes.Buffer
+	ctx := NewContext(context.Background(), slog.New(slog.NewJSONHandler(&buf, nil)))
+	ctx = With(ctx, KeyRequestID, "req-1")
+	ctx = With(ctx, KeyUserID, "u-1")
+	FromContext(ctx).Info("hello")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line[KeyRequestID] != "req-1" || line[KeyUserID] != "u-1" {
+		t.Fatalf("line = %v", line)
+	}
+}
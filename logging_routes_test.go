@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/princinho/sahobackend/middleware"
+)
+
+func TestRequestIDIsHonouredOrGenerated(t *testing.T) {
+	e := newTestEnv(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(middleware.RequestIDHeader, "edge-7f3a.42")
+	w := e.do(req)
+	if got := w.Header().Get(middleware.RequestIDHeader); got != "edge-7f3a.42" {
+		t.Fatalf("echoed request id = %q", got)
+	}
+
+	for _, incoming := range []string{"", "has spaces", strings.Repeat("a", 200)} {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if incoming != "" {
+			req.Header.Set(middleware.RequestIDHeader, incoming)
+		}
+		w := e.do(req)
+		if _, err := uuid.Parse(w.Header().Get(middleware.RequestIDHeader)); err != nil {
+			t.Fatalf("incoming %q: want a generated UUID, got %q", incoming, w.Header().Get(middleware.RequestIDHeader))
+		}
+	}
+}
+
+func TestAccessLogCarriesRequestAndUserID(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	e.logs.Reset()
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/quote-requests", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+	expectStatus(t, e.do(req), http.StatusOK)
+
+	lines := e.logLines()
+	if len(lines) != 1 {
+		t.Fatalf("want one access line, got %v", lines)
+	}
+	line := lines[0]
+	admin, _ := e.app.Users.FindByEmail(t.Context(), testAdminEmail)
+	if line["requestId"] != "req-123" || line["userId"] != admin.ID.Hex() {
+		t.Fatalf("access line = %v", line)
+	}
+	if line["route"] != "/admin/quote-requests" || line["status"] != float64(http.StatusOK) || line["level"] != "INFO" {
+		t.Fatalf("access line = %v", line)
+	}
+}
+
+func TestPanicsAreLoggedWithTheRequestID(t *testing.T) {
+	e := newTestEnv(t)
+	e.router.GET("/boom", func(c *gin.Context) { panic("boom") })
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-boom")
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	expectStatus(t, w, http.StatusInternalServerError)
+
+	var panicked, logged bool
+	for _, line := range e.logLines() {
+		if line["requestId"] != "req-boom" {
+			t.Fatalf("line without the request id: %v", line)
+		}
+		panicked = panicked || line["msg"] == "panic"
+		logged = logged || (line["msg"] == "request" && line["level"] == "ERROR")
+	}
+	if !panicked || !logged {
+		t.Fatalf("lines = %v", e.logLines())
+	}
+}
@@ -2,100 +2,166 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"net/http"
+	"log/slog"
+	"net"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/princinho/sahobackend/config"
 	"github.com/princinho/sahobackend/controllers"
 	"github.com/princinho/sahobackend/database"
-	"github.com/princinho/sahobackend/middleware"
+	"github.com/princinho/sahobackend/health"
+	"github.com/princinho/sahobackend/logging"
+	"github.com/princinho/sahobackend/metrics"
+	"github.com/princinho/sahobackend/migrations"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/storage"
+	"github.com/princinho/sahobackend/tracing"
 	"github.com/princinho/sahobackend/utils"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
 )
 
 func main() {
-	err := godotenv.Load()
+	ctx := context.Background()
+
+	if len(os.Args) > 1 && os.Args[1] != "migrate" && os.Args[1] != "images" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Fail before touching anything if a setting is missing or malformed.
+	cfg, err := config.Load()
 	if err != nil {
-		log.Println("Error loading .env file")
+		log.Fatal(err)
 	}
-	//seeding admin user
-	ctx := context.Background()
-	usersCol := database.OpenCollection("users")
-	if err := utils.SeedAdminUser(ctx, usersCol); err != nil {
+
+	// Every package logs through slog.Default; handlers get a per-request
+	// child of it from the request context.
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
 		log.Fatal(err)
 	}
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Options())
+	if err != nil {
+		fatal("tracing", err)
+	}
+	defer func() {
+		// flush the spans of the last requests; runs after the Mongo disconnect
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("tracing shutdown", "error", err)
+		}
+	}()
+
+	m := metrics.New()
 
-	r := gin.New()
-	v := utils.NewPDFOrImageValidator()
+	// One client for the whole process; every repository shares its pool.
+	client, err := database.Connect(ctx, cfg.Mongo.URI, m.MongoMonitor(), tracing.MongoMonitor())
+	if err != nil {
+		fatal("connect to mongo", err)
+	}
+	defer func() {
+		// runs after the HTTP server drained, so no request still needs the pool
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := client.Disconnect(ctx); err != nil {
+			slog.Error("mongo disconnect", "error", err)
+		}
+	}()
+	db := database.Database(client, cfg.Mongo.Database)
 
-	origins := os.Getenv("ALLOWED_ORIGINS")
-	log.Printf("Env config origins list: %q", origins)
-	allowedOrigins := map[string]bool{}
-	for _, origin := range strings.Split(origins, ",") {
-		origin = strings.TrimSpace(origin)
-		if origin != "" {
-			allowedOrigins[origin] = true
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(ctx, db, os.Args[2:], os.Stdout); err != nil {
+			fatal("migrate", err)
 		}
+		return
 	}
-	log.Printf("Allowed origins: %v", allowedOrigins)
-	r.Use(cors.New(cors.Config{
-		AllowOriginFunc: func(origin string) bool {
-			result := allowedOrigins[origin]
-			log.Printf("CORS check — origin: %q, allowed: %v", origin, result)
-			return allowedOrigins[origin]
-		},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-	r.Use(gin.Logger())
-	r.Use(gin.Recovery())
-
-	r.GET("/ping", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"message": "pong",
-		})
+
+	if cfg.Server.MigrateOnStart {
+		if _, err := migrations.Run(ctx, db, migrations.All); err != nil {
+			fatal("migrate", err)
+		}
+	}
+
+	store, err := storage.New(ctx, cfg.Storage.Options())
+	if err != nil {
+		fatal("open storage", err)
+	}
+
+	app := &controllers.App{
+		Config:          cfg,
+		Logger:          logger,
+		Products:        repositories.NewProductRepository(db),
+		Categories:      repositories.NewCategoryRepository(db),
+		QuoteRequests:   repositories.NewQuoteRequestRepository(db),
+		ProductRequests: repositories.NewProductRequestRepository(db),
+		Users:           repositories.NewUserRepository(db),
+		RefreshTokens:   repositories.NewRefreshTokenRepository(db),
+		StockMovements:  repositories.NewStockMovementRepository(db),
+		Revisions:       repositories.NewProductRevisionRepository(db),
+		ExchangeRates:   repositories.NewExchangeRateRepository(db),
+		Storage:         m.Storage(tracing.Storage(store, cfg.Storage.Driver), cfg.Storage.Driver),
+		Metrics:         m,
+		Health:          health.NewChecker(cfg.Server.ReadinessTimeout),
+	}
+
+	if len(os.Args) > 1 {
+		if err := runImagesCommand(ctx, app, os.Args[2:], os.Stdout); err != nil {
+			fatal("images", err)
+		}
+		return
+	}
+
+	//seeding admin user
+	if err := utils.SeedAdminUser(ctx, db.Collection("users"), cfg.Admin.Email, cfg.Admin.Password); err != nil {
+		fatal("seed admin user", err)
+	}
+
+	app.Health.Add("mongo", func(ctx context.Context) error {
+		return client.Ping(ctx, readpref.Primary())
 	})
+	app.Health.Add("storage", store.Ping)
 
-	r.POST("/auth/login", controllers.Login())
-	r.POST("/auth/refresh", controllers.Refresh())
-
-	r.GET("/products", controllers.GetProducts())
-	r.GET("/categories", controllers.GetCategories())
-	r.GET("/categories/:id", controllers.GetCategory())
-	r.GET("/categories/slug/:slug", controllers.GetCategory())
-	r.POST("/quote-requests", controllers.CreateQuoteRequest())
-	r.POST("/product-requests", controllers.CreateProductRequest(v))
-
-	admin := r.Group("/admin")
-	admin.Use(middleware.AuthMiddleware())
-	{
-		admin.POST("/products/add", controllers.AddProduct())
-		admin.PATCH("/products/update/:id", controllers.UpdateProduct())
-
-		admin.POST("/categories", controllers.AddCategory())
-		admin.PATCH("/categories/:id", controllers.UpdateCategory())
-		admin.DELETE("/categories/:id", controllers.DeleteCategory())
-
-		admin.GET("/quote-requests", controllers.GetQuoteRequests())
-		admin.GET("/quote-requests/:id", controllers.GetQuoteRequest())
-		admin.PATCH("/quote-requests/:id/status", controllers.UpdateQuoteStatus())
-		admin.POST("/quote-requests/:id/notes", controllers.AddQuoteNote())
-
-		admin.GET("/product-requests", controllers.GetProductRequests())
-		admin.GET("/product-requests/:id", controllers.GetProductRequest())
-		admin.PATCH("/product-requests/:id/status", controllers.UpdateProductRequestStatus())
-		admin.POST("/product-requests/:id/notes", controllers.AddProductRequestNote())
-		admin.POST("/users", controllers.CreateUser())
-		admin.POST("/users/me/password", controllers.ChangeMyPassword())
+	// Server will listen on 0.0.0.0:PORT (localhost:PORT on Windows)
+	srv := newServer(cfg.Server, newRouter(app))
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		fatal("listen", err)
 	}
-	// Start server on port 8080 (default)
-	// Server will listen on 0.0.0.0:8080 (localhost:8080 on Windows)
-	r.Run()
+
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go every(sigCtx, "trash purge", cfg.Trash.PurgeInterval, func(ctx context.Context) error {
+		n, err := app.PurgeTrash(ctx, time.Now().UTC())
+		if n > 0 {
+			slog.Info("trash purged", "products", n)
+		}
+		return err
+	})
+	go every(sigCtx, "product schedule", cfg.Catalog.ScheduleInterval, func(ctx context.Context) error {
+		published, archived, err := app.ApplyProductSchedule(ctx, time.Now().UTC())
+		if published > 0 || archived > 0 {
+			slog.Info("product schedule applied", "published", published, "archived", archived)
+		}
+		return err
+	})
+
+	if err := serve(sigCtx, srv, ln, app.Health, cfg.Server.ShutdownTimeout); err != nil {
+		slog.Error("server", "error", err)
+	}
+}
+
+// fatal logs err and exits. Deferred calls do not run, so it is only used
+// before the server starts.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
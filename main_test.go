@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/config"
+	"github.com/princinho/sahobackend/controllers"
+	"github.com/princinho/sahobackend/health"
+	"github.com/princinho/sahobackend/metrics"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/storage"
+	"github.com/princinho/sahobackend/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	testAdminEmail    = "admin@saho.test"
+	testAdminPassword = "correct-horse-battery"
+	testMediaURL      = "http://localhost:8080" + storage.MediaPath
+)
+
+// exercised records every "METHOD /route/:pattern" hit by the suite so
+// TestMain can fail when a route registered in newRouter has no test.
+var exercised sync.Map
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	code := m.Run()
+	if code == 0 && !testing.Short() {
+		if missing := unexercisedRoutes(); len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "routes without an HTTP test:\n  %s\n", strings.Join(missing, "\n  "))
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+func unexercisedRoutes() []string {
+	// a local store makes newRouter register the /media routes too
+	dir, err := os.MkdirTemp("", "saho-routes-")
+	if err != nil {
+		return []string{"(temp dir: " + err.Error() + ")"}
+	}
+	defer os.RemoveAll(dir)
+	store, err := storage.NewLocal(storage.LocalConfig{Dir: dir})
+	if err != nil {
+		return []string{"(local storage: " + err.Error() + ")"}
+	}
+
+	missing := []string{}
+	app := &controllers.App{Config: testConfig(), Storage: store, Metrics: metrics.New()}
+	for _, route := range newRouter(app).Routes() {
+		key := route.Method + " " + route.Path
+		if _, ok := exercised.Load(key); !ok {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// matchRoute resolves a request path to the registered route pattern,
+// preferring static segments over :params like gin's tree does. A trailing
+// *catchAll segment matches the rest of the path.
+func matchRoute(routes gin.RoutesInfo, method, path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	best, bestStatic := "", -1
+	for _, route := range routes {
+		if route.Method != method {
+			continue
+		}
+		pattern := strings.Split(strings.Trim(route.Path, "/"), "/")
+		catchAll := strings.HasPrefix(pattern[len(pattern)-1], "*")
+		if len(pattern) != len(parts) && !(catchAll && len(parts) >= len(pattern)) {
+			continue
+		}
+		static, ok := 0, true
+		for i, seg := range pattern {
+			if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+				continue
+			}
+			if seg != parts[i] {
+				ok = false
+				break
+			}
+			static++
+		}
+		if ok && static > bestStatic {
+			best, bestStatic = route.Path, static
+		}
+	}
+	return best
+}
+
+// ---- Test environment ---------------------------------------------------------
+
+type testEnv struct {
+	t      *testing.T
+	app    *controllers.App
+	router *gin.Engine
+	store  *storage.Local
+	logs   *bytes.Buffer
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	cfg := testConfig()
+	store, err := storage.NewLocal(storage.LocalConfig{Dir: t.TempDir(), BaseURL: testMediaURL})
+	if err != nil {
+		t.Fatalf("local storage: %v", err)
+	}
+
+	logs := &bytes.Buffer{}
+	m := metrics.New()
+	app := &controllers.App{
+		Config:          cfg,
+		Logger:          slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Products:        repositories.NewMemoryProductRepository(),
+		Categories:      repositories.NewMemoryCategoryRepository(),
+		QuoteRequests:   repositories.NewMemoryQuoteRequestRepository(),
+		ProductRequests: repositories.NewMemoryProductRequestRepository(),
+		Users:           repositories.NewMemoryUserRepository(),
+		RefreshTokens:   repositories.NewMemoryRefreshTokenRepository(),
+		StockMovements:  repositories.NewMemoryStockMovementRepository(),
+		Revisions:       repositories.NewMemoryProductRevisionRepository(),
+		ExchangeRates:   repositories.NewMemoryExchangeRateRepository(),
+		Storage:         m.Storage(store, storage.DriverLocal),
+		Metrics:         m,
+		Health:          health.NewChecker(time.Second),
+	}
+	app.Health.Add("storage", store.Ping)
+
+	return &testEnv{
+		t:      t,
+		app:    app,
+		router: newRouter(app),
+		store:  store,
+		logs:   logs,
+	}
+}
+
+// testConfig is the default configuration plus the values startup requires.
+func testConfig() *config.Config {
+	cfg := config.Defaults()
+	cfg.Server.AllowedOrigins = []string{"http://localhost:3000"}
+	cfg.Mongo.URI = "mongodb://unused"
+	cfg.Mongo.Database = "saho_test"
+	cfg.Auth.JWTSecret = "test-access-secret"
+	cfg.Auth.JWTRefreshSecret = "test-refresh-secret"
+	cfg.Admin.Email = testAdminEmail
+	cfg.Admin.Password = testAdminPassword
+	cfg.Storage.Driver = storage.DriverLocal
+	cfg.Storage.Local.BaseURL = testMediaURL
+	return cfg
+}
+
+func (e *testEnv) do(req *http.Request) *httptest.ResponseRecorder {
+	e.t.Helper()
+	if route := matchRoute(e.router.Routes(), req.Method, req.URL.Path); route != "" {
+		exercised.Store(req.Method+" "+route, true)
+	}
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w
+}
+
+// logLines decodes the JSON lines the app logged so far.
+func (e *testEnv) logLines() []map[string]any {
+	e.t.Helper()
+	var lines []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(e.logs.Bytes()))
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			e.t.Fatalf("log line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (e *testEnv) jsonRequest(method, path string, body any, token string) *http.Request {
+	e.t.Helper()
+	var r io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			e.t.Fatalf("marshal body: %v", err)
+		}
+		r = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, r)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+type testFile struct {
+	field    string
+	name     string
+	content  []byte
+	mimeType string
+}
+
+// multipartRequest builds the "data" + files form the admin endpoints expect.
+func (e *testEnv) multipartRequest(method, path string, data any, files []testFile, token string) *http.Request {
+	e.t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			e.t.Fatalf("marshal data: %v", err)
+		}
+		if err := mw.WriteField("data", string(raw)); err != nil {
+			e.t.Fatalf("write data field: %v", err)
+		}
+	}
+	for _, f := range files {
+		h := make(map[string][]string)
+		h["Content-Disposition"] = []string{fmt.Sprintf(`form-data; name=%q; filename=%q`, f.field, f.name)}
+		if f.mimeType != "" {
+			h["Content-Type"] = []string{f.mimeType}
+		}
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			e.t.Fatalf("create part: %v", err)
+		}
+		_, _ = part.Write(f.content)
+	}
+	_ = mw.Close()
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func decodeJSON[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var out T
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+	return out
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status = %d, want %d; body = %s", w.Code, want, w.Body.String())
+	}
+}
+
+// seedAdmin stores an active admin the same way SeedAdminUser does.
+func (e *testEnv) seedAdmin(email, password string) *models.User {
+	e.t.Helper()
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		e.t.Fatalf("hash password: %v", err)
+	}
+	user := &models.User{
+		Email:        email,
+		PasswordHash: hash,
+		Role:         models.RoleAdmin,
+		IsActive:     true,
+	}
+	if err := e.app.Users.Insert(e.t.Context(), user); err != nil {
+		e.t.Fatalf("insert admin: %v", err)
+	}
+	return user
+}
+
+// login goes through POST /auth/login and returns the access token and the
+// refresh cookie.
+func (e *testEnv) login(email, password string) (string, *http.Cookie) {
+	e.t.Helper()
+	w := e.do(e.jsonRequest(http.MethodPost, "/auth/login", gin.H{"email": email, "password": password}, ""))
+	expectStatus(e.t, w, http.StatusOK)
+	body := decodeJSON[map[string]string](e.t, w)
+	return body["access_token"], refreshCookie(w)
+}
+
+func (e *testEnv) adminToken() string {
+	e.t.Helper()
+	e.seedAdmin(testAdminEmail, testAdminPassword)
+	token, _ := e.login(testAdminEmail, testAdminPassword)
+	return token
+}
+
+func refreshCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "refreshToken" {
+			return c
+		}
+	}
+	return nil
+}
+
+func (e *testEnv) seedCategory(name, slug string) models.Category {
+	e.t.Helper()
+	cat := models.Category{Name: name, Slug: slug, IsActive: true}
+	if err := e.app.Categories.Insert(e.t.Context(), &cat); err != nil {
+		e.t.Fatalf("insert category: %v", err)
+	}
+	return cat
+}
+
+func (e *testEnv) seedProduct(p models.Product) models.Product {
+	e.t.Helper()
+	if p.Slug == "" {
+		p.Slug = utils.GenerateSlug(p.Name)
+	}
+	if err := e.app.Products.Insert(e.t.Context(), &p); err != nil {
+		e.t.Fatalf("insert product: %v", err)
+	}
+	return p
+}
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 120, B: 40, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func pdfBytes() []byte {
+	return []byte("%PDF-1.4\n1 0 obj<<>>endobj\ntrailer<<>>\n%%EOF\n")
+}
+
+func mustObjectID(t *testing.T, hex string) bson.ObjectID {
+	t.Helper()
+	id, err := bson.ObjectIDFromHex(hex)
+	if err != nil {
+		t.Fatalf("object id %q: %v", hex, err)
+	}
+	return id
+}
+
+// ---- Local storage -----------------------------------------------------------
+
+// storedObjects lists every object written to the test store.
+func (e *testEnv) storedObjects() []string {
+	e.t.Helper()
+	names := []string{}
+	err := filepath.WalkDir(e.store.Dir(), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(e.store.Dir(), p)
+		names = append(names, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		e.t.Fatalf("walk store: %v", err)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *testEnv) stored(objectName string) bool {
+	e.t.Helper()
+	r, err := e.store.Open(e.t.Context(), objectName)
+	if err != nil {
+		return false
+	}
+	_ = r.Close()
+	return true
+}
+
+// storedImage counts the files of img in the store: 3 once its renditions
+// were uploaded, 0 once it was deleted.
+func (e *testEnv) storedImage(img models.Image) int {
+	e.t.Helper()
+	n := 0
+	for _, url := range img.URLs() {
+		if e.stored(objectKey(url)) {
+			n++
+		}
+	}
+	return n
+}
+
+// imageAt is a seeded image with a single file for every rendition, like
+// the images saved before renditions existed.
+func imageAt(url string) models.Image {
+	return models.Image{URL: url, Card: url, Thumbnail: url}
+}
+
+// objectKey maps a public URL handed out by the test store back to its object name.
+func objectKey(url string) string {
+	return strings.TrimPrefix(url, testMediaURL+"/")
+}
+
+func TestPing(t *testing.T) {
+	e := newTestEnv(t)
+	w := e.do(httptest.NewRequest(http.MethodGet, "/ping", nil))
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeJSON[map[string]string](t, w)["message"]; got != "pong" {
+		t.Fatalf("message = %q, want pong", got)
+	}
+}
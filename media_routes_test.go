@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMediaServesLocalUploads(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	img := pngBytes(t)
+
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/categories",
+		gin.H{"name": "Lampes"},
+		[]testFile{{field: "image", name: "lampe.png", content: img, mimeType: "image/png"}},
+		token))
+	expectStatus(t, w, http.StatusCreated)
+	id := decodeJSON[map[string]string](t, w)["id"]
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/categories/"+id, nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	imageURL := decodeJSON[models.Category](t, w).Image.URL
+	path := strings.TrimPrefix(imageURL, "http://localhost:8080")
+	if !strings.HasPrefix(path, "/media/products/lampes/") || !strings.HasSuffix(path, "-full.webp") {
+		t.Fatalf("image url = %q", imageURL)
+	}
+
+	w = e.do(httptest.NewRequest(http.MethodGet, path, nil))
+	expectStatus(t, w, http.StatusOK)
+	// the rendition, re-encoded from the upload
+	if body := w.Body.Bytes(); len(body) < 12 || string(body[:4]) != "RIFF" || string(body[8:12]) != "WEBP" {
+		t.Fatalf("served %q, want a WebP file", w.Body.Bytes())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/webp" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	w = e.do(httptest.NewRequest(http.MethodHead, path, nil))
+	expectStatus(t, w, http.StatusOK)
+
+	w = e.do(httptest.NewRequest(http.MethodGet, "/media/products/lampes/missing.png", nil))
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestImagesMigrateEncodesLegacyImages(t *testing.T) {
+	e := newTestEnv(t)
+	original, categoryOriginal := testMediaURL+"/products/banc/1.png", testMediaURL+"/products/bancs/1.png"
+	for _, url := range []string{original, categoryOriginal} {
+		png := pngBytes(t)
+		if err := e.store.Put(t.Context(), objectKey(url), bytes.NewReader(png), int64(len(png)), storage.PutOptions{ContentType: "image/png"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	variant := bson.NewObjectID()
+	bench := e.seedProduct(models.Product{
+		Name:     "Banc",
+		Images:   []models.Image{imageAt(original), imageAt(testMediaURL + "/products/banc/perdu.png")},
+		Variants: []models.ProductVariant{{Id: variant, SKU: "BANC-1", ImageUrls: []string{original}}},
+	})
+	cat := e.seedCategory("Bancs", "bancs")
+	if err := e.app.Categories.Update(t.Context(), cat.Id, bson.M{"image": imageAt(categoryOriginal)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the missing original fails, the others are encoded
+	var out bytes.Buffer
+	if err := runImagesCommand(t.Context(), e.app, []string{"migrate"}, &out); err == nil || out.String() != "encoded 2 images\n" {
+		t.Fatalf("images migrate = %v, printed %q", err, out.String())
+	}
+	product, _ := e.app.Products.FindByID(t.Context(), bench.Id)
+	img := product.Images[0]
+	if !img.Processed() || e.storedImage(img) != 3 || product.Images[1].Processed() {
+		t.Fatalf("images = %+v", product.Images)
+	}
+	if urls := product.Variants[0].ImageUrls; len(urls) != 1 || urls[0] != img.URL {
+		t.Fatalf("variant images = %q", urls)
+	}
+	category, _ := e.app.Categories.FindByID(t.Context(), cat.Id)
+	if category.Image == nil || !category.Image.Processed() || e.storedImage(*category.Image) != 3 {
+		t.Fatalf("category image = %+v", category.Image)
+	}
+	if e.stored(objectKey(original)) || e.stored(objectKey(categoryOriginal)) {
+		t.Fatal("the originals were not deleted")
+	}
+}
@@ -0,0 +1,123 @@
+// Package metrics owns the Prometheus collectors of the API and the adapters
+// that feed them: the gin middleware (in package middleware), a MongoDB
+// command monitor and a storage decorator.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "saho"
+
+// UnmatchedRoute labels requests that matched no route (404s on random
+// paths), so scanners cannot grow the label set.
+const UnmatchedRoute = "unmatched"
+
+// Metrics holds every collector on a private registry, so tests can build as
+// many instances as they need.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	mongoDuration *prometheus.HistogramVec
+	mongoErrors   *prometheus.CounterVec
+
+	storageOps      *prometheus.CounterVec
+	storageBytes    *prometheus.CounterVec
+	storageDuration *prometheus.HistogramVec
+
+	quoteRequests   *prometheus.CounterVec
+	productRequests *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "http", Name: "requests_total",
+			Help: "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "http", Name: "request_duration_seconds",
+			Help:    "HTTP request latency by method and route pattern.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+
+		mongoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "mongo", Name: "command_duration_seconds",
+			Help:    "MongoDB command latency by collection and command.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"collection", "command"}),
+		mongoErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "mongo", Name: "command_errors_total",
+			Help: "Failed MongoDB commands by collection and command.",
+		}, []string{"collection", "command"}),
+
+		storageOps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "storage", Name: "operations_total",
+			Help: "Object storage operations by driver, operation and result.",
+		}, []string{"driver", "operation", "result"}),
+		storageBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "storage", Name: "uploaded_bytes_total",
+			Help: "Bytes successfully uploaded to object storage by driver.",
+		}, []string{"driver"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "storage", Name: "operation_duration_seconds",
+			Help:    "Object storage latency by driver and operation.",
+			Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"driver", "operation"}),
+
+		quoteRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "quote_requests_total",
+			Help: "Quote requests created (status NEW) or moved to a status by an admin.",
+		}, []string{"status"}),
+		productRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "product_requests_total",
+			Help: "Product requests created (status NEW) or moved to a status by an admin.",
+		}, []string{"status"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.mongoDuration, m.mongoErrors,
+		m.storageOps, m.storageBytes, m.storageDuration,
+		m.quoteRequests, m.productRequests,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveHTTP records one request. route must be the route pattern (gin's
+// FullPath), never the raw path.
+func (m *Metrics) ObserveHTTP(method, route string, status int, d time.Duration) {
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// QuoteRequestStatus counts a quote request entering status.
+func (m *Metrics) QuoteRequestStatus(status string) {
+	m.quoteRequests.WithLabelValues(status).Inc()
+}
+
+// ProductRequestStatus counts a product request entering status.
+func (m *Metrics) ProductRequestStatus(status string) {
+	m.productRequests.WithLabelValues(status).Inc()
+}
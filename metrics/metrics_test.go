@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/princinho/sahobackend/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	return w.Body.String()
+}
+
+func expectSamples(t *testing.T, body string, samples ...string) {
+	t.Helper()
+	for _, s := range samples {
+		if !strings.Contains("\n"+body, "\n"+s+"\n") {
+			t.Errorf("sample %s not found", s)
+		}
+	}
+}
+
+func TestStorageDecorator(t *testing.T) {
+	local, err := storage.NewLocal(storage.LocalConfig{Dir: t.TempDir(), BaseURL: "http://localhost/media"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New()
+	s := m.Storage(local, storage.DriverLocal)
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "a/b.txt", strings.NewReader("hello"), 5, storage.PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "a/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, "a/b.txt"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("open deleted object: %v", err)
+	}
+	if storage.Unwrap(s) != local {
+		t.Fatal("Unwrap must return the driver")
+	}
+
+	expectSamples(t, scrape(t, m),
+		`saho_storage_operations_total{driver="local",operation="put",result="ok"} 1`,
+		`saho_storage_operations_total{driver="local",operation="delete",result="ok"} 1`,
+		`saho_storage_operations_total{driver="local",operation="open",result="not_found"} 1`,
+		`saho_storage_uploaded_bytes_total{driver="local"} 5`,
+	)
+}
+
+func TestMongoMonitor(t *testing.T) {
+	m := New()
+	mon := m.MongoMonitor()
+	ctx := context.Background()
+
+	start := func(id int64, name string, cmd bson.D) {
+		raw, err := bson.Marshal(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mon.Started(ctx, &event.CommandStartedEvent{Command: raw, CommandName: name, RequestID: id})
+	}
+	finished := func(id int64, name string) event.CommandFinishedEvent {
+		return event.CommandFinishedEvent{CommandName: name, RequestID: id, Duration: 3 * time.Millisecond}
+	}
+
+	start(1, "find", bson.D{{Key: "find", Value: "products"}, {Key: "filter", Value: bson.D{}}})
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished(1, "find")})
+	start(2, "getMore", bson.D{{Key: "getMore", Value: int64(42)}, {Key: "collection", Value: "products"}})
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished(2, "getMore")})
+	start(3, "insert", bson.D{{Key: "insert", Value: "users"}})
+	mon.Failed(ctx, &event.CommandFailedEvent{CommandFinishedEvent: finished(3, "insert")})
+	start(4, "ping", bson.D{{Key: "ping", Value: 1}})
+	mon.Succeeded(ctx, &event.CommandSucceededEvent{CommandFinishedEvent: finished(4, "ping")})
+
+	body := scrape(t, m)
+	expectSamples(t, body,
+		`saho_mongo_command_duration_seconds_count{collection="products",command="find"} 1`,
+		`saho_mongo_command_duration_seconds_count{collection="products",command="getMore"} 1`,
+		`saho_mongo_command_errors_total{collection="users",command="insert"} 1`,
+	)
+	if strings.Contains(body, `command="ping"`) {
+		t.Fatal("commands without a collection must not be recorded")
+	}
+}
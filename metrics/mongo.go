@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	"github.com/princinho/sahobackend/database"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+// MongoMonitor times every command sent by the client. Commands that do not
+// target a collection (hello, ping, endSessions…) are not recorded.
+func (m *Metrics) MongoMonitor() *event.CommandMonitor {
+	var inflight sync.Map // request ID -> collection
+
+	finish := func(evt event.CommandFinishedEvent, failed bool) {
+		coll, ok := inflight.LoadAndDelete(evt.RequestID)
+		if !ok {
+			return
+		}
+		m.mongoDuration.WithLabelValues(coll.(string), evt.CommandName).Observe(evt.Duration.Seconds())
+		if failed {
+			m.mongoErrors.WithLabelValues(coll.(string), evt.CommandName).Inc()
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, evt *event.CommandStartedEvent) {
+			if coll := database.CommandCollection(evt.CommandName, evt.Command); coll != "" {
+				inflight.Store(evt.RequestID, coll)
+			}
+		},
+		Succeeded: func(_ context.Context, evt *event.CommandSucceededEvent) {
+			finish(evt.CommandFinishedEvent, false)
+		},
+		Failed: func(_ context.Context, evt *event.CommandFailedEvent) {
+			finish(evt.CommandFinishedEvent, true)
+		},
+	}
+}
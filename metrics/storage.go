@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/princinho/sahobackend/storage"
+)
+
+// Storage wraps s so uploads, deletes and reads are counted and timed under
+// driver. Ping is left out: it belongs to the readiness checks.
+func (m *Metrics) Storage(s storage.Storage, driver string) storage.Storage {
+	return &instrumentedStorage{Storage: s, m: m, driver: driver}
+}
+
+type instrumentedStorage struct {
+	storage.Storage
+	m      *Metrics
+	driver string
+}
+
+// Unwrap exposes the driver, see storage.Unwrap.
+func (s *instrumentedStorage) Unwrap() storage.Storage {
+	return s.Storage
+}
+
+func (s *instrumentedStorage) observe(op string, start time.Time, err error) {
+	result := "ok"
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		result = "not_found"
+	case err != nil:
+		result = "error"
+	}
+	s.m.storageOps.WithLabelValues(s.driver, op, result).Inc()
+	s.m.storageDuration.WithLabelValues(s.driver, op).Observe(time.Since(start).Seconds())
+}
+
+func (s *instrumentedStorage) Put(ctx context.Context, objectName string, r io.Reader, size int64, opts storage.PutOptions) error {
+	start := time.Now()
+	err := s.Storage.Put(ctx, objectName, r, size, opts)
+	s.observe("put", start, err)
+	if err == nil && size > 0 {
+		s.m.storageBytes.WithLabelValues(s.driver).Add(float64(size))
+	}
+	return err
+}
+
+func (s *instrumentedStorage) Delete(ctx context.Context, objectName string) error {
+	start := time.Now()
+	err := s.Storage.Delete(ctx, objectName)
+	s.observe("delete", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) Open(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	start := time.Now()
+	rc, err := s.Storage.Open(ctx, objectName)
+	s.observe("open", start, err)
+	return rc, err
+}
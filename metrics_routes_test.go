@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/models"
+)
+
+func (e *testEnv) scrape(token string) string {
+	e.t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := e.do(req)
+	expectStatus(e.t, w, http.StatusOK)
+	return w.Body.String()
+}
+
+// expectMetric looks for an exact `series value` line in the exposition.
+func expectMetric(t *testing.T, body, sample string) {
+	t.Helper()
+	if !strings.Contains("\n"+body, "\n"+sample+"\n") {
+		t.Fatalf("sample %s not found in:\n%s", sample, body)
+	}
+}
+
+func TestMetricsUseRoutePatterns(t *testing.T) {
+	e := newTestEnv(t)
+	for _, id := range []string{"66f1a0000000000000000001", "66f1a0000000000000000002"} {
+		e.do(httptest.NewRequest(http.MethodGet, "/categories/"+id, nil))
+	}
+	e.do(httptest.NewRequest(http.MethodGet, "/wp-admin/setup.php", nil))
+
+	body := e.scrape("")
+	expectMetric(t, body, `saho_http_requests_total{method="GET",route="/categories/:id",status="404"} 2`)
+	expectMetric(t, body, `saho_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	expectMetric(t, body, `saho_http_request_duration_seconds_count{method="GET",route="/categories/:id"} 2`)
+	if strings.Contains(body, "66f1a0000000000000000001") || strings.Contains(body, "wp-admin") {
+		t.Fatal("raw paths leaked into the labels")
+	}
+}
+
+func TestMetricsCountRequestsByStatus(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	chair := e.seedProduct(models.Product{Name: "Chaise", Price: 120})
+	id := e.createQuote([]gin.H{{"productId": chair.Id.Hex(), "quantity": 1}})
+	e.createQuote([]gin.H{{"productId": chair.Id.Hex(), "quantity": 2}})
+
+	w := e.do(e.jsonRequest(http.MethodPatch, "/admin/quote-requests/"+id+"/status", gin.H{"status": "QUOTED"}, token))
+	expectStatus(t, w, http.StatusOK)
+
+	body := e.scrape("")
+	expectMetric(t, body, `saho_quote_requests_total{status="NEW"} 2`)
+	expectMetric(t, body, `saho_quote_requests_total{status="QUOTED"} 1`)
+}
+
+func TestMetricsToken(t *testing.T) {
+	e := newTestEnv(t)
+	e.app.Config.Metrics.Token = "scrape-me"
+	e.router = newRouter(e.app)
+
+	w := e.do(httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	expectStatus(t, w, http.StatusUnauthorized)
+	expectMetric(t, e.scrape("scrape-me"), `saho_http_requests_total{method="GET",route="/metrics",status="401"} 1`)
+}
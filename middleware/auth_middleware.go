@@ -2,32 +2,34 @@
 package middleware
 
 import (
-	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/logging"
 	"github.com/princinho/sahobackend/utils"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware accepts requests bearing an access token signed with secret.
+func AuthMiddleware(secret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		header := c.GetHeader("Authorization")
 		if header == "" || !strings.HasPrefix(header, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
+			apierror.Abort(c, apierror.Unauthorized(apierror.CodeUnauthorized, "missing token"))
 			return
 		}
 
 		tokenStr := strings.TrimPrefix(header, "Bearer ")
-		claims, err := utils.ValidateToken(tokenStr, os.Getenv("JWT_SECRET"))
+		claims, err := utils.ValidateToken(tokenStr, secret)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			apierror.Abort(c, apierror.Unauthorized(apierror.CodeUnauthorized, "invalid or expired token").Wrap(err))
 			return
 		}
 
 		c.Set("userID", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("role", claims.Role)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), logging.KeyUserID, claims.UserID))
 		c.Next()
 	}
 }
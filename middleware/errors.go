@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/i18n"
+)
+
+// Errors renders the last error a handler attached with c.Error as the
+// apierror envelope, in the language of the Accept-Language header. Errors
+// that are not an *apierror.Error become a 500 whose details stay in the
+// access log (RequestLogger logs c.Errors). It must run after RequestLogger
+// and before Recovery.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		e := apierror.From(c.Errors.Last().Err)
+		tag := i18n.Match(c.GetHeader("Accept-Language"))
+		c.Header("Content-Language", tag.String())
+		if !slices.Contains(c.Writer.Header().Values("Vary"), "Accept-Language") {
+			c.Writer.Header().Add("Vary", "Accept-Language") // next to the Vary: Origin of CORS
+		}
+		c.JSON(e.Status, e.Render(i18n.Printer(tag), c.Writer.Header().Get(RequestIDHeader)))
+	}
+}
+
+// NoRoute answers unknown endpoints with the error envelope.
+func NoRoute() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apierror.Abort(c, apierror.New(http.StatusNotFound, apierror.CodeRouteNotFound, "route not found"))
+	}
+}
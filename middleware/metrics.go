@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/metrics"
+)
+
+// Metrics records the latency and status of every request under its route
+// pattern (c.FullPath()), so /products/:id is one series whatever the ID.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		m.ObserveHTTP(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
+
+// MetricsAuth protects the scrape endpoint with a static bearer token. An
+// empty token leaves it open, for scrapers on a private network.
+func MetricsAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := []byte(c.GetHeader("Authorization"))
+		if token != "" && subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) != 1 {
+			apierror.Abort(c, apierror.Unauthorized(apierror.CodeUnauthorized, "invalid metrics token"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is read from the client and always set on the response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds what a client may inject into every log line.
+const maxRequestIDLen = 128
+
+// RequestLogger assigns the request ID (the client's X-Request-ID when it is
+// usable, a new UUID otherwise), stores a logger carrying it in the request
+// context, and writes one access line per request once it completes. A nil
+// logger means slog.Default().
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set("requestID", id)
+		c.Header(RequestIDHeader, id)
+		l := logger.With(logging.KeyRequestID, id)
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			l = l.With(logging.KeyTraceID, sc.TraceID().String())
+		}
+		ctx := logging.NewContext(c.Request.Context(), l)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", c.Request.URL.Path,
+			"status", status,
+			"durationMs", time.Since(start).Milliseconds(),
+			"bytes", c.Writer.Size(),
+			"clientIp", c.ClientIP(),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+		// the handler chain may have enriched the logger (e.g. with the user ID)
+		logging.FromContext(c.Request.Context()).Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery turns a panic into a 500 and logs it with the request's logger.
+// It must run after RequestLogger and Errors, which renders the 500.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				logging.FromContext(c.Request.Context()).Error("panic",
+					"panic", rec, "stack", string(debug.Stack()))
+				apierror.Abort(c, apierror.Internal(nil))
+			}
+		}()
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
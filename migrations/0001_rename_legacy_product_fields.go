@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// legacyProductFields maps the keys products were inserted with before the
+// model had bson tags (the driver lowercases untagged field names) to the
+// camelCase keys every query uses.
+var legacyProductFields = map[string]string{
+	"categoryids":        "categoryIds",
+	"imageurls":          "imageUrls",
+	"istrending":         "isTrending",
+	"descriptionfull":    "descriptionFull",
+	"similarproductsids": "similarProductsIds",
+	"isdisabled":         "isDisabled",
+}
+
+// renameLegacyProductFields moves legacy keys to their camelCase names. When
+// both exist the camelCase one came from a later PATCH, so it wins and the
+// legacy key is dropped. Products missing the flags the listing filters on
+// get explicit false values.
+func renameLegacyProductFields(ctx context.Context, db *mongo.Database) error {
+	col := db.Collection("products")
+
+	for legacy, current := range legacyProductFields {
+		_, err := col.UpdateMany(ctx,
+			bson.M{legacy: bson.M{"$exists": true}, current: bson.M{"$exists": false}},
+			bson.M{"$rename": bson.M{legacy: current}},
+		)
+		if err != nil {
+			return fmt.Errorf("rename %s: %w", legacy, err)
+		}
+		_, err = col.UpdateMany(ctx,
+			bson.M{legacy: bson.M{"$exists": true}},
+			bson.M{"$unset": bson.M{legacy: ""}},
+		)
+		if err != nil {
+			return fmt.Errorf("unset %s: %w", legacy, err)
+		}
+	}
+
+	for _, flag := range []string{"isDisabled", "isTrending"} {
+		_, err := col.UpdateMany(ctx,
+			bson.M{flag: bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{flag: false}},
+		)
+		if err != nil {
+			return fmt.Errorf("backfill %s: %w", flag, err)
+		}
+	}
+	return nil
+}
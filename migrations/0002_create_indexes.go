@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// hasSlug keeps documents without a slug out of the unique slug indexes
+// instead of colliding on a shared null.
+var hasSlug = bson.M{"slug": bson.M{"$type": "string"}}
+
+var indexes = map[string][]mongo.IndexModel{
+	"products": {
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetName("slug_unique").SetUnique(true).SetPartialFilterExpression(hasSlug)},
+		{Keys: bson.D{{Key: "categoryIds", Value: 1}}, Options: options.Index().SetName("categoryIds")},
+		{Keys: bson.D{{Key: "isDisabled", Value: 1}, {Key: "isTrending", Value: 1}}, Options: options.Index().SetName("isDisabled_isTrending")},
+	},
+	"categories": {
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetName("slug_unique").SetUnique(true).SetPartialFilterExpression(hasSlug)},
+	},
+	"users": {
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("email_unique").SetUnique(true)},
+	},
+	"refresh_tokens": {
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetName("tokenHash_unique").SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetName("userId")},
+		// expired sessions are removed by MongoDB itself
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0)},
+	},
+	"quote_requests": {
+		{Keys: bson.D{{Key: "createdAt", Value: -1}}, Options: options.Index().SetName("createdAt")},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetName("status_createdAt")},
+	},
+	"product_requests": {
+		{Keys: bson.D{{Key: "createdAt", Value: -1}}, Options: options.Index().SetName("createdAt")},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetName("status_createdAt")},
+	},
+}
+
+// createIndexes builds the indexes the repositories rely on, notably the
+// unique ones behind repositories.ErrDuplicateKey. CreateMany is a no-op for
+// indexes that already exist with the same definition.
+func createIndexes(ctx context.Context, db *mongo.Database) error {
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("%s indexes: %w", collection, err)
+		}
+	}
+	return nil
+}
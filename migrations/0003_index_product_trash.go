@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// indexProductTrash indexes the trash listing and the purge, which both
+// select on deletedAt. Live products have no deletedAt and stay out of the
+// sparse index.
+func indexProductTrash(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("products").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "deletedAt", Value: 1}},
+		Options: options.Index().SetName("deletedAt").SetSparse(true),
+	})
+	if err != nil {
+		return fmt.Errorf("products deletedAt index: %w", err)
+	}
+	return nil
+}
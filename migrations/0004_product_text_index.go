@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/princinho/sahobackend/search"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// createProductTextIndex builds the index behind GET /products?q=. Text
+// indexes (version 3) ignore case and diacritics; French stemming makes
+// "chaises" find "chaise". A collection holds a single text index, so
+// changing the fields or weights means dropping it in a later migration.
+func createProductTextIndex(ctx context.Context, db *mongo.Database) error {
+	keys := bson.D{}
+	weights := bson.D{}
+	for _, field := range []string{"name", "materials", "colors", "description", "descriptionFull"} {
+		keys = append(keys, bson.E{Key: field, Value: "text"})
+		weights = append(weights, bson.E{Key: field, Value: search.ProductWeights[field]})
+	}
+
+	_, err := db.Collection("products").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: keys,
+		Options: options.Index().
+			SetName("product_text").
+			SetWeights(weights).
+			SetDefaultLanguage("french").
+			// products have no "language" field; keep Mongo from reading one
+			SetLanguageOverride("textLanguage"),
+	})
+	if err != nil {
+		return fmt.Errorf("products text index: %w", err)
+	}
+	return nil
+}
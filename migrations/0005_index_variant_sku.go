@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// indexVariantSKU makes variant SKUs unique across products. A multikey
+// unique index does not compare the entries of one document, so the
+// controllers also check the SKUs within a product.
+func indexVariantSKU(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("products").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "variants.sku", Value: 1}},
+		Options: options.Index().
+			SetName("variants_sku_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"variants.sku": bson.M{"$type": "string"}}),
+	})
+	if err != nil {
+		return fmt.Errorf("products variants.sku index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// indexStockMovements serves the movement history, newest first, for the
+// whole ledger and per product.
+func indexStockMovements(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("stock_movements").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "createdAt", Value: -1}}, Options: options.Index().SetName("createdAt")},
+		{Keys: bson.D{{Key: "productId", Value: 1}, {Key: "createdAt", Value: -1}}, Options: options.Index().SetName("productId_createdAt")},
+	})
+	if err != nil {
+		return fmt.Errorf("stock_movements indexes: %w", err)
+	}
+	return nil
+}
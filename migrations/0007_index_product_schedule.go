@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// indexProductSchedule indexes the two steps of the product scheduler:
+// scheduled products by publishAt, published ones by unpublishAt.
+func indexProductSchedule(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("products").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "publishAt", Value: 1}},
+			Options: options.Index().SetName("status_publishAt"),
+		},
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "unpublishAt", Value: 1}},
+			Options: options.Index().SetName("status_unpublishAt"),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("products schedule indexes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// indexProductRevisions numbers the revisions of each product uniquely and
+// serves the history, newest first.
+func indexProductRevisions(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("product_revisions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "productId", Value: 1}, {Key: "version", Value: -1}},
+		Options: options.Index().SetName("productId_version_unique").SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("product_revisions index: %w", err)
+	}
+	return nil
+}
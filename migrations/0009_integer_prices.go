@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// integerPrices converts the prices saved as doubles to whole francs, the
+// XOF minor unit, rounding half up, and marks the products and quotes saved
+// before currencies existed as XOF. Only doubles are rewritten, so a rerun
+// changes nothing. It also indexes the exchange rates by currency.
+func integerPrices(ctx context.Context, db *mongo.Database) error {
+	steps := []struct {
+		collection string
+		filter     bson.M
+		set        bson.M
+	}{
+		{"products", bson.M{"price": bson.M{"$type": "double"}}, bson.M{"price": roundedPrice("$price")}},
+		{"products", bson.M{"variants.price": bson.M{"$type": "double"}}, bson.M{"variants": roundedPrices("$variants", "price")}},
+		{"products", bson.M{"currency": bson.M{"$exists": false}}, bson.M{"currency": "XOF"}},
+		{"product_revisions", bson.M{"snapshot.price": bson.M{"$type": "double"}}, bson.M{"snapshot.price": roundedPrice("$snapshot.price")}},
+		{"product_revisions", bson.M{"snapshot.variants.price": bson.M{"$type": "double"}}, bson.M{"snapshot.variants": roundedPrices("$snapshot.variants", "price")}},
+		{"quote_requests", bson.M{"items.unitPrice": bson.M{"$type": "double"}}, bson.M{"items": roundedPrices("$items", "unitPrice")}},
+		{"quote_requests", bson.M{"currency": bson.M{"$exists": false}}, bson.M{"currency": "XOF"}},
+	}
+	for _, step := range steps {
+		_, err := db.Collection(step.collection).UpdateMany(ctx, step.filter, mongo.Pipeline{{{Key: "$set", Value: step.set}}})
+		if err != nil {
+			return fmt.Errorf("%s prices: %w", step.collection, err)
+		}
+	}
+
+	_, err := db.Collection("exchange_rates").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "currency", Value: 1}},
+		Options: options.Index().SetName("currency_unique").SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("exchange_rates index: %w", err)
+	}
+	return nil
+}
+
+// roundedPrice rounds a positive price expression half up to a long.
+func roundedPrice(expr any) bson.M {
+	return bson.M{"$toLong": bson.M{"$floor": bson.M{"$add": bson.A{expr, 0.5}}}}
+}
+
+// roundedPrices rounds the field of every element of an array expression
+// that holds a double, leaving the others as they are.
+func roundedPrices(array, field string) bson.M {
+	value := "$$e." + field
+	return bson.M{"$map": bson.M{
+		"input": array,
+		"as":    "e",
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$type": value}, "double"}},
+			bson.M{"$mergeObjects": bson.A{"$$e", bson.M{field: roundedPrice(value)}}},
+			"$$e",
+		}},
+	}}
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// imageRenditions turns the image URLs of products (imageUrls), of their
+// revision snapshots and of categories (imageUrl) into image objects. An
+// image saved before renditions existed is a single file, so its URL stands
+// for every rendition until "sahobackend images migrate" encodes it. Only
+// documents still holding the old field are rewritten.
+func imageRenditions(ctx context.Context, db *mongo.Database) error {
+	steps := []struct {
+		collection string
+		old, field string
+		set        any
+	}{
+		{"products", "imageUrls", "images", legacyImages("$imageUrls")},
+		{"product_revisions", "snapshot.imageUrls", "snapshot.images", legacyImages("$snapshot.imageUrls")},
+		{"categories", "imageUrl", "image", bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{bson.M{"$strLenCP": bson.M{"$ifNull": bson.A{"$imageUrl", ""}}}, 0}},
+			legacyImage("$imageUrl"),
+			"$$REMOVE",
+		}}},
+	}
+	for _, step := range steps {
+		_, err := db.Collection(step.collection).UpdateMany(ctx,
+			bson.M{step.old: bson.M{"$exists": true}},
+			mongo.Pipeline{
+				{{Key: "$set", Value: bson.M{step.field: step.set}}},
+				{{Key: "$unset", Value: step.old}},
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", step.collection, step.old, err)
+		}
+	}
+	return nil
+}
+
+// legacyImages maps an array expression of URLs to image objects.
+func legacyImages(urls string) bson.M {
+	return bson.M{"$map": bson.M{
+		"input": bson.M{"$ifNull": bson.A{urls, bson.A{}}},
+		"as":    "u",
+		"in":    legacyImage("$$u"),
+	}}
+}
+
+// legacyImage is the image object of a single file.
+func legacyImage(url string) bson.M {
+	return bson.M{"url": url, "card": url, "thumbnail": url}
+}
@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// imageGallery numbers the images of products and of their revision
+// snapshots in their stored order and makes the first one primary. Every
+// image, categories included, gets the object name of its URL: images are
+// stored under products/<slug>/<file>, whatever the storage driver puts
+// before it. Fields already set are kept, so a rerun changes nothing.
+func imageGallery(ctx context.Context, db *mongo.Database) error {
+	steps := []struct {
+		collection string
+		field      string
+	}{
+		{"products", "images"},
+		{"product_revisions", "snapshot.images"},
+	}
+	for _, step := range steps {
+		_, err := db.Collection(step.collection).UpdateMany(ctx,
+			bson.M{step.field: bson.M{"$elemMatch": bson.M{"position": bson.M{"$exists": false}}}},
+			mongo.Pipeline{{{Key: "$set", Value: bson.M{step.field: galleryImages("$" + step.field)}}}},
+		)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", step.collection, step.field, err)
+		}
+	}
+
+	_, err := db.Collection("categories").UpdateMany(ctx,
+		bson.M{"image.url": bson.M{"$exists": true}, "image.objectName": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"image.objectName": imageObjectName("$image.url")}}}},
+	)
+	if err != nil {
+		return fmt.Errorf("categories image: %w", err)
+	}
+	return nil
+}
+
+// galleryImages maps an array expression of images to the same images with
+// their position, primary flag and object name.
+func galleryImages(images string) bson.M {
+	return bson.M{"$map": bson.M{
+		"input": bson.M{"$range": bson.A{0, bson.M{"$size": bson.M{"$ifNull": bson.A{images, bson.A{}}}}}},
+		"as":    "i",
+		"in": bson.M{"$let": bson.M{
+			"vars": bson.M{"img": bson.M{"$arrayElemAt": bson.A{images, "$$i"}}},
+			"in": bson.M{"$mergeObjects": bson.A{"$$img", bson.M{
+				"position":   bson.M{"$ifNull": bson.A{"$$img.position", "$$i"}},
+				"isPrimary":  bson.M{"$ifNull": bson.A{"$$img.isPrimary", bson.M{"$eq": bson.A{"$$i", 0}}}},
+				"objectName": bson.M{"$ifNull": bson.A{"$$img.objectName", imageObjectName("$$img.url")}},
+			}}},
+		}},
+	}}
+}
+
+// imageObjectName extracts the object name from an image URL expression;
+// it is missing when the URL does not end with one.
+func imageObjectName(url string) bson.M {
+	return bson.M{"$let": bson.M{
+		"vars": bson.M{"m": bson.M{"$regexFind": bson.M{"input": url, "regex": `products/[^/]+/[^/]+$`}}},
+		"in":   "$$m.match",
+	}}
+}
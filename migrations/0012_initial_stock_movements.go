@@ -0,0 +1,96 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// initialStockMovements opens the ledger of the products and variants
+// created before it existed: each one whose quantity differs from the sum
+// of its movements gets an INITIAL movement for the difference, dated from
+// its id so it comes first in the history. Once it ran the sums match, so a
+// rerun inserts nothing; the movements are upserted by product, variant and
+// type so two instances migrating together write each one once.
+func initialStockMovements(ctx context.Context, db *mongo.Database) error {
+	type stockKey struct {
+		Product bson.ObjectID  `bson:"p"`
+		Variant *bson.ObjectID `bson:"v"`
+	}
+	sums := map[bson.ObjectID]map[bson.ObjectID]int{} // by product, then variant (zero for the product itself)
+	cursor, err := db.Collection("stock_movements").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": bson.M{"p": "$productId", "v": "$variantId"}, "sum": bson.M{"$sum": "$delta"}}}},
+	})
+	if err != nil {
+		return fmt.Errorf("sum stock movements: %w", err)
+	}
+	var groups []struct {
+		Key stockKey `bson:"_id"`
+		Sum int      `bson:"sum"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return fmt.Errorf("sum stock movements: %w", err)
+	}
+	for _, g := range groups {
+		if sums[g.Key.Product] == nil {
+			sums[g.Key.Product] = map[bson.ObjectID]int{}
+		}
+		var variant bson.ObjectID
+		if g.Key.Variant != nil {
+			variant = *g.Key.Variant
+		}
+		sums[g.Key.Product][variant] = g.Sum
+	}
+
+	cursor, err = db.Collection("products").Find(ctx, bson.M{},
+		options.Find().SetProjection(bson.M{"quantity": 1, "variants._id": 1, "variants.quantity": 1}))
+	if err != nil {
+		return fmt.Errorf("read products: %w", err)
+	}
+	var products []struct {
+		Id       bson.ObjectID `bson:"_id"`
+		Quantity int           `bson:"quantity"`
+		Variants []struct {
+			Id       bson.ObjectID `bson:"_id"`
+			Quantity int           `bson:"quantity"`
+		} `bson:"variants"`
+	}
+	if err := cursor.All(ctx, &products); err != nil {
+		return fmt.Errorf("read products: %w", err)
+	}
+
+	var movements []mongo.WriteModel
+	initial := func(product bson.ObjectID, variant *bson.ObjectID, delta int) mongo.WriteModel {
+		filter := bson.M{"productId": product, "variantId": bson.M{"$exists": false}, "type": "INITIAL"}
+		if variant != nil {
+			filter["variantId"] = *variant
+		}
+		return mongo.NewUpdateOneModel().SetFilter(filter).SetUpsert(true).SetUpdate(bson.M{"$setOnInsert": bson.M{
+			"delta": delta, "quantityAfter": delta, "reason": "ledger opening",
+			"authorId": bson.ObjectID{}, "authorEmail": "", "createdAt": product.Timestamp(),
+		}})
+	}
+	for _, p := range products {
+		// a product with variants keeps its stock in them
+		if len(p.Variants) == 0 {
+			if delta := p.Quantity - sums[p.Id][bson.ObjectID{}]; delta != 0 {
+				movements = append(movements, initial(p.Id, nil, delta))
+			}
+		}
+		for _, v := range p.Variants {
+			if delta := v.Quantity - sums[p.Id][v.Id]; delta != 0 {
+				movements = append(movements, initial(p.Id, &v.Id, delta))
+			}
+		}
+	}
+	if len(movements) == 0 {
+		return nil
+	}
+	if _, err := db.Collection("stock_movements").BulkWrite(ctx, movements); err != nil {
+		return fmt.Errorf("insert initial stock movements: %w", err)
+	}
+	return nil
+}
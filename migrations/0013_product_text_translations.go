@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// productTextTranslations rebuilds the product text index of migration 4 so
+// it also covers the translations: translations.<locale>.<field> for the
+// locales and translated fields below, weighted like the field they
+// translate. Text indexes take no wildcard below a map, so a new locale or
+// translated field needs another migration rebuilding the index.
+func productTextTranslations(ctx context.Context, db *mongo.Database) error {
+	// the weights of search.ProductWeights when this migration was written
+	weight := map[string]int{"name": 10, "materials": 4, "colors": 4, "description": 2, "descriptionFull": 1}
+	keys := bson.D{}
+	weights := bson.D{}
+	add := func(path, field string) {
+		keys = append(keys, bson.E{Key: path, Value: "text"})
+		weights = append(weights, bson.E{Key: path, Value: weight[field]})
+	}
+	for _, field := range []string{"name", "materials", "colors", "description", "descriptionFull"} {
+		add(field, field)
+	}
+	for _, locale := range []string{"fr", "en"} {
+		for _, field := range []string{"name", "description", "descriptionFull"} {
+			add("translations."+locale+"."+field, field)
+		}
+	}
+
+	indexes := db.Collection("products").Indexes()
+	var cmdErr mongo.CommandError
+	if err := indexes.DropOne(ctx, "product_text"); err != nil && !(errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound") {
+		return fmt.Errorf("drop products text index: %w", err)
+	}
+	_, err := indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys: keys,
+		Options: options.Index().
+			SetName("product_text").
+			SetWeights(weights).
+			SetDefaultLanguage("french").
+			SetLanguageOverride("textLanguage"),
+	})
+	if err != nil {
+		return fmt.Errorf("products text index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Package migrations versions the MongoDB schema: indexes and the data
+// backfills that go with model changes. Applied versions are recorded in the
+// schema_migrations collection so each migration runs once per database.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Collection records applied migrations, one document per version.
+const Collection = "schema_migrations"
+
+// ErrUnknownVersions is returned by List when schema_migrations holds
+// versions missing from the migration list.
+var ErrUnknownVersions = errors.New("database has migrations this build does not know")
+
+// Migration is one schema step. Up must be idempotent: two instances
+// starting together may both run a migration before either records it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+}
+
+// Record is the schema_migrations document written after a migration succeeds.
+type Record struct {
+	Version   int       `bson:"_id" json:"version"`
+	Name      string    `bson:"name" json:"name"`
+	AppliedAt time.Time `bson:"appliedAt" json:"appliedAt"`
+}
+
+// Status pairs a known migration with its record, if it was applied.
+type Status struct {
+	Migration
+	Applied *Record
+}
+
+// All is every migration of the application, in version order. Append new
+// migrations at the end and never renumber or edit a released one.
+var All = []Migration{
+	{Version: 1, Name: "rename_legacy_product_fields", Up: renameLegacyProductFields},
+	{Version: 2, Name: "create_indexes", Up: createIndexes},
+	{Version: 3, Name: "index_product_trash", Up: indexProductTrash},
+	{Version: 4, Name: "product_text_index", Up: createProductTextIndex},
+	{Version: 5, Name: "index_variant_sku", Up: indexVariantSKU},
+	{Version: 6, Name: "index_stock_movements", Up: indexStockMovements},
+	{Version: 7, Name: "index_product_schedule", Up: indexProductSchedule},
+	{Version: 8, Name: "index_product_revisions", Up: indexProductRevisions},
+	{Version: 9, Name: "integer_prices", Up: integerPrices},
+	{Version: 10, Name: "image_renditions", Up: imageRenditions},
+	{Version: 11, Name: "image_gallery", Up: imageGallery},
+	{Version: 12, Name: "initial_stock_movements", Up: initialStockMovements},
+	{Version: 13, Name: "product_text_translations", Up: productTextTranslations},
+}
+
+// Validate checks that versions are positive, unique and ascending.
+func Validate(migrations []Migration) error {
+	prev := 0
+	for _, m := range migrations {
+		if m.Version <= prev {
+			return fmt.Errorf("migration %d (%s): versions must be positive and strictly ascending", m.Version, m.Name)
+		}
+		if m.Up == nil {
+			return fmt.Errorf("migration %d (%s): missing Up", m.Version, m.Name)
+		}
+		prev = m.Version
+	}
+	return nil
+}
+
+// Pending returns the migrations whose version is not in applied, in order.
+func Pending(migrations []Migration, applied map[int]Record) []Migration {
+	out := []Migration{}
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func appliedRecords(ctx context.Context, db *mongo.Database) (map[int]Record, error) {
+	cursor, err := db.Collection(Collection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", Collection, err)
+	}
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("read %s: %w", Collection, err)
+	}
+	applied := make(map[int]Record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// Run applies every pending migration in order and returns the ones it ran.
+// It stops at the first failure; the failed migration is not recorded.
+func Run(ctx context.Context, db *mongo.Database, migrations []Migration) ([]Migration, error) {
+	if err := Validate(migrations); err != nil {
+		return nil, err
+	}
+	applied, err := appliedRecords(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	ran := []Migration{}
+	for _, m := range Pending(migrations, applied) {
+		slog.Info("applying migration", "version", m.Version, "name", m.Name)
+		if err := m.Up(ctx, db); err != nil {
+			return ran, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		rec := Record{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}
+		_, err := db.Collection(Collection).ReplaceOne(ctx, bson.M{"_id": m.Version}, rec, options.Replace().SetUpsert(true))
+		if err != nil {
+			return ran, fmt.Errorf("record migration %d: %w", m.Version, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// List reports every known migration with its applied record, plus an error
+// if the database holds versions this binary does not know (a newer deploy
+// already migrated it).
+func List(ctx context.Context, db *mongo.Database, migrations []Migration) ([]Status, error) {
+	applied, err := appliedRecords(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	known := map[int]bool{}
+	out := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		st := Status{Migration: m}
+		if rec, ok := applied[m.Version]; ok {
+			st.Applied = &rec
+		}
+		out = append(out, st)
+	}
+
+	unknown := []int{}
+	for v := range applied {
+		if !known[v] {
+			unknown = append(unknown, v)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Ints(unknown)
+		return out, fmt.Errorf("%w: %v", ErrUnknownVersions, unknown)
+	}
+	return out, nil
+}
@@ -0,0 +1,177 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func noop(context.Context, *mongo.Database) error { return nil }
+
+func TestAllIsValid(t *testing.T) {
+	if err := Validate(All); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		list []Migration
+		ok   bool
+	}{
+		{"empty", nil, true},
+		{"ascending", []Migration{{1, "a", noop}, {2, "b", noop}, {5, "c", noop}}, true},
+		{"zero version", []Migration{{0, "a", noop}}, false},
+		{"duplicate", []Migration{{1, "a", noop}, {1, "b", noop}}, false},
+		{"out of order", []Migration{{2, "a", noop}, {1, "b", noop}}, false},
+		{"missing up", []Migration{{1, "a", nil}}, false},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.list); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestPending(t *testing.T) {
+	list := []Migration{{1, "a", noop}, {2, "b", noop}, {3, "c", noop}}
+	got := Pending(list, map[int]Record{2: {Version: 2}})
+	if len(got) != 2 || got[0].Version != 1 || got[1].Version != 3 {
+		t.Fatalf("Pending = %+v", got)
+	}
+	if got := Pending(list, map[int]Record{1: {}, 2: {}, 3: {}}); len(got) != 0 {
+		t.Fatalf("Pending with everything applied = %+v", got)
+	}
+}
+
+// TestRunAgainstMongo needs a disposable server: MONGODB_TEST_URI=mongodb://localhost:27017
+func TestRunAgainstMongo(t *testing.T) {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+	db := client.Database(fmt.Sprintf("saho_migrations_test_%d", time.Now().UnixNano()))
+	defer db.Drop(context.Background())
+
+	// a product written before the model had bson tags, plus one that was
+	// PATCHed afterwards and holds both spellings
+	products := db.Collection("products")
+	_, err = products.InsertMany(ctx, []any{
+		bson.M{"name": "Chaise", "slug": "chaise", "imageurls": bson.A{"a.png"}, "isdisabled": true},
+		bson.M{"name": "Table", "slug": "table", "imageurls": bson.A{"old.png"}, "imageUrls": bson.A{"new.png"}},
+		bson.M{"name": "Banc", "slug": "banc", "quantity": 3, "translations": bson.M{"en": bson.M{"name": "Garden bench"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ran, err := Run(ctx, db, All)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != len(All) {
+		t.Fatalf("first run applied %d migrations, want %d", len(ran), len(All))
+	}
+	if ran, err := Run(ctx, db, All); err != nil || len(ran) != 0 {
+		t.Fatalf("second run = %v, %v; want nothing to do", ran, err)
+	}
+
+	var chaise, table bson.M
+	_ = products.FindOne(ctx, bson.M{"slug": "chaise"}).Decode(&chaise)
+	_ = products.FindOne(ctx, bson.M{"slug": "table"}).Decode(&table)
+	if _, ok := chaise["imageurls"]; ok || chaise["isDisabled"] != true || chaise["isTrending"] != false {
+		t.Fatalf("chaise not migrated: %v", chaise)
+	}
+	if _, ok := table["imageurls"]; ok {
+		t.Fatalf("table kept the wrong images: %v", table)
+	}
+	// its images are now a gallery of image objects
+	var gallery struct {
+		Images []struct {
+			URL        string `bson:"url"`
+			Thumbnail  string `bson:"thumbnail"`
+			ObjectName string `bson:"objectName"`
+			Position   int    `bson:"position"`
+			IsPrimary  bool   `bson:"isPrimary"`
+		} `bson:"images"`
+	}
+	_ = products.FindOne(ctx, bson.M{"slug": "table"}).Decode(&gallery)
+	if len(gallery.Images) != 1 || gallery.Images[0].URL != "new.png" || gallery.Images[0].Thumbnail != "new.png" ||
+		gallery.Images[0].Position != 0 || !gallery.Images[0].IsPrimary {
+		t.Fatalf("table images = %+v", gallery.Images)
+	}
+
+	// stock held before the ledger opens it, once despite the second run
+	opening, err := db.Collection("stock_movements").Find(ctx, bson.M{"type": "INITIAL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var initial []bson.M
+	if err := opening.All(ctx, &initial); err != nil {
+		t.Fatal(err)
+	}
+	if len(initial) != 1 || fmt.Sprint(initial[0]["delta"]) != "3" {
+		t.Fatalf("initial movements = %v", initial)
+	}
+
+	// the text index covers the translations
+	if n, err := products.CountDocuments(ctx, bson.M{"$text": bson.M{"$search": "bench"}}); err != nil || n != 1 {
+		t.Fatalf("english text search = %d, %v; want the bench", n, err)
+	}
+
+	// the unique slug index now backs ErrDuplicateKey
+	_, err = products.InsertOne(ctx, bson.M{"name": "Chaise 2", "slug": "chaise"})
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Fatalf("duplicate slug insert = %v, want duplicate key error", err)
+	}
+	// and variant SKUs are unique across products
+	withSKU := func(sku string) bson.M {
+		return bson.M{"$set": bson.M{"variants": bson.A{bson.M{"sku": sku}}}}
+	}
+	if _, err := products.UpdateOne(ctx, bson.M{"slug": "table"}, withSKU("TB-1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.UpdateOne(ctx, bson.M{"slug": "chaise"}, withSKU("TB-1")); !mongo.IsDuplicateKeyError(err) {
+		t.Fatalf("duplicate sku update = %v, want duplicate key error", err)
+	}
+
+	cursor, err := db.Collection("refresh_tokens").Indexes().List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var specs []bson.M
+	if err := cursor.All(ctx, &specs); err != nil {
+		t.Fatal(err)
+	}
+	ttl := false
+	for _, spec := range specs {
+		if spec["name"] == "expiresAt_ttl" && spec["expireAfterSeconds"] != nil {
+			ttl = true
+		}
+	}
+	if !ttl {
+		t.Fatalf("no TTL index on refresh_tokens: %v", specs)
+	}
+
+	// a newer deploy's migration shows up as unknown
+	_, _ = db.Collection(Collection).InsertOne(ctx, Record{Version: 999, Name: "future"})
+	if _, err := List(ctx, db, All); !errors.Is(err, ErrUnknownVersions) {
+		t.Fatalf("List = %v, want ErrUnknownVersions", err)
+	}
+}
@@ -8,5 +8,8 @@ type Category struct {
 	Slug        string        `bson:"slug,omitempty" json:"slug"`
 	Description string        `bson:"description,omitempty" json:"description,omitempty"`
 	IsActive    bool          `bson:"isActive" json:"isActive"`
-	ImageUrl    string        `bson:"imageUrl,omitempty" json:"imageUrl,omitempty"`
+	Image       *Image        `bson:"image,omitempty" json:"image,omitempty"`
+	// Translations holds the name and description in the other languages,
+	// keyed by locale; the fields above are in the default one.
+	Translations map[string]CategoryTranslation `bson:"translations,omitempty" json:"translations,omitempty"`
 }
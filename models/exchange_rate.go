@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ExchangeRate is what one unit of Currency is worth in XOF, maintained by
+// the admins.
+type ExchangeRate struct {
+	ID       bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Currency string        `bson:"currency" json:"currency"`
+	Rate     float64       `bson:"rate" json:"rate"`
+
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+	UpdatedBy string    `bson:"updatedBy,omitempty" json:"updatedBy,omitempty"`
+}
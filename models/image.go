@@ -0,0 +1,79 @@
+package models
+
+import "slices"
+
+// Image is an uploaded product or category picture, stored as WebP
+// renditions of fixed sizes. URL, the full-size rendition, identifies it:
+// variants and removals refer to the image by it.
+type Image struct {
+	URL       string `bson:"url" json:"url"`
+	Card      string `bson:"card" json:"card"`
+	Thumbnail string `bson:"thumbnail" json:"thumbnail"`
+	// ObjectName is the storage object of URL.
+	ObjectName string `bson:"objectName,omitempty" json:"objectName,omitempty"`
+	// Position is the rank of the image in the product gallery, from 0; the
+	// images of a product are kept in that order (see ArrangeImages).
+	Position int `bson:"position" json:"position"`
+	// Alt describes the image, by locale, for screen readers and search
+	// engines.
+	Alt map[string]string `bson:"alt,omitempty" json:"alt,omitempty"`
+	// IsPrimary marks the image the product is shown with in lists; a
+	// product with images has exactly one.
+	IsPrimary bool `bson:"isPrimary" json:"isPrimary"`
+}
+
+// URLs lists the distinct files of the image.
+func (i Image) URLs() []string {
+	return slices.Compact([]string{i.URL, i.Card, i.Thumbnail})
+}
+
+// Processed reports whether the image has its renditions. The images saved
+// before renditions existed have a single file, the original upload, for
+// every size until "sahobackend images migrate" encodes them.
+func (i Image) Processed() bool {
+	return i.Thumbnail != i.URL
+}
+
+// ArrangeImages numbers the images in their order and keeps a single
+// primary one: the first flagged, or else the first image.
+func ArrangeImages(images []Image) []Image {
+	primary := max(0, slices.IndexFunc(images, func(img Image) bool { return img.IsPrimary }))
+	for i := range images {
+		images[i].Position = i
+		images[i].IsPrimary = i == primary
+	}
+	return images
+}
+
+// ImageURLs lists the URL of each image, in order.
+func ImageURLs(images []Image) []string {
+	urls := make([]string, len(images))
+	for i, img := range images {
+		urls[i] = img.URL
+	}
+	return urls
+}
+
+// Image returns the image of p with the given URL.
+func (p *Product) Image(url string) (Image, bool) {
+	for _, img := range p.Images {
+		if img.URL == url {
+			return img, true
+		}
+	}
+	return Image{}, false
+}
+
+// PrimaryImage returns the image p is shown with in lists, or nil when it
+// has none.
+func (p *Product) PrimaryImage() *Image {
+	for i := range p.Images {
+		if p.Images[i].IsPrimary {
+			return &p.Images[i]
+		}
+	}
+	if len(p.Images) > 0 {
+		return &p.Images[0]
+	}
+	return nil
+}
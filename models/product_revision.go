@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type ProductRevisionAction string
+
+const (
+	ProductRevisionCreate ProductRevisionAction = "CREATE"
+	ProductRevisionUpdate ProductRevisionAction = "UPDATE"
+	// ProductRevisionRollback restores the fields of an earlier revision.
+	ProductRevisionRollback ProductRevisionAction = "ROLLBACK"
+)
+
+// ProductRevision is the state of a product after one change. Versions
+// count from 1 per product. The snapshot leaves the stock out (quantities
+// are zero): it has its own ledger, the stock movements.
+type ProductRevision struct {
+	ID        bson.ObjectID         `bson:"_id,omitempty" json:"id"`
+	ProductID bson.ObjectID         `bson:"productId" json:"productId"`
+	Version   int                   `bson:"version" json:"version"`
+	Action    ProductRevisionAction `bson:"action" json:"action"`
+	// ChangedFields names, as in the product JSON, the fields the change
+	// set; for a creation, the fields that are not empty.
+	ChangedFields []string `bson:"changedFields" json:"changedFields"`
+	// RestoredVersion is the revision a rollback went back to.
+	RestoredVersion int     `bson:"restoredVersion,omitempty" json:"restoredVersion,omitempty"`
+	Snapshot        Product `bson:"snapshot" json:"snapshot"`
+
+	AuthorID    bson.ObjectID `bson:"authorId" json:"authorId"`
+	AuthorEmail string        `bson:"authorEmail" json:"authorEmail"`
+	CreatedAt   time.Time     `bson:"createdAt" json:"createdAt"`
+}
@@ -1,22 +1,117 @@
 package models
 
-import "go.mongodb.org/mongo-driver/v2/bson"
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type ProductStatus string
+
+const (
+	// ProductStatusDraft is being prepared and not on sale.
+	ProductStatusDraft ProductStatus = "DRAFT"
+	// ProductStatusScheduled goes on sale at PublishAt.
+	ProductStatusScheduled ProductStatus = "SCHEDULED"
+	ProductStatusPublished ProductStatus = "PUBLISHED"
+	// ProductStatusArchived is no longer on sale.
+	ProductStatusArchived ProductStatus = "ARCHIVED"
+)
 
 type Product struct {
 	Id                 bson.ObjectID   `bson:"_id,omitempty" json:"id"`
-	Name               string          `json:"name"`
-	Price              float64         `json:"price"`
-	Quantity           int             `json:"quantity"`
+	Name               string          `bson:"name" json:"name"`
+	Price              int64           `bson:"price" json:"price"`
+	Currency           string          `bson:"currency,omitempty" json:"currency"` // of Price, in minor units: XOF as stored
+	Quantity           int             `bson:"quantity" json:"quantity"`
 	Slug               string          `bson:"slug,omitempty" json:"slug"`
-	CategoryIds        []bson.ObjectID `json:"categoryIds"`
-	ImageUrls          []string        `json:"imageUrls"`
-	IsTrending         bool            `json:"isTrending"`
-	Materials          []string        `json:"materials"`
-	Colors             []string        `json:"colors"`
-	Description        string          `json:"description"`
-	DescriptionFull    string          `json:"descriptionFull"`
-	Dimensions         string          `json:"dimensions"`
-	Weight             string          `json:"weight"`
-	SimilarProductsIds []bson.ObjectID `json:"similarProductsIds"`
-	IsDisabled         bool            `json:"isDisabled"`
+	CategoryIds        []bson.ObjectID `bson:"categoryIds" json:"categoryIds"`
+	Images             []Image         `bson:"images" json:"images"`
+	IsTrending         bool            `bson:"isTrending" json:"isTrending"`
+	Materials          []string        `bson:"materials" json:"materials"`
+	Colors             []string        `bson:"colors" json:"colors"`
+	Description        string          `bson:"description" json:"description"`
+	DescriptionFull    string          `bson:"descriptionFull" json:"descriptionFull"`
+	Dimensions         string          `bson:"dimensions" json:"dimensions"`
+	Weight             string          `bson:"weight" json:"weight"`
+	SimilarProductsIds []bson.ObjectID `bson:"similarProductsIds" json:"similarProductsIds"`
+	IsDisabled         bool            `bson:"isDisabled" json:"isDisabled"`
+	// Translations holds the name and descriptions in the other languages,
+	// keyed by locale ("en"); the fields above are in the default one.
+	Translations map[string]ProductTranslation `bson:"translations,omitempty" json:"translations,omitempty"`
+	// Status drives when the product is on sale; the scheduler publishes it
+	// at PublishAt and archives it at UnpublishAt.
+	Status      ProductStatus `bson:"status,omitempty" json:"status"`
+	PublishAt   *time.Time    `bson:"publishAt,omitempty" json:"publishAt,omitempty"`
+	UnpublishAt *time.Time    `bson:"unpublishAt,omitempty" json:"unpublishAt,omitempty"`
+	// Variants are the versions of the product sold separately, each with
+	// its own SKU and stock; a product without variants is sold as is.
+	Variants []ProductVariant `bson:"variants,omitempty" json:"variants,omitempty"`
+	// DeletedAt is set while the product sits in the trash.
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+// IsPublished reports whether the product is on sale at now: published, or
+// scheduled with a PublishAt already past, and before its UnpublishAt. It
+// does not wait for the scheduler to flip the status. Products saved before
+// the lifecycle existed have no status and are published.
+func (p *Product) IsPublished(now time.Time) bool {
+	switch p.Status {
+	case "", ProductStatusPublished:
+	case ProductStatusScheduled:
+		if p.PublishAt == nil || p.PublishAt.After(now) {
+			return false
+		}
+	default:
+		return false
+	}
+	return p.UnpublishAt == nil || p.UnpublishAt.After(now)
+}
+
+// ProductVariant is one purchasable version of a product, told apart by its
+// option values (finish, size...). Price and ImageUrls fall back to the
+// product's when unset.
+type ProductVariant struct {
+	Id      bson.ObjectID   `bson:"_id" json:"id"`
+	SKU     string          `bson:"sku" json:"sku"`
+	Options []VariantOption `bson:"options" json:"options"`
+	// Price overrides the product price, in the same currency.
+	Price    *int64 `bson:"price,omitempty" json:"price,omitempty"`
+	Quantity int    `bson:"quantity" json:"quantity"`
+	// ImageUrls is the subset of the product images showing this variant,
+	// by Image.URL.
+	ImageUrls []string `bson:"imageUrls,omitempty" json:"imageUrls,omitempty"`
+}
+
+type VariantOption struct {
+	Name  string `bson:"name" json:"name"`
+	Value string `bson:"value" json:"value"`
+}
+
+// Label names the variant by its option values: "Teck / Naturel".
+func (v ProductVariant) Label() string {
+	values := make([]string, len(v.Options))
+	for i, o := range v.Options {
+		values[i] = o.Value
+	}
+	return strings.Join(values, " / ")
+}
+
+// Variant returns the variant with the given id.
+func (p *Product) Variant(id bson.ObjectID) (*ProductVariant, bool) {
+	for i := range p.Variants {
+		if p.Variants[i].Id == id {
+			return &p.Variants[i], true
+		}
+	}
+	return nil, false
+}
+
+// PriceOf is the price of v, or the product price without an override.
+func (p *Product) PriceOf(v *ProductVariant) int64 {
+	if v != nil && v.Price != nil {
+		return *v.Price
+	}
+	return p.Price
 }
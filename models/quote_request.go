@@ -38,9 +38,15 @@ type QuoteRequestItem struct {
 	ProductID bson.ObjectID `bson:"productId" json:"productId"`
 	Quantity  int           `bson:"quantity" json:"quantity"`
 
-	ProductName string  `bson:"productName,omitempty" json:"productName,omitempty"`
-	ProductSlug string  `bson:"productSlug,omitempty" json:"productSlug,omitempty"`
-	UnitPrice   float64 `bson:"unitPrice,omitempty" json:"unitPrice,omitempty"`
+	ProductName string `bson:"productName,omitempty" json:"productName,omitempty"`
+	ProductSlug string `bson:"productSlug,omitempty" json:"productSlug,omitempty"`
+	// UnitPrice is in minor units of the quote currency.
+	UnitPrice int64 `bson:"unitPrice,omitempty" json:"unitPrice,omitempty"`
+
+	// The variant fields snapshot the variant quoted, if any.
+	VariantID    *bson.ObjectID `bson:"variantId,omitempty" json:"variantId,omitempty"`
+	VariantSKU   string         `bson:"variantSku,omitempty" json:"variantSku,omitempty"`
+	VariantLabel string         `bson:"variantLabel,omitempty" json:"variantLabel,omitempty"`
 }
 
 type QuoteRequest struct {
@@ -57,6 +63,11 @@ type QuoteRequest struct {
 	Message string             `bson:"message,omitempty" json:"message,omitempty"`
 	Items   []QuoteRequestItem `bson:"items" json:"items"`
 
+	// Currency is the one the customer asked for; ExchangeRate snapshots
+	// its rate to XOF when the quote was made, unset for XOF itself.
+	Currency     string  `bson:"currency,omitempty" json:"currency"`
+	ExchangeRate float64 `bson:"exchangeRate,omitempty" json:"exchangeRate,omitempty"`
+
 	Status   QuoteRequestStatus `bson:"status" json:"status"`
 	QuotedAt *time.Time         `bson:"quotedAt,omitempty" json:"quotedAt,omitempty"`
 
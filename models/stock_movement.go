@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type StockMovementType string
+
+const (
+	// StockMovementInitial records the quantity a product or variant was
+	// created with.
+	StockMovementInitial StockMovementType = "INITIAL"
+	// StockMovementReceipt adds goods received from a supplier or workshop.
+	StockMovementReceipt StockMovementType = "RECEIPT"
+	// StockMovementReservation sets units aside for a customer.
+	StockMovementReservation StockMovementType = "RESERVATION"
+	// StockMovementRelease puts reserved units back on sale.
+	StockMovementRelease StockMovementType = "RELEASE"
+	// StockMovementAdjustment corrects the count (inventory, breakage...).
+	StockMovementAdjustment StockMovementType = "ADJUSTMENT"
+)
+
+// StockMovement is one entry of the inventory ledger. The product (or
+// variant) quantity is the sum of its movements; QuantityAfter records it
+// once the movement was applied.
+type StockMovement struct {
+	ID        bson.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ProductID bson.ObjectID     `bson:"productId" json:"productId"`
+	VariantID *bson.ObjectID    `bson:"variantId,omitempty" json:"variantId,omitempty"`
+	Type      StockMovementType `bson:"type" json:"type"`
+	// Delta is signed: negative for reservations and downward adjustments.
+	Delta         int    `bson:"delta" json:"delta"`
+	QuantityAfter int    `bson:"quantityAfter" json:"quantityAfter"`
+	Reason        string `bson:"reason,omitempty" json:"reason,omitempty"`
+
+	AuthorID    bson.ObjectID `bson:"authorId" json:"authorId"`
+	AuthorEmail string        `bson:"authorEmail" json:"authorEmail"`
+	CreatedAt   time.Time     `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import "cmp"
+
+// ProductTranslation is the content of a product in a language other than
+// the catalog's default one. An empty field falls back to the product's.
+type ProductTranslation struct {
+	Name            string `bson:"name,omitempty" json:"name,omitempty"`
+	Description     string `bson:"description,omitempty" json:"description,omitempty"`
+	DescriptionFull string `bson:"descriptionFull,omitempty" json:"descriptionFull,omitempty"`
+}
+
+// CategoryTranslation is the content of a category in a language other than
+// the catalog's default one. An empty field falls back to the category's.
+type CategoryTranslation struct {
+	Name        string `bson:"name,omitempty" json:"name,omitempty"`
+	Description string `bson:"description,omitempty" json:"description,omitempty"`
+}
+
+// ProductTranslatedFields and CategoryTranslatedFields are the bson (and
+// JSON) names of the fields a translation covers.
+var (
+	ProductTranslatedFields  = []string{"name", "description", "descriptionFull"}
+	CategoryTranslatedFields = []string{"name", "description"}
+)
+
+// Localized returns the product with its content in locale and without its
+// translations. Fields that have no translation stay in the default locale.
+func (p Product) Localized(locale string) Product {
+	t := p.Translations[locale]
+	p.Name = cmp.Or(t.Name, p.Name)
+	p.Description = cmp.Or(t.Description, p.Description)
+	p.DescriptionFull = cmp.Or(t.DescriptionFull, p.DescriptionFull)
+	p.Translations = nil
+	return p
+}
+
+// MissingTranslations lists the fields of p that are set but have no
+// translation in locale.
+func (p *Product) MissingTranslations(locale string) []string {
+	t := p.Translations[locale]
+	return missingTranslations(ProductTranslatedFields,
+		[]string{p.Name, p.Description, p.DescriptionFull},
+		[]string{t.Name, t.Description, t.DescriptionFull})
+}
+
+// Localized returns the category with its content in locale and without
+// its translations.
+func (c Category) Localized(locale string) Category {
+	t := c.Translations[locale]
+	c.Name = cmp.Or(t.Name, c.Name)
+	c.Description = cmp.Or(t.Description, c.Description)
+	c.Translations = nil
+	return c
+}
+
+// MissingTranslations lists the fields of c that are set but have no
+// translation in locale.
+func (c *Category) MissingTranslations(locale string) []string {
+	t := c.Translations[locale]
+	return missingTranslations(CategoryTranslatedFields,
+		[]string{c.Name, c.Description},
+		[]string{t.Name, t.Description})
+}
+
+func missingTranslations(fields, values, translated []string) []string {
+	var missing []string
+	for i, field := range fields {
+		if values[i] != "" && translated[i] == "" {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
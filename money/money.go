@@ -0,0 +1,108 @@
+// Package money converts amounts between the base currency the catalog is
+// priced in (XOF) and the currencies customers may ask for. Amounts are
+// integers in the minor unit of their currency (francs, cents); conversions
+// are computed exactly and rounded half away from zero.
+package money
+
+import (
+	"errors"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Base is the currency prices are stored in. An exchange rate says how many
+// XOF one unit of a currency is worth: 655.957 for EUR.
+const Base = "XOF"
+
+// ErrCurrency is returned for a code missing from Currencies.
+var ErrCurrency = errors.New("unsupported currency")
+
+// Currencies are the supported ISO 4217 codes, with the number of digits of
+// their minor unit.
+var Currencies = map[string]int{
+	"XOF": 0,
+	"XAF": 0,
+	"EUR": 2,
+	"USD": 2,
+	"GBP": 2,
+	"CHF": 2,
+	"CAD": 2,
+	"GHS": 2,
+	"NGN": 2,
+}
+
+// Codes lists the supported currencies in alphabetical order.
+func Codes() []string {
+	codes := make([]string, 0, len(Currencies))
+	for code := range Currencies {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// Normalize uppercases a currency code and checks that it is supported.
+func Normalize(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if _, ok := Currencies[code]; !ok {
+		return "", ErrCurrency
+	}
+	return code, nil
+}
+
+// FromBase converts an amount of XOF to the minor units of currency, worth
+// rate XOF per unit.
+func FromBase(amount int64, currency string, rate float64) (int64, error) {
+	digits, r, err := conversion(currency, rate)
+	if err != nil {
+		return 0, err
+	}
+	v := new(big.Rat).SetInt64(amount)
+	v.Mul(v, pow10(digits))
+	v.Quo(v, r)
+	return round(v), nil
+}
+
+// ToBase converts minor units of currency, worth rate XOF per unit, to XOF.
+func ToBase(amount int64, currency string, rate float64) (int64, error) {
+	digits, r, err := conversion(currency, rate)
+	if err != nil {
+		return 0, err
+	}
+	v := new(big.Rat).SetInt64(amount)
+	v.Mul(v, r)
+	v.Quo(v, pow10(digits))
+	return round(v), nil
+}
+
+func conversion(currency string, rate float64) (int, *big.Rat, error) {
+	digits, ok := Currencies[currency]
+	if !ok {
+		return 0, nil, ErrCurrency
+	}
+	// the decimal rate as typed, not its binary approximation
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	if !ok || r.Sign() <= 0 {
+		return 0, nil, errors.New("exchange rate must be positive")
+	}
+	return digits, r, nil
+}
+
+func pow10(n int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil))
+}
+
+// round rounds v half away from zero.
+func round(v *big.Rat) int64 {
+	num, den := new(big.Int).Abs(v.Num()), v.Denom()
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Lsh(r, 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if v.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
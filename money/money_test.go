@@ -0,0 +1,46 @@
+package money
+
+import "testing"
+
+func TestConversionsRoundHalfAwayFromZero(t *testing.T) {
+	cases := []struct {
+		name     string
+		convert  func(int64, string, float64) (int64, error)
+		amount   int64
+		currency string
+		rate     float64
+		want     int64
+	}{
+		{"xof to eur", FromBase, 150000, "EUR", 655.957, 22867},
+		{"xof to eur, small", FromBase, 10000, "EUR", 655.957, 1524},
+		{"half a cent", FromBase, 1, "USD", 200, 1},
+		{"negative half", FromBase, -1, "USD", 200, -1},
+		{"zero digits", FromBase, 100, "XAF", 1, 100},
+		{"eur to xof", ToBase, 2287, "EUR", 655.957, 15002},
+		{"exact rate", ToBase, 100, "EUR", 655.957, 656},
+	}
+	for _, tc := range cases {
+		got, err := tc.convert(tc.amount, tc.currency, tc.rate)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %d, %v; want %d", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func TestConversionRejectsBadInput(t *testing.T) {
+	if _, err := FromBase(100, "BTC", 1); err != ErrCurrency {
+		t.Fatalf("unknown currency: %v", err)
+	}
+	if _, err := FromBase(100, "EUR", 0); err == nil {
+		t.Fatal("zero rate accepted")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if code, err := Normalize(" eur "); err != nil || code != "EUR" {
+		t.Fatalf("Normalize = %q, %v", code, err)
+	}
+	if _, err := Normalize("euro"); err != ErrCurrency {
+		t.Fatalf("Normalize(euro) = %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+// Package openapi builds the OpenAPI 3 description of the API served at
+// GET /openapi.json. Schemas are generated by reflection from the request
+// DTOs (package dto) and the response models (package models); the
+// operations are listed in routes.go, next to nothing else, so a new route
+// is documented in one place.
+package openapi
+
+import (
+	"maps"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps a lower-case HTTP method to its operation.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema   *Schema              `json:"schema"`
+	Encoding map[string]*Encoding `json:"encoding,omitempty"`
+}
+
+type Encoding struct {
+	ContentType string `json:"contentType"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// bearerAuth names the security scheme of the /admin routes.
+const bearerAuth = "bearerAuth"
+
+var (
+	specOnce sync.Once
+	spec     *Document
+)
+
+// Spec returns the document, built on first use.
+func Spec() *Document {
+	specOnce.Do(func() { spec = Build(Routes) })
+	return spec
+}
+
+// Build assembles the document for routes.
+func Build(routes []Route) *Document {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:   "SAHO API",
+			Version: "1.0.0",
+			Description: "API de la boutique vitrine SAHO : catalogue, demandes de devis et de produits sur mesure, administration. " +
+				"Les messages d'erreur suivent l'en-tête Accept-Language (fr par défaut, en).",
+		},
+		Tags:  tags,
+		Paths: map[string]PathItem{},
+	}
+	for _, r := range routes {
+		path := Path(r.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = PathItem{}
+		}
+		doc.Paths[path][strings.ToLower(r.Method)] = r.operation(g)
+	}
+	doc.Components = Components{
+		Schemas: g.schemas,
+		SecuritySchemes: map[string]*SecurityScheme{
+			bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		},
+	}
+	return doc
+}
+
+// Path converts gin's /products/:id and /media/*filepath to the OpenAPI
+// /products/{id} and /media/{filepath}.
+func Path(ginPath string) string {
+	parts := strings.Split(ginPath, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Has reports whether the document describes method on the gin path.
+func (d *Document) Has(method, ginPath string) bool {
+	item, ok := d.Paths[Path(ginPath)]
+	return ok && item[strings.ToLower(method)] != nil
+}
+
+// ---- schema generation ---------------------------------------------------------
+
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func newGenerator() *generator {
+	return &generator{schemas: map[string]*Schema{}}
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(bson.ObjectID{})
+)
+
+// schemaOf returns the schema of t; named structs become components and are
+// referenced.
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		s := g.schemaOf(t.Elem())
+		if s.Ref != "" {
+			return s // OpenAPI 3.0 ignores siblings of $ref
+		}
+		s.Nullable = true
+		return s
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case objectIDType:
+		return &Schema{Type: "string", Pattern: "^[0-9a-f]{24}$"}
+	}
+	if values, ok := enums[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := componentName(t)
+		if _, ok := g.schemas[name]; !ok {
+			g.schemas[name] = &Schema{} // placeholder breaks recursion
+			g.schemas[name] = g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+// componentName is the type name, with generic instances spelled after their
+// argument: Page[…/models.Product] becomes ProductPage.
+func componentName(t reflect.Type) string {
+	name := t.Name()
+	base, arg, ok := strings.Cut(name, "[")
+	if !ok {
+		return name
+	}
+	arg = strings.TrimSuffix(arg, "]")
+	return arg[strings.LastIndex(arg, ".")+1:] + base
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		// embedded structs are flattened, as encoding/json does
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded := g.structSchema(f.Type)
+			maps.Copy(s.Properties, embedded.Properties)
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := g.schemaOf(f.Type)
+		if applyBinding(prop, f.Tag.Get("binding")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+	return s
+}
+
+// applyBinding maps the gin validator rules the DTOs use onto the schema and
+// reports whether the field is required.
+func applyBinding(s *Schema, binding string) (required bool) {
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		n, err := strconv.Atoi(value)
+		switch {
+		case key == "required":
+			required = true
+		case key == "email":
+			s.Format = "email"
+		case key == "min" && err == nil:
+			switch s.Type {
+			case "string":
+				s.MinLength = &n
+			case "array":
+				s.MinItems = &n
+			default:
+				f := float64(n)
+				s.Minimum = &f
+			}
+		case key == "max" && err == nil && s.Type == "string":
+			s.MaxLength = &n
+		case (key == "gt" || key == "gte") && err == nil:
+			f := float64(n)
+			s.Minimum = &f
+			s.ExclusiveMinimum = key == "gt"
+		}
+	}
+	return required
+}
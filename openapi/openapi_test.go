@@ -0,0 +1,97 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestPath(t *testing.T) {
+	cases := map[string]string{
+		"/products":                       "/products",
+		"/admin/quote-requests/:id/notes": "/admin/quote-requests/{id}/notes",
+		"/media/*filepath":                "/media/{filepath}",
+	}
+	for in, want := range cases {
+		if got := Path(in); got != want {
+			t.Errorf("Path(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSchemaFromBindingTags(t *testing.T) {
+	g := newGenerator()
+	ref := g.schemaOf(typeOf[dto.CreateQuoteRequestDTO]())
+	if ref.Ref != "#/components/schemas/CreateQuoteRequestDTO" {
+		t.Fatalf("ref = %q", ref.Ref)
+	}
+	s := g.schemas["CreateQuoteRequestDTO"]
+	if got, _ := json.Marshal(s.Required); string(got) != `["fullName","email","items"]` {
+		t.Fatalf("required = %s", got)
+	}
+	if s.Properties["email"].Format != "email" {
+		t.Fatalf("email = %+v", s.Properties["email"])
+	}
+	if items := s.Properties["items"]; items.Type != "array" || items.MinItems == nil || *items.MinItems != 1 {
+		t.Fatalf("items = %+v", items)
+	}
+	item := g.schemas["QuoteRequestItemDTO"]
+	if q := item.Properties["quantity"]; q.Type != "integer" || q.Minimum == nil || *q.Minimum != 1 {
+		t.Fatalf("quantity = %+v", q)
+	}
+}
+
+func TestSchemaOfSpecialTypes(t *testing.T) {
+	g := newGenerator()
+	if s := g.schemaOf(typeOf[time.Time]()); s.Format != "date-time" {
+		t.Fatalf("time = %+v", s)
+	}
+	if s := g.schemaOf(typeOf[bson.ObjectID]()); s.Type != "string" || s.Pattern == "" {
+		t.Fatalf("object id = %+v", s)
+	}
+	if s := g.schemaOf(typeOf[*time.Time]()); !s.Nullable {
+		t.Fatalf("pointer = %+v", s)
+	}
+	if s := g.schemaOf(typeOf[models.QuoteRequestStatus]()); len(s.Enum) != 5 {
+		t.Fatalf("status enum = %+v", s)
+	}
+	g.schemaOf(typeOf[Page[models.Category]]())
+	if _, ok := g.schemas["CategoryPage"]; !ok {
+		t.Fatalf("generic component names = %v", keys(g.schemas))
+	}
+	g.schemaOf(typeOf[dto.ProductDetailDTO]())
+	if s := g.schemas["ProductDetailDTO"]; s.Properties["slug"] == nil || s.Properties["similarProducts"] == nil || s.Properties["Product"] != nil {
+		t.Fatalf("embedded struct not flattened: %v", keys(s.Properties))
+	}
+}
+
+func TestSpecOperationsAreUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, r := range Routes {
+		if seen[r.ID] {
+			t.Errorf("duplicate operationId %q", r.ID)
+		}
+		seen[r.ID] = true
+		if r.Tag == "" || r.Summary == "" {
+			t.Errorf("%s %s: tag and summary are required", r.Method, r.Path)
+		}
+	}
+	if _, err := json.Marshal(Spec()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func typeOf[T any]() reflect.Type { return reflect.TypeOf((*T)(nil)).Elem() }
+
+func keys(m map[string]*Schema) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
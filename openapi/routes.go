@@ -0,0 +1,574 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/health"
+	"github.com/princinho/sahobackend/i18n"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/money"
+	"github.com/princinho/sahobackend/repositories"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Route documents one operation registered in newRouter. Every route of the
+// router must appear here; the HTTP test suite fails otherwise.
+type Route struct {
+	Method, Path string // gin syntax: /products/:id
+	ID           string // operationId, the handler name
+	Tag          string
+	Summary      string
+	Description  string
+	// Admin routes require the bearer access token.
+	Admin bool
+	Query []Parameter
+	// Body is a zero value of the JSON request body.
+	Body any
+	// Multipart describes a multipart/form-data request body.
+	Multipart *Multipart
+	// Status is the success status, 200 when zero.
+	Status int
+	// Response is a zero value of the JSON success body, or a *Schema.
+	Response any
+	// ContentType of a non-JSON success body (text/html, text/plain…).
+	ContentType string
+	// Errors lists the documented error statuses.
+	Errors []int
+}
+
+// Multipart is a form whose "data" field carries the JSON DTO, next to file
+// fields.
+type Multipart struct {
+	Data         any
+	DataRequired bool
+	Files        []File
+}
+
+type File struct {
+	Name     string
+	Multiple bool
+	Required bool
+}
+
+// ---- documentation-only response shapes ----------------------------------------
+
+type OK struct {
+	OK bool `json:"ok"`
+}
+
+type Created struct {
+	ID bson.ObjectID `json:"id"`
+}
+
+type Submitted struct {
+	ID      bson.ObjectID `json:"id"`
+	Message string        `json:"message"`
+}
+
+type LoginResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+type RefreshResponse struct {
+	AccessToken string `json:"accessToken"`
+}
+
+type Page[T any] struct {
+	Items []T `json:"items"`
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+	Total int `json:"total"`
+}
+
+// ProductPage is GET /products; items carry score and highlights only when
+// ?q= is set.
+type ProductPage struct {
+	Items    []dto.ProductSearchHitDTO `json:"items"`
+	Facets   dto.ProductFacetsDTO      `json:"facets"`
+	Page     int                       `json:"page"`
+	Limit    int                       `json:"limit"`
+	Total    int                       `json:"total"`
+	Currency string                    `json:"currency"`
+	Q        string                    `json:"q"`
+	Category string                    `json:"category"`
+	Sort     string                    `json:"sort"`
+	TS       string                    `json:"ts"`
+}
+
+// LowStockPage is GET /admin/products/low-stock.
+type LowStockPage struct {
+	Items     []dto.LowStockProductDTO `json:"items"`
+	Threshold int                      `json:"threshold"`
+	Page      int                      `json:"page"`
+	Limit     int                      `json:"limit"`
+	Total     int                      `json:"total"`
+}
+
+// ExchangeRates is GET /exchange-rates.
+type ExchangeRates struct {
+	Base  string                `json:"base"`
+	Items []models.ExchangeRate `json:"items"`
+}
+
+type UserResponse struct {
+	ID        bson.ObjectID `json:"id"`
+	Email     string        `json:"email"`
+	Role      models.Role   `json:"role"`
+	IsActive  bool          `json:"isActive"`
+	CreatedAt string        `json:"createdAt"`
+	UpdatedAt string        `json:"updatedAt"`
+}
+
+type Pong struct {
+	Message string `json:"message"`
+}
+
+type Liveness struct {
+	Status string `json:"status"`
+}
+
+// enums lists the values of the string types used as enumerations.
+var enums = map[reflect.Type][]any{
+	reflect.TypeOf(models.QuoteRequestStatus("")): {
+		models.QuoteStatusNew, models.QuoteStatusInProgress, models.QuoteStatusQuoted,
+		models.QuoteStatusRejected, models.QuoteStatusClosed,
+	},
+	reflect.TypeOf(models.ProductRequestStatus("")): {
+		models.ProductRequestStatusNew, models.ProductRequestStatusInProgress, models.ProductRequestStatusAnswered,
+		models.ProductRequestStatusRejected, models.ProductRequestStatusClosed,
+	},
+	reflect.TypeOf(models.StockMovementType("")): {
+		models.StockMovementInitial, models.StockMovementReceipt, models.StockMovementReservation,
+		models.StockMovementRelease, models.StockMovementAdjustment,
+	},
+	reflect.TypeOf(models.ProductStatus("")): {
+		models.ProductStatusDraft, models.ProductStatusScheduled, models.ProductStatusPublished, models.ProductStatusArchived,
+	},
+	reflect.TypeOf(models.ProductRevisionAction("")): {
+		models.ProductRevisionCreate, models.ProductRevisionUpdate, models.ProductRevisionRollback,
+	},
+	reflect.TypeOf(models.Role("")): {models.RoleAdmin},
+	reflect.TypeOf(apierror.Code("")): {
+		apierror.CodeInvalidRequest, apierror.CodeValidationFailed, apierror.CodeInvalidID, apierror.CodeInvalidUpload,
+		apierror.CodeNotFound, apierror.CodeRouteNotFound, apierror.CodeConflict, apierror.CodeInsufficientStock, apierror.CodeUnauthorized,
+		apierror.CodeInvalidCredentials, apierror.CodeForbidden, apierror.CodeAccountDisabled, apierror.CodeInternal,
+	},
+}
+
+var tags = []Tag{
+	{Name: "Auth", Description: "Connexion des administrateurs"},
+	{Name: "Produits"},
+	{Name: "Catégories"},
+	{Name: "Stock", Description: "Mouvements de stock et alertes de stock bas"},
+	{Name: "Devises", Description: "Taux de change vers le franc CFA (XOF), la devise des prix enregistrés"},
+	{Name: "Traductions", Description: "Contenu du catalogue dans les langues autres que CATALOG_DEFAULT_LOCALE"},
+	{Name: "Demandes de devis"},
+	{Name: "Demandes de produit sur mesure"},
+	{Name: "Utilisateurs"},
+	{Name: "Exploitation", Description: "Sondes, métriques, configuration et documentation"},
+}
+
+func pageParams(extra ...Parameter) []Parameter {
+	return append([]Parameter{
+		{Name: "page", In: "query", Description: "Page, à partir de 1", Schema: &Schema{Type: "integer", Format: "int32"}},
+		{Name: "limit", In: "query", Description: "Taille de page (READ_QUERY_MAX_LIMIT au plus)", Schema: &Schema{Type: "integer", Format: "int32"}},
+	}, extra...)
+}
+
+func query(name, description string, s *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: s}
+}
+
+var (
+	stringSchema = &Schema{Type: "string"}
+	boolSchema   = &Schema{Type: "boolean"}
+	priceSchema  = &Schema{Type: "integer", Format: "int64", Description: "En unités mineures de `currency` (francs, centimes)"}
+	currency     = query("currency", "Devise des prix renvoyés (XOF par défaut)", &Schema{Type: "string", Enum: currencyCodes()})
+	lang         = query("lang", "Langue du contenu ; à défaut Accept-Language, puis CATALOG_DEFAULT_LOCALE", &Schema{Type: "string", Enum: localeCodes()})
+	productSort  = &Schema{Type: "string", Enum: []any{
+		repositories.ProductSortPriceAsc, repositories.ProductSortPriceDesc,
+		repositories.ProductSortStockAsc, repositories.ProductSortStockDesc,
+	}, Description: "Par nom si absent"}
+)
+
+func currencyCodes() []any {
+	var codes []any
+	for _, code := range money.Codes() {
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+func localeCodes() []any {
+	var codes []any
+	for _, locale := range i18n.Locales() {
+		codes = append(codes, locale)
+	}
+	return codes
+}
+
+// Routes is the documented API, in router order.
+var Routes = []Route{
+	// ---- operations
+	{Method: http.MethodGet, Path: "/ping", ID: "ping", Tag: "Exploitation", Summary: "Répond pong", Response: Pong{}},
+	{Method: http.MethodGet, Path: "/healthz", ID: "healthz", Tag: "Exploitation", Summary: "Liveness : le processus répond", Response: Liveness{}},
+	{Method: http.MethodGet, Path: "/readyz", ID: "readyz", Tag: "Exploitation", Summary: "Readiness : état de chaque dépendance",
+		Response: health.Report{}, Errors: []int{http.StatusServiceUnavailable}},
+	{Method: http.MethodGet, Path: "/metrics", ID: "metrics", Tag: "Exploitation", Summary: "Métriques Prometheus",
+		Description: "Protégée par `Authorization: Bearer <METRICS_TOKEN>` lorsque la variable est définie.",
+		ContentType: "text/plain", Errors: []int{http.StatusUnauthorized}},
+	{Method: http.MethodGet, Path: "/openapi.json", ID: "openapi", Tag: "Exploitation", Summary: "Ce document",
+		Response: &Schema{Type: "object"}},
+	{Method: http.MethodGet, Path: "/docs", ID: "docs", Tag: "Exploitation", Summary: "Swagger UI", ContentType: "text/html"},
+	{Method: http.MethodGet, Path: "/media/*filepath", ID: "getMedia", Tag: "Exploitation", Summary: "Fichier du stockage local",
+		Description: "Uniquement avec STORAGE_DRIVER=local.", ContentType: "application/octet-stream", Errors: []int{http.StatusNotFound}},
+	{Method: http.MethodHead, Path: "/media/*filepath", ID: "headMedia", Tag: "Exploitation", Summary: "En-têtes d'un fichier du stockage local",
+		Description: "Uniquement avec STORAGE_DRIVER=local.", ContentType: "application/octet-stream", Errors: []int{http.StatusNotFound}},
+
+	// ---- auth
+	{Method: http.MethodPost, Path: "/auth/login", ID: "login", Tag: "Auth", Summary: "Connexion",
+		Description: "Renvoie un access token et pose le cookie HttpOnly `refreshToken`.",
+		Body:        dto.LoginDTO{}, Response: LoginResponse{}, Errors: []int{http.StatusBadRequest, http.StatusUnauthorized}},
+	{Method: http.MethodPost, Path: "/auth/refresh", ID: "refresh", Tag: "Auth", Summary: "Renouvelle l'access token",
+		Description: "Lit le cookie `refreshToken`, le révoque et en pose un nouveau (rotation).",
+		Response:    RefreshResponse{}, Errors: []int{http.StatusUnauthorized, http.StatusForbidden}},
+	{Method: http.MethodPost, Path: "/auth/logout", ID: "logout", Tag: "Auth", Summary: "Déconnexion",
+		Description: "Révoque le refresh token du cookie et efface le cookie.", Response: OK{}},
+
+	// ---- catalogue
+	{Method: http.MethodGet, Path: "/products", ID: "getProducts", Tag: "Produits", Summary: "Liste des produits",
+		Description: "Avec `q`, les produits sont cherchés dans le nom, les matériaux, les couleurs et les descriptions, " +
+			"sans tenir compte de la casse ni des accents, et classés par pertinence (sauf `sort` explicite). " +
+			"Les filtres à valeurs multiples acceptent `a,b` ou un paramètre répété. `facets` compte les produits " +
+			"par catégorie, matériau, couleur et tranche de prix (PRICE_FACET_BOUNDS), chaque facette ignorant son propre filtre. " +
+			"Seuls les produits en vente sont listés : publiés, ou programmés dont `publishAt` est passé, et avant leur `unpublishAt`. " +
+			"Les prix, les filtres de prix et les tranches sont dans `currency`, convertis au taux de `GET /exchange-rates` et arrondis à l'unité mineure. " +
+			"Les noms et descriptions sont traduits dans `lang` (en-tête `Content-Language`), la recherche porte sur la langue par défaut.",
+		Query: pageParams(
+			query("q", "Recherche plein texte (100 caractères max)", stringSchema),
+			query("highlight", "Extraits surlignés (`<mark>`) par champ, avec `q`", boolSchema),
+			query("category", "Slugs de catégorie", stringSchema),
+			query("materials", "Matériaux", stringSchema),
+			query("colors", "Couleurs", stringSchema),
+			query("minPrice", "Prix minimum (inclus)", priceSchema),
+			query("maxPrice", "Prix maximum (inclus)", priceSchema),
+			currency,
+			lang,
+			query("sort", "Tri", productSort),
+			query("isTrending", "Produits mis en avant", boolSchema),
+			query("isDisabled", "Produits désactivés", boolSchema),
+		),
+		Response: ProductPage{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodGet, Path: "/products/:id", ID: "getProduct", Tag: "Produits", Summary: "Produit par identifiant",
+		Description: "Inclut les catégories actives et les produits similaires visibles. Un produit désactivé ou hors vente répond 404.",
+		Query:       []Parameter{currency, lang},
+		Response:    dto.ProductDetailDTO{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodGet, Path: "/products/slug/:slug", ID: "getProductBySlug", Tag: "Produits", Summary: "Produit par slug",
+		Description: "Inclut les catégories actives et les produits similaires visibles. Un produit désactivé ou hors vente répond 404.",
+		Query:       []Parameter{currency, lang},
+		Response:    dto.ProductDetailDTO{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodGet, Path: "/categories", ID: "getCategories", Tag: "Catégories", Summary: "Liste des catégories",
+		Description: "Noms et descriptions traduits dans `lang`.",
+		Query:       pageParams(query("q", "Recherche sur le nom", stringSchema), query("isActive", "Filtre sur l'état", boolSchema), lang),
+		Response:    Page[models.Category]{}},
+	{Method: http.MethodGet, Path: "/categories/:id", ID: "getCategory", Tag: "Catégories", Summary: "Catégorie par identifiant",
+		Query: []Parameter{lang}, Response: models.Category{}, Errors: []int{http.StatusNotFound}},
+	{Method: http.MethodGet, Path: "/categories/slug/:slug", ID: "getCategoryBySlug", Tag: "Catégories", Summary: "Catégorie par slug",
+		Query: []Parameter{lang}, Response: models.Category{}, Errors: []int{http.StatusNotFound}},
+	{Method: http.MethodGet, Path: "/exchange-rates", ID: "getExchangeRates", Tag: "Devises", Summary: "Taux de change",
+		Description: "Ce que vaut une unité de chaque devise en XOF. Une devise sans taux ne peut pas être demandée.",
+		Response:    ExchangeRates{}},
+
+	// ---- public forms
+	{Method: http.MethodPost, Path: "/quote-requests", ID: "createQuoteRequest", Tag: "Demandes de devis", Summary: "Demande de devis",
+		Description: "Le nom, le slug et le prix de chaque produit sont figés dans la demande, ainsi que le SKU et le libellé de la variante. " +
+			"`variantId` est obligatoire pour un produit vendu en variantes. " +
+			"Les prix sont convertis dans `currency` (XOF par défaut) et la demande garde le taux de change appliqué.",
+		Body: dto.CreateQuoteRequestDTO{}, Status: http.StatusCreated, Response: Submitted{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/product-requests", ID: "createProductRequest", Tag: "Demandes de produit sur mesure", Summary: "Demande de produit sur mesure",
+		Multipart: &Multipart{Data: dto.CreateProductRequestDTO{}, DataRequired: true, Files: []File{{Name: "image"}}},
+		Status:    http.StatusCreated, Response: Submitted{}, Errors: []int{http.StatusBadRequest}},
+
+	// ---- admin: products
+	{Method: http.MethodGet, Path: "/admin/products", ID: "getAdminProducts", Tag: "Produits", Summary: "Liste de tous les produits", Admin: true,
+		Description: "Comme `GET /products`, avec les brouillons, les produits programmés et les archives.",
+		Query: pageParams(
+			query("status", "Statut du cycle de vie (un produit sans statut est publié)", &Schema{Type: "string", Enum: enums[reflect.TypeOf(models.ProductStatus(""))]}),
+			query("q", "Recherche plein texte (100 caractères max)", stringSchema),
+			query("category", "Slugs de catégorie", stringSchema),
+			query("materials", "Matériaux", stringSchema),
+			query("colors", "Couleurs", stringSchema),
+			query("minPrice", "Prix minimum (inclus)", priceSchema),
+			query("maxPrice", "Prix maximum (inclus)", priceSchema),
+			currency,
+			query("sort", "Tri", productSort),
+			query("isTrending", "Produits mis en avant", boolSchema),
+			query("isDisabled", "Produits désactivés", boolSchema),
+		),
+		Response: ProductPage{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/admin/products/add", ID: "addProduct", Tag: "Produits", Summary: "Crée un produit", Admin: true,
+		Description: "Sans `status`, le produit est publié, ou programmé si `publishAt` est à venir. " +
+			"`SCHEDULED` exige `publishAt` ; `unpublishAt` doit suivre `publishAt`. " +
+			"Les images (PNG, JPEG ou WebP) sont redressées, débarrassées de leurs métadonnées et réencodées en WebP : " +
+			"`url` (1600 px), `card` (600 px) et `thumbnail` (200 px).",
+		Multipart: &Multipart{Data: dto.CreateProductDTO{}, DataRequired: true, Files: []File{{Name: "images", Multiple: true, Required: true}}},
+		Status:    http.StatusCreated, Response: models.Product{}, Errors: []int{http.StatusBadRequest, http.StatusConflict}},
+	{Method: http.MethodPatch, Path: "/admin/products/update/:id", ID: "updateProduct", Tag: "Produits", Summary: "Modifie un produit", Admin: true,
+		Description: "Les images dont l'`url` est listée dans `removedImagesUrls` sont supprimées du stockage, avec toutes leurs tailles, après la mise à jour. " +
+			"Un changement de `quantity` est enregistré comme un mouvement `ADJUSTMENT`.",
+		Multipart: &Multipart{Data: dto.UpdateProductDTO{}, DataRequired: true, Files: []File{{Name: "images", Multiple: true}}},
+		Response:  OK{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+	{Method: http.MethodDelete, Path: "/admin/products/:id", ID: "deleteProduct", Tag: "Produits", Summary: "Met un produit à la corbeille", Admin: true,
+		Description: "Avec `permanent=true`, le produit est supprimé définitivement avec ses images et retiré des produits similaires des autres produits.",
+		Query:       []Parameter{query("permanent", "Suppression définitive", boolSchema)},
+		Response:    OK{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodGet, Path: "/admin/products/trash", ID: "getTrashedProducts", Tag: "Produits", Summary: "Produits à la corbeille", Admin: true,
+		Query:    pageParams(query("sort", "Tri", productSort)),
+		Response: Page[models.Product]{}},
+	{Method: http.MethodPost, Path: "/admin/products/:id/restore", ID: "restoreProduct", Tag: "Produits", Summary: "Restaure un produit de la corbeille", Admin: true,
+		Response: OK{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodPatch, Path: "/admin/products/:id/status", ID: "updateProductStatus", Tag: "Produits", Summary: "Change le statut d'un produit", Admin: true,
+		Description: "Remplace le statut et les deux dates ; une date absente est effacée. Toutes les PUBLISH_SCHEDULE_INTERVAL, " +
+			"les produits programmés dont `publishAt` est passé sont publiés et les produits publiés dont `unpublishAt` est passé sont archivés.",
+		Body: dto.UpdateProductStatusDTO{}, Response: models.Product{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodPatch, Path: "/admin/products/:id/images", ID: "updateProductImages", Tag: "Produits", Summary: "Organise la galerie d'un produit", Admin: true,
+		Description: "Liste toutes les images du produit, désignées par `url`, dans leur nouvel ordre. " +
+			"Fixe l'image principale (`isPrimary`, une seule) et remplace le texte alternatif par langue des images où `alt` est donné, sans renvoyer les fichiers. " +
+			"Sans image marquée principale, l'image principale ne change pas.",
+		Body: dto.UpdateProductImagesDTO{}, Response: models.Product{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodGet, Path: "/admin/products/:id/revisions", ID: "getProductRevisions", Tag: "Produits", Summary: "Historique d'un produit", Admin: true,
+		Description: "Une révision par création ou modification du produit, de la plus récente à la plus ancienne, avec son auteur, " +
+			"les champs modifiés et l'état complet du produit après la modification (hors stock, suivi par les mouvements de stock).",
+		Query:    pageParams(),
+		Response: Page[models.ProductRevision]{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodGet, Path: "/admin/products/:id/revisions/diff", ID: "getProductRevisionDiff", Tag: "Produits", Summary: "Compare deux révisions", Admin: true,
+		Description: "Les champs qui diffèrent entre les révisions `from` et `to`, avec leur valeur dans chacune.",
+		Query: []Parameter{
+			query("from", "Version de départ", &Schema{Type: "integer", Format: "int32"}),
+			query("to", "Version d'arrivée", &Schema{Type: "integer", Format: "int32"}),
+		},
+		Response: dto.ProductRevisionDiffDTO{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodPost, Path: "/admin/products/:id/revisions/:version/rollback", ID: "rollbackProduct", Tag: "Produits", Summary: "Revient à une révision", Admin: true,
+		Description: "Restaure les champs du produit tels qu'à cette révision, sauf les images, les variantes et le stock, " +
+			"et enregistre le résultat comme une nouvelle révision `ROLLBACK`.",
+		Response: models.Product{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+	{Method: http.MethodGet, Path: "/admin/products/export", ID: "exportProducts", Tag: "Produits", Summary: "Exporte le catalogue", Admin: true,
+		Description: "Un produit par ligne (hors corbeille et variantes), dans les colonnes lues par l'import. " +
+			"Avec `format=xlsx`, le fichier est un classeur Excel.",
+		Query:       []Parameter{query("format", "Format du fichier", &Schema{Type: "string", Enum: []any{"csv", "xlsx"}})},
+		ContentType: "text/csv", Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/admin/products/import", ID: "importProducts", Tag: "Produits", Summary: "Importe un catalogue CSV ou XLSX", Admin: true,
+		Description: "Chaque ligne est validée comme une création de produit puis créée ou mise à jour selon son slug. " +
+			"Les images sont cherchées dans `archive` (zip), puis dans `IMPORT_IMAGES_DIR`. " +
+			"Les lignes en erreur sont ignorées et détaillées dans le rapport ; avec `dryRun=true`, rien n'est enregistré.",
+		Query:     []Parameter{query("dryRun", "Valide sans rien enregistrer", boolSchema)},
+		Multipart: &Multipart{Files: []File{{Name: "file", Required: true}, {Name: "archive"}}},
+		Response:  dto.ProductImportReportDTO{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodPost, Path: "/admin/products/:id/variants", ID: "addProductVariant", Tag: "Produits", Summary: "Ajoute une variante", Admin: true,
+		Description: "Le SKU est unique sur tout le catalogue ; les options distinguent la variante des autres variantes du produit. " +
+			"Sans `price`, la variante est au prix du produit ; `imageUrls` choisit parmi les images du produit. " +
+			"La première variante reprend le stock du produit par deux mouvements `ADJUSTMENT`.",
+		Body:   dto.CreateVariantDTO{},
+		Status: http.StatusCreated, Response: models.ProductVariant{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+	{Method: http.MethodPatch, Path: "/admin/products/:id/variants/:variantId", ID: "updateProductVariant", Tag: "Produits", Summary: "Modifie une variante", Admin: true,
+		Description: "Seuls les champs fournis changent ; `price: 0` rend à la variante le prix du produit.",
+		Body:        dto.UpdateVariantDTO{},
+		Response:    OK{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+	{Method: http.MethodDelete, Path: "/admin/products/:id/variants/:variantId", ID: "deleteProductVariant", Tag: "Produits", Summary: "Supprime une variante", Admin: true,
+		Description: "Le stock restant de la variante sort du journal par un mouvement `ADJUSTMENT`.",
+		Response:    OK{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// ---- admin: stock
+	{Method: http.MethodPost, Path: "/admin/products/:id/stock-movements", ID: "addStockMovement", Tag: "Stock", Summary: "Enregistre un mouvement de stock", Admin: true,
+		Description: "`RECEIPT` et `RELEASE` ajoutent `quantity` unités, `RESERVATION` les retire, `ADJUSTMENT` applique `quantity` signé. " +
+			"`variantId` est obligatoire pour un produit à variantes. Un stock qui deviendrait négatif est refusé (`insufficient_stock`).",
+		Body:   dto.CreateStockMovementDTO{},
+		Status: http.StatusCreated, Response: models.StockMovement{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+	{Method: http.MethodGet, Path: "/admin/stock-movements", ID: "getStockMovements", Tag: "Stock", Summary: "Historique des mouvements de stock", Admin: true,
+		Description: "Du plus récent au plus ancien.",
+		Query: pageParams(
+			query("productId", "Produit", stringSchema),
+			query("variantId", "Variante", stringSchema),
+			query("type", "Type de mouvement", &Schema{Type: "string", Enum: enums[reflect.TypeOf(models.StockMovementType(""))]}),
+		),
+		Response: Page[models.StockMovement]{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodGet, Path: "/admin/products/low-stock", ID: "getLowStockProducts", Tag: "Stock", Summary: "Produits en stock bas", Admin: true,
+		Description: "Produits dont le stock (ou celui d'une variante) est au plus `threshold`, du plus bas au plus haut.",
+		Query:       pageParams(query("threshold", "Seuil (LOW_STOCK_THRESHOLD par défaut)", &Schema{Type: "integer", Format: "int32"})),
+		Response:    LowStockPage{}, Errors: []int{http.StatusBadRequest}},
+
+	// ---- admin: categories
+	{Method: http.MethodGet, Path: "/admin/categories", ID: "getAdminCategories", Tag: "Catégories", Summary: "Liste des catégories avec leurs traductions", Admin: true,
+		Description: "Comme `GET /categories`, sans traduire : les champs sont dans CATALOG_DEFAULT_LOCALE et `translations` donne les autres langues.",
+		Query:       pageParams(query("q", "Recherche sur le nom", stringSchema), query("isActive", "Filtre sur l'état", boolSchema)),
+		Response:    Page[models.Category]{}},
+	{Method: http.MethodPost, Path: "/admin/categories", ID: "addCategory", Tag: "Catégories", Summary: "Crée une catégorie", Admin: true,
+		Description: "L'image est réencodée en WebP aux mêmes tailles que celles des produits.",
+		Multipart:   &Multipart{Data: dto.CreateCategoryDTO{}, DataRequired: true, Files: []File{{Name: "image"}}},
+		Status:      http.StatusCreated, Response: Created{}, Errors: []int{http.StatusBadRequest, http.StatusConflict}},
+	{Method: http.MethodPatch, Path: "/admin/categories/:id", ID: "updateCategory", Tag: "Catégories", Summary: "Modifie une catégorie", Admin: true,
+		Multipart: &Multipart{Data: dto.UpdateCategoryDTO{}, Files: []File{{Name: "image"}}},
+		Response:  OK{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}},
+	{Method: http.MethodDelete, Path: "/admin/categories/:id", ID: "deleteCategory", Tag: "Catégories", Summary: "Supprime une catégorie", Admin: true,
+		Response: OK{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// ---- admin: translations
+	{Method: http.MethodGet, Path: "/admin/translations/missing", ID: "getMissingTranslations", Tag: "Traductions", Summary: "Traductions manquantes", Admin: true,
+		Description: "Les produits non supprimés et les catégories dont un champ renseigné n'est pas traduit, une entrée par langue. " +
+			"Sans `locale`, toutes les langues sauf CATALOG_DEFAULT_LOCALE sont vérifiées.",
+		Query: pageParams(
+			query("locale", "Langue à vérifier", &Schema{Type: "string", Enum: localeCodes()}),
+			query("type", "Type de contenu", &Schema{Type: "string", Enum: []any{"product", "category"}}),
+		),
+		Response: Page[dto.MissingTranslationDTO]{}, Errors: []int{http.StatusBadRequest}},
+
+	// ---- admin: exchange rates
+	{Method: http.MethodPut, Path: "/admin/exchange-rates/:currency", ID: "setExchangeRate", Tag: "Devises", Summary: "Enregistre un taux de change", Admin: true,
+		Description: "Crée ou remplace le taux de la devise. Les demandes de devis déjà faites gardent leur taux.",
+		Body:        dto.SetExchangeRateDTO{}, Response: models.ExchangeRate{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodDelete, Path: "/admin/exchange-rates/:currency", ID: "deleteExchangeRate", Tag: "Devises", Summary: "Supprime un taux de change", Admin: true,
+		Response: OK{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// ---- admin: quote requests
+	{Method: http.MethodGet, Path: "/admin/quote-requests", ID: "getQuoteRequests", Tag: "Demandes de devis", Summary: "Liste des demandes de devis", Admin: true,
+		Query:    pageParams(query("status", "Statut", &Schema{Type: "string", Enum: enums[reflect.TypeOf(models.QuoteRequestStatus(""))]})),
+		Response: Page[models.QuoteRequest]{}},
+	{Method: http.MethodGet, Path: "/admin/quote-requests/:id", ID: "getQuoteRequest", Tag: "Demandes de devis", Summary: "Détail d'une demande de devis", Admin: true,
+		Response: models.QuoteRequest{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodPatch, Path: "/admin/quote-requests/:id/status", ID: "updateQuoteStatus", Tag: "Demandes de devis", Summary: "Change le statut", Admin: true,
+		Body: dto.UpdateQuoteStatusDTO{}, Response: OK{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodPost, Path: "/admin/quote-requests/:id/notes", ID: "addQuoteNote", Tag: "Demandes de devis", Summary: "Ajoute une note (et un devis PDF)", Admin: true,
+		Description: "Une demande NEW passe IN_PROGRESS.",
+		Multipart:   &Multipart{Data: dto.AddAdminNoteDTO{}, DataRequired: true, Files: []File{{Name: "pdf"}}},
+		Status:      http.StatusCreated, Response: models.QuoteAdminNote{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// ---- admin: product requests
+	{Method: http.MethodGet, Path: "/admin/product-requests", ID: "getProductRequests", Tag: "Demandes de produit sur mesure", Summary: "Liste des demandes de produit", Admin: true,
+		Query: pageParams(
+			query("status", "Statut", &Schema{Type: "string", Enum: enums[reflect.TypeOf(models.ProductRequestStatus(""))]}),
+			query("email", "Email du client", stringSchema),
+			query("q", "Recherche plein texte", stringSchema),
+		),
+		Response: Page[models.ProductRequest]{}},
+	{Method: http.MethodGet, Path: "/admin/product-requests/:id", ID: "getProductRequest", Tag: "Demandes de produit sur mesure", Summary: "Détail d'une demande de produit", Admin: true,
+		Response: models.ProductRequest{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodPatch, Path: "/admin/product-requests/:id/status", ID: "updateProductRequestStatus", Tag: "Demandes de produit sur mesure", Summary: "Change le statut", Admin: true,
+		Body: dto.UpdateProductRequestStatusDTO{}, Response: OK{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+	{Method: http.MethodPost, Path: "/admin/product-requests/:id/notes", ID: "addProductRequestNote", Tag: "Demandes de produit sur mesure", Summary: "Ajoute une note (et une pièce jointe)", Admin: true,
+		Multipart: &Multipart{Data: dto.AddAdminNoteDTO{}, DataRequired: true, Files: []File{{Name: "file"}}},
+		Status:    http.StatusCreated, Response: models.ProductRequestAdminNote{}, Errors: []int{http.StatusBadRequest, http.StatusNotFound}},
+
+	// ---- admin: users and configuration
+	{Method: http.MethodPost, Path: "/admin/users", ID: "createUser", Tag: "Utilisateurs", Summary: "Crée un administrateur", Admin: true,
+		Body: dto.RegisterUserDTO{}, Status: http.StatusCreated, Response: UserResponse{}, Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusConflict}},
+	{Method: http.MethodPost, Path: "/admin/users/me/password", ID: "changeMyPassword", Tag: "Utilisateurs", Summary: "Change son mot de passe", Admin: true,
+		Description: "Révoque toutes les sessions (refresh tokens) de l'utilisateur.",
+		Body:        dto.ChangeMyPasswordDTO{}, Response: OK{}, Errors: []int{http.StatusBadRequest}},
+	{Method: http.MethodGet, Path: "/admin/config", ID: "getConfig", Tag: "Exploitation", Summary: "Configuration effective, secrets masqués", Admin: true,
+		Response: &Schema{Type: "object"}},
+}
+
+// operation renders r against the shared schema generator.
+func (r Route) operation(g *generator) *Operation {
+	op := &Operation{
+		OperationID: r.ID,
+		Summary:     r.Summary,
+		Description: r.Description,
+		Parameters:  append(pathParams(r.Path), r.Query...),
+		Responses:   map[string]*Response{},
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+	}
+
+	switch {
+	case r.Body != nil:
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{
+			"application/json": {Schema: g.schemaOf(reflect.TypeOf(r.Body))},
+		}}
+	case r.Multipart != nil:
+		op.RequestBody = r.Multipart.requestBody(g)
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	switch {
+	case r.ContentType != "":
+		success.Content = map[string]*MediaType{r.ContentType: {Schema: &Schema{Type: "string"}}}
+		if r.Method == http.MethodHead {
+			success.Content = nil
+		}
+	case r.Response != nil:
+		s, ok := r.Response.(*Schema)
+		if !ok {
+			s = g.schemaOf(reflect.TypeOf(r.Response))
+		}
+		success.Content = map[string]*MediaType{"application/json": {Schema: s}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	errs := append([]int{}, r.Errors...)
+	if r.Admin {
+		op.Security = []map[string][]string{{bearerAuth: {}}}
+		errs = append(errs, http.StatusUnauthorized)
+	}
+	errorSchema := g.schemaOf(reflect.TypeOf(apierror.Envelope{}))
+	for _, code := range errs {
+		op.Responses[strconv.Itoa(code)] = &Response{
+			Description: http.StatusText(code),
+			Content:     map[string]*MediaType{"application/json": {Schema: errorSchema}},
+		}
+	}
+	return op
+}
+
+func (m *Multipart) requestBody(g *generator) *RequestBody {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	if m.Data != nil {
+		data := g.schemaOf(reflect.TypeOf(m.Data))
+		data.Description = "JSON"
+		s.Properties["data"] = data
+		if m.DataRequired {
+			s.Required = append(s.Required, "data")
+		}
+	}
+	for _, f := range m.Files {
+		file := &Schema{Type: "string", Format: "binary"}
+		if f.Multiple {
+			file = &Schema{Type: "array", Items: file}
+		}
+		s.Properties[f.Name] = file
+		if f.Required {
+			s.Required = append(s.Required, f.Name)
+		}
+	}
+	sort.Strings(s.Required)
+	return &RequestBody{Required: true, Content: map[string]*MediaType{
+		"multipart/form-data": {Schema: s, Encoding: map[string]*Encoding{"data": {ContentType: "application/json"}}},
+	}}
+}
+
+func pathParams(ginPath string) []Parameter {
+	var params []Parameter
+	for _, seg := range strings.Split(ginPath, "/") {
+		if !strings.HasPrefix(seg, ":") && !strings.HasPrefix(seg, "*") {
+			continue
+		}
+		name := seg[1:]
+		s := &Schema{Type: "string"}
+		if name == "id" {
+			s.Pattern = "^[0-9a-f]{24}$"
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: s})
+	}
+	return params
+}
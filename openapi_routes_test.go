@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/princinho/sahobackend/controllers"
+	"github.com/princinho/sahobackend/metrics"
+	"github.com/princinho/sahobackend/openapi"
+	"github.com/princinho/sahobackend/storage"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	e := newTestEnv(t)
+
+	w := e.do(httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	expectStatus(t, w, http.StatusOK)
+	doc := decodeJSON[openapi.Document](t, w)
+	if doc.OpenAPI != openapi.Version || len(doc.Paths) == 0 {
+		t.Fatalf("document = %+v", doc.Info)
+	}
+	if op := doc.Paths["/admin/categories/{id}"]["delete"]; op == nil || len(op.Security) == 0 {
+		t.Fatalf("admin operation without security: %+v", op)
+	}
+	if _, ok := doc.Components.Schemas["CreateQuoteRequestDTO"]; !ok {
+		t.Fatal("request DTOs are not documented")
+	}
+
+	w = e.do(httptest.NewRequest(http.MethodGet, "/docs", nil))
+	expectStatus(t, w, http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") || !strings.Contains(w.Body.String(), "/openapi.json") {
+		t.Fatalf("docs page: %s %q", ct, w.Body.String())
+	}
+}
+
+// TestOpenAPICoversRouter fails when a route is added to newRouter without
+// being documented in openapi/routes.go, or the other way round.
+func TestOpenAPICoversRouter(t *testing.T) {
+	// a local store makes newRouter register the /media routes too
+	store, err := storage.NewLocal(storage.LocalConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &controllers.App{Config: testConfig(), Storage: store, Metrics: metrics.New()}
+	spec := openapi.Spec()
+
+	registered := map[string]bool{}
+	for _, route := range newRouter(app).Routes() {
+		registered[route.Method+" "+openapi.Path(route.Path)] = true
+		if !spec.Has(route.Method, route.Path) {
+			t.Errorf("%s %s is missing from the OpenAPI document", route.Method, route.Path)
+		}
+	}
+	for path, item := range spec.Paths {
+		for method := range item {
+			if !registered[strings.ToUpper(method)+" "+path] {
+				t.Errorf("%s %s is documented but not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
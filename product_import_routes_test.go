@@ -0,0 +1,177 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/spreadsheet"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func zipBytes(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write(content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImportProductsUpsertsBySlug(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	chairs := e.seedCategory("Chaises", "chaises")
+	bench := e.seedProduct(models.Product{Name: "Banc", Price: 80, Quantity: 1, Images: []models.Image{imageAt(testMediaURL + "/products/banc/1.png")}})
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "photos"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "photos", "tabouret.png"), pngBytes(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e.app.Config.Import.ImagesDir = dir
+
+	catalog := "name;price;quantity;categories;materials;images\n" +
+		// thousands separated by a no-break space, then a narrow one
+		"Chaise Lomé;52\u00a0000;3;chaises;Teck | Rotin;lome.png\n" +
+		"Banc;1\u202f090;4;chaises;;" + bench.Images[0].URL + "\n" +
+		";;;;;\n" +
+		"Tabouret;abc;1;inconnue;;photos/tabouret.png\n" +
+		"Chaise lome;10;1;chaises;;lome.png\n" +
+		"Table;10;1;chaises;;../secret.png\n"
+	files := []testFile{
+		{field: "file", name: "catalogue.csv", content: []byte(catalog)},
+		{field: "archive", name: "images.zip", content: zipBytes(t, map[string][]byte{"export/lome.png": pngBytes(t)})},
+	}
+
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/products/import?dryRun=true", nil, files, token))
+	expectStatus(t, w, http.StatusOK)
+	report := decodeJSON[dto.ProductImportReportDTO](t, w)
+	if !report.DryRun || report.Created != 1 || report.Updated != 1 || report.Failed != 3 || len(report.Rows) != 5 {
+		t.Fatalf("dry run report = %+v", report)
+	}
+	fields := map[string]bool{}
+	for _, f := range report.Rows[2].Errors {
+		fields[f.Field] = true
+	}
+	if report.Rows[2].Row != 5 || !fields["price"] || !fields["categories"] || fields["images"] {
+		t.Fatalf("invalid row = %+v", report.Rows[2])
+	}
+	if r := report.Rows[3]; r.Slug != "chaise-lome" || len(r.Errors) != 1 || r.Errors[0].Field != "slug" {
+		t.Fatalf("duplicate row = %+v", r)
+	}
+	if r := report.Rows[4]; len(r.Errors) != 1 || r.Errors[0].Message != "image ../secret.png introuvable" {
+		t.Fatalf("missing image row = %+v", r)
+	}
+	if _, err := e.app.Products.FindBySlug(t.Context(), "chaise-lome"); err == nil {
+		t.Fatal("a dry run created a product")
+	}
+
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/products/import", nil, files, token))
+	expectStatus(t, w, http.StatusOK)
+	if report := decodeJSON[dto.ProductImportReportDTO](t, w); report.DryRun || report.Created != 1 || report.Updated != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	chair, err := e.app.Products.FindBySlug(t.Context(), "chaise-lome")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chair.Price != 52000 || chair.Quantity != 3 || len(chair.Materials) != 2 || len(chair.CategoryIds) != 1 || chair.CategoryIds[0] != chairs.Id {
+		t.Fatalf("created product = %+v", chair)
+	}
+	if len(chair.Images) != 1 || !strings.HasPrefix(chair.Images[0].URL, testMediaURL+"/products/chaise-lome/") || e.storedImage(chair.Images[0]) != 3 {
+		t.Fatalf("created images = %+v", chair.Images)
+	}
+	stored, err := e.app.Products.FindByID(t.Context(), bench.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Price != 1090 || stored.Quantity != 4 || stored.Images[0].URL != bench.Images[0].URL {
+		t.Fatalf("updated product = %+v", stored)
+	}
+	movements, _, err := e.app.StockMovements.List(t.Context(), repositories.StockMovementFilter{ProductID: &bench.Id}, repositories.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movements) != 1 || movements[0].Delta != 3 || movements[0].Reason != "import" {
+		t.Fatalf("bench movements = %+v", movements)
+	}
+
+	// the image directory is searched too
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/products/import", nil, []testFile{
+		{field: "file", name: "catalogue.csv", content: []byte("name,price,categories,images\nTabouret,40,chaises,photos/tabouret.png\n")},
+	}, token))
+	expectStatus(t, w, http.StatusOK)
+	if report := decodeJSON[dto.ProductImportReportDTO](t, w); report.Created != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	for name, content := range map[string]string{
+		"catalogue.csv": "name,categories\nTable,chaises\n",
+		"catalogue.xls": "name,price,categories\n",
+		"vide.csv":      "name,price,categories\n",
+	} {
+		w = e.do(e.multipartRequest(http.MethodPost, "/admin/products/import", nil,
+			[]testFile{{field: "file", name: name, content: []byte(content)}}, token))
+		expectStatus(t, w, http.StatusBadRequest)
+	}
+}
+
+func TestExportProductsReadsBackAsAnImport(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	cat := e.seedCategory("Tables", "tables")
+	e.seedProduct(models.Product{
+		Name: "Table Kara", Price: 300, Quantity: 2, CategoryIds: []bson.ObjectID{cat.Id},
+		Colors: []string{"Noyer", "Miel"}, IsTrending: true, Images: []models.Image{imageAt(testMediaURL + "/products/table-kara/1.png")},
+	})
+	e.seedProduct(models.Product{Name: "Banc", Price: 80, CategoryIds: []bson.ObjectID{cat.Id}, Images: []models.Image{imageAt(testMediaURL + "/products/banc/1.png")}})
+
+	for _, format := range []string{spreadsheet.CSV, spreadsheet.XLSX} {
+		w := e.do(e.jsonRequest(http.MethodGet, "/admin/products/export?format="+format, nil, token))
+		expectStatus(t, w, http.StatusOK)
+		if got := w.Header().Get("Content-Type"); got != spreadsheet.ContentTypes[format] {
+			t.Fatalf("%s: content type = %q", format, got)
+		}
+		if !strings.Contains(w.Header().Get("Content-Disposition"), "."+format) {
+			t.Fatalf("%s: disposition = %q", format, w.Header().Get("Content-Disposition"))
+		}
+		export := w.Body.Bytes()
+
+		rows, err := spreadsheet.Read(format, bytes.NewReader(export))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"banc", "Banc", "80", "0", "tables", "", "", "", "", "", "", "false", "false", testMediaURL + "/products/banc/1.png"}
+		if len(rows) != 3 || strings.Join(rows[1], ",") != strings.Join(want, ",") || rows[2][6] != "Noyer|Miel" {
+			t.Fatalf("%s: rows = %q", format, rows)
+		}
+
+		w = e.do(e.multipartRequest(http.MethodPost, "/admin/products/import?dryRun=true", nil,
+			[]testFile{{field: "file", name: "catalogue." + format, content: export}}, token))
+		expectStatus(t, w, http.StatusOK)
+		if report := decodeJSON[dto.ProductImportReportDTO](t, w); report.Updated != 2 || report.Failed != 0 {
+			t.Fatalf("%s: re-import = %+v", format, report)
+		}
+	}
+
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/admin/products/export?format=pdf", nil, token)), http.StatusBadRequest)
+}
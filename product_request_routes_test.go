@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type productRequestsPage struct {
+	Items []models.ProductRequest `json:"items"`
+	Total int64                   `json:"total"`
+}
+
+func TestCreateProductRequestValidatesAttachment(t *testing.T) {
+	e := newTestEnv(t)
+	data := gin.H{
+		"fullName":    "Awa Mensah",
+		"email":       "awa@example.com",
+		"company":     "Hôtel du Lac",
+		"description": "Tabourets de bar sur mesure",
+	}
+
+	w := e.do(e.multipartRequest(http.MethodPost, "/product-requests", data,
+		[]testFile{{field: "image", name: "ref.png", content: pngBytes(t), mimeType: "image/png"}}, ""))
+	expectStatus(t, w, http.StatusCreated)
+	id := decodeJSON[map[string]string](t, w)["id"]
+
+	req, err := e.app.ProductRequests.FindByID(t.Context(), mustObjectID(t, id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Quantity != 1 || req.Status != models.ProductRequestStatusNew {
+		t.Fatalf("stored request = %+v", req)
+	}
+	if req.ReferenceImage == nil || !e.stored(req.ReferenceImage.ObjectName) {
+		t.Fatalf("reference image not uploaded: %+v", req.ReferenceImage)
+	}
+
+	// sniffed content must match the allow-list, whatever the extension says
+	w = e.do(e.multipartRequest(http.MethodPost, "/product-requests", data,
+		[]testFile{{field: "image", name: "ref.png", content: []byte("MZ\x90\x00 not an image"), mimeType: "image/png"}}, ""))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	w = e.do(e.multipartRequest(http.MethodPost, "/product-requests", gin.H{"fullName": "Awa"}, nil, ""))
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestAdminProductRequestWorkflow(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+
+	create := func(name, email, company string) string {
+		w := e.do(e.multipartRequest(http.MethodPost, "/product-requests", gin.H{
+			"fullName": name, "email": email, "company": company, "description": "Une table ronde",
+		}, nil, ""))
+		expectStatus(t, w, http.StatusCreated)
+		return decodeJSON[map[string]string](t, w)["id"]
+	}
+	first := create("Awa Mensah", "awa@example.com", "Hôtel du Lac")
+	second := create("Kofi Agbo", "kofi@example.com", "")
+
+	w := e.do(e.jsonRequest(http.MethodGet, "/admin/product-requests", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeJSON[productRequestsPage](t, w); got.Total != 2 {
+		t.Fatalf("listing = %+v", got)
+	}
+	for _, q := range []string{"du+lac", "HOTEL", "h%C3%B4tel"} {
+		w = e.do(e.jsonRequest(http.MethodGet, "/admin/product-requests?q="+q, nil, token))
+		if got := decodeJSON[productRequestsPage](t, w); got.Total != 1 || got.Items[0].Id.Hex() != first {
+			t.Fatalf("q=%s listing = %+v", q, got)
+		}
+	}
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/product-requests?email=kofi@example.com", nil, token))
+	if got := decodeJSON[productRequestsPage](t, w); got.Total != 1 || got.Items[0].Id.Hex() != second {
+		t.Fatalf("email listing = %+v", got)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/product-requests/"+first, nil, token))
+	expectStatus(t, w, http.StatusOK)
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/product-requests/"+bson.NewObjectID().Hex(), nil, token))
+	expectStatus(t, w, http.StatusNotFound)
+
+	// first note auto-advances NEW → IN_PROGRESS
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/product-requests/"+first+"/notes",
+		gin.H{"content": "Plan joint"},
+		[]testFile{{field: "file", name: "plan.pdf", content: pdfBytes(), mimeType: "application/pdf"}},
+		token))
+	expectStatus(t, w, http.StatusCreated)
+	note := decodeJSON[models.ProductRequestAdminNote](t, w)
+	if note.Attachment == nil || !e.stored(note.Attachment.ObjectName) {
+		t.Fatalf("note attachment not uploaded: %+v", note.Attachment)
+	}
+	req, _ := e.app.ProductRequests.FindByID(t.Context(), mustObjectID(t, first))
+	if req.Status != models.ProductRequestStatusInProgress || len(req.Notes) != 1 {
+		t.Fatalf("after note: status=%s notes=%d", req.Status, len(req.Notes))
+	}
+
+	// ANSWERED stamps answeredAt and is not downgraded by later notes
+	w = e.do(e.jsonRequest(http.MethodPatch, "/admin/product-requests/"+second+"/status", gin.H{"status": "ANSWERED"}, token))
+	expectStatus(t, w, http.StatusOK)
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/product-requests/"+second+"/notes", gin.H{"content": "Suivi"}, nil, token))
+	expectStatus(t, w, http.StatusCreated)
+	req, _ = e.app.ProductRequests.FindByID(t.Context(), mustObjectID(t, second))
+	if req.Status != models.ProductRequestStatusAnswered || req.AnsweredAt == nil {
+		t.Fatalf("answered request = status %s answeredAt %v", req.Status, req.AnsweredAt)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodPatch, "/admin/product-requests/"+first+"/status", gin.H{"status": "QUOTED"}, token))
+	expectStatus(t, w, http.StatusBadRequest)
+	w = e.do(e.jsonRequest(http.MethodPatch, "/admin/product-requests/"+bson.NewObjectID().Hex()+"/status", gin.H{"status": "CLOSED"}, token))
+	expectStatus(t, w, http.StatusNotFound)
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/product-requests/"+bson.NewObjectID().Hex()+"/notes", gin.H{"content": "?"}, nil, token))
+	expectStatus(t, w, http.StatusNotFound)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type revisionsPage struct {
+	Items []models.ProductRevision `json:"items"`
+	Total int64                    `json:"total"`
+}
+
+func TestProductRevisionsDiffAndRollback(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	cat := e.seedCategory("Chaises", "chaises")
+
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/products/add",
+		gin.H{"name": "Tabouret", "price": 40, "quantity": 2, "description": "Teck", "categoryIds": []string{cat.Id.Hex()}},
+		[]testFile{{field: "images", name: "a.png", content: pngBytes(t), mimeType: "image/png"}}, token))
+	expectStatus(t, w, http.StatusCreated)
+	product := decodeJSON[models.Product](t, w)
+	base := "/admin/products/" + product.Id.Hex()
+
+	for _, data := range []gin.H{
+		{"price": 4, "description": "Teck massif", "categoryIds": []string{cat.Id.Hex()}},
+		// the stock has its own ledger: no revision
+		{"quantity": 5, "categoryIds": []string{cat.Id.Hex()}},
+	} {
+		expectStatus(t, e.do(e.multipartRequest(http.MethodPatch, "/admin/products/update/"+product.Id.Hex(), data, nil, token)), http.StatusOK)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, base+"/revisions", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	page := decodeJSON[revisionsPage](t, w)
+	if page.Total != 2 || page.Items[0].Version != 2 || page.Items[1].Action != models.ProductRevisionCreate {
+		t.Fatalf("revisions = %+v", page)
+	}
+	update := page.Items[0]
+	if update.Action != models.ProductRevisionUpdate || !slices.Equal(update.ChangedFields, []string{"description", "price"}) ||
+		update.AuthorEmail != testAdminEmail || update.Snapshot.Price != 4 || update.Snapshot.Quantity != 0 {
+		t.Fatalf("update revision = %+v", update)
+	}
+	if created := page.Items[1]; !slices.Contains(created.ChangedFields, "name") || slices.Contains(created.ChangedFields, "isTrending") {
+		t.Fatalf("create revision fields = %v", created.ChangedFields)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, base+"/revisions/diff?from=1&to=2", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	diff := decodeJSON[dto.ProductRevisionDiffDTO](t, w)
+	if len(diff.Changes) != 2 || diff.Changes[1].Field != "price" || diff.Changes[1].From != 40.0 || diff.Changes[1].To != 4.0 {
+		t.Fatalf("diff = %+v", diff)
+	}
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, base+"/revisions/diff?from=1", nil, token)), http.StatusBadRequest)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, base+"/revisions/diff?from=1&to=9", nil, token)), http.StatusNotFound)
+
+	w = e.do(e.jsonRequest(http.MethodPost, base+"/revisions/1/rollback", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if restored := decodeJSON[models.Product](t, w); restored.Price != 40 || restored.Description != "Teck" ||
+		restored.Quantity != 5 || len(restored.Images) != 1 {
+		t.Fatalf("restored = %+v", restored)
+	}
+	latest, err := e.app.Revisions.Latest(t.Context(), product.Id)
+	if err != nil || latest.Version != 3 || latest.Action != models.ProductRevisionRollback || latest.RestoredVersion != 1 {
+		t.Fatalf("rollback revision = %+v, %v", latest, err)
+	}
+
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPost, base+"/revisions/9/rollback", nil, token)), http.StatusNotFound)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPost, base+"/revisions/zero/rollback", nil, token)), http.StatusBadRequest)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPost, "/admin/products/"+bson.NewObjectID().Hex()+"/revisions/1/rollback", nil, token)), http.StatusNotFound)
+}
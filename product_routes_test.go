@@ -0,0 +1,714 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"github.com/princinho/sahobackend/upload"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type productsPage struct {
+	Items []models.Product `json:"items"`
+	Total int64            `json:"total"`
+	Page  int              `json:"page"`
+	Limit int              `json:"limit"`
+}
+
+func TestGetProductsFiltersSortsAndPaginates(t *testing.T) {
+	e := newTestEnv(t)
+	chairs := e.seedCategory("Chaises", "chaises")
+	tables := e.seedCategory("Tables", "tables")
+
+	e.seedProduct(models.Product{Name: "Chaise teck", Price: 120, CategoryIds: []bson.ObjectID{chairs.Id}, IsTrending: true})
+	e.seedProduct(models.Product{Name: "Chaise rotin", Price: 80, CategoryIds: []bson.ObjectID{chairs.Id}})
+	e.seedProduct(models.Product{Name: "Table basse", Price: 300, CategoryIds: []bson.ObjectID{tables.Id}, IsDisabled: true})
+
+	w := e.do(e.jsonRequest(http.MethodGet, "/products", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	all := decodeJSON[productsPage](t, w)
+	if all.Total != 3 || all.Items[0].Name != "Chaise rotin" {
+		t.Fatalf("default listing = %+v, want 3 items sorted by name", all)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/products?category=chaises&sort=price_desc", nil, ""))
+	got := decodeJSON[productsPage](t, w)
+	if got.Total != 2 || got.Items[0].Name != "Chaise teck" || got.Items[1].Name != "Chaise rotin" {
+		t.Fatalf("category+sort listing = %+v", got)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/products?category=unknown", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeJSON[productsPage](t, w); got.Total != 0 || len(got.Items) != 0 {
+		t.Fatalf("unknown category listing = %+v, want empty", got)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/products?isTrending=true", nil, ""))
+	if got := decodeJSON[productsPage](t, w); got.Total != 1 || got.Items[0].Name != "Chaise teck" {
+		t.Fatalf("trending listing = %+v", got)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/products?isDisabled=false&sort=price_asc", nil, ""))
+	if got := decodeJSON[productsPage](t, w); got.Total != 2 || got.Items[0].Price != 80 {
+		t.Fatalf("enabled listing = %+v", got)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/products?page=2&limit=2", nil, ""))
+	if got := decodeJSON[productsPage](t, w); got.Total != 3 || len(got.Items) != 1 || got.Page != 2 {
+		t.Fatalf("second page = %+v", got)
+	}
+}
+
+func TestGetProductEmbedsCategoriesAndSimilarProducts(t *testing.T) {
+	e := newTestEnv(t)
+	chairs := e.seedCategory("Chaises", "chaises")
+	hidden := models.Category{Name: "Archives", Slug: "archives"}
+	if err := e.app.Categories.Insert(t.Context(), &hidden); err != nil {
+		t.Fatal(err)
+	}
+
+	stool := e.seedProduct(models.Product{Name: "Tabouret", Price: 40, Images: []models.Image{imageAt("https://cdn.test/tabouret.webp"), imageAt("https://cdn.test/b.webp")}})
+	bench := e.seedProduct(models.Product{Name: "Banc", Price: 90})
+	retired := e.seedProduct(models.Product{Name: "Banc ancien", Price: 70, IsDisabled: true})
+	chair := e.seedProduct(models.Product{
+		Name:               "Chaise teck",
+		Price:              120,
+		CategoryIds:        []bson.ObjectID{hidden.Id, chairs.Id, bson.NewObjectID()},
+		SimilarProductsIds: []bson.ObjectID{bench.Id, retired.Id, stool.Id, bson.NewObjectID()},
+	})
+
+	for _, path := range []string{"/products/" + chair.Id.Hex(), "/products/slug/" + chair.Slug} {
+		w := e.do(e.jsonRequest(http.MethodGet, path, nil, ""))
+		expectStatus(t, w, http.StatusOK)
+		got := decodeJSON[dto.ProductDetailDTO](t, w)
+
+		if got.Id != chair.Id || got.Name != "Chaise teck" || len(got.SimilarProductsIds) != 4 {
+			t.Fatalf("%s: product = %+v", path, got.Product)
+		}
+		if len(got.Categories) != 1 || got.Categories[0].Slug != "chaises" {
+			t.Fatalf("%s: categories = %+v", path, got.Categories)
+		}
+		if len(got.SimilarProducts) != 2 || got.SimilarProducts[0].Name != "Banc" || got.SimilarProducts[1].Name != "Tabouret" {
+			t.Fatalf("%s: similar = %+v", path, got.SimilarProducts)
+		}
+		if got.SimilarProducts[1].Image.URL != "https://cdn.test/tabouret.webp" || got.SimilarProducts[1].Price != 40 {
+			t.Fatalf("%s: summary = %+v", path, got.SimilarProducts[1])
+		}
+	}
+
+	// nothing to resolve still yields lists, never null
+	w := e.do(e.jsonRequest(http.MethodGet, "/products/slug/"+bench.Slug, nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	if !strings.Contains(w.Body.String(), `"categories":[]`) || !strings.Contains(w.Body.String(), `"similarProducts":[]`) {
+		t.Fatalf("body = %s", w.Body.String())
+	}
+}
+
+func TestGetProductHidesDisabledAndMissingProducts(t *testing.T) {
+	e := newTestEnv(t)
+	retired := e.seedProduct(models.Product{Name: "Banc ancien", Price: 70, IsDisabled: true})
+
+	for _, path := range []string{
+		"/products/" + retired.Id.Hex(),
+		"/products/slug/" + retired.Slug,
+		"/products/" + bson.NewObjectID().Hex(),
+		"/products/slug/inconnu",
+	} {
+		expectStatus(t, e.do(e.jsonRequest(http.MethodGet, path, nil, "")), http.StatusNotFound)
+	}
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/products/not-an-id", nil, "")), http.StatusBadRequest)
+}
+func TestAddProductUploadsImages(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	cat := e.seedCategory("Chaises", "chaises")
+
+	data := gin.H{
+		"name":        "Chaise Lomé",
+		"price":       150,
+		"quantity":    3,
+		"categoryIds": []string{cat.Id.Hex()},
+		"materials":   []string{"teck"},
+		"colors":      []string{"naturel"},
+	}
+	files := []testFile{
+		{field: "images", name: "front.png", content: pngBytes(t), mimeType: "image/png"},
+		{field: "images", name: "side.png", content: pngBytes(t), mimeType: "image/png"},
+	}
+
+	// auth is required
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/products/add", data, files, ""))
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/products/add", data, files, token))
+	expectStatus(t, w, http.StatusCreated)
+	created := decodeJSON[models.Product](t, w)
+
+	if created.Slug != "chaise-lome" {
+		t.Fatalf("slug = %q, want chaise-lome", created.Slug)
+	}
+	if len(created.Images) != 2 {
+		t.Fatalf("images = %+v, want 2", created.Images)
+	}
+	for _, img := range created.Images {
+		for _, u := range img.URLs() {
+			if !strings.HasPrefix(u, testMediaURL+"/products/chaise-lome/") || !strings.HasSuffix(u, ".webp") {
+				t.Fatalf("unexpected public url %q", u)
+			}
+		}
+		if e.storedImage(img) != 3 {
+			t.Fatalf("renditions of %+v were not uploaded; store has %v", img, e.storedObjects())
+		}
+	}
+	// the gallery follows the upload order, the first image being the primary one
+	for i, img := range created.Images {
+		if img.Position != i || img.IsPrimary != (i == 0) || objectKey(img.URL) != img.ObjectName {
+			t.Fatalf("images[%d] = %+v", i, img)
+		}
+	}
+
+	stored, err := e.app.Products.FindByID(t.Context(), created.Id)
+	if err != nil {
+		t.Fatalf("product not stored: %v", err)
+	}
+	if len(stored.CategoryIds) != 1 || stored.CategoryIds[0] != cat.Id {
+		t.Fatalf("categoryIds = %v", stored.CategoryIds)
+	}
+
+	// same name → same slug → conflict
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/products/add", data, files, token))
+	expectStatus(t, w, http.StatusConflict)
+
+	// images are mandatory
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/products/add", gin.H{"name": "Sans image", "categoryIds": []string{cat.Id.Hex()}}, nil, token))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	// every file is checked by content, whatever its name says, and each
+	// rejected one is reported
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/products/add", gin.H{"name": "Faux", "categoryIds": []string{cat.Id.Hex()}},
+		[]testFile{
+			{field: "images", name: "vrai.png", content: pngBytes(t), mimeType: "image/png"},
+			{field: "images", name: "faux.png", content: []byte("not a picture"), mimeType: "image/png"},
+			{field: "images", name: "coupe.png", content: pngBytes(t)[:40], mimeType: "image/png"},
+		}, token))
+	expectStatus(t, w, http.StatusBadRequest)
+	body := decodeJSON[apierror.Envelope](t, w)
+	if f := body.Error.Fields; body.Error.Code != apierror.CodeInvalidUpload || len(f) != 2 ||
+		f[0].Field != "images[1]" || f[0].Rule != upload.RuleType || f[1].Field != "images[2]" || f[1].Rule != upload.RuleDecodable {
+		t.Fatalf("rejection = %+v", body.Error)
+	}
+
+	// data is mandatory
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/products/add", nil, files, token))
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestUpdateProductReplacesImages(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	cat := e.seedCategory("Chaises", "chaises")
+
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/products/add",
+		gin.H{"name": "Tabouret", "price": 40, "quantity": 1, "categoryIds": []string{cat.Id.Hex()}},
+		[]testFile{
+			{field: "images", name: "a.png", content: pngBytes(t), mimeType: "image/png"},
+			{field: "images", name: "b.png", content: pngBytes(t), mimeType: "image/png"},
+		}, token))
+	expectStatus(t, w, http.StatusCreated)
+	product := decodeJSON[models.Product](t, w)
+	removed, kept := product.Images[0], product.Images[1]
+
+	path := "/admin/products/update/" + product.Id.Hex()
+	w = e.do(e.multipartRequest(http.MethodPatch, path,
+		gin.H{
+			"price":             55.0,
+			"isTrending":        true,
+			"categoryIds":       []string{cat.Id.Hex()},
+			"removedImagesUrls": []string{removed.URL, "https://elsewhere.test/not-ours.png"},
+		},
+		[]testFile{{field: "images", name: "c.png", content: pngBytes(t), mimeType: "image/png"}},
+		token))
+	expectStatus(t, w, http.StatusOK)
+
+	updated, err := e.app.Products.FindByID(t.Context(), product.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Price != 55 || !updated.IsTrending {
+		t.Fatalf("fields not updated: price=%v trending=%v", updated.Price, updated.IsTrending)
+	}
+	// the primary image was removed: the kept one takes its place
+	if len(updated.Images) != 2 || updated.Images[0].URL != kept.URL || !updated.Images[0].IsPrimary ||
+		updated.Images[1].Position != 1 || updated.Images[1].IsPrimary {
+		t.Fatalf("images = %+v, want [%+v, <new>]", updated.Images, kept)
+	}
+	if e.storedImage(removed) != 0 {
+		t.Fatalf("removed image %+v is still in the store", removed)
+	}
+	if e.storedImage(updated.Images[1]) != 3 {
+		t.Fatalf("new image %+v was not uploaded", updated.Images[1])
+	}
+
+	// the image cap (MAX_PROD_IMAGES) counts kept + new images
+	e.app.Config.Uploads.MaxProductImages = 2
+	w = e.do(e.multipartRequest(http.MethodPatch, path, gin.H{"name": "Tabouret"},
+		[]testFile{{field: "images", name: "d.png", content: pngBytes(t), mimeType: "image/png"}}, token))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	w = e.do(e.multipartRequest(http.MethodPatch, path, gin.H{}, nil, token))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	// a slug taken by another product is a conflict, not a server error
+	e.seedProduct(models.Product{Name: "Banc", Slug: "banc", Price: 10})
+	w = e.do(e.multipartRequest(http.MethodPatch, path, gin.H{"slug": "banc"}, nil, token))
+	expectStatus(t, w, http.StatusConflict)
+	if body := decodeJSON[apierror.Envelope](t, w); body.Error.Code != apierror.CodeConflict || body.Error.Fields[0].Field != "slug" {
+		t.Fatalf("conflict = %+v", body.Error)
+	}
+
+	w = e.do(e.multipartRequest(http.MethodPatch, "/admin/products/update/not-an-id", gin.H{"name": "x"}, nil, token))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	w = e.do(e.multipartRequest(http.MethodPatch, "/admin/products/update/"+cat.Id.Hex(), gin.H{"name": "x"}, nil, token))
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestAdminArrangesProductImages(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	a, b, c := imageAt(testMediaURL+"/products/banc/a.webp"), imageAt(testMediaURL+"/products/banc/b.webp"), imageAt(testMediaURL+"/products/banc/c.webp")
+	b.Alt = map[string]string{"fr": "Banc de côté"}
+	product := e.seedProduct(models.Product{Name: "Banc", Price: 100, Images: models.ArrangeImages([]models.Image{a, b, c})})
+	path := "/admin/products/" + product.Id.Hex() + "/images"
+
+	w := e.do(e.jsonRequest(http.MethodPatch, path, gin.H{"images": []gin.H{
+		{"url": c.URL, "isPrimary": true, "alt": gin.H{"fr": " Banc en teck ", "en": "Teak bench"}},
+		{"url": a.URL},
+		{"url": b.URL},
+	}}, token))
+	expectStatus(t, w, http.StatusOK)
+	updated := decodeJSON[models.Product](t, w)
+	if got := models.ImageURLs(updated.Images); !slices.Equal(got, []string{c.URL, a.URL, b.URL}) {
+		t.Fatalf("order = %v", got)
+	}
+	for i, img := range updated.Images {
+		if img.Position != i || img.IsPrimary != (i == 0) {
+			t.Fatalf("images[%d] = %+v", i, img)
+		}
+	}
+	if alt := updated.Images[0].Alt; alt["fr"] != "Banc en teck" || alt["en"] != "Teak bench" {
+		t.Fatalf("new alt = %v", alt)
+	}
+	if alt := updated.Images[2].Alt; alt["fr"] != "Banc de côté" {
+		t.Fatalf("alt left out was changed: %v", alt)
+	}
+	if latest, err := e.app.Revisions.Latest(t.Context(), product.Id); err != nil || !slices.Equal(latest.ChangedFields, []string{"images"}) {
+		t.Fatalf("latest revision = %+v, %v", latest, err)
+	}
+
+	// no primary flagged: it stays; an empty alt clears the locale
+	w = e.do(e.jsonRequest(http.MethodPatch, path, gin.H{"images": []gin.H{
+		{"url": a.URL}, {"url": b.URL, "alt": gin.H{"fr": ""}}, {"url": c.URL},
+	}}, token))
+	expectStatus(t, w, http.StatusOK)
+	updated = decodeJSON[models.Product](t, w)
+	if img := updated.Images[2]; img.URL != c.URL || !img.IsPrimary || updated.Images[0].IsPrimary {
+		t.Fatalf("images = %+v", updated.Images)
+	}
+	if updated.Images[1].Alt != nil {
+		t.Fatalf("cleared alt = %v", updated.Images[1].Alt)
+	}
+
+	// unknown, repeated, two primaries, unsupported locale
+	w = e.do(e.jsonRequest(http.MethodPatch, path, gin.H{"images": []gin.H{
+		{"url": a.URL, "isPrimary": true},
+		{"url": a.URL, "isPrimary": true},
+		{"url": testMediaURL + "/products/autre/x.webp"},
+		{"url": b.URL, "alt": gin.H{"de": "Bank"}},
+	}}, token))
+	expectStatus(t, w, http.StatusBadRequest)
+	body := decodeJSON[apierror.Envelope](t, w)
+	fields := map[string]string{}
+	for _, f := range body.Error.Fields {
+		fields[f.Field] = f.Rule
+	}
+	want := map[string]string{
+		"images": apierror.RuleInvalid, "images[1].url": apierror.RuleUnique,
+		"images[2].url": apierror.RuleExists, "images[3].alt.de": apierror.RuleOneOf,
+	}
+	if !maps.Equal(fields, want) {
+		t.Fatalf("fields = %v, want %v", fields, want)
+	}
+
+	// every image must be listed
+	w = e.do(e.jsonRequest(http.MethodPatch, path, gin.H{"images": []gin.H{{"url": a.URL}, {"url": b.URL}}}, token))
+	expectStatus(t, w, http.StatusBadRequest)
+	if f := decodeJSON[apierror.Envelope](t, w).Error.Fields; len(f) != 1 || f[0].Field != "images" || f[0].Rule != apierror.RuleRequired {
+		t.Fatalf("fields = %+v", f)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodPatch, "/admin/products/"+bson.NewObjectID().Hex()+"/images",
+		gin.H{"images": []gin.H{{"url": a.URL}}}, token))
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestDeleteProductGoesThroughTheTrash(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	cat := e.seedCategory("Chaises", "chaises")
+
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/products/add",
+		gin.H{"name": "Tabouret", "price": 40, "quantity": 1, "categoryIds": []string{cat.Id.Hex()}},
+		[]testFile{{field: "images", name: "a.png", content: pngBytes(t), mimeType: "image/png"}}, token))
+	expectStatus(t, w, http.StatusCreated)
+	stool := decodeJSON[models.Product](t, w)
+	bench := e.seedProduct(models.Product{Name: "Banc", Price: 90, SimilarProductsIds: []bson.ObjectID{stool.Id}})
+
+	w = e.do(e.jsonRequest(http.MethodPost, "/quote-requests", gin.H{
+		"fullName": "Awa", "email": "awa@example.com",
+		"items": []gin.H{{"productId": stool.Id.Hex(), "quantity": 2}},
+	}, ""))
+	expectStatus(t, w, http.StatusCreated)
+	quoteID := decodeJSON[map[string]string](t, w)["id"]
+
+	path := "/admin/products/" + stool.Id.Hex()
+	expectStatus(t, e.do(e.jsonRequest(http.MethodDelete, path, nil, token)), http.StatusOK)
+
+	// trashed: gone from the shop, kept in the trash with its images
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/products/"+stool.Id.Hex(), nil, "")), http.StatusNotFound)
+	if got := decodeJSON[productsPage](t, e.do(e.jsonRequest(http.MethodGet, "/products", nil, ""))); got.Total != 1 {
+		t.Fatalf("listing = %+v, want only the bench", got)
+	}
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/products/trash", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if trash := decodeJSON[productsPage](t, w); trash.Total != 1 || trash.Items[0].Id != stool.Id || trash.Items[0].DeletedAt == nil {
+		t.Fatalf("trash = %+v", trash)
+	}
+	if e.storedImage(stool.Images[0]) != 3 {
+		t.Fatal("trashing deleted the images")
+	}
+	w = e.do(e.jsonRequest(http.MethodPost, "/quote-requests", gin.H{
+		"fullName": "Awa", "email": "awa@example.com",
+		"items": []gin.H{{"productId": stool.Id.Hex(), "quantity": 1}},
+	}, ""))
+	expectStatus(t, w, http.StatusBadRequest)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodDelete, path, nil, token)), http.StatusNotFound)
+
+	// restored: public again, once
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPost, path+"/restore", nil, token)), http.StatusOK)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/products/"+stool.Id.Hex(), nil, "")), http.StatusOK)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPost, path+"/restore", nil, token)), http.StatusNotFound)
+
+	// permanent: document, images and similar-product links go
+	expectStatus(t, e.do(e.jsonRequest(http.MethodDelete, path+"?permanent=maybe", nil, token)), http.StatusBadRequest)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodDelete, path+"?permanent=true", nil, token)), http.StatusOK)
+	if e.storedImage(stool.Images[0]) != 0 {
+		t.Fatal("image still stored after permanent deletion")
+	}
+	if left, _ := e.app.Products.FindByID(t.Context(), bench.Id); len(left.SimilarProductsIds) != 0 {
+		t.Fatalf("bench similar products = %v", left.SimilarProductsIds)
+	}
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPost, path+"/restore", nil, token)), http.StatusNotFound)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodDelete, path+"?permanent=true", nil, token)), http.StatusNotFound)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodDelete, "/admin/products/not-an-id", nil, token)), http.StatusBadRequest)
+
+	// the quote keeps its snapshot of the deleted product
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/quote-requests/"+quoteID, nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if quote := decodeJSON[models.QuoteRequest](t, w); quote.Items[0].ProductName != "Tabouret" || quote.Items[0].UnitPrice != 40 {
+		t.Fatalf("quote items = %+v", quote.Items)
+	}
+}
+
+func TestPurgeTrashDeletesExpiredProducts(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := t.Context()
+	now := time.Now().UTC()
+	e.app.Config.Trash.Retention = 30 * 24 * time.Hour
+
+	old := e.seedProduct(models.Product{Name: "Vieux banc"})
+	recent := e.seedProduct(models.Product{Name: "Banc récent"})
+	e.seedProduct(models.Product{Name: "Banc en vente", SimilarProductsIds: []bson.ObjectID{old.Id}})
+	if err := e.app.Products.Trash(ctx, old.Id, now.Add(-31*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.app.Products.Trash(ctx, recent.Id, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := e.app.PurgeTrash(ctx, now)
+	if err != nil || n != 1 {
+		t.Fatalf("purged %d, %v; want 1", n, err)
+	}
+	trash, _, _ := e.app.Products.List(ctx, repositories.ProductFilter{Trashed: true}, repositories.Page{})
+	if len(trash) != 1 || trash[0].Id != recent.Id {
+		t.Fatalf("trash = %+v", trash)
+	}
+	if n, _ := e.app.PurgeTrash(ctx, now); n != 0 {
+		t.Fatalf("second purge deleted %d", n)
+	}
+
+	e.app.Config.Trash.Retention = 0
+	if n, _ := e.app.PurgeTrash(ctx, now.Add(365*24*time.Hour)); n != 0 {
+		t.Fatalf("purge without retention deleted %d", n)
+	}
+}
+
+func TestGetProductsSearchesText(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedProduct(models.Product{Name: "Chaise Lomé", Price: 120, Materials: []string{"teck"}, Description: "Assise en bois huilé"})
+	e.seedProduct(models.Product{Name: "Tabouret en bois", Price: 40, Colors: []string{"naturel"}})
+	e.seedProduct(models.Product{Name: "Banc", Price: 90, DescriptionFull: "Banc de jardin assorti aux chaises Lomé.",
+		Translations: map[string]models.ProductTranslation{"en": {Name: "Garden bench", DescriptionFull: "Matches the Lomé chairs."}}})
+	e.seedProduct(models.Product{Name: "Table basse", Price: 300, Materials: []string{"métal"}})
+	e.seedProduct(models.Product{Name: "Chaise cachée", Price: 10, IsDisabled: true})
+
+	type hitsPage struct {
+		Items []dto.ProductSearchHitDTO `json:"items"`
+		Total int64                     `json:"total"`
+	}
+	search := func(query string) hitsPage {
+		t.Helper()
+		w := e.do(e.jsonRequest(http.MethodGet, "/products?"+query, nil, ""))
+		expectStatus(t, w, http.StatusOK)
+		return decodeJSON[hitsPage](t, w)
+	}
+	names := func(p hitsPage) string {
+		var out []string
+		for _, h := range p.Items {
+			out = append(out, h.Name)
+		}
+		return strings.Join(out, ", ")
+	}
+
+	// accents and case do not matter; a match in the name outranks the description
+	got := search("q=lome&isDisabled=false")
+	if names(got) != "Chaise Lomé, Banc" || got.Items[0].Score <= got.Items[1].Score {
+		t.Fatalf("q=lome: %s %+v", names(got), got.Items)
+	}
+	if got := search("q=BOIS&isDisabled=false"); names(got) != "Tabouret en bois, Chaise Lomé" {
+		t.Fatalf("q=BOIS: %s", names(got))
+	}
+	// any word matches, plurals included; sort overrides relevance
+	if got := search("q=chaises+metal&isDisabled=false&sort=price_desc"); names(got) != "Table basse, Chaise Lomé, Banc" {
+		t.Fatalf("sorted search: %s", names(got))
+	}
+	if got := search("q=canap%C3%A9"); got.Total != 0 || got.Items == nil {
+		t.Fatalf("no hit: %+v", got)
+	}
+
+	got = search("q=huile+lome&isDisabled=false&highlight=true")
+	if h := got.Items[0].Highlights; h["name"] != "Chaise <mark>Lomé</mark>" || h["description"] != "Assise en bois <mark>huilé</mark>" {
+		t.Fatalf("highlights = %+v", h)
+	}
+	if _, ok := got.Items[0].Highlights["colors"]; ok {
+		t.Fatalf("highlight for a field without match: %+v", got.Items[0].Highlights)
+	}
+	if got := search("q=lome"); got.Items[0].Highlights != nil {
+		t.Fatalf("highlights without highlight=true: %+v", got.Items[0].Highlights)
+	}
+
+	// translations are searched too, and hits come back in the request locale
+	got = search("q=bench&lang=en&highlight=true")
+	if names(got) != "Garden bench" || got.Items[0].Highlights["name"] != "Garden <mark>bench</mark>" {
+		t.Fatalf("english search: %s %+v", names(got), got.Items)
+	}
+
+	w := e.do(e.jsonRequest(http.MethodGet, "/products?q="+strings.Repeat("a", 101), nil, ""))
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestGetProductsFiltersAndCountsFacets(t *testing.T) {
+	e := newTestEnv(t)
+	e.app.Config.Catalog.PriceFacetBounds = []int64{100, 200}
+	chairs := e.seedCategory("Chaises", "chaises")
+	tables := e.seedCategory("Tables", "tables")
+	hidden := models.Category{Name: "Archives", Slug: "archives"}
+	if err := e.app.Categories.Insert(t.Context(), &hidden); err != nil {
+		t.Fatalf("insert category: %v", err)
+	}
+	e.seedProduct(models.Product{Name: "Chaise teck", Price: 80, CategoryIds: []bson.ObjectID{chairs.Id, hidden.Id},
+		Materials: []string{"teck"}, Colors: []string{"naturel"}})
+	e.seedProduct(models.Product{Name: "Chaise rotin", Price: 150, CategoryIds: []bson.ObjectID{chairs.Id},
+		Materials: []string{"rotin"}, Colors: []string{"noir", "naturel"}})
+	e.seedProduct(models.Product{Name: "Table teck", Price: 200, CategoryIds: []bson.ObjectID{tables.Id},
+		Materials: []string{"teck", "métal"}, Colors: []string{"noir"}})
+	e.seedProduct(models.Product{Name: "Table cachée", Price: 90, CategoryIds: []bson.ObjectID{tables.Id},
+		Materials: []string{"teck"}, IsDisabled: true})
+
+	type facetsPage struct {
+		Items  []models.Product     `json:"items"`
+		Total  int64                `json:"total"`
+		Facets dto.ProductFacetsDTO `json:"facets"`
+	}
+	list := func(query string) facetsPage {
+		t.Helper()
+		w := e.do(e.jsonRequest(http.MethodGet, "/products?isDisabled=false&"+query, nil, ""))
+		expectStatus(t, w, http.StatusOK)
+		return decodeJSON[facetsPage](t, w)
+	}
+	names := func(p facetsPage) string {
+		var out []string
+		for _, prod := range p.Items {
+			out = append(out, prod.Name)
+		}
+		return strings.Join(out, ", ")
+	}
+	values := func(facet []dto.FacetValueDTO) string {
+		var out []string
+		for _, v := range facet {
+			out = append(out, fmt.Sprintf("%s:%d", v.Value, v.Count))
+		}
+		return strings.Join(out, " ")
+	}
+
+	// values of one filter are alternatives, filters combine
+	if got := list("materials=teck,rotin&colors=noir"); names(got) != "Chaise rotin, Table teck" {
+		t.Fatalf("materials and colors: %s", names(got))
+	}
+	if got := list("materials=teck&materials=rotin"); got.Total != 3 {
+		t.Fatalf("repeated param: total %d", got.Total)
+	}
+	if got := list("minPrice=90&maxPrice=150"); names(got) != "Chaise rotin" {
+		t.Fatalf("price range: %s", names(got))
+	}
+	if got := list("category=tables,inconnue"); names(got) != "Table teck" {
+		t.Fatalf("category: %s", names(got))
+	}
+	if got := list("category=inconnue"); got.Total != 0 || len(got.Items) != 0 {
+		t.Fatalf("unknown category: %+v", got)
+	}
+
+	// each facet ignores its own filter but applies the others
+	got := list("colors=noir&materials=teck")
+	if names(got) != "Table teck" {
+		t.Fatalf("filtered listing: %s", names(got))
+	}
+	f := got.Facets
+	if values(f.Colors) != "naturel:1 noir:1" {
+		t.Fatalf("colors facet = %s", values(f.Colors))
+	}
+	if values(f.Materials) != "métal:1 rotin:1 teck:1" {
+		t.Fatalf("materials facet = %s", values(f.Materials))
+	}
+	if len(f.Categories) != 1 || f.Categories[0].Slug != "tables" || f.Categories[0].Count != 1 {
+		t.Fatalf("categories facet = %+v", f.Categories)
+	}
+
+	// every bucket is listed, the last one is open; only active categories count
+	f = list("").Facets
+	if len(f.Prices) != 3 {
+		t.Fatalf("price buckets = %+v", f.Prices)
+	}
+	for i, want := range []struct {
+		min   int64
+		max   int64
+		count int64
+	}{{0, 100, 1}, {100, 200, 1}, {200, -1, 1}} {
+		b := f.Prices[i]
+		if b.Min != want.min || b.Count != want.count || (want.max < 0) != (b.Max == nil) || (b.Max != nil && *b.Max != want.max) {
+			t.Fatalf("price bucket %d = %+v", i, b)
+		}
+	}
+	if len(f.Categories) != 2 || f.Categories[0].Slug != "chaises" || f.Categories[0].Count != 2 || f.Categories[1].Count != 1 {
+		t.Fatalf("categories facet = %+v", f.Categories)
+	}
+	if values(f.Materials) != "teck:2 métal:1 rotin:1" {
+		t.Fatalf("materials facet = %s", values(f.Materials))
+	}
+
+	for _, query := range []string{"minPrice=abc", "maxPrice=-1", "minPrice=200&maxPrice=100"} {
+		w := e.do(e.jsonRequest(http.MethodGet, "/products?"+query, nil, ""))
+		expectStatus(t, w, http.StatusBadRequest)
+		if body := decodeJSON[apierror.Envelope](t, w); body.Error.Code != apierror.CodeValidationFailed {
+			t.Fatalf("%s: code %s", query, body.Error.Code)
+		}
+	}
+}
+
+func TestAdminManagesProductVariants(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	cat := e.seedCategory("Chaises", "chaises")
+
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/products/add",
+		gin.H{"name": "Chaise Lomé", "price": 120, "quantity": 1, "categoryIds": []string{cat.Id.Hex()}},
+		[]testFile{
+			{field: "images", name: "a.png", content: pngBytes(t), mimeType: "image/png"},
+			{field: "images", name: "b.png", content: pngBytes(t), mimeType: "image/png"},
+		}, token))
+	expectStatus(t, w, http.StatusCreated)
+	chair := decodeJSON[models.Product](t, w)
+	variants := "/admin/products/" + chair.Id.Hex() + "/variants"
+
+	addVariant := func(body gin.H, status int) models.ProductVariant {
+		t.Helper()
+		w := e.do(e.jsonRequest(http.MethodPost, variants, body, token))
+		expectStatus(t, w, status)
+		return decodeJSON[models.ProductVariant](t, w)
+	}
+	teak := addVariant(gin.H{
+		"sku": " CH-TECK ", "options": []gin.H{{"name": "Finition", "value": "Teck"}},
+		"price": 150, "quantity": 3, "imageUrls": []string{chair.Images[0].URL},
+	}, http.StatusCreated)
+	// the first variant takes over the stock of the chair
+	if teak.Id.IsZero() || teak.SKU != "CH-TECK" || teak.Price == nil || *teak.Price != 150 || teak.Quantity != 4 {
+		t.Fatalf("created variant = %+v", teak)
+	}
+	rattan := addVariant(gin.H{"sku": "CH-ROTIN", "options": []gin.H{{"name": "Finition", "value": "Rotin"}}}, http.StatusCreated)
+
+	addVariant(gin.H{"sku": "ch-teck", "options": []gin.H{{"name": "Finition", "value": "Noyer"}}}, http.StatusConflict)
+	addVariant(gin.H{"sku": "CH-2", "options": []gin.H{{"name": "finition", "value": "TECK"}}}, http.StatusBadRequest)
+	addVariant(gin.H{"sku": "CH-3", "options": []gin.H{{"name": "Finition", "value": "Noyer"}, {"name": "finition", "value": "Teck"}}}, http.StatusBadRequest)
+	addVariant(gin.H{"sku": "CH-4", "options": []gin.H{{"name": "Finition", "value": "Noyer"}}, "imageUrls": []string{"https://elsewhere/x.png"}}, http.StatusBadRequest)
+	addVariant(gin.H{"sku": "CH-5"}, http.StatusBadRequest)
+	// SKUs are unique across products too
+	stool := e.seedProduct(models.Product{Name: "Tabouret", Price: 40})
+	w = e.do(e.jsonRequest(http.MethodPost, "/admin/products/"+stool.Id.Hex()+"/variants",
+		gin.H{"sku": "CH-TECK", "options": []gin.H{{"name": "Finition", "value": "Teck"}}}, token))
+	expectStatus(t, w, http.StatusConflict)
+
+	// the storefront sees the variants
+	w = e.do(e.jsonRequest(http.MethodGet, "/products/"+chair.Id.Hex(), nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	detail := decodeJSON[dto.ProductDetailDTO](t, w)
+	if len(detail.Variants) != 2 || detail.Variants[0].Label() != "Teck" || detail.Variants[1].Price != nil {
+		t.Fatalf("variants = %+v", detail.Variants)
+	}
+
+	teakPath := variants + "/" + teak.Id.Hex()
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPatch, teakPath, gin.H{"price": 0, "quantity": 5}, token)), http.StatusOK)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPatch, teakPath, gin.H{}, token)), http.StatusBadRequest)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPatch, teakPath, gin.H{"sku": "CH-ROTIN"}, token)), http.StatusConflict)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPatch, variants+"/"+bson.NewObjectID().Hex(), gin.H{"quantity": 1}, token)), http.StatusNotFound)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPatch, variants+"/bad", gin.H{"quantity": 1}, token)), http.StatusBadRequest)
+
+	// removing a product image drops it from the variants
+	w = e.do(e.multipartRequest(http.MethodPatch, "/admin/products/update/"+chair.Id.Hex(),
+		gin.H{"removedImagesUrls": []string{chair.Images[0].URL}}, nil, token))
+	expectStatus(t, w, http.StatusOK)
+
+	expectStatus(t, e.do(e.jsonRequest(http.MethodDelete, variants+"/"+rattan.Id.Hex(), nil, token)), http.StatusOK)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodDelete, variants+"/"+rattan.Id.Hex(), nil, token)), http.StatusNotFound)
+
+	stored, err := e.app.Products.FindByID(t.Context(), chair.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Variants) != 1 || stored.Quantity != 0 {
+		t.Fatalf("after delete = %+v", stored)
+	}
+	if v := stored.Variants[0]; v.Id != teak.Id || v.Price != nil || v.Quantity != 5 || len(v.ImageUrls) != 0 {
+		t.Fatalf("updated variant = %+v", v)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func productNames(items []models.Product) []string {
+	names := make([]string, len(items))
+	for i, p := range items {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func TestShopOnlyShowsProductsOnSale(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	past, future := time.Now().UTC().Add(-time.Hour), time.Now().UTC().Add(time.Hour)
+
+	e.seedProduct(models.Product{Name: "Ancien"}) // saved before the lifecycle
+	e.seedProduct(models.Product{Name: "Banc", Status: models.ProductStatusPublished, UnpublishAt: &future})
+	e.seedProduct(models.Product{Name: "Chaise", Status: models.ProductStatusScheduled, PublishAt: &past})
+	draft := e.seedProduct(models.Product{Name: "Desserte", Status: models.ProductStatusDraft})
+	e.seedProduct(models.Product{Name: "Etagere", Status: models.ProductStatusScheduled, PublishAt: &future})
+	expired := e.seedProduct(models.Product{Name: "Fauteuil", Status: models.ProductStatusPublished, UnpublishAt: &past})
+	e.seedProduct(models.Product{Name: "Guéridon", Status: models.ProductStatusArchived})
+	e.seedProduct(models.Product{Name: "Hamac", SimilarProductsIds: []bson.ObjectID{draft.Id, expired.Id}})
+
+	w := e.do(e.jsonRequest(http.MethodGet, "/products", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	if got := productNames(decodeJSON[productsPage](t, w).Items); !slices.Equal(got, []string{"Ancien", "Banc", "Chaise", "Hamac"}) {
+		t.Fatalf("public products = %v", got)
+	}
+	for _, path := range []string{"/products/" + draft.Id.Hex(), "/products/slug/" + expired.Slug} {
+		expectStatus(t, e.do(e.jsonRequest(http.MethodGet, path, nil, "")), http.StatusNotFound)
+	}
+	w = e.do(e.jsonRequest(http.MethodGet, "/products/slug/hamac", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	if similar := decodeJSON[struct {
+		SimilarProducts []any `json:"similarProducts"`
+	}](t, w).SimilarProducts; len(similar) != 0 {
+		t.Fatalf("similar products = %v", similar)
+	}
+
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/admin/products", nil, "")), http.StatusUnauthorized)
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/products", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if page := decodeJSON[productsPage](t, w); page.Total != 8 {
+		t.Fatalf("admin total = %d, want 8", page.Total)
+	}
+	for status, want := range map[string][]string{
+		"published": {"Ancien", "Banc", "Fauteuil", "Hamac"},
+		"SCHEDULED": {"Chaise", "Etagere"},
+		"DRAFT":     {"Desserte"},
+	} {
+		w = e.do(e.jsonRequest(http.MethodGet, "/admin/products?status="+status, nil, token))
+		expectStatus(t, w, http.StatusOK)
+		if got := productNames(decodeJSON[productsPage](t, w).Items); !slices.Equal(got, want) {
+			t.Fatalf("status %s = %v, want %v", status, got, want)
+		}
+	}
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/admin/products?status=SOLD", nil, token)), http.StatusBadRequest)
+}
+
+func TestAdminSchedulesProducts(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	cat := e.seedCategory("Chaises", "chaises")
+	publishAt := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second)
+
+	// a future publishAt schedules a new product
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/products/add", gin.H{
+		"name": "Chaise Lomé", "price": 150, "categoryIds": []string{cat.Id.Hex()}, "publishAt": publishAt,
+	}, []testFile{{field: "images", name: "front.png", content: pngBytes(t), mimeType: "image/png"}}, token))
+	expectStatus(t, w, http.StatusCreated)
+	created := decodeJSON[models.Product](t, w)
+	if created.Status != models.ProductStatusScheduled || created.PublishAt == nil || !created.PublishAt.Equal(publishAt) {
+		t.Fatalf("created = %+v", created)
+	}
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/products/"+created.Id.Hex(), nil, "")), http.StatusNotFound)
+
+	path := "/admin/products/" + created.Id.Hex() + "/status"
+	for _, body := range []gin.H{
+		{},
+		{"status": "SOLD"},
+		{"status": "SCHEDULED"},
+		{"status": "PUBLISHED", "publishAt": publishAt, "unpublishAt": publishAt.Add(-time.Hour)},
+	} {
+		expectStatus(t, e.do(e.jsonRequest(http.MethodPatch, path, body, token)), http.StatusBadRequest)
+	}
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPatch, "/admin/products/"+bson.NewObjectID().Hex()+"/status",
+		gin.H{"status": "DRAFT"}, token)), http.StatusNotFound)
+
+	w = e.do(e.jsonRequest(http.MethodPatch, path, gin.H{"status": "published"}, token))
+	expectStatus(t, w, http.StatusOK)
+	if p := decodeJSON[models.Product](t, w); p.Status != models.ProductStatusPublished || p.PublishAt != nil {
+		t.Fatalf("published = %+v", p)
+	}
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/products/"+created.Id.Hex(), nil, "")), http.StatusOK)
+}
+
+func TestApplyProductSchedule(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := t.Context()
+	now := time.Now().UTC()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+	due := e.seedProduct(models.Product{Name: "Banc", Status: models.ProductStatusScheduled, PublishAt: &past, UnpublishAt: &future})
+	later := e.seedProduct(models.Product{Name: "Chaise", Status: models.ProductStatusScheduled, PublishAt: &future})
+	expired := e.seedProduct(models.Product{Name: "Table", UnpublishAt: &past})
+	draft := e.seedProduct(models.Product{Name: "Tabouret", Status: models.ProductStatusDraft, UnpublishAt: &past})
+
+	published, archived, err := e.app.ApplyProductSchedule(ctx, now)
+	if err != nil || published != 1 || archived != 1 {
+		t.Fatalf("schedule = %d published, %d archived, %v", published, archived, err)
+	}
+	for id, want := range map[bson.ObjectID]models.ProductStatus{
+		due.Id:     models.ProductStatusPublished,
+		later.Id:   models.ProductStatusScheduled,
+		expired.Id: models.ProductStatusArchived,
+		draft.Id:   models.ProductStatusDraft,
+	} {
+		p, err := e.app.Products.FindByID(ctx, id)
+		if err != nil || p.Status != want {
+			t.Fatalf("%v status = %q, %v; want %s", id, p.Status, err, want)
+		}
+	}
+
+	// the scheduled product is archived once its unpublishAt passes
+	published, archived, _ = e.app.ApplyProductSchedule(ctx, future)
+	if published != 1 || archived != 1 {
+		t.Fatalf("later schedule = %d published, %d archived", published, archived)
+	}
+	live, _, _ := e.app.Products.List(ctx, repositories.ProductFilter{Status: models.ProductStatusPublished}, repositories.Page{})
+	if got := productNames(live); !slices.Equal(got, []string{"Chaise"}) {
+		t.Fatalf("published = %v", got)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type quoteRequestsPage struct {
+	Items []models.QuoteRequest `json:"items"`
+	Total int64                 `json:"total"`
+}
+
+func (e *testEnv) createQuote(items []gin.H) string {
+	e.t.Helper()
+	w := e.do(e.jsonRequest(http.MethodPost, "/quote-requests", gin.H{
+		"fullName": "  Jean Dupont ",
+		"email":    "jean@example.com",
+		"city":     "Lomé",
+		"items":    items,
+	}, ""))
+	expectStatus(e.t, w, http.StatusCreated)
+	return decodeJSON[map[string]string](e.t, w)["id"]
+}
+
+func TestCreateQuoteRequestSnapshotsProducts(t *testing.T) {
+	e := newTestEnv(t)
+	chair := e.seedProduct(models.Product{Name: "Chaise", Price: 120})
+	table := e.seedProduct(models.Product{Name: "Table", Price: 300})
+
+	id := e.createQuote([]gin.H{
+		{"productId": chair.Id.Hex(), "quantity": 4},
+		{"productId": table.Id.Hex(), "quantity": 1},
+	})
+
+	quote, err := e.app.QuoteRequests.FindByID(t.Context(), mustObjectID(t, id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.FullName != "Jean Dupont" || quote.Status != models.QuoteStatusNew {
+		t.Fatalf("stored quote = %+v", quote)
+	}
+	if len(quote.Items) != 2 || quote.Items[0].ProductName != "Chaise" || quote.Items[0].UnitPrice != 120 || quote.Items[0].Quantity != 4 {
+		t.Fatalf("items = %+v", quote.Items)
+	}
+
+	w := e.do(e.jsonRequest(http.MethodPost, "/quote-requests", gin.H{
+		"fullName": "X", "email": "x@example.com",
+		"items": []gin.H{{"productId": "bad", "quantity": 1}},
+	}, ""))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	w = e.do(e.jsonRequest(http.MethodPost, "/quote-requests", gin.H{
+		"fullName": "X", "email": "x@example.com",
+		"items": []gin.H{{"productId": bson.NewObjectID().Hex(), "quantity": 1}},
+	}, ""))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	// products off sale are as good as missing
+	later := time.Now().Add(time.Hour)
+	for _, hidden := range []models.Product{
+		{Name: "Brouillon", Price: 10, Status: models.ProductStatusDraft},
+		{Name: "Archivé", Price: 10, Status: models.ProductStatusArchived},
+		{Name: "Bientôt", Price: 10, Status: models.ProductStatusScheduled, PublishAt: &later},
+		{Name: "Masqué", Price: 10, IsDisabled: true},
+	} {
+		hidden = e.seedProduct(hidden)
+		w = e.do(e.jsonRequest(http.MethodPost, "/quote-requests", gin.H{
+			"fullName": "X", "email": "x@example.com",
+			"items": []gin.H{{"productId": hidden.Id.Hex(), "quantity": 1}},
+		}, ""))
+		expectStatus(t, w, http.StatusBadRequest)
+		if f := decodeJSON[apierror.Envelope](t, w).Error.Fields; len(f) != 1 || f[0].Rule != apierror.RuleExists {
+			t.Fatalf("%s: fields = %+v", hidden.Name, f)
+		}
+	}
+
+	w = e.do(e.jsonRequest(http.MethodPost, "/quote-requests", gin.H{"fullName": "X", "email": "x@example.com"}, ""))
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestAdminQuoteRequestWorkflow(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	chair := e.seedProduct(models.Product{Name: "Chaise", Price: 120})
+	first := e.createQuote([]gin.H{{"productId": chair.Id.Hex(), "quantity": 1}})
+	second := e.createQuote([]gin.H{{"productId": chair.Id.Hex(), "quantity": 2}})
+
+	w := e.do(e.jsonRequest(http.MethodGet, "/admin/quote-requests", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeJSON[quoteRequestsPage](t, w); got.Total != 2 {
+		t.Fatalf("listing = %+v", got)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/quote-requests/"+first, nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeJSON[models.QuoteRequest](t, w); got.ID.Hex() != first {
+		t.Fatalf("get returned %+v", got)
+	}
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/quote-requests/"+bson.NewObjectID().Hex(), nil, token))
+	expectStatus(t, w, http.StatusNotFound)
+
+	// the attachment must be a complete PDF
+	for _, content := range [][]byte{[]byte("%PDF-1.4\n1 0 obj<<"), pngBytes(t)} {
+		w = e.do(e.multipartRequest(http.MethodPost, "/admin/quote-requests/"+first+"/notes",
+			gin.H{"content": "Voici le devis."},
+			[]testFile{{field: "pdf", name: "devis.pdf", content: content, mimeType: "application/pdf"}},
+			token))
+		expectStatus(t, w, http.StatusBadRequest)
+	}
+
+	// first note auto-advances NEW → IN_PROGRESS and stores the PDF
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/quote-requests/"+first+"/notes",
+		gin.H{"content": "Voici le devis."},
+		[]testFile{{field: "pdf", name: "devis.pdf", content: pdfBytes(), mimeType: "application/pdf"}},
+		token))
+	expectStatus(t, w, http.StatusCreated)
+	note := decodeJSON[models.QuoteAdminNote](t, w)
+	if note.AuthorEmail != testAdminEmail || note.QuotePDF == nil || !e.stored(note.QuotePDF.ObjectName) {
+		t.Fatalf("note = %+v; store has %v", note, e.storedObjects())
+	}
+
+	quote, _ := e.app.QuoteRequests.FindByID(t.Context(), mustObjectID(t, first))
+	if quote.Status != models.QuoteStatusInProgress || len(quote.Notes) != 1 {
+		t.Fatalf("after first note: status=%s notes=%d", quote.Status, len(quote.Notes))
+	}
+
+	// a QUOTED request keeps its status when more notes arrive
+	w = e.do(e.jsonRequest(http.MethodPatch, "/admin/quote-requests/"+second+"/status", gin.H{"status": "QUOTED"}, token))
+	expectStatus(t, w, http.StatusOK)
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/quote-requests/"+second+"/notes", gin.H{"content": "Relance"}, nil, token))
+	expectStatus(t, w, http.StatusCreated)
+
+	quote, _ = e.app.QuoteRequests.FindByID(t.Context(), mustObjectID(t, second))
+	if quote.Status != models.QuoteStatusQuoted || quote.QuotedAt == nil || len(quote.Notes) != 1 {
+		t.Fatalf("quoted request = status %s quotedAt %v notes %d", quote.Status, quote.QuotedAt, len(quote.Notes))
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/quote-requests?status=QUOTED", nil, token))
+	if got := decodeJSON[quoteRequestsPage](t, w); got.Total != 1 || got.Items[0].ID.Hex() != second {
+		t.Fatalf("status listing = %+v", got)
+	}
+
+	// validation
+	w = e.do(e.jsonRequest(http.MethodPatch, "/admin/quote-requests/"+first+"/status", gin.H{"status": "LOST"}, token))
+	expectStatus(t, w, http.StatusBadRequest)
+	w = e.do(e.jsonRequest(http.MethodPatch, "/admin/quote-requests/"+bson.NewObjectID().Hex()+"/status", gin.H{"status": "CLOSED"}, token))
+	expectStatus(t, w, http.StatusNotFound)
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/quote-requests/"+first+"/notes", gin.H{"content": "  "}, nil, token))
+	expectStatus(t, w, http.StatusBadRequest)
+	w = e.do(e.multipartRequest(http.MethodPost, "/admin/quote-requests/"+bson.NewObjectID().Hex()+"/notes", gin.H{"content": "?"}, nil, token))
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestCreateQuoteRequestSnapshotsVariants(t *testing.T) {
+	e := newTestEnv(t)
+	override := int64(150)
+	chair := e.seedProduct(models.Product{Name: "Chaise", Price: 120, Variants: []models.ProductVariant{
+		{Id: bson.NewObjectID(), SKU: "CH-TECK", Options: []models.VariantOption{{Name: "Finition", Value: "Teck"}, {Name: "Assise", Value: "Lin"}}, Price: &override},
+		{Id: bson.NewObjectID(), SKU: "CH-ROTIN", Options: []models.VariantOption{{Name: "Finition", Value: "Rotin"}}},
+	}})
+	stool := e.seedProduct(models.Product{Name: "Tabouret", Price: 40})
+	teak, rattan := chair.Variants[0], chair.Variants[1]
+
+	// the same product may be quoted in several variants
+	id := e.createQuote([]gin.H{
+		{"productId": chair.Id.Hex(), "variantId": teak.Id.Hex(), "quantity": 2},
+		{"productId": chair.Id.Hex(), "variantId": rattan.Id.Hex(), "quantity": 1},
+		{"productId": stool.Id.Hex(), "quantity": 3},
+	})
+	quote, err := e.app.QuoteRequests.FindByID(t.Context(), mustObjectID(t, id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := quote.Items
+	if len(items) != 3 {
+		t.Fatalf("items = %+v", items)
+	}
+	if items[0].VariantID == nil || *items[0].VariantID != teak.Id || items[0].VariantSKU != "CH-TECK" ||
+		items[0].VariantLabel != "Teck / Lin" || items[0].UnitPrice != 150 || items[0].Quantity != 2 {
+		t.Fatalf("teak item = %+v", items[0])
+	}
+	if items[1].VariantLabel != "Rotin" || items[1].UnitPrice != 120 || items[1].Quantity != 1 {
+		t.Fatalf("rattan item = %+v", items[1])
+	}
+	if items[2].VariantID != nil || items[2].UnitPrice != 40 {
+		t.Fatalf("stool item = %+v", items[2])
+	}
+
+	for _, item := range []gin.H{
+		{"productId": chair.Id.Hex(), "quantity": 1},
+		{"productId": chair.Id.Hex(), "variantId": "bad", "quantity": 1},
+		{"productId": chair.Id.Hex(), "variantId": bson.NewObjectID().Hex(), "quantity": 1},
+	} {
+		w := e.do(e.jsonRequest(http.MethodPost, "/quote-requests", gin.H{
+			"fullName": "X", "email": "x@example.com", "items": []gin.H{item},
+		}, ""))
+		expectStatus(t, w, http.StatusBadRequest)
+		if body := decodeJSON[apierror.Envelope](t, w); len(body.Error.Fields) != 1 || body.Error.Fields[0].Field != "items[0].variantId" {
+			t.Fatalf("%v: %+v", item, body.Error)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/search"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// CategoryFilter narrows a category listing. Empty/nil fields are ignored.
+type CategoryFilter struct {
+	// Name is matched anywhere in the name, ignoring case and accents.
+	Name     string
+	IsActive *bool
+	// MissingTranslation keeps the categories with a field that is set but
+	// not translated in this locale.
+	MissingTranslation string
+}
+
+type CategoryRepository interface {
+	List(ctx context.Context, filter CategoryFilter, page Page) ([]models.Category, int64, error)
+	// Count is the total List returns, without loading the categories.
+	Count(ctx context.Context, filter CategoryFilter) (int64, error)
+	FindByID(ctx context.Context, id bson.ObjectID) (*models.Category, error)
+	FindBySlug(ctx context.Context, slug string) (*models.Category, error)
+	FindByIDs(ctx context.Context, ids []bson.ObjectID) ([]models.Category, error)
+	Insert(ctx context.Context, category *models.Category) error
+	Update(ctx context.Context, id bson.ObjectID, set bson.M) error
+	Delete(ctx context.Context, id bson.ObjectID) error
+	// WithLegacyImage returns the categories whose image was saved before
+	// renditions existed (see models.Image.Processed).
+	WithLegacyImage(ctx context.Context) ([]models.Category, error)
+}
+
+type mongoCategoryRepository struct {
+	col *mongo.Collection
+}
+
+func NewCategoryRepository(db *mongo.Database) CategoryRepository {
+	return &mongoCategoryRepository{col: db.Collection("categories")}
+}
+
+func (r *mongoCategoryRepository) List(ctx context.Context, filter CategoryFilter, page Page) ([]models.Category, int64, error) {
+	return findPage[models.Category](ctx, r.col, categoryQuery(filter), bson.D{{Key: "name", Value: 1}}, page)
+}
+
+func (r *mongoCategoryRepository) Count(ctx context.Context, filter CategoryFilter) (int64, error) {
+	return r.col.CountDocuments(ctx, categoryQuery(filter))
+}
+
+func categoryQuery(filter CategoryFilter) bson.M {
+	query := bson.M{}
+	if filter.Name != "" {
+		query["name"] = bson.M{"$regex": search.Pattern(filter.Name)}
+	}
+	if filter.IsActive != nil {
+		query["isActive"] = *filter.IsActive
+	}
+	if filter.MissingTranslation != "" {
+		query["$and"] = bson.A{missingTranslationQuery(filter.MissingTranslation, models.CategoryTranslatedFields)}
+	}
+	return query
+}
+
+func (r *mongoCategoryRepository) FindByID(ctx context.Context, id bson.ObjectID) (*models.Category, error) {
+	return findOne[models.Category](ctx, r.col, bson.M{"_id": id})
+}
+
+func (r *mongoCategoryRepository) FindBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	return findOne[models.Category](ctx, r.col, bson.M{"slug": slug})
+}
+
+func (r *mongoCategoryRepository) FindByIDs(ctx context.Context, ids []bson.ObjectID) ([]models.Category, error) {
+	items, _, err := findPage[models.Category](ctx, r.col, bson.M{"_id": bson.M{"$in": ids}}, bson.D{{Key: "_id", Value: 1}}, Page{})
+	return items, err
+}
+
+func (r *mongoCategoryRepository) Insert(ctx context.Context, category *models.Category) error {
+	if category.Id.IsZero() {
+		category.Id = bson.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, category)
+	return translateError(err)
+}
+
+func (r *mongoCategoryRepository) Update(ctx context.Context, id bson.ObjectID, set bson.M) error {
+	return updateByID(ctx, r.col, id, set)
+}
+
+func (r *mongoCategoryRepository) Delete(ctx context.Context, id bson.ObjectID) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoCategoryRepository) WithLegacyImage(ctx context.Context) ([]models.Category, error) {
+	legacy := bson.M{"image": bson.M{"$type": "object"}, "$expr": bson.M{"$eq": bson.A{"$image.url", "$image.thumbnail"}}}
+	items, _, err := findPage[models.Category](ctx, r.col, legacy, bson.D{{Key: "_id", Value: 1}}, Page{})
+	return items, err
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ExchangeRateRepository holds one rate per currency.
+type ExchangeRateRepository interface {
+	// List returns every rate, by currency code.
+	List(ctx context.Context) ([]models.ExchangeRate, error)
+	FindByCurrency(ctx context.Context, currency string) (*models.ExchangeRate, error)
+	// Upsert sets the rate of its currency, creating it if needed, and
+	// fills in its ID.
+	Upsert(ctx context.Context, rate *models.ExchangeRate) error
+	Delete(ctx context.Context, currency string) error
+}
+
+type mongoExchangeRateRepository struct {
+	col *mongo.Collection
+}
+
+func NewExchangeRateRepository(db *mongo.Database) ExchangeRateRepository {
+	return &mongoExchangeRateRepository{col: db.Collection("exchange_rates")}
+}
+
+func (r *mongoExchangeRateRepository) List(ctx context.Context) ([]models.ExchangeRate, error) {
+	items, _, err := findPage[models.ExchangeRate](ctx, r.col, bson.M{}, bson.D{{Key: "currency", Value: 1}}, Page{})
+	return items, err
+}
+
+func (r *mongoExchangeRateRepository) FindByCurrency(ctx context.Context, currency string) (*models.ExchangeRate, error) {
+	return findOne[models.ExchangeRate](ctx, r.col, bson.M{"currency": currency})
+}
+
+func (r *mongoExchangeRateRepository) Upsert(ctx context.Context, rate *models.ExchangeRate) error {
+	var saved models.ExchangeRate
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{"currency": rate.Currency},
+		bson.M{"$set": bson.M{"rate": rate.Rate, "updatedAt": rate.UpdatedAt, "updatedBy": rate.UpdatedBy}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&saved)
+	if err != nil {
+		return translateError(err)
+	}
+	rate.ID = saved.ID
+	return nil
+}
+
+func (r *mongoExchangeRateRepository) Delete(ctx context.Context, currency string) error {
+	res, err := r.col.DeleteOne(ctx, bson.M{"currency": currency})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
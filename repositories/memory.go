@@ -0,0 +1,237 @@
+package repositories
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// memoryCollection is the storage shared by the in-memory repositories. It
+// keeps documents in insertion order (which mirrors _id order in Mongo) and
+// round-trips every read and write through BSON, so callers never alias the
+// stored values and field names follow the same bson tags as the real
+// collections.
+type memoryCollection[T any] struct {
+	mu   sync.RWMutex
+	docs []*T
+
+	id func(*T) bson.ObjectID
+	// unique returns the values of the collection's unique keys (slug,
+	// email...), prefixed when a collection has several indexes. Empty values
+	// are not indexed, like a sparse index.
+	unique func(*T) []string
+}
+
+func newMemoryCollection[T any](id func(*T) bson.ObjectID, unique func(*T) []string) *memoryCollection[T] {
+	return &memoryCollection[T]{id: id, unique: unique}
+}
+
+func cloneDoc[T any](doc *T) *T {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		panic(fmt.Sprintf("memory repository: marshal %T: %v", doc, err))
+	}
+	var out T
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		panic(fmt.Sprintf("memory repository: unmarshal %T: %v", doc, err))
+	}
+	return &out
+}
+
+func (m *memoryCollection[T]) duplicateLocked(doc *T, skip bson.ObjectID) bool {
+	if m.unique == nil {
+		return false
+	}
+	keys := slices.DeleteFunc(m.unique(doc), func(k string) bool { return k == "" })
+	if len(keys) == 0 {
+		return false
+	}
+	for _, d := range m.docs {
+		if m.id(d) == skip {
+			continue
+		}
+		for _, k := range m.unique(d) {
+			if slices.Contains(keys, k) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *memoryCollection[T]) insert(doc *T) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.id(doc)
+	for _, d := range m.docs {
+		if m.id(d) == id {
+			return ErrDuplicateKey
+		}
+	}
+	if m.duplicateLocked(doc, id) {
+		return ErrDuplicateKey
+	}
+	m.docs = append(m.docs, cloneDoc(doc))
+	return nil
+}
+
+// find returns copies of every document accepted by match, in insertion order.
+func (m *memoryCollection[T]) find(match func(*T) bool) []T {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]T, 0)
+	for _, d := range m.docs {
+		if match == nil || match(d) {
+			out = append(out, *cloneDoc(d))
+		}
+	}
+	return out
+}
+
+func (m *memoryCollection[T]) findOne(match func(*T) bool) (*T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, d := range m.docs {
+		if match(d) {
+			return cloneDoc(d), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *memoryCollection[T]) findByID(id bson.ObjectID) (*T, error) {
+	return m.findOne(func(d *T) bool { return m.id(d) == id })
+}
+
+// mutate applies fn to the first document accepted by match. fn works on a
+// copy; the change is only stored if it keeps the unique key free.
+func (m *memoryCollection[T]) mutate(match func(*T) bool, fn func(*T) error) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, d := range m.docs {
+		if !match(d) {
+			continue
+		}
+		updated := cloneDoc(d)
+		if err := fn(updated); err != nil {
+			return true, err
+		}
+		if m.duplicateLocked(updated, m.id(d)) {
+			return true, ErrDuplicateKey
+		}
+		m.docs[i] = cloneDoc(updated)
+		return true, nil
+	}
+	return false, nil
+}
+
+// mutateAll applies fn to every document accepted by match.
+func (m *memoryCollection[T]) mutateAll(match func(*T) bool, fn func(*T)) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for i, d := range m.docs {
+		if match(d) {
+			updated := cloneDoc(d)
+			fn(updated)
+			m.docs[i] = updated
+			n++
+		}
+	}
+	return n
+}
+
+func (m *memoryCollection[T]) updateByID(id bson.ObjectID, fn func(*T) error) error {
+	found, err := m.mutate(func(d *T) bool { return m.id(d) == id }, fn)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// setByID emulates {$set: set} on one document. Keys use the bson field
+// names and may be dotted paths into embedded documents.
+func (m *memoryCollection[T]) setByID(id bson.ObjectID, set bson.M) error {
+	return m.updateByID(id, func(doc *T) error {
+		return applySet(doc, set)
+	})
+}
+
+func (m *memoryCollection[T]) deleteByID(id bson.ObjectID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, d := range m.docs {
+		if m.id(d) == id {
+			m.docs = slices.Delete(m.docs, i, i+1)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func applySet[T any](doc *T, set bson.M) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		return err
+	}
+
+	for key, value := range set {
+		path := strings.Split(key, ".")
+		target := m
+		for _, part := range path[:len(path)-1] {
+			var next bson.M
+			switch v := target[part].(type) {
+			case bson.M:
+				next = v
+			case bson.D:
+				next = bson.M{}
+				for _, e := range v {
+					next[e.Key] = e.Value
+				}
+			default:
+				next = bson.M{}
+			}
+			target[part] = next
+			target = next
+		}
+		target[path[len(path)-1]] = value
+	}
+
+	raw, err = bson.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var out T
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		return err
+	}
+	*doc = out
+	return nil
+}
+
+// pageOf slices items according to page and returns the pre-paging total.
+func pageOf[T any](items []T, page Page) ([]T, int64) {
+	total := int64(len(items))
+	start := min(page.Skip, total)
+	end := total
+	if page.Limit > 0 {
+		end = min(start+page.Limit, total)
+	}
+	return items[start:end], total
+}
@@ -0,0 +1,809 @@
+package repositories
+
+import (
+	"cmp"
+	"context"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/search"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// The in-memory repositories implement the same contracts as the Mongo ones
+// (filters, sort order, unique keys, note auto-advance) so handlers can be
+// exercised without a database. They are meant for tests and local demos.
+
+// ---- Products ---------------------------------------------------------------
+
+type memoryProductRepository struct {
+	docs *memoryCollection[models.Product]
+}
+
+func NewMemoryProductRepository() ProductRepository {
+	return &memoryProductRepository{docs: newMemoryCollection(
+		func(p *models.Product) bson.ObjectID { return p.Id },
+		func(p *models.Product) []string {
+			// like the slug_unique and variants_sku_unique indexes
+			keys := []string{"slug:" + p.Slug}
+			if p.Slug == "" {
+				keys = nil
+			}
+			for _, v := range p.Variants {
+				if v.SKU != "" {
+					keys = append(keys, "sku:"+v.SKU)
+				}
+			}
+			return keys
+		},
+	)}
+}
+
+func (r *memoryProductRepository) List(_ context.Context, filter ProductFilter, page Page) ([]models.Product, int64, error) {
+	items := r.docs.find(func(p *models.Product) bool { return matchProduct(p, filter) })
+	sortProducts(items, filter.Sort, func(p models.Product) models.Product { return p })
+
+	items, total := pageOf(items, page)
+	return items, total, nil
+}
+
+func (r *memoryProductRepository) Count(ctx context.Context, filter ProductFilter) (int64, error) {
+	_, total, err := r.List(ctx, filter, Page{})
+	return total, err
+}
+
+// Search scores a product by the weighted number of words matching the
+// query in each field. Mongo's text score differs in scale, not in order
+// for the simple queries the tests use.
+func (r *memoryProductRepository) Search(_ context.Context, q string, filter ProductFilter, page Page) ([]ProductHit, int64, error) {
+	terms := search.Terms(q)
+	hits := make([]ProductHit, 0)
+	for _, p := range r.docs.find(func(p *models.Product) bool { return matchProduct(p, filter) }) {
+		if score := searchScore(&p, terms); score > 0 {
+			hits = append(hits, ProductHit{Product: p, Score: score})
+		}
+	}
+
+	if filter.Sort == ProductSortName {
+		slices.SortStableFunc(hits, func(a, b ProductHit) int {
+			return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Name, b.Name))
+		})
+	} else {
+		sortProducts(hits, filter.Sort, func(h ProductHit) models.Product { return h.Product })
+	}
+
+	hits, total := pageOf(hits, page)
+	return hits, total, nil
+}
+
+func searchScore(p *models.Product, terms []string) float64 {
+	fields := map[string]string{
+		"name":            p.Name,
+		"materials":       strings.Join(p.Materials, " "),
+		"colors":          strings.Join(p.Colors, " "),
+		"description":     p.Description,
+		"descriptionFull": p.DescriptionFull,
+	}
+	score := 0.0
+	for field, weight := range search.ProductWeights {
+		score += float64(weight * search.Count(fields[field], terms))
+	}
+	// like the text index, every translation counts as much as the field it translates
+	for _, t := range p.Translations {
+		for field, text := range map[string]string{"name": t.Name, "description": t.Description, "descriptionFull": t.DescriptionFull} {
+			score += float64(search.ProductWeights[field] * search.Count(text, terms))
+		}
+	}
+	return score
+}
+
+func (r *memoryProductRepository) Facets(_ context.Context, q string, filter ProductFilter, priceBounds []int64) (*ProductFacets, error) {
+	terms := search.Terms(q)
+	products := r.docs.find(func(p *models.Product) bool { return q == "" || searchScore(p, terms) > 0 })
+
+	count := func(facet string, values func(p *models.Product) []string) []ValueCount {
+		counts := map[string]int64{}
+		f := withoutFacet(filter, facet)
+		for i := range products {
+			if matchProduct(&products[i], f) {
+				for _, v := range values(&products[i]) {
+					counts[v]++
+				}
+			}
+		}
+		out := make([]ValueCount, 0, len(counts))
+		for v, n := range counts {
+			out = append(out, ValueCount{Value: v, Count: n})
+		}
+		slices.SortFunc(out, func(a, b ValueCount) int { return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Value, b.Value)) })
+		return out
+	}
+
+	facets := &ProductFacets{
+		Materials: count(FacetMaterials, func(p *models.Product) []string { return p.Materials }),
+		Colors:    count(FacetColors, func(p *models.Product) []string { return p.Colors }),
+	}
+	for _, c := range count(FacetCategories, func(p *models.Product) []string {
+		hexes := make([]string, len(p.CategoryIds))
+		for i, id := range p.CategoryIds {
+			hexes[i] = id.Hex()
+		}
+		return hexes
+	}) {
+		id, _ := bson.ObjectIDFromHex(c.Value)
+		facets.Categories = append(facets.Categories, CategoryCount{ID: id, Count: c.Count})
+	}
+	if facets.Categories == nil {
+		facets.Categories = []CategoryCount{}
+	}
+
+	prices := map[int64]int64{}
+	f := withoutFacet(filter, FacetPrice)
+	for i := range products {
+		if !matchProduct(&products[i], f) {
+			continue
+		}
+		lower := int64(0)
+		for _, bound := range priceBounds {
+			if products[i].Price < bound {
+				break
+			}
+			lower = bound
+		}
+		prices[lower]++
+	}
+	facets.Prices = priceBuckets(priceBounds, prices)
+	return facets, nil
+}
+
+func matchProduct(p *models.Product, filter ProductFilter) bool {
+	if (p.DeletedAt != nil) != filter.Trashed {
+		return false
+	}
+	if len(filter.CategoryIDs) > 0 && !slices.ContainsFunc(p.CategoryIds, func(id bson.ObjectID) bool { return slices.Contains(filter.CategoryIDs, id) }) {
+		return false
+	}
+	if len(filter.Materials) > 0 && !slices.ContainsFunc(p.Materials, func(m string) bool { return slices.Contains(filter.Materials, m) }) {
+		return false
+	}
+	if len(filter.Colors) > 0 && !slices.ContainsFunc(p.Colors, func(c string) bool { return slices.Contains(filter.Colors, c) }) {
+		return false
+	}
+	if filter.MinPrice != nil && p.Price < *filter.MinPrice {
+		return false
+	}
+	if filter.MaxPrice != nil && p.Price > *filter.MaxPrice {
+		return false
+	}
+	if filter.IsTrending != nil && p.IsTrending != *filter.IsTrending {
+		return false
+	}
+	if filter.IsDisabled != nil && p.IsDisabled != *filter.IsDisabled {
+		return false
+	}
+	if filter.Status != "" && cmp.Or(p.Status, models.ProductStatusPublished) != filter.Status {
+		return false
+	}
+	if filter.VisibleAt != nil && !p.IsPublished(*filter.VisibleAt) {
+		return false
+	}
+	if filter.MissingTranslation != "" && len(p.MissingTranslations(filter.MissingTranslation)) == 0 {
+		return false
+	}
+	return true
+}
+
+func sortProducts[T any](items []T, sort ProductSort, product func(T) models.Product) {
+	switch sort {
+	case ProductSortPriceAsc:
+		slices.SortStableFunc(items, func(a, b T) int { return cmp.Compare(product(a).Price, product(b).Price) })
+	case ProductSortPriceDesc:
+		slices.SortStableFunc(items, func(a, b T) int { return cmp.Compare(product(b).Price, product(a).Price) })
+	case ProductSortStockAsc, ProductSortStockDesc:
+		// products carry no createdAt; Mongo falls back to natural order
+	default:
+		slices.SortStableFunc(items, func(a, b T) int { return cmp.Compare(product(a).Name, product(b).Name) })
+	}
+}
+
+func (r *memoryProductRepository) FindByID(_ context.Context, id bson.ObjectID) (*models.Product, error) {
+	return r.docs.findOne(func(p *models.Product) bool { return p.Id == id && p.DeletedAt == nil })
+}
+
+func (r *memoryProductRepository) FindBySlug(_ context.Context, slug string) (*models.Product, error) {
+	return r.docs.findOne(func(p *models.Product) bool { return p.Slug == slug && p.DeletedAt == nil })
+}
+
+func (r *memoryProductRepository) FindByIDs(_ context.Context, ids []bson.ObjectID) ([]models.Product, error) {
+	return r.docs.find(func(p *models.Product) bool { return slices.Contains(ids, p.Id) && p.DeletedAt == nil }), nil
+}
+
+func (r *memoryProductRepository) Insert(_ context.Context, product *models.Product) error {
+	if product.Id.IsZero() {
+		product.Id = bson.NewObjectID()
+	}
+	return r.docs.insert(product)
+}
+
+func (r *memoryProductRepository) Update(_ context.Context, id bson.ObjectID, set bson.M) error {
+	return r.docs.setByID(id, set)
+}
+
+func (r *memoryProductRepository) Trash(_ context.Context, id bson.ObjectID, at time.Time) error {
+	found, err := r.docs.mutate(
+		func(p *models.Product) bool { return p.Id == id && p.DeletedAt == nil },
+		func(p *models.Product) error { p.DeletedAt = &at; return nil },
+	)
+	if err == nil && !found {
+		err = ErrNotFound
+	}
+	return err
+}
+
+func (r *memoryProductRepository) Restore(_ context.Context, id bson.ObjectID) error {
+	found, err := r.docs.mutate(
+		func(p *models.Product) bool { return p.Id == id && p.DeletedAt != nil },
+		func(p *models.Product) error { p.DeletedAt = nil; return nil },
+	)
+	if err == nil && !found {
+		err = ErrNotFound
+	}
+	return err
+}
+
+func (r *memoryProductRepository) TrashedBefore(_ context.Context, cutoff time.Time) ([]models.Product, error) {
+	items := r.docs.find(func(p *models.Product) bool { return p.DeletedAt != nil && p.DeletedAt.Before(cutoff) })
+	slices.SortStableFunc(items, func(a, b models.Product) int { return a.DeletedAt.Compare(*b.DeletedAt) })
+	return items, nil
+}
+
+func (r *memoryProductRepository) WithLegacyImages(context.Context) ([]models.Product, error) {
+	return r.docs.find(func(p *models.Product) bool {
+		return slices.ContainsFunc(p.Images, func(img models.Image) bool { return !img.Processed() })
+	}), nil
+}
+
+func (r *memoryProductRepository) Delete(_ context.Context, id bson.ObjectID) (*models.Product, error) {
+	deleted, err := r.docs.findByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.docs.deleteByID(id); err != nil {
+		return nil, err
+	}
+	r.docs.mutateAll(
+		func(p *models.Product) bool { return slices.Contains(p.SimilarProductsIds, id) },
+		func(p *models.Product) {
+			p.SimilarProductsIds = slices.DeleteFunc(p.SimilarProductsIds, func(s bson.ObjectID) bool { return s == id })
+		},
+	)
+	return deleted, nil
+}
+
+func (r *memoryProductRepository) AddVariant(_ context.Context, id bson.ObjectID, variant models.ProductVariant) error {
+	found, err := r.docs.mutate(
+		func(p *models.Product) bool { return p.Id == id },
+		func(p *models.Product) error { p.Variants = append(p.Variants, variant); return nil },
+	)
+	if err == nil && !found {
+		err = ErrNotFound
+	}
+	return err
+}
+
+func (r *memoryProductRepository) UpdateVariant(_ context.Context, id bson.ObjectID, variant models.ProductVariant) error {
+	found, err := r.docs.mutate(
+		func(p *models.Product) bool { return p.Id == id },
+		func(p *models.Product) error {
+			v, ok := p.Variant(variant.Id)
+			if !ok {
+				return ErrNotFound
+			}
+			v.SKU, v.Options, v.Price, v.ImageUrls = variant.SKU, variant.Options, variant.Price, variant.ImageUrls
+			return nil
+		},
+	)
+	if err == nil && !found {
+		err = ErrNotFound
+	}
+	return err
+}
+
+func (r *memoryProductRepository) DeleteVariant(_ context.Context, id, variantID bson.ObjectID) error {
+	found, err := r.docs.mutate(
+		func(p *models.Product) bool { return p.Id == id },
+		func(p *models.Product) error {
+			if _, ok := p.Variant(variantID); !ok {
+				return ErrNotFound
+			}
+			p.Variants = slices.DeleteFunc(p.Variants, func(v models.ProductVariant) bool { return v.Id == variantID })
+			return nil
+		},
+	)
+	if err == nil && !found {
+		err = ErrNotFound
+	}
+	return err
+}
+
+func (r *memoryProductRepository) RenameVariantImages(_ context.Context, id bson.ObjectID, renamed map[string]string) error {
+	_, err := r.docs.mutate(
+		func(p *models.Product) bool { return p.Id == id },
+		func(p *models.Product) error {
+			for i := range p.Variants {
+				urls := p.Variants[i].ImageUrls
+				for j, url := range urls {
+					if to, ok := renamed[url]; ok {
+						urls[j] = to
+					}
+				}
+				p.Variants[i].ImageUrls = slices.DeleteFunc(urls, func(url string) bool { return url == "" })
+			}
+			return nil
+		},
+	)
+	return err
+}
+
+func (r *memoryProductRepository) AdjustStock(_ context.Context, id bson.ObjectID, variantID *bson.ObjectID, delta int) (int, error) {
+	after := 0
+	found, err := r.docs.mutate(
+		func(p *models.Product) bool { return p.Id == id && p.DeletedAt == nil },
+		func(p *models.Product) error {
+			quantity := &p.Quantity
+			if variantID != nil {
+				v, ok := p.Variant(*variantID)
+				if !ok {
+					return ErrNotFound
+				}
+				quantity = &v.Quantity
+			}
+			if delta < 0 && *quantity+delta < 0 {
+				return ErrInsufficientStock
+			}
+			*quantity += delta
+			after = *quantity
+			return nil
+		},
+	)
+	if err == nil && !found {
+		err = ErrNotFound
+	}
+	return after, err
+}
+
+func (r *memoryProductRepository) ApplySchedule(_ context.Context, now time.Time) (int64, int64, error) {
+	published := r.docs.mutateAll(
+		func(p *models.Product) bool {
+			return p.DeletedAt == nil && p.Status == models.ProductStatusScheduled && p.PublishAt != nil && !p.PublishAt.After(now)
+		},
+		func(p *models.Product) { p.Status = models.ProductStatusPublished },
+	)
+	archived := r.docs.mutateAll(
+		func(p *models.Product) bool {
+			return p.DeletedAt == nil && cmp.Or(p.Status, models.ProductStatusPublished) == models.ProductStatusPublished &&
+				p.UnpublishAt != nil && !p.UnpublishAt.After(now)
+		},
+		func(p *models.Product) { p.Status = models.ProductStatusArchived },
+	)
+	return int64(published), int64(archived), nil
+}
+
+func (r *memoryProductRepository) LowStock(_ context.Context, threshold int, page Page) ([]models.Product, int64, error) {
+	items := r.docs.find(func(p *models.Product) bool { return p.DeletedAt == nil && lowestStock(p) <= threshold })
+	slices.SortStableFunc(items, func(a, b models.Product) int {
+		return cmp.Or(cmp.Compare(lowestStock(&a), lowestStock(&b)), cmp.Compare(a.Name, b.Name))
+	})
+
+	items, total := pageOf(items, page)
+	return items, total, nil
+}
+
+// ---- Categories -------------------------------------------------------------
+
+type memoryCategoryRepository struct {
+	docs *memoryCollection[models.Category]
+}
+
+func NewMemoryCategoryRepository() CategoryRepository {
+	return &memoryCategoryRepository{docs: newMemoryCollection(
+		func(c *models.Category) bson.ObjectID { return c.Id },
+		func(c *models.Category) []string { return []string{c.Slug} },
+	)}
+}
+
+func (r *memoryCategoryRepository) List(_ context.Context, filter CategoryFilter, page Page) ([]models.Category, int64, error) {
+	var name *regexp.Regexp
+	if filter.Name != "" {
+		re, err := regexp.Compile(search.Pattern(filter.Name))
+		if err != nil {
+			return nil, 0, err
+		}
+		name = re
+	}
+
+	items := r.docs.find(func(c *models.Category) bool {
+		if name != nil && !name.MatchString(c.Name) {
+			return false
+		}
+		if filter.IsActive != nil && c.IsActive != *filter.IsActive {
+			return false
+		}
+		if filter.MissingTranslation != "" && len(c.MissingTranslations(filter.MissingTranslation)) == 0 {
+			return false
+		}
+		return true
+	})
+	slices.SortStableFunc(items, func(a, b models.Category) int { return cmp.Compare(a.Name, b.Name) })
+
+	items, total := pageOf(items, page)
+	return items, total, nil
+}
+
+func (r *memoryCategoryRepository) Count(ctx context.Context, filter CategoryFilter) (int64, error) {
+	_, total, err := r.List(ctx, filter, Page{})
+	return total, err
+}
+
+func (r *memoryCategoryRepository) FindByID(_ context.Context, id bson.ObjectID) (*models.Category, error) {
+	return r.docs.findByID(id)
+}
+
+func (r *memoryCategoryRepository) FindBySlug(_ context.Context, slug string) (*models.Category, error) {
+	return r.docs.findOne(func(c *models.Category) bool { return c.Slug == slug })
+}
+
+func (r *memoryCategoryRepository) FindByIDs(_ context.Context, ids []bson.ObjectID) ([]models.Category, error) {
+	return r.docs.find(func(c *models.Category) bool { return slices.Contains(ids, c.Id) }), nil
+}
+
+func (r *memoryCategoryRepository) Insert(_ context.Context, category *models.Category) error {
+	if category.Id.IsZero() {
+		category.Id = bson.NewObjectID()
+	}
+	return r.docs.insert(category)
+}
+
+func (r *memoryCategoryRepository) Update(_ context.Context, id bson.ObjectID, set bson.M) error {
+	return r.docs.setByID(id, set)
+}
+
+func (r *memoryCategoryRepository) Delete(_ context.Context, id bson.ObjectID) error {
+	return r.docs.deleteByID(id)
+}
+
+func (r *memoryCategoryRepository) WithLegacyImage(context.Context) ([]models.Category, error) {
+	return r.docs.find(func(c *models.Category) bool { return c.Image != nil && !c.Image.Processed() }), nil
+}
+
+// ---- Stock movements --------------------------------------------------------
+
+type memoryStockMovementRepository struct {
+	docs *memoryCollection[models.StockMovement]
+}
+
+func NewMemoryStockMovementRepository() StockMovementRepository {
+	return &memoryStockMovementRepository{docs: newMemoryCollection(
+		func(m *models.StockMovement) bson.ObjectID { return m.ID },
+		nil,
+	)}
+}
+
+func (r *memoryStockMovementRepository) List(_ context.Context, filter StockMovementFilter, page Page) ([]models.StockMovement, int64, error) {
+	items := r.docs.find(func(m *models.StockMovement) bool {
+		return (filter.ProductID == nil || m.ProductID == *filter.ProductID) &&
+			(filter.VariantID == nil || (m.VariantID != nil && *m.VariantID == *filter.VariantID)) &&
+			(filter.Type == "" || m.Type == filter.Type)
+	})
+	// newest first; insertion order stands in for _id between equal times
+	slices.Reverse(items)
+	slices.SortStableFunc(items, func(a, b models.StockMovement) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	items, total := pageOf(items, page)
+	return items, total, nil
+}
+
+func (r *memoryStockMovementRepository) Insert(_ context.Context, movement *models.StockMovement) error {
+	if movement.ID.IsZero() {
+		movement.ID = bson.NewObjectID()
+	}
+	return r.docs.insert(movement)
+}
+
+// ---- Product revisions -----------------------------------------------------
+
+type memoryProductRevisionRepository struct {
+	docs *memoryCollection[models.ProductRevision]
+}
+
+func NewMemoryProductRevisionRepository() ProductRevisionRepository {
+	return &memoryProductRevisionRepository{docs: newMemoryCollection(
+		func(r *models.ProductRevision) bson.ObjectID { return r.ID },
+		// like the productId_version_unique index
+		func(r *models.ProductRevision) []string {
+			return []string{r.ProductID.Hex() + "/" + strconv.Itoa(r.Version)}
+		},
+	)}
+}
+
+func (r *memoryProductRevisionRepository) List(_ context.Context, productID bson.ObjectID, page Page) ([]models.ProductRevision, int64, error) {
+	items := r.docs.find(func(rev *models.ProductRevision) bool { return rev.ProductID == productID })
+	slices.SortStableFunc(items, func(a, b models.ProductRevision) int { return cmp.Compare(b.Version, a.Version) })
+
+	items, total := pageOf(items, page)
+	return items, total, nil
+}
+
+func (r *memoryProductRevisionRepository) FindByVersion(_ context.Context, productID bson.ObjectID, version int) (*models.ProductRevision, error) {
+	return r.docs.findOne(func(rev *models.ProductRevision) bool { return rev.ProductID == productID && rev.Version == version })
+}
+
+func (r *memoryProductRevisionRepository) Latest(ctx context.Context, productID bson.ObjectID) (*models.ProductRevision, error) {
+	items, _, _ := r.List(ctx, productID, Page{Limit: 1})
+	if len(items) == 0 {
+		return nil, ErrNotFound
+	}
+	return &items[0], nil
+}
+
+func (r *memoryProductRevisionRepository) Insert(_ context.Context, revision *models.ProductRevision) error {
+	if revision.ID.IsZero() {
+		revision.ID = bson.NewObjectID()
+	}
+	return r.docs.insert(revision)
+}
+
+// ---- Exchange rates ---------------------------------------------------------
+
+type memoryExchangeRateRepository struct {
+	docs *memoryCollection[models.ExchangeRate]
+}
+
+func NewMemoryExchangeRateRepository() ExchangeRateRepository {
+	return &memoryExchangeRateRepository{docs: newMemoryCollection(
+		func(r *models.ExchangeRate) bson.ObjectID { return r.ID },
+		func(r *models.ExchangeRate) []string { return []string{r.Currency} },
+	)}
+}
+
+func (r *memoryExchangeRateRepository) List(_ context.Context) ([]models.ExchangeRate, error) {
+	items := r.docs.find(nil)
+	slices.SortFunc(items, func(a, b models.ExchangeRate) int { return strings.Compare(a.Currency, b.Currency) })
+	return items, nil
+}
+
+func (r *memoryExchangeRateRepository) FindByCurrency(_ context.Context, currency string) (*models.ExchangeRate, error) {
+	return r.docs.findOne(func(e *models.ExchangeRate) bool { return e.Currency == currency })
+}
+
+func (r *memoryExchangeRateRepository) Upsert(_ context.Context, rate *models.ExchangeRate) error {
+	found, err := r.docs.mutate(func(e *models.ExchangeRate) bool { return e.Currency == rate.Currency }, func(e *models.ExchangeRate) error {
+		rate.ID = e.ID
+		e.Rate, e.UpdatedAt, e.UpdatedBy = rate.Rate, rate.UpdatedAt, rate.UpdatedBy
+		return nil
+	})
+	if err != nil || found {
+		return err
+	}
+	rate.ID = bson.NewObjectID()
+	return r.docs.insert(rate)
+}
+
+func (r *memoryExchangeRateRepository) Delete(ctx context.Context, currency string) error {
+	rate, err := r.FindByCurrency(ctx, currency)
+	if err != nil {
+		return err
+	}
+	return r.docs.deleteByID(rate.ID)
+}
+
+// ---- Quote requests ---------------------------------------------------------
+
+type memoryQuoteRequestRepository struct {
+	docs *memoryCollection[models.QuoteRequest]
+}
+
+func NewMemoryQuoteRequestRepository() QuoteRequestRepository {
+	return &memoryQuoteRequestRepository{docs: newMemoryCollection(
+		func(q *models.QuoteRequest) bson.ObjectID { return q.ID },
+		nil,
+	)}
+}
+
+func (r *memoryQuoteRequestRepository) List(_ context.Context, filter QuoteRequestFilter, page Page) ([]models.QuoteRequest, int64, error) {
+	items := r.docs.find(func(q *models.QuoteRequest) bool {
+		return filter.Status == "" || string(q.Status) == filter.Status
+	})
+	slices.SortStableFunc(items, func(a, b models.QuoteRequest) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	items, total := pageOf(items, page)
+	return items, total, nil
+}
+
+func (r *memoryQuoteRequestRepository) FindByID(_ context.Context, id bson.ObjectID) (*models.QuoteRequest, error) {
+	return r.docs.findByID(id)
+}
+
+func (r *memoryQuoteRequestRepository) Insert(_ context.Context, quote *models.QuoteRequest) error {
+	if quote.ID.IsZero() {
+		quote.ID = bson.NewObjectID()
+	}
+	return r.docs.insert(quote)
+}
+
+func (r *memoryQuoteRequestRepository) Update(_ context.Context, id bson.ObjectID, set bson.M) error {
+	return r.docs.setByID(id, set)
+}
+
+func (r *memoryQuoteRequestRepository) AddNote(_ context.Context, id bson.ObjectID, note models.QuoteAdminNote) error {
+	return r.docs.updateByID(id, func(q *models.QuoteRequest) error {
+		q.Notes = append(q.Notes, note)
+		if q.Status == models.QuoteStatusNew {
+			q.Status = models.QuoteStatusInProgress
+		}
+		q.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+}
+
+// ---- Product requests -------------------------------------------------------
+
+type memoryProductRequestRepository struct {
+	docs *memoryCollection[models.ProductRequest]
+}
+
+func NewMemoryProductRequestRepository() ProductRequestRepository {
+	return &memoryProductRequestRepository{docs: newMemoryCollection(
+		func(r *models.ProductRequest) bson.ObjectID { return r.Id },
+		nil,
+	)}
+}
+
+func (r *memoryProductRequestRepository) List(_ context.Context, filter ProductRequestFilter, page Page) ([]models.ProductRequest, int64, error) {
+	q := search.Fold(filter.Q)
+	items := r.docs.find(func(req *models.ProductRequest) bool {
+		if filter.Status != "" && string(req.Status) != filter.Status {
+			return false
+		}
+		if filter.Email != "" && req.Email != filter.Email {
+			return false
+		}
+		if q != "" {
+			hit := false
+			for _, field := range []string{req.FullName, req.Email, req.Company, req.Description} {
+				if strings.Contains(search.Fold(field), q) {
+					hit = true
+					break
+				}
+			}
+			if !hit {
+				return false
+			}
+		}
+		return true
+	})
+	slices.SortStableFunc(items, func(a, b models.ProductRequest) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	items, total := pageOf(items, page)
+	return items, total, nil
+}
+
+func (r *memoryProductRequestRepository) FindByID(_ context.Context, id bson.ObjectID) (*models.ProductRequest, error) {
+	return r.docs.findByID(id)
+}
+
+func (r *memoryProductRequestRepository) Insert(_ context.Context, req *models.ProductRequest) error {
+	if req.Id.IsZero() {
+		req.Id = bson.NewObjectID()
+	}
+	return r.docs.insert(req)
+}
+
+func (r *memoryProductRequestRepository) Update(_ context.Context, id bson.ObjectID, set bson.M) error {
+	return r.docs.setByID(id, set)
+}
+
+func (r *memoryProductRequestRepository) AddNote(_ context.Context, id bson.ObjectID, note models.ProductRequestAdminNote) error {
+	return r.docs.updateByID(id, func(req *models.ProductRequest) error {
+		req.Notes = append(req.Notes, note)
+		if req.Status == models.ProductRequestStatusNew {
+			req.Status = models.ProductRequestStatusInProgress
+		}
+		req.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+}
+
+// ---- Users ------------------------------------------------------------------
+
+type memoryUserRepository struct {
+	docs *memoryCollection[models.User]
+}
+
+func NewMemoryUserRepository() UserRepository {
+	return &memoryUserRepository{docs: newMemoryCollection(
+		func(u *models.User) bson.ObjectID { return u.ID },
+		func(u *models.User) []string { return []string{u.Email} },
+	)}
+}
+
+func (r *memoryUserRepository) FindByID(_ context.Context, id bson.ObjectID) (*models.User, error) {
+	return r.docs.findByID(id)
+}
+
+func (r *memoryUserRepository) FindByEmail(_ context.Context, email string) (*models.User, error) {
+	return r.docs.findOne(func(u *models.User) bool { return u.Email == email })
+}
+
+func (r *memoryUserRepository) Insert(_ context.Context, user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = bson.NewObjectID()
+	}
+	return r.docs.insert(user)
+}
+
+func (r *memoryUserRepository) Update(_ context.Context, id bson.ObjectID, set bson.M) error {
+	return r.docs.setByID(id, set)
+}
+
+// ---- Refresh tokens ---------------------------------------------------------
+
+type memoryRefreshTokenRepository struct {
+	docs *memoryCollection[models.RefreshToken]
+}
+
+func NewMemoryRefreshTokenRepository() RefreshTokenRepository {
+	return &memoryRefreshTokenRepository{docs: newMemoryCollection(
+		func(t *models.RefreshToken) bson.ObjectID { return t.ID },
+		func(t *models.RefreshToken) []string { return []string{t.TokenHash} },
+	)}
+}
+
+func (r *memoryRefreshTokenRepository) Insert(_ context.Context, token *models.RefreshToken) error {
+	if token.ID.IsZero() {
+		token.ID = bson.NewObjectID()
+	}
+	return r.docs.insert(token)
+}
+
+func (r *memoryRefreshTokenRepository) FindActive(_ context.Context, tokenHash string, now time.Time) (*models.RefreshToken, error) {
+	return r.docs.findOne(func(t *models.RefreshToken) bool {
+		return t.TokenHash == tokenHash && t.RevokedAt == nil && t.ExpiresAt.After(now)
+	})
+}
+
+func (r *memoryRefreshTokenRepository) Revoke(_ context.Context, id bson.ObjectID, replacedBy *string) error {
+	return r.docs.updateByID(id, func(t *models.RefreshToken) error {
+		now := time.Now().UTC()
+		t.RevokedAt = &now
+		if replacedBy != nil {
+			t.ReplacedBy = replacedBy
+		}
+		return nil
+	})
+}
+
+func (r *memoryRefreshTokenRepository) RevokeByHash(_ context.Context, tokenHash string) error {
+	_, err := r.docs.mutate(func(t *models.RefreshToken) bool {
+		return t.TokenHash == tokenHash && t.RevokedAt == nil
+	}, func(t *models.RefreshToken) error {
+		now := time.Now().UTC()
+		t.RevokedAt = &now
+		return nil
+	})
+	return err
+}
+
+func (r *memoryRefreshTokenRepository) RevokeAllForUser(_ context.Context, userID bson.ObjectID) error {
+	r.docs.mutateAll(func(t *models.RefreshToken) bool {
+		return t.UserID == userID && t.RevokedAt == nil
+	}, func(t *models.RefreshToken) {
+		now := time.Now().UTC()
+		t.RevokedAt = &now
+	})
+	return nil
+}
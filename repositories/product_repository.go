@@ -0,0 +1,651 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ProductSort is the public ?sort= value accepted by GET /products.
+type ProductSort string
+
+const (
+	ProductSortName      ProductSort = ""
+	ProductSortPriceAsc  ProductSort = "price_asc"
+	ProductSortPriceDesc ProductSort = "price_desc"
+	ProductSortStockAsc  ProductSort = "stock_asc"
+	ProductSortStockDesc ProductSort = "stock_desc"
+)
+
+// ProductFilter narrows a product listing. Nil and empty fields are
+// ignored; a multi-value field matches products having any of the values.
+type ProductFilter struct {
+	CategoryIDs []bson.ObjectID
+	Materials   []string
+	Colors      []string
+	// MinPrice and MaxPrice are inclusive.
+	MinPrice   *int64
+	MaxPrice   *int64
+	IsTrending *bool
+	IsDisabled *bool
+	// Status lists the products in one lifecycle status, products saved
+	// without a status counting as published.
+	Status models.ProductStatus
+	// VisibleAt keeps the products on sale at that time (see
+	// models.Product.IsPublished), whatever the scheduler has applied yet.
+	VisibleAt *time.Time
+	// MissingTranslation keeps the products with a field that is set but
+	// not translated in this locale.
+	MissingTranslation string
+	// Trashed lists the trash instead of the live products.
+	Trashed bool
+	Sort    ProductSort
+}
+
+// The facets of a product listing. Each one is counted with every filter
+// applied but its own, so a selected value still shows its alternatives.
+const (
+	FacetCategories = "categories"
+	FacetMaterials  = "materials"
+	FacetColors     = "colors"
+	FacetPrice      = "price"
+)
+
+// ProductFacets counts the products of a listing per filter value.
+type ProductFacets struct {
+	Categories []CategoryCount
+	Materials  []ValueCount
+	Colors     []ValueCount
+	Prices     []PriceBucket
+}
+
+type ValueCount struct {
+	Value string `bson:"_id"`
+	Count int64  `bson:"count"`
+}
+
+type CategoryCount struct {
+	ID    bson.ObjectID `bson:"_id"`
+	Count int64         `bson:"count"`
+}
+
+// PriceBucket counts the prices from Min (inclusive) to Max (exclusive); the
+// last bucket has no Max.
+type PriceBucket struct {
+	Min   int64
+	Max   *int64
+	Count int64
+}
+
+// withoutFacet clears the filter field a facet counts over.
+func withoutFacet(filter ProductFilter, facet string) ProductFilter {
+	switch facet {
+	case FacetCategories:
+		filter.CategoryIDs = nil
+	case FacetMaterials:
+		filter.Materials = nil
+	case FacetColors:
+		filter.Colors = nil
+	case FacetPrice:
+		filter.MinPrice, filter.MaxPrice = nil, nil
+	}
+	return filter
+}
+
+// priceBuckets lays out the buckets split by bounds, with counts by lower
+// bound; buckets without products are kept with a zero count.
+func priceBuckets(bounds []int64, counts map[int64]int64) []PriceBucket {
+	buckets := make([]PriceBucket, 0, len(bounds)+1)
+	lower := int64(0)
+	for _, bound := range bounds {
+		buckets = append(buckets, PriceBucket{Min: lower, Max: &bound, Count: counts[lower]})
+		lower = bound
+	}
+	return append(buckets, PriceBucket{Min: lower, Count: counts[lower]})
+}
+
+// ProductHit is a search result with its relevance; higher is better.
+type ProductHit struct {
+	models.Product `bson:",inline"`
+	Score          float64 `bson:"score"`
+}
+
+type ProductRepository interface {
+	List(ctx context.Context, filter ProductFilter, page Page) ([]models.Product, int64, error)
+	// Count is the total List returns, without loading the products.
+	Count(ctx context.Context, filter ProductFilter) (int64, error)
+	// Search lists the products matching any word of q, ignoring case and
+	// accents, most relevant first unless filter.Sort asks otherwise.
+	Search(ctx context.Context, q string, filter ProductFilter, page Page) ([]ProductHit, int64, error)
+	// Facets counts the products List (or Search, when q is set) would
+	// return, per category, material, color and price bucket.
+	Facets(ctx context.Context, q string, filter ProductFilter, priceBounds []int64) (*ProductFacets, error)
+	FindByID(ctx context.Context, id bson.ObjectID) (*models.Product, error)
+	FindBySlug(ctx context.Context, slug string) (*models.Product, error)
+	FindByIDs(ctx context.Context, ids []bson.ObjectID) ([]models.Product, error)
+	Insert(ctx context.Context, product *models.Product) error
+	Update(ctx context.Context, id bson.ObjectID, set bson.M) error
+	// Trash moves a live product to the trash. Only List with Trashed set
+	// and TrashedBefore see it until it is restored.
+	Trash(ctx context.Context, id bson.ObjectID, at time.Time) error
+	Restore(ctx context.Context, id bson.ObjectID) error
+	// TrashedBefore returns the products trashed before cutoff.
+	TrashedBefore(ctx context.Context, cutoff time.Time) ([]models.Product, error)
+	// WithLegacyImages returns the products, trashed or not, with an image
+	// saved before renditions existed (see models.Image.Processed).
+	WithLegacyImages(ctx context.Context) ([]models.Product, error)
+	// Delete removes a product for good, trashed or not, drops its id from
+	// the other products' similarProductsIds and returns what was deleted.
+	Delete(ctx context.Context, id bson.ObjectID) (*models.Product, error)
+	// AddVariant appends a variant to a product.
+	AddVariant(ctx context.Context, id bson.ObjectID, variant models.ProductVariant) error
+	// UpdateVariant saves the SKU, options, price and images of a variant.
+	// Its quantity is left alone: only AdjustStock changes it.
+	UpdateVariant(ctx context.Context, id bson.ObjectID, variant models.ProductVariant) error
+	DeleteVariant(ctx context.Context, id, variantID bson.ObjectID) error
+	// RenameVariantImages replaces, in the images of every variant of a
+	// product, each URL key of renamed by its value; an empty value removes
+	// the URL. Each change touches only the images of the variants, so it
+	// never overwrites a stock movement.
+	RenameVariantImages(ctx context.Context, id bson.ObjectID, renamed map[string]string) error
+	// AdjustStock adds delta to the quantity of a live product, or of one
+	// of its variants, and returns the new quantity. It fails with
+	// ErrInsufficientStock rather than go below zero.
+	AdjustStock(ctx context.Context, id bson.ObjectID, variantID *bson.ObjectID, delta int) (int, error)
+	// LowStock lists the live products with at most threshold units left,
+	// counting the variants of products that have some: lowest stock first.
+	LowStock(ctx context.Context, threshold int, page Page) ([]models.Product, int64, error)
+	// ApplySchedule publishes the scheduled products whose publishAt has
+	// passed, then archives the published ones whose unpublishAt has, and
+	// returns how many products each step changed.
+	ApplySchedule(ctx context.Context, now time.Time) (published, archived int64, err error)
+}
+
+type mongoProductRepository struct {
+	col *mongo.Collection
+}
+
+func NewProductRepository(db *mongo.Database) ProductRepository {
+	return &mongoProductRepository{col: db.Collection("products")}
+}
+
+func (r *mongoProductRepository) List(ctx context.Context, filter ProductFilter, page Page) ([]models.Product, int64, error) {
+	return findPage[models.Product](ctx, r.col, productQuery(filter), productSort(filter.Sort), page)
+}
+
+func (r *mongoProductRepository) Count(ctx context.Context, filter ProductFilter) (int64, error) {
+	return r.col.CountDocuments(ctx, productQuery(filter))
+}
+
+// Search relies on the product_text index (migration 4), which is
+// diacritic-insensitive and stems French words.
+func (r *mongoProductRepository) Search(ctx context.Context, q string, filter ProductFilter, page Page) ([]ProductHit, int64, error) {
+	query := productQuery(filter)
+	query["$text"] = bson.M{"$search": q}
+
+	score := bson.M{"$meta": "textScore"}
+	sort := bson.D{{Key: "score", Value: score}, {Key: "name", Value: 1}}
+	if filter.Sort != ProductSortName {
+		sort = productSort(filter.Sort)
+	}
+
+	opts := options.Find().SetProjection(bson.M{"score": score}).SetSort(sort)
+	if page.Skip > 0 {
+		opts.SetSkip(page.Skip)
+	}
+	if page.Limit > 0 {
+		opts.SetLimit(page.Limit)
+	}
+	cursor, err := r.col.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	hits := make([]ProductHit, 0)
+	if err := cursor.All(ctx, &hits); err != nil {
+		return nil, 0, err
+	}
+	total, err := r.col.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return hits, total, nil
+}
+
+// Facets runs one aggregation: the filters shared by every facet first,
+// then a $facet branch per facet adding the other facets' filters.
+func (r *mongoProductRepository) Facets(ctx context.Context, q string, filter ProductFilter, priceBounds []int64) (*ProductFacets, error) {
+	shared := filter
+	for _, facet := range []string{FacetCategories, FacetMaterials, FacetColors, FacetPrice} {
+		shared = withoutFacet(shared, facet)
+	}
+	first := productQuery(shared)
+	if q != "" {
+		first["$text"] = bson.M{"$search": q}
+	}
+
+	countValues := func(facet, field string) bson.A {
+		return bson.A{
+			bson.M{"$match": productQuery(withoutFacet(filter, facet))},
+			bson.M{"$unwind": "$" + field},
+			bson.M{"$group": bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		}
+	}
+	boundaries := bson.A{int64(0)}
+	for _, b := range priceBounds {
+		boundaries = append(boundaries, b)
+	}
+	boundaries = append(boundaries, int64(math.MaxInt64))
+
+	pipeline := bson.A{
+		bson.M{"$match": first},
+		bson.M{"$facet": bson.M{
+			FacetCategories: countValues(FacetCategories, "categoryIds"),
+			FacetMaterials:  countValues(FacetMaterials, "materials"),
+			FacetColors:     countValues(FacetColors, "colors"),
+			FacetPrice: bson.A{
+				bson.M{"$match": productQuery(withoutFacet(filter, FacetPrice))},
+				bson.M{"$bucket": bson.M{"groupBy": "$price", "boundaries": boundaries, "default": "other"}},
+			},
+		}},
+	}
+
+	cursor, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Categories []CategoryCount `bson:"categories"`
+		Materials  []ValueCount    `bson:"materials"`
+		Colors     []ValueCount    `bson:"colors"`
+		Price      []struct {
+			Min   any   `bson:"_id"`
+			Count int64 `bson:"count"`
+		} `bson:"price"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	facets := &ProductFacets{Categories: []CategoryCount{}, Materials: []ValueCount{}, Colors: []ValueCount{}}
+	counts := map[int64]int64{}
+	if len(results) == 1 {
+		res := results[0]
+		facets.Categories = append(facets.Categories, res.Categories...)
+		facets.Materials = append(facets.Materials, res.Materials...)
+		facets.Colors = append(facets.Colors, res.Colors...)
+		for _, b := range res.Price {
+			// "other" only collects products without a numeric price
+			if lower, ok := b.Min.(int64); ok {
+				counts[lower] = b.Count
+			}
+		}
+	}
+	facets.Prices = priceBuckets(priceBounds, counts)
+	return facets, nil
+}
+
+func productQuery(filter ProductFilter) bson.M {
+	// a null deletedAt also matches products saved before the trash existed
+	query := bson.M{"deletedAt": nil}
+	if filter.Trashed {
+		query["deletedAt"] = bson.M{"$ne": nil}
+	}
+	if len(filter.CategoryIDs) > 0 {
+		query["categoryIds"] = bson.M{"$in": filter.CategoryIDs}
+	}
+	if len(filter.Materials) > 0 {
+		query["materials"] = bson.M{"$in": filter.Materials}
+	}
+	if len(filter.Colors) > 0 {
+		query["colors"] = bson.M{"$in": filter.Colors}
+	}
+	if filter.MinPrice != nil || filter.MaxPrice != nil {
+		price := bson.M{}
+		if filter.MinPrice != nil {
+			price["$gte"] = *filter.MinPrice
+		}
+		if filter.MaxPrice != nil {
+			price["$lte"] = *filter.MaxPrice
+		}
+		query["price"] = price
+	}
+	if filter.IsTrending != nil {
+		query["isTrending"] = *filter.IsTrending
+	}
+	if filter.IsDisabled != nil {
+		query["isDisabled"] = *filter.IsDisabled
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+		if filter.Status == models.ProductStatusPublished {
+			query["status"] = bson.M{"$in": bson.A{models.ProductStatusPublished, nil}}
+		}
+	}
+	if filter.VisibleAt != nil {
+		now := *filter.VisibleAt
+		query["$or"] = bson.A{
+			bson.M{"status": bson.M{"$in": bson.A{models.ProductStatusPublished, nil}}},
+			bson.M{"status": models.ProductStatusScheduled, "publishAt": bson.M{"$lte": now}},
+		}
+		// $not also matches the products without an unpublishAt
+		query["unpublishAt"] = bson.M{"$not": bson.M{"$lte": now}}
+	}
+	if filter.MissingTranslation != "" {
+		query["$and"] = bson.A{missingTranslationQuery(filter.MissingTranslation, models.ProductTranslatedFields)}
+	}
+	return query
+}
+
+func productSort(sort ProductSort) bson.D {
+	switch sort {
+	case ProductSortPriceAsc:
+		return bson.D{{Key: "price", Value: 1}}
+	case ProductSortPriceDesc:
+		return bson.D{{Key: "price", Value: -1}}
+	case ProductSortStockAsc:
+		return bson.D{{Key: "createdAt", Value: 1}}
+	case ProductSortStockDesc:
+		return bson.D{{Key: "createdAt", Value: -1}}
+	}
+	return bson.D{{Key: "name", Value: 1}}
+}
+
+func (r *mongoProductRepository) FindByID(ctx context.Context, id bson.ObjectID) (*models.Product, error) {
+	return findOne[models.Product](ctx, r.col, bson.M{"_id": id, "deletedAt": nil})
+}
+
+func (r *mongoProductRepository) FindBySlug(ctx context.Context, slug string) (*models.Product, error) {
+	return findOne[models.Product](ctx, r.col, bson.M{"slug": slug, "deletedAt": nil})
+}
+
+func (r *mongoProductRepository) FindByIDs(ctx context.Context, ids []bson.ObjectID) ([]models.Product, error) {
+	items, _, err := findPage[models.Product](ctx, r.col, bson.M{"_id": bson.M{"$in": ids}, "deletedAt": nil}, bson.D{{Key: "_id", Value: 1}}, Page{})
+	return items, err
+}
+
+func (r *mongoProductRepository) Insert(ctx context.Context, product *models.Product) error {
+	if product.Id.IsZero() {
+		product.Id = bson.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, product)
+	return translateError(err)
+}
+
+func (r *mongoProductRepository) Update(ctx context.Context, id bson.ObjectID, set bson.M) error {
+	return updateByID(ctx, r.col, id, set)
+}
+
+func (r *mongoProductRepository) Trash(ctx context.Context, id bson.ObjectID, at time.Time) error {
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "deletedAt": nil}, bson.M{"$set": bson.M{"deletedAt": at}})
+	if err != nil {
+		return translateError(err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoProductRepository) Restore(ctx context.Context, id bson.ObjectID) error {
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "deletedAt": bson.M{"$ne": nil}}, bson.M{"$unset": bson.M{"deletedAt": ""}})
+	if err != nil {
+		return translateError(err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoProductRepository) TrashedBefore(ctx context.Context, cutoff time.Time) ([]models.Product, error) {
+	items, _, err := findPage[models.Product](ctx, r.col, bson.M{"deletedAt": bson.M{"$lt": cutoff}}, bson.D{{Key: "deletedAt", Value: 1}}, Page{})
+	return items, err
+}
+
+func (r *mongoProductRepository) WithLegacyImages(ctx context.Context) ([]models.Product, error) {
+	legacy := bson.M{"$expr": bson.M{"$anyElementTrue": bson.A{bson.M{"$map": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$images", bson.A{}}},
+		"as":    "i",
+		"in":    bson.M{"$eq": bson.A{"$$i.url", "$$i.thumbnail"}},
+	}}}}}
+	items, _, err := findPage[models.Product](ctx, r.col, legacy, bson.D{{Key: "_id", Value: 1}}, Page{})
+	return items, err
+}
+
+func (r *mongoProductRepository) Delete(ctx context.Context, id bson.ObjectID) (*models.Product, error) {
+	var deleted models.Product
+	if err := r.col.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&deleted); err != nil {
+		return nil, translateError(err)
+	}
+	// a failure here leaves dangling ids, which product pages already skip
+	if _, err := r.col.UpdateMany(ctx, bson.M{"similarProductsIds": id}, bson.M{"$pull": bson.M{"similarProductsIds": id}}); err != nil {
+		return &deleted, err
+	}
+	return &deleted, nil
+}
+
+func (r *mongoProductRepository) AddVariant(ctx context.Context, id bson.ObjectID, variant models.ProductVariant) error {
+	res, err := r.col.UpdateByID(ctx, id, bson.M{"$push": bson.M{"variants": variant}})
+	if err != nil {
+		return translateError(err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoProductRepository) UpdateVariant(ctx context.Context, id bson.ObjectID, variant models.ProductVariant) error {
+	set := bson.M{"variants.$[v].sku": variant.SKU, "variants.$[v].options": variant.Options}
+	unset := bson.M{}
+	if variant.Price != nil {
+		set["variants.$[v].price"] = *variant.Price
+	} else {
+		unset["variants.$[v].price"] = ""
+	}
+	if len(variant.ImageUrls) > 0 {
+		set["variants.$[v].imageUrls"] = variant.ImageUrls
+	} else {
+		unset["variants.$[v].imageUrls"] = ""
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "variants._id": variant.Id}, update,
+		options.UpdateOne().SetArrayFilters([]any{bson.M{"v._id": variant.Id}}))
+	if err != nil {
+		return translateError(err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoProductRepository) DeleteVariant(ctx context.Context, id, variantID bson.ObjectID) error {
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "variants._id": variantID},
+		bson.M{"$pull": bson.M{"variants": bson.M{"_id": variantID}}})
+	if err != nil {
+		return translateError(err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *mongoProductRepository) RenameVariantImages(ctx context.Context, id bson.ObjectID, renamed map[string]string) error {
+	// only products and variants holding one of the URLs are walked, so a
+	// missing variants or imageUrls field is never traversed
+	set := bson.M{}
+	var removed, replaced bson.A
+	var filters []any
+	for i, old := range slices.Sorted(maps.Keys(renamed)) {
+		if renamed[old] == "" {
+			removed = append(removed, old)
+			continue
+		}
+		replaced = append(replaced, old)
+		v, u := fmt.Sprintf("v%d", i), fmt.Sprintf("u%d", i)
+		set["variants.$["+v+"].imageUrls.$["+u+"]"] = renamed[old]
+		filters = append(filters, bson.M{v + ".imageUrls": old}, bson.M{u: old})
+	}
+	if len(set) > 0 {
+		_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "variants.imageUrls": bson.M{"$in": replaced}},
+			bson.M{"$set": set}, options.UpdateOne().SetArrayFilters(filters))
+		if err != nil {
+			return translateError(err)
+		}
+	}
+	if len(removed) > 0 {
+		_, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "variants.imageUrls": bson.M{"$in": removed}},
+			bson.M{"$pull": bson.M{"variants.$[v].imageUrls": bson.M{"$in": removed}}},
+			options.UpdateOne().SetArrayFilters([]any{bson.M{"v.imageUrls": bson.M{"$in": removed}}}))
+		if err != nil {
+			return translateError(err)
+		}
+	}
+	return nil
+}
+
+func (r *mongoProductRepository) AdjustStock(ctx context.Context, id bson.ObjectID, variantID *bson.ObjectID, delta int) (int, error) {
+	filter := bson.M{"_id": id, "deletedAt": nil}
+	field := "quantity"
+	var enough any = bson.M{"$gte": -delta}
+	if variantID != nil {
+		elem := bson.M{"_id": *variantID}
+		if delta < 0 {
+			elem["quantity"] = enough
+		}
+		filter["variants"] = bson.M{"$elemMatch": elem}
+		field = "variants.$.quantity"
+	} else if delta < 0 {
+		filter["quantity"] = enough
+	}
+
+	var updated models.Product
+	err := r.col.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{field: delta}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// tell a missing product or variant from a short stock
+		product, err := r.FindByID(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		if variantID != nil {
+			if _, ok := product.Variant(*variantID); !ok {
+				return 0, ErrNotFound
+			}
+		}
+		return 0, ErrInsufficientStock
+	}
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return stockOf(&updated, variantID), nil
+}
+
+func (r *mongoProductRepository) ApplySchedule(ctx context.Context, now time.Time) (int64, int64, error) {
+	published, err := r.col.UpdateMany(ctx,
+		bson.M{"deletedAt": nil, "status": models.ProductStatusScheduled, "publishAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"status": models.ProductStatusPublished}})
+	if err != nil {
+		return 0, 0, err
+	}
+	archived, err := r.col.UpdateMany(ctx,
+		bson.M{"deletedAt": nil, "status": bson.M{"$in": bson.A{models.ProductStatusPublished, nil}}, "unpublishAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"status": models.ProductStatusArchived}})
+	if err != nil {
+		return published.ModifiedCount, 0, err
+	}
+	return published.ModifiedCount, archived.ModifiedCount, nil
+}
+
+func (r *mongoProductRepository) LowStock(ctx context.Context, threshold int, page Page) ([]models.Product, int64, error) {
+	items := bson.A{bson.M{"$sort": bson.D{{Key: "stock", Value: 1}, {Key: "name", Value: 1}}}}
+	if page.Skip > 0 {
+		items = append(items, bson.M{"$skip": page.Skip})
+	}
+	if page.Limit > 0 {
+		items = append(items, bson.M{"$limit": page.Limit})
+	}
+	items = append(items, bson.M{"$unset": "stock"})
+
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"deletedAt": nil}},
+		// the stock of a product sold in variants is its lowest variant's
+		bson.M{"$addFields": bson.M{"stock": bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$variants", bson.A{}}}}, 0}},
+			bson.M{"$min": "$variants.quantity"},
+			"$quantity",
+		}}}},
+		bson.M{"$match": bson.M{"stock": bson.M{"$lte": threshold}}},
+		bson.M{"$facet": bson.M{
+			"items": items,
+			"total": bson.A{bson.M{"$count": "n"}},
+		}},
+	}
+
+	cursor, err := r.col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Items []models.Product `bson:"items"`
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, 0, err
+	}
+	out, total := []models.Product{}, int64(0)
+	if len(results) == 1 {
+		out = append(out, results[0].Items...)
+		if len(results[0].Total) == 1 {
+			total = results[0].Total[0].N
+		}
+	}
+	return out, total, nil
+}
+
+// stockOf is the quantity of a product, or of its variant when variantID is
+// set.
+func stockOf(p *models.Product, variantID *bson.ObjectID) int {
+	if variantID == nil {
+		return p.Quantity
+	}
+	if v, ok := p.Variant(*variantID); ok {
+		return v.Quantity
+	}
+	return 0
+}
+
+// lowestStock is what LowStock sorts on: the product quantity, or the lowest
+// variant quantity.
+func lowestStock(p *models.Product) int {
+	if len(p.Variants) == 0 {
+		return p.Quantity
+	}
+	lowest := p.Variants[0].Quantity
+	for _, v := range p.Variants[1:] {
+		lowest = min(lowest, v.Quantity)
+	}
+	return lowest
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/search"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ProductRequestFilter narrows the admin product request listing.
+type ProductRequestFilter struct {
+	Status string
+	Email  string
+	// Q is matched literally (case-insensitive) against fullName, email,
+	// company and description.
+	Q string
+}
+
+type ProductRequestRepository interface {
+	List(ctx context.Context, filter ProductRequestFilter, page Page) ([]models.ProductRequest, int64, error)
+	FindByID(ctx context.Context, id bson.ObjectID) (*models.ProductRequest, error)
+	Insert(ctx context.Context, req *models.ProductRequest) error
+	Update(ctx context.Context, id bson.ObjectID, set bson.M) error
+	// AddNote appends an admin note and moves the request from NEW to
+	// IN_PROGRESS. A request in any other status only gets the note.
+	AddNote(ctx context.Context, id bson.ObjectID, note models.ProductRequestAdminNote) error
+}
+
+type mongoProductRequestRepository struct {
+	col *mongo.Collection
+}
+
+func NewProductRequestRepository(db *mongo.Database) ProductRequestRepository {
+	return &mongoProductRequestRepository{col: db.Collection("product_requests")}
+}
+
+func (r *mongoProductRequestRepository) List(ctx context.Context, filter ProductRequestFilter, page Page) ([]models.ProductRequest, int64, error) {
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Email != "" {
+		query["email"] = filter.Email
+	}
+	if filter.Q != "" {
+		pattern := search.Pattern(filter.Q)
+		query["$or"] = []bson.M{
+			{"fullName": bson.M{"$regex": pattern}},
+			{"email": bson.M{"$regex": pattern}},
+			{"company": bson.M{"$regex": pattern}},
+			{"description": bson.M{"$regex": pattern}},
+		}
+	}
+	return findPage[models.ProductRequest](ctx, r.col, query, bson.D{{Key: "createdAt", Value: -1}}, page)
+}
+
+func (r *mongoProductRequestRepository) FindByID(ctx context.Context, id bson.ObjectID) (*models.ProductRequest, error) {
+	return findOne[models.ProductRequest](ctx, r.col, bson.M{"_id": id})
+}
+
+func (r *mongoProductRequestRepository) Insert(ctx context.Context, req *models.ProductRequest) error {
+	if req.Id.IsZero() {
+		req.Id = bson.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, req)
+	return translateError(err)
+}
+
+func (r *mongoProductRequestRepository) Update(ctx context.Context, id bson.ObjectID, set bson.M) error {
+	return updateByID(ctx, r.col, id, set)
+}
+
+func (r *mongoProductRequestRepository) AddNote(ctx context.Context, id bson.ObjectID, note models.ProductRequestAdminNote) error {
+	now := time.Now().UTC()
+
+	// auto-advance NEW -> IN_PROGRESS if adding first note
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.ProductRequestStatusNew},
+		bson.M{
+			"$push": bson.M{"notes": note},
+			"$set": bson.M{
+				"status":    models.ProductRequestStatusInProgress,
+				"updatedAt": now,
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	// if not NEW, just push note + updatedAt
+	res, err = r.col.UpdateByID(ctx, id, bson.M{
+		"$push": bson.M{"notes": note},
+		"$set":  bson.M{"updatedAt": now},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
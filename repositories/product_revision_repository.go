@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ProductRevisionRepository keeps the history of the products. Revisions
+// are only ever appended; a product and version pair is unique, so two
+// concurrent writers of the same version get ErrDuplicateKey.
+type ProductRevisionRepository interface {
+	// List returns the revisions of a product, newest first.
+	List(ctx context.Context, productID bson.ObjectID, page Page) ([]models.ProductRevision, int64, error)
+	FindByVersion(ctx context.Context, productID bson.ObjectID, version int) (*models.ProductRevision, error)
+	// Latest returns the last revision of a product, or ErrNotFound for a
+	// product without history.
+	Latest(ctx context.Context, productID bson.ObjectID) (*models.ProductRevision, error)
+	Insert(ctx context.Context, revision *models.ProductRevision) error
+}
+
+type mongoProductRevisionRepository struct {
+	col *mongo.Collection
+}
+
+func NewProductRevisionRepository(db *mongo.Database) ProductRevisionRepository {
+	return &mongoProductRevisionRepository{col: db.Collection("product_revisions")}
+}
+
+func (r *mongoProductRevisionRepository) List(ctx context.Context, productID bson.ObjectID, page Page) ([]models.ProductRevision, int64, error) {
+	return findPage[models.ProductRevision](ctx, r.col, bson.M{"productId": productID}, bson.D{{Key: "version", Value: -1}}, page)
+}
+
+func (r *mongoProductRevisionRepository) FindByVersion(ctx context.Context, productID bson.ObjectID, version int) (*models.ProductRevision, error) {
+	return findOne[models.ProductRevision](ctx, r.col, bson.M{"productId": productID, "version": version})
+}
+
+func (r *mongoProductRevisionRepository) Latest(ctx context.Context, productID bson.ObjectID) (*models.ProductRevision, error) {
+	var latest models.ProductRevision
+	err := r.col.FindOne(ctx, bson.M{"productId": productID}, options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})).Decode(&latest)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &latest, nil
+}
+
+func (r *mongoProductRevisionRepository) Insert(ctx context.Context, revision *models.ProductRevision) error {
+	if revision.ID.IsZero() {
+		revision.ID = bson.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, revision)
+	return translateError(err)
+}
@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// QuoteRequestFilter narrows the admin quote request listing.
+type QuoteRequestFilter struct {
+	Status string
+}
+
+type QuoteRequestRepository interface {
+	List(ctx context.Context, filter QuoteRequestFilter, page Page) ([]models.QuoteRequest, int64, error)
+	FindByID(ctx context.Context, id bson.ObjectID) (*models.QuoteRequest, error)
+	Insert(ctx context.Context, quote *models.QuoteRequest) error
+	Update(ctx context.Context, id bson.ObjectID, set bson.M) error
+	// AddNote appends an admin note and moves the quote from NEW to
+	// IN_PROGRESS. A quote in any other status only gets the note.
+	AddNote(ctx context.Context, id bson.ObjectID, note models.QuoteAdminNote) error
+}
+
+type mongoQuoteRequestRepository struct {
+	col *mongo.Collection
+}
+
+func NewQuoteRequestRepository(db *mongo.Database) QuoteRequestRepository {
+	return &mongoQuoteRequestRepository{col: db.Collection("quote_requests")}
+}
+
+func (r *mongoQuoteRequestRepository) List(ctx context.Context, filter QuoteRequestFilter, page Page) ([]models.QuoteRequest, int64, error) {
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	// newest first
+	return findPage[models.QuoteRequest](ctx, r.col, query, bson.D{{Key: "createdAt", Value: -1}}, page)
+}
+
+func (r *mongoQuoteRequestRepository) FindByID(ctx context.Context, id bson.ObjectID) (*models.QuoteRequest, error) {
+	return findOne[models.QuoteRequest](ctx, r.col, bson.M{"_id": id})
+}
+
+func (r *mongoQuoteRequestRepository) Insert(ctx context.Context, quote *models.QuoteRequest) error {
+	if quote.ID.IsZero() {
+		quote.ID = bson.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, quote)
+	return translateError(err)
+}
+
+func (r *mongoQuoteRequestRepository) Update(ctx context.Context, id bson.ObjectID, set bson.M) error {
+	return updateByID(ctx, r.col, id, set)
+}
+
+func (r *mongoQuoteRequestRepository) AddNote(ctx context.Context, id bson.ObjectID, note models.QuoteAdminNote) error {
+	now := time.Now().UTC()
+
+	// only auto-advance from NEW — won't overwrite a more advanced status
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.QuoteStatusNew},
+		bson.M{
+			"$push": bson.M{"notes": note},
+			"$set": bson.M{
+				"status":    models.QuoteStatusInProgress,
+				"updatedAt": now,
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	// If the quote wasn't NEW, just push the note without changing status
+	res, err = r.col.UpdateByID(ctx, id, bson.M{
+		"$push": bson.M{"notes": note},
+		"$set":  bson.M{"updatedAt": now},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type RefreshTokenRepository interface {
+	Insert(ctx context.Context, token *models.RefreshToken) error
+	// FindActive returns the token with the given hash if it is neither
+	// revoked nor expired at now.
+	FindActive(ctx context.Context, tokenHash string, now time.Time) (*models.RefreshToken, error)
+	// Revoke marks one token as revoked, recording the token that replaced it (if any).
+	Revoke(ctx context.Context, id bson.ObjectID, replacedBy *string) error
+	RevokeByHash(ctx context.Context, tokenHash string) error
+	RevokeAllForUser(ctx context.Context, userID bson.ObjectID) error
+}
+
+type mongoRefreshTokenRepository struct {
+	col *mongo.Collection
+}
+
+func NewRefreshTokenRepository(db *mongo.Database) RefreshTokenRepository {
+	return &mongoRefreshTokenRepository{col: db.Collection("refresh_tokens")}
+}
+
+func (r *mongoRefreshTokenRepository) Insert(ctx context.Context, token *models.RefreshToken) error {
+	if token.ID.IsZero() {
+		token.ID = bson.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, token)
+	return translateError(err)
+}
+
+func (r *mongoRefreshTokenRepository) FindActive(ctx context.Context, tokenHash string, now time.Time) (*models.RefreshToken, error) {
+	return findOne[models.RefreshToken](ctx, r.col, bson.M{
+		"tokenHash": tokenHash,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": now},
+	})
+}
+
+func (r *mongoRefreshTokenRepository) Revoke(ctx context.Context, id bson.ObjectID, replacedBy *string) error {
+	set := bson.M{"revokedAt": time.Now().UTC()}
+	if replacedBy != nil {
+		set["replacedBy"] = *replacedBy
+	}
+	return updateByID(ctx, r.col, id, set)
+}
+
+func (r *mongoRefreshTokenRepository) RevokeByHash(ctx context.Context, tokenHash string) error {
+	_, err := r.col.UpdateOne(ctx, bson.M{
+		"tokenHash": tokenHash,
+		"revokedAt": bson.M{"$exists": false},
+	}, bson.M{
+		"$set": bson.M{"revokedAt": time.Now().UTC()},
+	})
+	return err
+}
+
+func (r *mongoRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID bson.ObjectID) error {
+	_, err := r.col.UpdateMany(ctx, bson.M{
+		"userId":    userID,
+		"revokedAt": bson.M{"$exists": false},
+	}, bson.M{
+		"$set": bson.M{"revokedAt": time.Now().UTC()},
+	})
+	return err
+}
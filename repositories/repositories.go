@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/princinho/sahobackend/utils"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	// ErrNotFound is returned when no document matches the lookup.
+	ErrNotFound = errors.New("document not found")
+	// ErrDuplicateKey is returned when a write violates a unique index (e.g. slug, email).
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrInsufficientStock is returned when a stock movement would take a
+	// quantity below zero.
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
+
+// Page holds the skip/limit pair computed by the handlers from ?page=&limit=.
+type Page struct {
+	Skip  int64
+	Limit int64
+}
+
+// translateError maps driver errors onto the repository sentinel errors so
+// controllers never have to import the mongo package.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	if utils.IsDuplicateKey(err) {
+		return errors.Join(ErrDuplicateKey, err)
+	}
+	return err
+}
+
+// findPage runs a paginated Find and the matching CountDocuments.
+func findPage[T any](ctx context.Context, col *mongo.Collection, filter bson.M, sort bson.D, page Page) ([]T, int64, error) {
+	opts := options.Find().SetSort(sort)
+	if page.Skip > 0 {
+		opts.SetSkip(page.Skip)
+	}
+	if page.Limit > 0 {
+		opts.SetLimit(page.Limit)
+	}
+
+	cursor, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	items := make([]T, 0)
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// findOne decodes a single document into a new T.
+func findOne[T any](ctx context.Context, col *mongo.Collection, filter bson.M) (*T, error) {
+	var out T
+	if err := col.FindOne(ctx, filter).Decode(&out); err != nil {
+		return nil, translateError(err)
+	}
+	return &out, nil
+}
+
+// updateByID applies a $set to one document, returning ErrNotFound when nothing matched.
+func updateByID(ctx context.Context, col *mongo.Collection, id bson.ObjectID, set bson.M) error {
+	res, err := col.UpdateByID(ctx, id, bson.M{"$set": set})
+	if err != nil {
+		return translateError(err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// missingTranslationQuery matches the documents with one of fields set but
+// not translated in locale; $in with nil also matches a missing field.
+func missingTranslationQuery(locale string, fields []string) bson.M {
+	or := bson.A{}
+	for _, field := range fields {
+		or = append(or, bson.M{
+			field:                                  bson.M{"$nin": bson.A{"", nil}},
+			"translations." + locale + "." + field: bson.M{"$in": bson.A{"", nil}},
+		})
+	}
+	return bson.M{"$or": or}
+}
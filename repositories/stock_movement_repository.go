@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// StockMovementFilter narrows the movement history; zero fields match all.
+type StockMovementFilter struct {
+	ProductID *bson.ObjectID
+	VariantID *bson.ObjectID
+	Type      models.StockMovementType
+}
+
+// StockMovementRepository is the inventory ledger. Movements are only ever
+// appended; ProductRepository.AdjustStock applies them to the quantities.
+type StockMovementRepository interface {
+	// List returns the movements newest first.
+	List(ctx context.Context, filter StockMovementFilter, page Page) ([]models.StockMovement, int64, error)
+	Insert(ctx context.Context, movement *models.StockMovement) error
+}
+
+type mongoStockMovementRepository struct {
+	col *mongo.Collection
+}
+
+func NewStockMovementRepository(db *mongo.Database) StockMovementRepository {
+	return &mongoStockMovementRepository{col: db.Collection("stock_movements")}
+}
+
+func (r *mongoStockMovementRepository) List(ctx context.Context, filter StockMovementFilter, page Page) ([]models.StockMovement, int64, error) {
+	query := bson.M{}
+	if filter.ProductID != nil {
+		query["productId"] = *filter.ProductID
+	}
+	if filter.VariantID != nil {
+		query["variantId"] = *filter.VariantID
+	}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	// _id breaks ties between movements recorded in the same millisecond
+	sort := bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}
+	return findPage[models.StockMovement](ctx, r.col, query, sort, page)
+}
+
+func (r *mongoStockMovementRepository) Insert(ctx context.Context, movement *models.StockMovement) error {
+	if movement.ID.IsZero() {
+		movement.ID = bson.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, movement)
+	return translateError(err)
+}
@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/princinho/sahobackend/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type UserRepository interface {
+	FindByID(ctx context.Context, id bson.ObjectID) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	Insert(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, id bson.ObjectID, set bson.M) error
+}
+
+type mongoUserRepository struct {
+	col *mongo.Collection
+}
+
+func NewUserRepository(db *mongo.Database) UserRepository {
+	return &mongoUserRepository{col: db.Collection("users")}
+}
+
+func (r *mongoUserRepository) FindByID(ctx context.Context, id bson.ObjectID) (*models.User, error) {
+	return findOne[models.User](ctx, r.col, bson.M{"_id": id})
+}
+
+func (r *mongoUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return findOne[models.User](ctx, r.col, bson.M{"email": email})
+}
+
+func (r *mongoUserRepository) Insert(ctx context.Context, user *models.User) error {
+	if user.ID.IsZero() {
+		user.ID = bson.NewObjectID()
+	}
+	_, err := r.col.InsertOne(ctx, user)
+	return translateError(err)
+}
+
+func (r *mongoUserRepository) Update(ctx context.Context, id bson.ObjectID, set bson.M) error {
+	return updateByID(ctx, r.col, id, set)
+}
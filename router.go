@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/controllers"
+	"github.com/princinho/sahobackend/middleware"
+	"github.com/princinho/sahobackend/storage"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// newRouter registers every route of the API on a fresh engine. It is shared
+// by main and the HTTP test suite so both exercise the same wiring.
+func newRouter(app *controllers.App) *gin.Engine {
+	r := gin.New()
+	cfg := app.Config
+
+	allowedOrigins := map[string]bool{}
+	for _, origin := range cfg.Server.AllowedOrigins {
+		allowedOrigins[origin] = true
+	}
+	// The server span comes first so every log line can carry its trace ID;
+	// request logging follows so CORS rejections and panics are logged with
+	// a request ID too. Errors renders what the handlers (and Recovery)
+	// abort with, so everything observing the final status wraps it.
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(tracedRequest)))
+	r.Use(middleware.RequestLogger(app.Logger))
+	r.Use(middleware.Metrics(app.Metrics))
+	r.Use(middleware.Errors())
+	r.Use(middleware.Recovery())
+	r.Use(cors.New(cors.Config{
+		AllowOriginFunc: func(origin string) bool {
+			return allowedOrigins[origin]
+		},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", middleware.RequestIDHeader, "traceparent", "tracestate", "baggage"},
+		ExposeHeaders:    []string{"Content-Length", middleware.RequestIDHeader},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}))
+
+	r.NoRoute(middleware.NoRoute())
+
+	r.GET("/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "pong",
+		})
+	})
+	r.GET("/healthz", app.Healthz())
+	r.GET("/readyz", app.Readyz())
+	r.GET("/metrics", middleware.MetricsAuth(cfg.Metrics.Token), gin.WrapH(app.Metrics.Handler()))
+	r.GET("/openapi.json", app.OpenAPI())
+	r.GET("/docs", app.Docs())
+
+	// Files written by the local storage driver are served by the API itself;
+	// cloud drivers hand out bucket URLs instead.
+	if local, ok := storage.Unwrap(app.Storage).(*storage.Local); ok {
+		r.Static(storage.MediaPath, local.Dir())
+	}
+
+	r.POST("/auth/login", app.Login())
+	r.POST("/auth/refresh", app.Refresh())
+	r.POST("/auth/logout", app.Logout())
+
+	r.GET("/products", app.GetProducts())
+	r.GET("/products/:id", app.GetProduct())
+	r.GET("/products/slug/:slug", app.GetProduct())
+	r.GET("/categories", app.GetCategories())
+	r.GET("/categories/:id", app.GetCategory())
+	r.GET("/categories/slug/:slug", app.GetCategory())
+	r.GET("/exchange-rates", app.GetExchangeRates())
+	r.POST("/quote-requests", app.CreateQuoteRequest())
+	r.POST("/product-requests", app.CreateProductRequest())
+
+	admin := r.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(cfg.Auth.JWTSecret))
+	{
+		admin.GET("/products", app.GetAdminProducts())
+		admin.POST("/products/add", app.AddProduct())
+		admin.PATCH("/products/update/:id", app.UpdateProduct())
+		admin.DELETE("/products/:id", app.DeleteProduct())
+		admin.GET("/products/trash", app.GetTrashedProducts())
+		admin.POST("/products/:id/restore", app.RestoreProduct())
+		admin.PATCH("/products/:id/status", app.UpdateProductStatus())
+		admin.PATCH("/products/:id/images", app.UpdateProductImages())
+		admin.GET("/products/:id/revisions", app.GetProductRevisions())
+		admin.GET("/products/:id/revisions/diff", app.GetProductRevisionDiff())
+		admin.POST("/products/:id/revisions/:version/rollback", app.RollbackProduct())
+		admin.GET("/products/export", app.ExportProducts())
+		admin.POST("/products/import", app.ImportProducts())
+		admin.POST("/products/:id/variants", app.AddProductVariant())
+		admin.PATCH("/products/:id/variants/:variantId", app.UpdateProductVariant())
+		admin.DELETE("/products/:id/variants/:variantId", app.DeleteProductVariant())
+		admin.POST("/products/:id/stock-movements", app.AddStockMovement())
+		admin.GET("/products/low-stock", app.GetLowStockProducts())
+		admin.GET("/stock-movements", app.GetStockMovements())
+
+		admin.GET("/categories", app.GetAdminCategories())
+		admin.POST("/categories", app.AddCategory())
+		admin.PATCH("/categories/:id", app.UpdateCategory())
+		admin.DELETE("/categories/:id", app.DeleteCategory())
+		admin.GET("/translations/missing", app.GetMissingTranslations())
+
+		admin.PUT("/exchange-rates/:currency", app.SetExchangeRate())
+		admin.DELETE("/exchange-rates/:currency", app.DeleteExchangeRate())
+
+		admin.GET("/quote-requests", app.GetQuoteRequests())
+		admin.GET("/quote-requests/:id", app.GetQuoteRequest())
+		admin.PATCH("/quote-requests/:id/status", app.UpdateQuoteStatus())
+		admin.POST("/quote-requests/:id/notes", app.AddQuoteNote())
+
+		admin.GET("/product-requests", app.GetProductRequests())
+		admin.GET("/product-requests/:id", app.GetProductRequest())
+		admin.PATCH("/product-requests/:id/status", app.UpdateProductRequestStatus())
+		admin.POST("/product-requests/:id/notes", app.AddProductRequestNote())
+		admin.POST("/users", app.CreateUser())
+		admin.POST("/users/me/password", app.ChangeMyPassword())
+
+		admin.GET("/config", app.GetConfig())
+	}
+	return r
+}
+
+// tracedRequest keeps probes and scrapes out of the traces.
+func tracedRequest(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/readyz", "/metrics", "/ping":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+// Package search holds the text matching rules shared by the product search
+// (MongoDB text index, in-memory repository, highlighted snippets) and the
+// regex filters of the other listings: case and accents never matter, so
+// "lome" finds "Lomé".
+package search
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/princinho/sahobackend/utils"
+)
+
+// MaxQueryLength bounds the ?q= parameter.
+const MaxQueryLength = 100
+
+// ProductWeights ranks the searched product fields (bson names); their
+// translations weigh the same. The text index is built with them, so
+// changing one needs a new migration.
+var ProductWeights = map[string]int{
+	"name":            10,
+	"materials":       4,
+	"colors":          4,
+	"description":     2,
+	"descriptionFull": 1,
+}
+
+// Fold lowercases s and strips its accents.
+func Fold(s string) string {
+	return strings.ToLower(utils.StripAccents(s))
+}
+
+// Terms splits a query into distinct folded words.
+func Terms(q string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, w := range words(Fold(q)) {
+		if !seen[w] {
+			seen[w] = true
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
+
+// Matches reports whether a folded word matches a term. Plurals and short
+// inflections count ("chaises" matches "chaise"), roughly like the stemming
+// of the French text index.
+func Matches(word, term string) bool {
+	if word == term {
+		return true
+	}
+	if len(term) < 3 || len(word) < 3 {
+		return false
+	}
+	long, short := word, term
+	if len(short) > len(long) {
+		long, short = short, long
+	}
+	return strings.HasPrefix(long, short) && len(long)-len(short) <= 2
+}
+
+// Count returns how many words of text match one of terms.
+func Count(text string, terms []string) int {
+	n := 0
+	for _, w := range words(Fold(text)) {
+		for _, t := range terms {
+			if Matches(w, t) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// accented lists the letters a folded letter may stand for.
+var accented = map[rune]string{
+	'a': "aàâäáãå",
+	'c': "cç",
+	'e': "eéèêë",
+	'i': "iîïíì",
+	'n': "nñ",
+	'o': "oôöóòõ",
+	'u': "uùûüú",
+	'y': "yÿý",
+}
+
+// Pattern turns a literal query into a regular expression that matches it
+// regardless of case and accents. Both cases are spelled out because
+// MongoDB's "i" option only folds ASCII.
+func Pattern(q string) string {
+	var b strings.Builder
+	for _, r := range Fold(q) {
+		variants, ok := accented[r]
+		if !ok {
+			if unicode.IsLetter(r) {
+				b.WriteString("[" + string(r) + strings.ToUpper(string(r)) + "]")
+			} else {
+				b.WriteString(regexp.QuoteMeta(string(r)))
+			}
+			continue
+		}
+		b.WriteString("[" + variants + strings.ToUpper(variants) + "]")
+	}
+	return b.String()
+}
+
+// Highlight returns an HTML snippet of text around the first word matching
+// terms, matches wrapped in <mark>. At most context words are kept on each
+// side, cut ends are shown as "…". It returns "" when nothing matches.
+func Highlight(text string, terms []string, context int) string {
+	type token struct {
+		text  string
+		match bool
+	}
+	var tokens []token
+	first := -1
+	for _, part := range splitKeep(text) {
+		match := false
+		if isWord(part) {
+			folded := Fold(part)
+			for _, t := range terms {
+				if Matches(folded, t) {
+					match = true
+					break
+				}
+			}
+		}
+		if match && first < 0 {
+			first = len(tokens)
+		}
+		tokens = append(tokens, token{part, match})
+	}
+	if first < 0 {
+		return ""
+	}
+
+	// count context in words, not in the separators between them
+	start, seen := first, 0
+	for start > 0 && seen < context {
+		start--
+		if isWord(tokens[start].text) {
+			seen++
+		}
+	}
+	end, seen := first+1, 0
+	for end < len(tokens) && seen < context {
+		if isWord(tokens[end].text) {
+			seen++
+		}
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for _, tok := range tokens[start:end] {
+		if tok.match {
+			b.WriteString("<mark>" + html.EscapeString(tok.text) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(tok.text))
+		}
+	}
+	if end < len(tokens) {
+		b.WriteString("…")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// splitKeep cuts text into alternating words and separators.
+func splitKeep(text string) []string {
+	var parts []string
+	start := 0
+	inWord := false
+	for i, r := range text {
+		w := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		if i > 0 && w != inWord {
+			parts = append(parts, text[start:i])
+			start = i
+		}
+		inWord = w
+	}
+	if start < len(text) {
+		parts = append(parts, text[start:])
+	}
+	return parts
+}
+
+func isWord(s string) bool {
+	for _, r := range s {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestTermsFoldAndDeduplicate(t *testing.T) {
+	got := Terms("  Tabouret en BOIS, tabouret d'Été ")
+	want := []string{"tabouret", "en", "bois", "d", "ete"}
+	if len(got) != len(want) {
+		t.Fatalf("Terms = %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Terms = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestMatchesToleratesPlurals(t *testing.T) {
+	cases := []struct {
+		word, term string
+		want       bool
+	}{
+		{"chaise", "chaise", true},
+		{"chaises", "chaise", true},
+		{"chaise", "chaises", true},
+		{"chaiseslongues", "chaise", false},
+		{"en", "ens", false},
+		{"bois", "boiserie", false},
+	}
+	for _, tc := range cases {
+		if got := Matches(tc.word, tc.term); got != tc.want {
+			t.Errorf("Matches(%q, %q) = %v", tc.word, tc.term, got)
+		}
+	}
+}
+
+func TestPatternIgnoresCaseAndAccents(t *testing.T) {
+	re := regexp.MustCompile(Pattern("lome (TG)"))
+	for _, s := range []string{"Lomé (Tg)", "LOMÉ (TG)", "lome (tg)", "à Lomê (tG) !"} {
+		if !re.MatchString(s) {
+			t.Errorf("%q does not match %s", s, re)
+		}
+	}
+	if re.MatchString("Lomxe (TG)") || re.MatchString("lome TG") {
+		t.Fatalf("pattern is not literal: %s", re)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	text := "Une chaise en teck massif, finition huilée <b>naturelle</b>, idéale pour la terrasse ou le jardin."
+	got := Highlight(text, Terms("huilee jardin"), 2)
+	want := "…massif, finition <mark>huilée</mark> &lt;b&gt;naturelle…"
+	if got != want {
+		t.Fatalf("Highlight =\n%q\nwant\n%q", got, want)
+	}
+
+	if got := Highlight("Chaises longues", Terms("chaise"), 8); got != "<mark>Chaises</mark> longues" {
+		t.Fatalf("whole text = %q", got)
+	}
+	if got := Highlight("Table basse", Terms("chaise"), 8); got != "" {
+		t.Fatalf("no match = %q", got)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/princinho/sahobackend/config"
+	"github.com/princinho/sahobackend/health"
+)
+
+// newServer applies the configured timeouts to handler.
+func newServer(cfg config.Server, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// serve runs srv on ln until ctx is cancelled (SIGINT/SIGTERM in main), then
+// drains: readiness turns unavailable and in-flight requests get
+// shutdownTimeout to finish. It returns early if the listener fails.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, checker *health.Checker, shutdownTimeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", ln.Addr().String())
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, draining in-flight requests", "timeout", shutdownTimeout.String())
+	checker.Drain()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/princinho/sahobackend/config"
+	"github.com/princinho/sahobackend/health"
+)
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := health.NewChecker(time.Second)
+	srv := newServer(config.Defaults().Server, mux)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- serve(ctx, srv, ln, checker, 5*time.Second) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	got := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			got <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		got <- result{string(b), err}
+	}()
+
+	<-started
+	cancel()
+	// readiness flips before the in-flight request completes
+	deadline := time.Now().Add(2 * time.Second)
+	for checker.Check(context.Background()).Status != health.StatusDraining {
+		if time.Now().After(deadline) {
+			t.Fatal("checker never started draining")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+
+	if r := <-got; r.err != nil || r.body != "done" {
+		t.Fatalf("in-flight request = %q, %v", r.body, r.err)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+}
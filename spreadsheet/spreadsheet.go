@@ -0,0 +1,166 @@
+// Package spreadsheet reads and writes the tables of the catalog import and
+// export as CSV or XLSX. A table is a header row followed by data rows; every
+// cell is a string and the callers parse the values.
+package spreadsheet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Format names accepted by Read and Write.
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+)
+
+// ContentTypes of the formats, for downloads.
+var ContentTypes = map[string]string{
+	CSV:  "text/csv; charset=utf-8",
+	XLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ErrFormat is returned for a format other than CSV and XLSX.
+var ErrFormat = errors.New("unsupported spreadsheet format (allowed: csv, xlsx)")
+
+// sheetName is the sheet written to XLSX files; Read takes the first sheet
+// whatever its name.
+const sheetName = "Produits"
+
+// FormatOf guesses the format from a file name.
+func FormatOf(fileName string) (string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return CSV, nil
+	case ".xlsx":
+		return XLSX, nil
+	}
+	return "", ErrFormat
+}
+
+// Read returns the rows of a table. Trailing empty cells are kept as ""
+// up to the header width, so every row is at least as long as the header.
+func Read(format string, r io.Reader) ([][]string, error) {
+	var rows [][]string
+	var err error
+	switch format {
+	case CSV:
+		rows, err = readCSV(r)
+	case XLSX:
+		rows, err = readXLSX(r)
+	default:
+		return nil, ErrFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		width := len(rows[0])
+		for i, row := range rows {
+			for len(row) < width {
+				row = append(row, "")
+			}
+			rows[i] = row
+		}
+	}
+	return rows, nil
+}
+
+// Write encodes rows, the header first.
+func Write(format string, w io.Writer, rows [][]string) error {
+	switch format {
+	case CSV:
+		return writeCSV(w, rows)
+	case XLSX:
+		return writeXLSX(w, rows)
+	}
+	return ErrFormat
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+	// spreadsheets set to French write "name;price" rather than "name,price"
+	header, _ := br.Peek(4096)
+	header, _, _ = bytes.Cut(header, []byte("\n"))
+
+	cr := csv.NewReader(br)
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		// Excel saves "CSV UTF-8" with a byte order mark
+		rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+	}
+	return rows, nil
+}
+
+func writeCSV(w io.Writer, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("write csv: %w", err)
+	}
+	return nil
+}
+
+func readXLSX(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read xlsx: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, nil
+	}
+	rows, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("read xlsx: %w", err)
+	}
+	return rows, nil
+}
+
+func writeXLSX(w io.Writer, rows [][]string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName(f.GetSheetName(0), sheetName); err != nil {
+		return fmt.Errorf("write xlsx: %w", err)
+	}
+	sw, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		return fmt.Errorf("write xlsx: %w", err)
+	}
+	for i, row := range rows {
+		cells := make([]any, len(row))
+		for j, v := range row {
+			cells[j] = v
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := sw.SetRow(cell, cells); err != nil {
+			return fmt.Errorf("write xlsx: %w", err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		return fmt.Errorf("write xlsx: %w", err)
+	}
+	if _, err := f.WriteTo(w); err != nil {
+		return fmt.Errorf("write xlsx: %w", err)
+	}
+	return nil
+}
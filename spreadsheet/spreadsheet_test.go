@@ -0,0 +1,52 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWriteThenReadRoundTrips(t *testing.T) {
+	rows := [][]string{
+		{"slug", "name", "price"},
+		{"table-kara", "Table Kara, noyer", "300"},
+		{"banc", "Banc \"long\"", ""},
+	}
+	for _, format := range []string{CSV, XLSX} {
+		var buf bytes.Buffer
+		if err := Write(format, &buf, rows); err != nil {
+			t.Fatalf("%s: write: %v", format, err)
+		}
+		got, err := Read(format, &buf)
+		if err != nil {
+			t.Fatalf("%s: read: %v", format, err)
+		}
+		if !slices.EqualFunc(got, rows, slices.Equal) {
+			t.Fatalf("%s: rows = %q, want %q", format, got, rows)
+		}
+	}
+}
+
+func TestReadCSVDetectsSemicolonsAndByteOrderMark(t *testing.T) {
+	got, err := Read(CSV, strings.NewReader("\ufeffslug;name;price\nbanc;Banc;80,5\ntabouret\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"slug", "name", "price"}, {"banc", "Banc", "80,5"}, {"tabouret", "", ""}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Fatalf("rows = %q, want %q", got, want)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for name, want := range map[string]string{"catalogue.CSV": CSV, "a/b.xlsx": XLSX} {
+		if got, err := FormatOf(name); err != nil || got != want {
+			t.Errorf("FormatOf(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := FormatOf("catalogue.xls"); !errors.Is(err, ErrFormat) {
+		t.Errorf("FormatOf(xls) error = %v", err)
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/apierror"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/repositories"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type stockMovementsPage struct {
+	Items []models.StockMovement `json:"items"`
+	Total int64                  `json:"total"`
+}
+
+type lowStockPage struct {
+	Items     []dto.LowStockProductDTO `json:"items"`
+	Threshold int                      `json:"threshold"`
+	Total     int64                    `json:"total"`
+}
+
+func TestStockMovementsKeepTheLedger(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	cat := e.seedCategory("Tables", "tables")
+
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/products/add",
+		gin.H{"name": "Table Kara", "price": 300, "quantity": 4, "categoryIds": []string{cat.Id.Hex()}},
+		[]testFile{{field: "images", name: "a.png", content: pngBytes(t), mimeType: "image/png"}}, token))
+	expectStatus(t, w, http.StatusCreated)
+	table := decodeJSON[models.Product](t, w)
+	movements := "/admin/products/" + table.Id.Hex() + "/stock-movements"
+
+	move := func(body gin.H, status int) models.StockMovement {
+		t.Helper()
+		w := e.do(e.jsonRequest(http.MethodPost, movements, body, token))
+		expectStatus(t, w, status)
+		return decodeJSON[models.StockMovement](t, w)
+	}
+	receipt := move(gin.H{"type": "receipt", "quantity": 6, "reason": "Livraison atelier"}, http.StatusCreated)
+	if receipt.Type != models.StockMovementReceipt || receipt.Delta != 6 || receipt.QuantityAfter != 10 || receipt.AuthorEmail == "" {
+		t.Fatalf("receipt = %+v", receipt)
+	}
+	reservation := move(gin.H{"type": "RESERVATION", "quantity": 8}, http.StatusCreated)
+	if reservation.Delta != -8 || reservation.QuantityAfter != 2 {
+		t.Fatalf("reservation = %+v", reservation)
+	}
+	move(gin.H{"type": "RESERVATION", "quantity": 0}, http.StatusBadRequest)
+	move(gin.H{"type": "INITIAL", "quantity": 3}, http.StatusBadRequest)
+	move(gin.H{"type": "RECEIPT", "quantity": 1, "variantId": bson.NewObjectID().Hex()}, http.StatusBadRequest)
+
+	// the stock never goes below zero
+	w = e.do(e.jsonRequest(http.MethodPost, movements, gin.H{"type": "RESERVATION", "quantity": 3}, token))
+	expectStatus(t, w, http.StatusConflict)
+	if body := decodeJSON[apierror.Envelope](t, w); body.Error.Code != apierror.CodeInsufficientStock {
+		t.Fatalf("error = %+v", body.Error)
+	}
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPost, "/admin/products/"+bson.NewObjectID().Hex()+"/stock-movements",
+		gin.H{"type": "RECEIPT", "quantity": 1}, token)), http.StatusNotFound)
+
+	// a quantity set through the product update becomes an adjustment
+	w = e.do(e.multipartRequest(http.MethodPatch, "/admin/products/update/"+table.Id.Hex(), gin.H{"quantity": 7}, nil, token))
+	expectStatus(t, w, http.StatusOK)
+	w = e.do(e.multipartRequest(http.MethodPatch, "/admin/products/update/"+table.Id.Hex(), gin.H{"quantity": -1}, nil, token))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/stock-movements?productId="+table.Id.Hex(), nil, token))
+	expectStatus(t, w, http.StatusOK)
+	history := decodeJSON[stockMovementsPage](t, w)
+	if history.Total != 4 {
+		t.Fatalf("history = %+v", history)
+	}
+	types := []models.StockMovementType{models.StockMovementAdjustment, models.StockMovementReservation, models.StockMovementReceipt, models.StockMovementInitial}
+	for i, m := range history.Items {
+		if m.Type != types[i] {
+			t.Fatalf("movement %d = %+v, want %s", i, m, types[i])
+		}
+	}
+	if adj := history.Items[0]; adj.Delta != 5 || adj.QuantityAfter != 7 || adj.Reason != "product update" {
+		t.Fatalf("adjustment = %+v", adj)
+	}
+	if initial := history.Items[3]; initial.Delta != 4 || initial.QuantityAfter != 4 {
+		t.Fatalf("initial = %+v", initial)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/stock-movements?type=reservation", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if page := decodeJSON[stockMovementsPage](t, w); page.Total != 1 || page.Items[0].ID != reservation.ID {
+		t.Fatalf("reservations = %+v", page)
+	}
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/admin/stock-movements?productId=bad", nil, token)), http.StatusBadRequest)
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/admin/stock-movements", nil, "")), http.StatusUnauthorized)
+
+	stored, err := e.app.Products.FindByID(t.Context(), table.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Quantity != 7 {
+		t.Fatalf("quantity = %d, want 7", stored.Quantity)
+	}
+}
+
+func TestStockOfProductsWithVariants(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+
+	chair := e.seedProduct(models.Product{Name: "Chaise Lomé", Price: 120})
+	w := e.do(e.jsonRequest(http.MethodPost, "/admin/products/"+chair.Id.Hex()+"/variants",
+		gin.H{"sku": "CH-TECK", "options": []gin.H{{"name": "Finition", "value": "Teck"}}, "quantity": 2}, token))
+	expectStatus(t, w, http.StatusCreated)
+	teak := decodeJSON[models.ProductVariant](t, w)
+	movements := "/admin/products/" + chair.Id.Hex() + "/stock-movements"
+
+	// the variants hold the stock
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPost, movements, gin.H{"type": "RECEIPT", "quantity": 1}, token)), http.StatusBadRequest)
+	w = e.do(e.multipartRequest(http.MethodPatch, "/admin/products/update/"+chair.Id.Hex(), gin.H{"quantity": 9}, nil, token))
+	expectStatus(t, w, http.StatusBadRequest)
+
+	w = e.do(e.jsonRequest(http.MethodPost, movements, gin.H{"type": "ADJUSTMENT", "quantity": -1, "variantId": teak.Id.Hex()}, token))
+	expectStatus(t, w, http.StatusCreated)
+	if m := decodeJSON[models.StockMovement](t, w); m.VariantID == nil || *m.VariantID != teak.Id || m.QuantityAfter != 1 {
+		t.Fatalf("movement = %+v", m)
+	}
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPatch, "/admin/products/"+chair.Id.Hex()+"/variants/"+teak.Id.Hex(),
+		gin.H{"quantity": 12}, token)), http.StatusOK)
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/stock-movements?variantId="+teak.Id.Hex(), nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if page := decodeJSON[stockMovementsPage](t, w); page.Total != 3 || page.Items[0].Delta != 11 || page.Items[0].Reason != "variant update" {
+		t.Fatalf("variant history = %+v", page)
+	}
+
+	// low stock: the default threshold, then an explicit one
+	e.seedProduct(models.Product{Name: "Banc", Price: 80, Quantity: 0})
+	e.seedProduct(models.Product{Name: "Lit", Price: 500, Quantity: 30})
+	e.seedProduct(models.Product{Name: "Tabouret", Price: 40, Quantity: 3})
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/products/low-stock", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	low := decodeJSON[lowStockPage](t, w)
+	if low.Threshold != e.app.Config.Inventory.LowStockThreshold || low.Total != 2 ||
+		low.Items[0].Name != "Banc" || low.Items[1].Name != "Tabouret" {
+		t.Fatalf("low stock = %+v", low)
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/products/low-stock?threshold=20", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	low = decodeJSON[lowStockPage](t, w)
+	if low.Total != 3 || low.Items[2].Id != chair.Id || len(low.Items[2].Variants) != 1 || low.Items[2].Variants[0].SKU != "CH-TECK" {
+		t.Fatalf("low stock at 20 = %+v", low)
+	}
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/admin/products/low-stock?threshold=-2", nil, token)), http.StatusBadRequest)
+}
+
+func TestVariantStockGoesThroughTheLedger(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	stool := e.seedProduct(models.Product{Name: "Tabouret", Price: 40, Quantity: 5})
+	variants := "/admin/products/" + stool.Id.Hex() + "/variants"
+
+	ledger := func() []models.StockMovement {
+		t.Helper()
+		movements, _, err := e.app.StockMovements.List(t.Context(), repositories.StockMovementFilter{ProductID: &stool.Id}, repositories.Page{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return movements
+	}
+
+	// the first variant takes over the stock of the product
+	w := e.do(e.jsonRequest(http.MethodPost, variants,
+		gin.H{"sku": "TB-TECK", "options": []gin.H{{"name": "Finition", "value": "Teck"}}, "quantity": 2}, token))
+	expectStatus(t, w, http.StatusCreated)
+	teak := decodeJSON[models.ProductVariant](t, w)
+	if teak.Quantity != 7 {
+		t.Fatalf("variant quantity = %d", teak.Quantity)
+	}
+	p, err := e.app.Products.FindByID(t.Context(), stool.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Quantity != 0 || p.Variants[0].Quantity != 7 {
+		t.Fatalf("product = %+v", p)
+	}
+	movements := ledger()
+	if len(movements) != 3 || movements[2].Delta != -5 || movements[2].VariantID != nil ||
+		movements[1].Type != models.StockMovementInitial || movements[1].Delta != 2 ||
+		movements[0].Delta != 5 || movements[0].QuantityAfter != 7 || *movements[0].VariantID != teak.Id {
+		t.Fatalf("movements after add = %+v", movements)
+	}
+
+	// the next ones start from their own quantity
+	w = e.do(e.jsonRequest(http.MethodPost, variants,
+		gin.H{"sku": "TB-ROTIN", "options": []gin.H{{"name": "Finition", "value": "Rotin"}}, "quantity": 1}, token))
+	expectStatus(t, w, http.StatusCreated)
+	if rattan := decodeJSON[models.ProductVariant](t, w); rattan.Quantity != 1 || len(ledger()) != 4 {
+		t.Fatalf("second variant = %+v", rattan)
+	}
+
+	// a deletion whose stock cannot leave the ledger is refused
+	teakPath := variants + "/" + teak.Id.Hex()
+	saved := e.app.StockMovements
+	e.app.StockMovements = brokenLedger{saved}
+	w = e.do(e.jsonRequest(http.MethodDelete, teakPath, nil, token))
+	e.app.StockMovements = saved
+	expectStatus(t, w, http.StatusInternalServerError)
+	if p, err := e.app.Products.FindByID(t.Context(), stool.Id); err != nil || len(p.Variants) != 2 || p.Variants[0].Quantity != 7 {
+		t.Fatalf("product after failed delete = %+v, %v", p, err)
+	}
+
+	expectStatus(t, e.do(e.jsonRequest(http.MethodDelete, teakPath, nil, token)), http.StatusOK)
+	movements = ledger()
+	if m := movements[0]; len(movements) != 5 || m.Type != models.StockMovementAdjustment || m.Delta != -7 ||
+		m.QuantityAfter != 0 || m.Reason != "variant deleted" || *m.VariantID != teak.Id {
+		t.Fatalf("movements after delete = %+v", movements)
+	}
+}
+
+// brokenLedger fails every ledger entry.
+type brokenLedger struct {
+	repositories.StockMovementRepository
+}
+
+func (brokenLedger) Insert(context.Context, *models.StockMovement) error {
+	return errors.New("ledger unavailable")
+}
+
+func TestUpdateProductMovesStockWithTheOtherFields(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	e.seedProduct(models.Product{Name: "Banc", Slug: "banc", Price: 80})
+	stool := e.seedProduct(models.Product{Name: "Tabouret", Slug: "tabouret", Price: 40, Quantity: 4})
+	path := "/admin/products/update/" + stool.Id.Hex()
+
+	stored := func() *models.Product {
+		t.Helper()
+		p, err := e.app.Products.FindByID(t.Context(), stool.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	// the field update fails: the stock move is reverted in the ledger
+	w := e.do(e.multipartRequest(http.MethodPatch, path, gin.H{"slug": "banc", "quantity": 9}, nil, token))
+	expectStatus(t, w, http.StatusConflict)
+	if p := stored(); p.Slug != "tabouret" || p.Quantity != 4 {
+		t.Fatalf("product = %+v", p)
+	}
+	movements, _, err := e.app.StockMovements.List(t.Context(), repositories.StockMovementFilter{ProductID: &stool.Id}, repositories.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movements) != 2 || movements[0].Delta != -5 || movements[0].QuantityAfter != 4 || movements[1].Delta != 5 {
+		t.Fatalf("movements = %+v", movements)
+	}
+
+	// the stock move fails: the other fields are not saved either
+	ledger := e.app.StockMovements
+	e.app.StockMovements = brokenLedger{ledger}
+	w = e.do(e.multipartRequest(http.MethodPatch, path, gin.H{"name": "Tabouret haut", "quantity": 9}, nil, token))
+	e.app.StockMovements = ledger
+	expectStatus(t, w, http.StatusInternalServerError)
+	if p := stored(); p.Name != "Tabouret" || p.Quantity != 4 {
+		t.Fatalf("product = %+v", p)
+	}
+}
+
+// brokenVariantImages fails every update of the variant images.
+type brokenVariantImages struct {
+	repositories.ProductRepository
+}
+
+func (brokenVariantImages) RenameVariantImages(context.Context, bson.ObjectID, map[string]string) error {
+	return errors.New("variants unavailable")
+}
+
+func TestUpdateProductSucceedsWhenVariantImagesCannotFollow(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/products/add", gin.H{"name": "Chaise", "price": 120},
+		[]testFile{
+			{field: "images", name: "a.png", content: pngBytes(t), mimeType: "image/png"},
+			{field: "images", name: "b.png", content: pngBytes(t), mimeType: "image/png"},
+		}, token))
+	expectStatus(t, w, http.StatusCreated)
+	chair := decodeJSON[models.Product](t, w)
+	removed := chair.Images[0]
+	expectStatus(t, e.do(e.jsonRequest(http.MethodPost, "/admin/products/"+chair.Id.Hex()+"/variants", gin.H{
+		"sku": "CH-TECK", "options": []gin.H{{"name": "Finition", "value": "Teck"}}, "quantity": 2, "imageUrls": []string{removed.URL},
+	}, token)), http.StatusCreated)
+
+	products := e.app.Products
+	e.app.Products = brokenVariantImages{products}
+	w = e.do(e.multipartRequest(http.MethodPatch, "/admin/products/update/"+chair.Id.Hex(),
+		gin.H{"name": "Chaise haute", "removedImagesUrls": []string{removed.URL}}, nil, token))
+	e.app.Products = products
+
+	// the update is saved and answered as such, with its revision; the
+	// files stay for the variant that still shows the image
+	expectStatus(t, w, http.StatusOK)
+	p, err := e.app.Products.FindByID(t.Context(), chair.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Chaise haute" || len(p.Images) != 1 || p.Variants[0].Quantity != 2 {
+		t.Fatalf("product = %+v", p)
+	}
+	if latest, err := e.app.Revisions.Latest(t.Context(), chair.Id); err != nil || !slices.Contains(latest.ChangedFields, "name") {
+		t.Fatalf("latest revision = %+v, %v", latest, err)
+	}
+	if n := e.storedImage(removed); n != 3 {
+		t.Fatalf("%d files of the removed image left, want 3", n)
+	}
+}
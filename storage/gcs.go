@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+type GCSConfig struct {
+	Bucket string
+	// CredentialsFile is a service account key, relative to the working directory.
+	CredentialsFile string
+}
+
+// GCS stores objects in a Google Cloud Storage bucket.
+type GCS struct {
+	Client *gcs.Client
+	Bucket string
+}
+
+func NewGCS(ctx context.Context, cfg GCSConfig) (*GCS, error) {
+	if cfg.Bucket == "" || cfg.CredentialsFile == "" {
+		return nil, fmt.Errorf("gcs: bucket and credentials file are required")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	client, err := gcs.NewClient(ctx, option.WithAuthCredentialsFile(option.ServiceAccount, filepath.Join(wd, cfg.CredentialsFile)))
+	if err != nil {
+		return nil, fmt.Errorf("gcs client: %w", err)
+	}
+	return &GCS{Client: client, Bucket: cfg.Bucket}, nil
+}
+
+func (g *GCS) Put(ctx context.Context, objectName string, r io.Reader, _ int64, opts PutOptions) error {
+	w := g.Client.Bucket(g.Bucket).Object(objectName).NewWriter(ctx)
+	w.ContentType = opts.ContentType
+	w.CacheControl = opts.CacheControl
+
+	if _, err := io.Copy(w, r); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("upload copy: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("upload close: %w", err)
+	}
+	return nil
+}
+
+func (g *GCS) Delete(ctx context.Context, objectName string) error {
+	err := g.Client.Bucket(g.Bucket).Object(objectName).Delete(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (g *GCS) PublicURL(objectName string) string {
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", g.Bucket, objectName)
+}
+
+func (g *GCS) ObjectNameFromURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+
+	host := strings.ToLower(u.Host)
+	path := strings.TrimPrefix(u.Path, "/")
+
+	// style 1: storage.googleapis.com/<bucket>/<object>
+	if host == "storage.googleapis.com" {
+		prefix := g.Bucket + "/"
+		if !strings.HasPrefix(path, prefix) {
+			return "", fmt.Errorf("url bucket mismatch")
+		}
+		return strings.TrimPrefix(path, prefix), nil
+	}
+
+	// style 2: <bucket>.storage.googleapis.com/<object>
+	if host == strings.ToLower(g.Bucket)+".storage.googleapis.com" {
+		if path == "" {
+			return "", fmt.Errorf("missing object path")
+		}
+		return path, nil
+	}
+
+	return "", fmt.Errorf("not a gcs public url")
+}
+
+func (g *GCS) Open(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	r, err := g.Client.Bucket(g.Bucket).Object(objectName).NewReader(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	return r, err
+}
+
+func (g *GCS) Ping(ctx context.Context) error {
+	_, err := g.Client.Bucket(g.Bucket).Attrs(ctx)
+	return err
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MediaPath is where the router serves a Local store's files.
+const MediaPath = "/media"
+
+type LocalConfig struct {
+	// Dir is the root directory objects are written to (default "./uploads").
+	Dir string
+	// BaseURL prefixes public URLs (default "http://localhost:8080/media").
+	// Its path should end with MediaPath so the router can serve the files.
+	BaseURL string
+}
+
+// Local stores objects on disk so development and CI can run the whole
+// upload path without a cloud bucket.
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocal(cfg LocalConfig) (*Local, error) {
+	if cfg.Dir == "" {
+		cfg.Dir = "uploads"
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://localhost:8080" + MediaPath
+	}
+	dir, err := filepath.Abs(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("local storage dir: %w", err)
+	}
+	return &Local{dir: dir, baseURL: strings.TrimRight(cfg.BaseURL, "/")}, nil
+}
+
+// Dir is the directory served under MediaPath.
+func (l *Local) Dir() string {
+	return l.dir
+}
+
+// path resolves objectName inside the root, rejecting anything that would
+// escape it ("../", absolute paths).
+func (l *Local) path(objectName string) (string, error) {
+	p := filepath.FromSlash(objectName)
+	if objectName == "" || !filepath.IsLocal(p) {
+		return "", fmt.Errorf("invalid object name %q", objectName)
+	}
+	return filepath.Join(l.dir, p), nil
+}
+
+func (l *Local) Put(_ context.Context, objectName string, r io.Reader, _ int64, _ PutOptions) error {
+	dst, err := l.path(objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	// write then rename so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func (l *Local) Delete(_ context.Context, objectName string) error {
+	p, err := l.path(objectName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) PublicURL(objectName string) string {
+	return l.baseURL + "/" + objectName
+}
+
+func (l *Local) ObjectNameFromURL(raw string) (string, error) {
+	if !strings.HasPrefix(raw, l.baseURL+"/") {
+		return "", fmt.Errorf("not a local storage url")
+	}
+	name := path.Clean(strings.TrimPrefix(raw, l.baseURL+"/"))
+	if _, err := l.path(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (l *Local) Open(_ context.Context, objectName string) (io.ReadCloser, error) {
+	p, err := l.path(objectName)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Ping checks the root directory is still there and writable.
+func (l *Local) Ping(_ context.Context) error {
+	f, err := os.CreateTemp(l.dir, ".ping-*")
+	if err != nil {
+		return err
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	l, err := NewLocal(LocalConfig{Dir: dir, BaseURL: "http://cdn.test/media/"})
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	ctx := t.Context()
+
+	if err := l.Put(ctx, "quotes/q1/a.pdf", strings.NewReader("%PDF"), 4, PutOptions{}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "quotes", "q1", "a.pdf")); err != nil {
+		t.Fatalf("file not written: %v", err)
+	}
+
+	url := l.PublicURL("quotes/q1/a.pdf")
+	if url != "http://cdn.test/media/quotes/q1/a.pdf" {
+		t.Fatalf("PublicURL = %q", url)
+	}
+	name, err := l.ObjectNameFromURL(url)
+	if err != nil || name != "quotes/q1/a.pdf" {
+		t.Fatalf("ObjectNameFromURL = %q, %v", name, err)
+	}
+
+	rc, err := l.Open(ctx, name)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	body, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	if string(body) != "%PDF" {
+		t.Fatalf("read %q", body)
+	}
+
+	if err := l.Delete(ctx, name); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := l.Delete(ctx, name); err != nil {
+		t.Fatalf("second Delete: %v", err)
+	}
+	if _, err := l.Open(ctx, name); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open after delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalRejectsEscapingNames(t *testing.T) {
+	l, err := NewLocal(LocalConfig{Dir: t.TempDir(), BaseURL: "http://cdn.test/media"})
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+
+	for _, name := range []string{"", "../x", "/etc/passwd", "a/../../x"} {
+		if err := l.Put(t.Context(), name, strings.NewReader("x"), 1, PutOptions{}); err == nil {
+			t.Errorf("Put(%q) succeeded", name)
+		}
+	}
+	for _, url := range []string{"http://cdn.test/media/../secret", "http://other.test/media/a.png"} {
+		if _, err := l.ObjectNameFromURL(url); err == nil {
+			t.Errorf("ObjectNameFromURL(%q) succeeded", url)
+		}
+	}
+}
+
+func TestLocalPing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "media")
+	l, err := NewLocal(LocalConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	if err := l.Ping(t.Context()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("Ping left files behind: %v", entries)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Ping(t.Context()); err == nil {
+		t.Fatal("Ping succeeded without a root directory")
+	}
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type R2Config struct {
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Endpoint        string // https://<account-id>.r2.cloudflarestorage.com
+	// PublicDomain is the custom domain or r2.dev URL objects are served
+	// from, e.g. "https://files.yourdomain.com" or "https://pub-xxx.r2.dev".
+	PublicDomain string
+}
+
+// R2 stores objects in a Cloudflare R2 bucket through the S3 API.
+type R2 struct {
+	S3     *s3.Client
+	Bucket string
+	domain string
+}
+
+func NewR2(ctx context.Context, cfg R2Config) (*R2, error) {
+	if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" || cfg.Endpoint == "" {
+		return nil, fmt.Errorf("r2: bucket, access key id, secret access key and endpoint are required")
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx,
+		config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		),
+		config.WithRegion("auto"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("r2 config: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(cfg.Endpoint)
+		o.UsePathStyle = true // required for R2
+	})
+
+	return &R2{
+		S3:     client,
+		Bucket: cfg.Bucket,
+		domain: strings.TrimRight(cfg.PublicDomain, "/"),
+	}, nil
+}
+
+func (r *R2) Put(ctx context.Context, objectName string, body io.Reader, size int64, opts PutOptions) error {
+	in := &s3.PutObjectInput{
+		Bucket:        aws.String(r.Bucket),
+		Key:           aws.String(objectName),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+	}
+	if opts.ContentType != "" {
+		in.ContentType = aws.String(opts.ContentType)
+	}
+	if opts.CacheControl != "" {
+		in.CacheControl = aws.String(opts.CacheControl)
+	}
+	_, err := r.S3.PutObject(ctx, in)
+	return err
+}
+
+func (r *R2) Delete(ctx context.Context, objectName string) error {
+	_, err := r.S3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(r.Bucket),
+		Key:    aws.String(objectName),
+	})
+	return err
+}
+
+// PublicURL keeps the historical "<domain>/<bucket>/<object>" layout so URLs
+// already stored on products stay valid.
+func (r *R2) PublicURL(objectName string) string {
+	return fmt.Sprintf("%s/%s/%s", r.domain, r.Bucket, objectName)
+}
+
+// ObjectNameFromURL supports both custom domain (PublicDomain) and r2.dev
+// subdomain URLs.
+func (r *R2) ObjectNameFromURL(raw string) (string, error) {
+	if r.domain != "" && strings.HasPrefix(raw, r.domain+"/"+r.Bucket+"/") {
+		return strings.TrimPrefix(raw, r.domain+"/"+r.Bucket+"/"), nil
+	}
+
+	// r2.dev style: https://<bucket>.<account>.r2.dev/<object>
+	// Just strip the scheme + host and return the path
+	for _, prefix := range []string{"https://", "http://"} {
+		if strings.HasPrefix(raw, prefix) {
+			withoutScheme := strings.TrimPrefix(raw, prefix)
+			slash := strings.Index(withoutScheme, "/")
+			if slash == -1 {
+				return "", fmt.Errorf("no object path in url")
+			}
+			return withoutScheme[slash+1:], nil
+		}
+	}
+
+	return "", fmt.Errorf("not a recognised R2 public url")
+}
+
+func (r *R2) Open(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	out, err := r.S3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.Bucket),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (r *R2) Ping(ctx context.Context) error {
+	_, err := r.S3.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(r.Bucket)})
+	return err
+}
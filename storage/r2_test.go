@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a minimal path-style S3 endpoint: PUT stores, GET reads, DELETE removes.
+type fakeS3 struct {
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/"+f.bucket+"/")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = body
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>`)
+			return
+		}
+		_, _ = w.Write(body)
+	case http.MethodHead:
+		if key == "" || key == "/"+f.bucket {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestR2(t *testing.T) (*R2, *fakeS3) {
+	t.Helper()
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	fake := &fakeS3{bucket: "saho-test", objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	r2, err := NewR2(t.Context(), R2Config{
+		Bucket:          "saho-test",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		Endpoint:        srv.URL,
+		PublicDomain:    "https://files.saho.test/",
+	})
+	if err != nil {
+		t.Fatalf("NewR2: %v", err)
+	}
+	return r2, fake
+}
+
+func TestR2PutOpenDelete(t *testing.T) {
+	r2, fake := newTestR2(t)
+	ctx := t.Context()
+
+	if err := r2.Put(ctx, "products/chaise/1.png", strings.NewReader("png"), 3, PutOptions{ContentType: "image/png"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := string(fake.objects["products/chaise/1.png"]); got != "png" {
+		t.Fatalf("stored %q", got)
+	}
+
+	rc, err := r2.Open(ctx, "products/chaise/1.png")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	body, _ := io.ReadAll(rc)
+	_ = rc.Close()
+	if string(body) != "png" {
+		t.Fatalf("read %q", body)
+	}
+
+	if err := DeleteURLs(ctx, r2, []string{r2.PublicURL("products/chaise/1.png")}); err != nil {
+		t.Fatalf("DeleteURLs: %v", err)
+	}
+	if _, err := r2.Open(ctx, "products/chaise/1.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open after delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestR2Ping(t *testing.T) {
+	r2, _ := newTestR2(t)
+	if err := r2.Ping(t.Context()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	r2.Bucket = "other"
+	if err := r2.Ping(t.Context()); err == nil {
+		t.Fatal("Ping succeeded for a missing bucket")
+	}
+}
+
+func TestR2URLs(t *testing.T) {
+	r2, _ := newTestR2(t)
+
+	url := r2.PublicURL("products/chaise/1.png")
+	if url != "https://files.saho.test/saho-test/products/chaise/1.png" {
+		t.Fatalf("PublicURL = %q", url)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url, "products/chaise/1.png"},
+		{"https://saho-test.abc.r2.dev/products/chaise/1.png", "products/chaise/1.png"},
+	}
+	for _, tt := range tests {
+		got, err := r2.ObjectNameFromURL(tt.url)
+		if err != nil || got != tt.want {
+			t.Errorf("ObjectNameFromURL(%q) = %q, %v; want %q", tt.url, got, err, tt.want)
+		}
+	}
+	if _, err := r2.ObjectNameFromURL("ftp://elsewhere/x"); err == nil {
+		t.Error("ObjectNameFromURL accepted a non-http url")
+	}
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// ErrNotFound is returned by Open when the object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// PutOptions carries the optional metadata stored with an object.
+type PutOptions struct {
+	ContentType  string
+	CacheControl string
+}
+
+// Storage is the object store behind every upload (product and category
+// images, quote PDFs, product request attachments). Object names are
+// slash-separated keys such as "products/<slug>/<nanos>.jpg".
+type Storage interface {
+	// Put stores size bytes read from r under objectName, replacing any existing object.
+	Put(ctx context.Context, objectName string, r io.Reader, size int64, opts PutOptions) error
+	// Delete removes objectName. Deleting a missing object is not an error.
+	Delete(ctx context.Context, objectName string) error
+	// PublicURL is the URL clients use to download objectName.
+	PublicURL(objectName string) string
+	// ObjectNameFromURL is the inverse of PublicURL.
+	ObjectNameFromURL(rawURL string) (string, error)
+	// Open streams an object back; the caller closes the reader.
+	Open(ctx context.Context, objectName string) (io.ReadCloser, error)
+	// Ping checks that the backend is reachable and usable (readiness).
+	Ping(ctx context.Context) error
+}
+
+// Driver names accepted by Config.Driver.
+const (
+	DriverR2    = "r2"
+	DriverGCS   = "gcs"
+	DriverLocal = "local"
+)
+
+// Config selects a driver and carries the settings of each one; only the
+// selected driver's section is used.
+type Config struct {
+	Driver string // DriverR2 (default), DriverGCS or DriverLocal
+	R2     R2Config
+	GCS    GCSConfig
+	Local  LocalConfig
+}
+
+// New builds the driver selected by cfg.Driver.
+func New(ctx context.Context, cfg Config) (Storage, error) {
+	driver := strings.ToLower(strings.TrimSpace(cfg.Driver))
+	if driver == "" {
+		driver = DriverR2
+	}
+	slog.Info("storage ready", "driver", driver)
+
+	switch driver {
+	case DriverR2:
+		return NewR2(ctx, cfg.R2)
+	case DriverGCS:
+		return NewGCS(ctx, cfg.GCS)
+	case DriverLocal:
+		return NewLocal(cfg.Local)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q (expected %s, %s or %s)", driver, DriverR2, DriverGCS, DriverLocal)
+	}
+}
+
+// Unwrap returns the driver underneath decorators (metrics, tracing), which
+// expose it through an Unwrap() Storage method.
+func Unwrap(s Storage) Storage {
+	for {
+		u, ok := s.(interface{ Unwrap() Storage })
+		if !ok {
+			return s
+		}
+		s = u.Unwrap()
+	}
+}
+
+// DeleteObjects removes every non-empty object name and reports the first failure.
+func DeleteObjects(ctx context.Context, s Storage, objectNames []string) error {
+	var firstErr error
+	for _, obj := range objectNames {
+		if obj == "" {
+			continue
+		}
+		if err := s.Delete(ctx, obj); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("delete %s: %w", obj, err)
+		}
+	}
+	return firstErr
+}
+
+// DeleteURLs removes the objects behind public URLs, skipping URLs that do
+// not belong to s.
+func DeleteURLs(ctx context.Context, s Storage, urls []string) error {
+	objectNames := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if obj, err := s.ObjectNameFromURL(u); err == nil {
+			objectNames = append(objectNames, obj)
+		}
+	}
+	return DeleteObjects(ctx, s, objectNames)
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/princinho/sahobackend/imaging"
+	"github.com/princinho/sahobackend/models"
+	"github.com/princinho/sahobackend/upload"
+)
+
+// Errors caused by the uploaded file itself rather than by the backend;
+// handlers answer them with a 400.
+var (
+	ErrNoImages = errors.New("at least one image is required")
+	ErrNotImage = errors.New("not a PNG, JPEG or WebP image")
+)
+
+// imageCacheControl is sent with the renditions: their object names are
+// never reused, so browsers and CDNs may keep them for good.
+const imageCacheControl = "public, max-age=31536000, immutable"
+
+// UploadImages stores the renditions of product or category images, checked
+// by their upload policy, under products/<slug>/ and returns the images in
+// upload order. The files are decoded and encoded one after the other, at
+// quality (see imaging.Process). Callers enforce the image count.
+func UploadImages(ctx context.Context, s Storage, slug string, files []*upload.File, quality int) ([]models.Image, error) {
+	if len(files) == 0 {
+		return nil, ErrNoImages
+	}
+
+	images := make([]models.Image, 0, len(files))
+	for _, f := range files {
+		img, err := uploadImage(ctx, s, slug, f, quality)
+		if err != nil {
+			// don't leave half an upload behind
+			_ = DeleteImages(ctx, s, images)
+			return nil, fmt.Errorf("upload %s: %w", f.Name, err)
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+func uploadImage(ctx context.Context, s Storage, slug string, f *upload.File, quality int) (models.Image, error) {
+	renditions, err := imaging.Process(f.Data, quality)
+	if err != nil {
+		return models.Image{}, err
+	}
+	return putRenditions(ctx, s, slug, renditions)
+}
+
+// UploadImageData encodes the renditions of an image (see imaging.Process)
+// and stores them like UploadImages. It fails with ErrNotImage when data is
+// not a PNG, JPEG or WebP picture.
+func UploadImageData(ctx context.Context, s Storage, slug string, data []byte, quality int) (models.Image, error) {
+	renditions, err := imaging.Process(data, quality)
+	if errors.Is(err, imaging.ErrFormat) {
+		return models.Image{}, ErrNotImage
+	}
+	if err != nil {
+		return models.Image{}, err
+	}
+	return putRenditions(ctx, s, slug, renditions)
+}
+
+// putRenditions stores renditions under products/<slug>/<nanos>-<rendition>.webp.
+func putRenditions(ctx context.Context, s Storage, slug string, renditions []imaging.Encoded) (models.Image, error) {
+	base := fmt.Sprintf("products/%s/%d", slug, time.Now().UnixNano())
+	var img models.Image
+	stored := make([]string, 0, len(renditions))
+	for _, r := range renditions {
+		objectName := base + "-" + r.Name + imaging.Extension
+		opts := PutOptions{ContentType: imaging.ContentType, CacheControl: imageCacheControl}
+		if err := s.Put(ctx, objectName, bytes.NewReader(r.Data), int64(len(r.Data)), opts); err != nil {
+			_ = DeleteObjects(ctx, s, stored)
+			return models.Image{}, fmt.Errorf("upload %s rendition: %w", r.Name, err)
+		}
+		stored = append(stored, objectName)
+
+		switch url := s.PublicURL(objectName); r.Name {
+		case imaging.Full:
+			img.URL, img.ObjectName = url, objectName
+		case imaging.Card:
+			img.Card = url
+		case imaging.Thumbnail:
+			img.Thumbnail = url
+		}
+	}
+	return img, nil
+}
+
+// EncodeLegacyImage encodes the renditions of an image saved before they
+// existed from its original file. The new image keeps the position, alt
+// text and primary flag of the legacy one. The original is kept: the caller
+// deletes it once the new image is saved.
+func EncodeLegacyImage(ctx context.Context, s Storage, slug string, legacy models.Image, quality int) (models.Image, error) {
+	objectName, err := s.ObjectNameFromURL(legacy.URL)
+	if err != nil {
+		return models.Image{}, err
+	}
+	r, err := s.Open(ctx, objectName)
+	if err != nil {
+		return models.Image{}, fmt.Errorf("open %s: %w", objectName, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return models.Image{}, fmt.Errorf("read %s: %w", objectName, err)
+	}
+	img, err := UploadImageData(ctx, s, slug, data, quality)
+	if err != nil {
+		return models.Image{}, err
+	}
+	img.Position, img.Alt, img.IsPrimary = legacy.Position, legacy.Alt, legacy.IsPrimary
+	return img, nil
+}
+
+// DeleteImages removes every file of the images.
+func DeleteImages(ctx context.Context, s Storage, images []models.Image) error {
+	var urls []string
+	for _, img := range images {
+		urls = append(urls, img.URLs()...)
+	}
+	return DeleteURLs(ctx, s, urls)
+}
+
+// UploadQuotePDF stores the PDF, checked by its upload policy, attached to
+// an admin quote note.
+func UploadQuotePDF(ctx context.Context, s Storage, quoteID string, f *upload.File) (*models.QuoteAttachment, error) {
+	objectName := fmt.Sprintf("quotes/%s/%d-%s.pdf", quoteID, time.Now().UTC().Unix(), uuid.New().String())
+	err := s.Put(ctx, objectName, bytes.NewReader(f.Data), int64(len(f.Data)), PutOptions{
+		ContentType:  f.ContentType,
+		CacheControl: "no-cache",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("upload pdf: %w", err)
+	}
+
+	return &models.QuoteAttachment{
+		PublicURL:  s.PublicURL(objectName),
+		ObjectName: objectName,
+		MimeType:   f.ContentType,
+		SizeBytes:  int64(len(f.Data)),
+	}, nil
+}
+
+// UploadProductRequestFile stores a product request reference file or an
+// admin note attachment, checked by its upload policy. It is stored with the
+// extension of its sniffed type, whatever its name says.
+func UploadProductRequestFile(ctx context.Context, s Storage, requestID string, f *upload.File) (*models.ProductRequestAttachment, error) {
+	objectName := fmt.Sprintf(
+		"product-requests/%s/%d-%s%s",
+		requestID, time.Now().UTC().Unix(), uuid.New().String(), f.Extension,
+	)
+	err := s.Put(ctx, objectName, bytes.NewReader(f.Data), int64(len(f.Data)), PutOptions{
+		ContentType:  f.ContentType,
+		CacheControl: "no-cache",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("upload file: %w", err)
+	}
+
+	return &models.ProductRequestAttachment{
+		ImageURL:   s.PublicURL(objectName),
+		ObjectName: objectName,
+		MimeType:   f.ContentType,
+		SizeBytes:  int64(len(f.Data)),
+		FileName:   f.Name,
+		UploadedAt: time.Now().UTC(),
+	}, nil
+}
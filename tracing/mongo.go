@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/princinho/sahobackend/database"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/princinho/sahobackend/tracing"
+
+// MongoMonitor opens a client span per command, as a child of the span in
+// the command's context (the request span). Query values are never recorded.
+func MongoMonitor() *event.CommandMonitor {
+	var spans sync.Map // request ID -> trace.Span
+
+	finish := func(evt event.CommandFinishedEvent, err error) {
+		v, ok := spans.LoadAndDelete(evt.RequestID)
+		if !ok {
+			return
+		}
+		span := v.(trace.Span)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			// commands outside a request (startup, probes) would each start a trace
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				return
+			}
+			attrs := []attribute.KeyValue{
+				semconv.DBSystemNameMongoDB,
+				semconv.DBNamespace(evt.DatabaseName),
+				semconv.DBOperationName(evt.CommandName),
+			}
+			name := evt.CommandName
+			if coll := database.CommandCollection(evt.CommandName, evt.Command); coll != "" {
+				attrs = append(attrs, semconv.DBCollectionName(coll))
+				name = evt.CommandName + " " + coll
+			}
+			_, span := otel.Tracer(instrumentationName).Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			spans.Store(evt.RequestID, span)
+		},
+		Succeeded: func(_ context.Context, evt *event.CommandSucceededEvent) {
+			finish(evt.CommandFinishedEvent, nil)
+		},
+		Failed: func(_ context.Context, evt *event.CommandFailedEvent) {
+			finish(evt.CommandFinishedEvent, evt.Failure)
+		},
+	}
+}
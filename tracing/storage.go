@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/princinho/sahobackend/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Storage wraps s so every upload, delete and read gets a client span
+// ("storage.Put" …) under the request span, whatever the driver.
+func Storage(s storage.Storage, driver string) storage.Storage {
+	return &tracedStorage{Storage: s, driver: driver}
+}
+
+type tracedStorage struct {
+	storage.Storage
+	driver string
+}
+
+// Unwrap exposes the driver, see storage.Unwrap.
+func (s *tracedStorage) Unwrap() storage.Storage {
+	return s.Storage
+}
+
+func (s *tracedStorage) start(ctx context.Context, op, objectName string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, "storage."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("storage.driver", s.driver),
+			attribute.String("storage.object", objectName),
+		))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracedStorage) Put(ctx context.Context, objectName string, r io.Reader, size int64, opts storage.PutOptions) error {
+	ctx, span := s.start(ctx, "Put", objectName)
+	span.SetAttributes(attribute.Int64("storage.size_bytes", size))
+	err := s.Storage.Put(ctx, objectName, r, size, opts)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) Delete(ctx context.Context, objectName string) error {
+	ctx, span := s.start(ctx, "Delete", objectName)
+	err := s.Storage.Delete(ctx, objectName)
+	end(span, err)
+	return err
+}
+
+func (s *tracedStorage) Open(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	ctx, span := s.start(ctx, "Open", objectName)
+	rc, err := s.Storage.Open(ctx, objectName)
+	end(span, err)
+	return rc, err
+}
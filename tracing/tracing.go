@@ -0,0 +1,92 @@
+// Package tracing installs the process-wide OpenTelemetry tracer provider
+// and propagators. Instrumentation (gin, MongoDB, S3) picks them up through
+// the otel globals.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Exporter names accepted by Options.Exporter.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type Options struct {
+	Exporter string // ExporterNone (default), ExporterStdout or ExporterOTLP
+	// OTLPEndpoint is host:port of the collector (OTLP over HTTP). Empty
+	// falls back to OTEL_EXPORTER_OTLP_ENDPOINT, then localhost:4318.
+	OTLPEndpoint string
+	OTLPInsecure bool
+	ServiceName  string
+	// SampleRatio is the share of new traces recorded; a sampled parent
+	// from the frontend is always followed.
+	SampleRatio float64
+	// Stdout receives the stdout exporter's output (os.Stdout when nil).
+	Stdout io.Writer
+}
+
+// Propagator reads and writes W3C traceparent/tracestate and baggage.
+var Propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Setup installs the tracer provider described by opts and returns the
+// function that flushes pending spans on shutdown. With ExporterNone only
+// the propagator is installed, so incoming trace IDs still reach the logs.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(Propagator)
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		w := opts.Stdout
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w), stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		var o []otlptracehttp.Option
+		if opts.OTLPEndpoint != "" {
+			o = append(o, otlptracehttp.WithEndpoint(opts.OTLPEndpoint))
+		}
+		if opts.OTLPInsecure {
+			o = append(o, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, o...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q (expected %s, %s or %s)", opts.Exporter, ExporterNone, ExporterStdout, ExporterOTLP)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
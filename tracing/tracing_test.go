@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/princinho/sahobackend/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestStdoutExporter(t *testing.T) {
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), Options{
+		Exporter: ExporterStdout, ServiceName: "saho-test", SampleRatio: 1, Stdout: &buf,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "UpdateProduct")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, `"Name": "UpdateProduct"`) || !strings.Contains(out, "saho-test") {
+		t.Fatalf("exported:\n%s", out)
+	}
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), Options{Exporter: "zipkin"}); err == nil {
+		t.Fatal("want an error for an unknown exporter")
+	}
+}
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	return rec
+}
+
+func TestMongoMonitorSpans(t *testing.T) {
+	rec := recordSpans(t)
+	mon := MongoMonitor()
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "GET /products")
+
+	cmd, _ := bson.Marshal(bson.D{{Key: "find", Value: "products"}})
+	mon.Started(ctx, &event.CommandStartedEvent{Command: cmd, CommandName: "find", DatabaseName: "saho", RequestID: 1})
+	mon.Failed(ctx, &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1},
+		Failure:              errors.New("boom"),
+	})
+	// outside a request: no trace is started
+	mon.Started(context.Background(), &event.CommandStartedEvent{Command: cmd, CommandName: "find", RequestID: 2})
+	mon.Succeeded(context.Background(), &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 2},
+	})
+	parent.End()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("want the command span and its parent, got %d spans", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "find products" || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("span %q, parent %s", span.Name(), span.Parent().SpanID())
+	}
+	if span.Status().Code != codes.Error {
+		t.Fatalf("status = %v", span.Status())
+	}
+}
+
+func TestStorageSpans(t *testing.T) {
+	rec := recordSpans(t)
+	local, err := storage.NewLocal(storage.LocalConfig{Dir: t.TempDir(), BaseURL: "http://localhost/media"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Storage(local, storage.DriverLocal)
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "a/b.txt", strings.NewReader("hello"), 5, storage.PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "a/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if storage.Unwrap(s) != local {
+		t.Fatal("Unwrap must return the driver")
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 2 || spans[0].Name() != "storage.Put" || spans[1].Name() != "storage.Delete" {
+		t.Fatalf("spans = %v", spans)
+	}
+}
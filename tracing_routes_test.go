@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/princinho/sahobackend/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordSpans installs an in-memory tracer provider for the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(tracing.Propagator)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	return rec
+}
+
+func TestRequestsJoinTheFrontendTrace(t *testing.T) {
+	rec := recordSpans(t)
+	e := newTestEnv(t)
+
+	const traceID, parentID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodGet, "/categories/66f1a0000000000000000001", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	e.do(req)
+	e.do(httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("want one span (probes are not traced), got %d", len(spans))
+	}
+	span := spans[0]
+	if span.SpanContext().TraceID().String() != traceID || span.Parent().SpanID().String() != parentID {
+		t.Fatalf("span %s/%s is not a child of the incoming traceparent", span.SpanContext().TraceID(), span.Parent().SpanID())
+	}
+	if span.Name() != "GET /categories/:id" {
+		t.Fatalf("span name = %q", span.Name())
+	}
+
+	lines := e.logLines()
+	if len(lines) == 0 || lines[0]["traceId"] != traceID {
+		t.Fatalf("log lines = %v", lines)
+	}
+}
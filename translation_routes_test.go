@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/princinho/sahobackend/dto"
+	"github.com/princinho/sahobackend/models"
+)
+
+func TestProductsAreServedInTheRequestedLocale(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+	cat := e.seedCategory("Chaises", "chaises")
+
+	data := gin.H{
+		"name": "Chaise longue", "price": 150, "categoryIds": []string{cat.Id.Hex()},
+		"description":  "En teck massif",
+		"translations": gin.H{"en": gin.H{"name": " Lounge chair ", "description": "Solid teak"}},
+	}
+	files := []testFile{{field: "images", name: "front.png", content: pngBytes(t), mimeType: "image/png"}}
+	expectStatus(t, e.do(e.multipartRequest(http.MethodPost, "/admin/products/add",
+		gin.H{"name": "Banc", "price": 90, "translations": gin.H{"de": gin.H{"name": "Bank"}}}, files, token)), http.StatusBadRequest)
+	expectStatus(t, e.do(e.multipartRequest(http.MethodPost, "/admin/products/add",
+		gin.H{"name": "Banc", "price": 90, "translations": gin.H{"fr": gin.H{"name": "Banc"}}}, files, token)), http.StatusBadRequest)
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/products/add", data, files, token))
+	expectStatus(t, w, http.StatusCreated)
+	created := decodeJSON[models.Product](t, w)
+	if created.Translations["en"] != (models.ProductTranslation{Name: "Lounge chair", Description: "Solid teak"}) {
+		t.Fatalf("translations = %+v", created.Translations)
+	}
+
+	for _, tc := range []struct {
+		query, acceptLanguage, name, description string
+	}{
+		{"", "", "Chaise longue", "En teck massif"},
+		{"?lang=en", "", "Lounge chair", "Solid teak"},
+		{"", "en-GB,en;q=0.9", "Lounge chair", "Solid teak"},
+		{"?lang=fr", "en", "Chaise longue", "En teck massif"},
+		{"?lang=de", "de-DE", "Chaise longue", "En teck massif"},
+	} {
+		req := e.jsonRequest(http.MethodGet, "/products/slug/chaise-longue"+tc.query, nil, "")
+		if tc.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		w := e.do(req)
+		expectStatus(t, w, http.StatusOK)
+		detail := decodeJSON[dto.ProductDetailDTO](t, w)
+		if detail.Name != tc.name || detail.Description != tc.description || detail.Translations != nil {
+			t.Errorf("%q %q: detail = %+v", tc.query, tc.acceptLanguage, detail.Product)
+		}
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/products?lang=en", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("Content-Language"); got != "en" {
+		t.Fatalf("Content-Language = %q", got)
+	}
+	if got := productNames(decodeJSON[productsPage](t, w).Items); !slices.Equal(got, []string{"Lounge chair"}) {
+		t.Fatalf("listing = %v", got)
+	}
+	// a cross-origin response still varies on the origin
+	req := e.jsonRequest(http.MethodGet, "/products?lang=en", nil, "")
+	req.Header.Set("Origin", "http://localhost:3000")
+	w = e.do(req)
+	expectStatus(t, w, http.StatusOK)
+	if vary := w.Header().Values("Vary"); !slices.Contains(vary, "Origin") || !slices.Contains(vary, "Accept-Language") {
+		t.Fatalf("Vary = %q", vary)
+	}
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/products?lang=en", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if items := decodeJSON[productsPage](t, w).Items; items[0].Name != "Chaise longue" || items[0].Translations["en"].Name != "Lounge chair" {
+		t.Fatalf("admin listing = %+v", items)
+	}
+
+	// an update replaces the locales it lists; an empty one is removed
+	path := "/admin/products/update/" + created.Id.Hex()
+	expectStatus(t, e.do(e.multipartRequest(http.MethodPatch, path,
+		gin.H{"translations": gin.H{"en": gin.H{"name": "Deck chair"}}}, nil, token)), http.StatusOK)
+	product, _ := e.app.Products.FindByID(t.Context(), created.Id)
+	if product.Translations["en"] != (models.ProductTranslation{Name: "Deck chair"}) {
+		t.Fatalf("updated translations = %+v", product.Translations)
+	}
+	expectStatus(t, e.do(e.multipartRequest(http.MethodPatch, path,
+		gin.H{"translations": gin.H{"en": gin.H{}}}, nil, token)), http.StatusOK)
+	if product, _ = e.app.Products.FindByID(t.Context(), created.Id); product.Translations != nil {
+		t.Fatalf("removed translations = %+v", product.Translations)
+	}
+}
+
+func TestCategoriesAreServedInTheRequestedLocale(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+
+	w := e.do(e.multipartRequest(http.MethodPost, "/admin/categories", gin.H{
+		"name": "Tabourets", "description": "Assises", "isActive": true,
+		"translations": gin.H{"en": gin.H{"name": "Stools"}},
+	}, nil, token))
+	expectStatus(t, w, http.StatusCreated)
+	id := decodeJSON[map[string]string](t, w)["id"]
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/categories/"+id+"?lang=en", nil, ""))
+	expectStatus(t, w, http.StatusOK)
+	if cat := decodeJSON[models.Category](t, w); cat.Name != "Stools" || cat.Description != "Assises" || cat.Translations != nil {
+		t.Fatalf("category = %+v", cat)
+	}
+
+	expectStatus(t, e.do(e.multipartRequest(http.MethodPatch, "/admin/categories/"+id,
+		gin.H{"translations": gin.H{"en": gin.H{"name": "Stools", "description": "Seats"}}}, nil, token)), http.StatusOK)
+	req := e.jsonRequest(http.MethodGet, "/categories", nil, "")
+	req.Header.Set("Accept-Language", "en-US")
+	w = e.do(req)
+	expectStatus(t, w, http.StatusOK)
+	if items := decodeJSON[categoriesPage](t, w).Items; items[0].Name != "Stools" || items[0].Description != "Seats" {
+		t.Fatalf("listing = %+v", items)
+	}
+
+	expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/admin/categories", nil, "")), http.StatusUnauthorized)
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/categories", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if items := decodeJSON[categoriesPage](t, w).Items; items[0].Name != "Tabourets" || items[0].Translations["en"].Description != "Seats" {
+		t.Fatalf("admin listing = %+v", items)
+	}
+}
+
+func TestAdminListsMissingTranslations(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+
+	e.seedProduct(models.Product{Name: "Banc", Description: "En iroko"})
+	e.seedProduct(models.Product{Name: "Chaise", Translations: map[string]models.ProductTranslation{"en": {Name: "Chair"}}})
+	e.seedCategory("Tables", "tables")
+
+	type missingPage struct {
+		Items []dto.MissingTranslationDTO `json:"items"`
+		Total int                         `json:"total"`
+	}
+	w := e.do(e.jsonRequest(http.MethodGet, "/admin/translations/missing", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	got := decodeJSON[missingPage](t, w)
+	if got.Total != 2 || got.Items[0].Name != "Banc" || !slices.Equal(got.Items[0].Fields, []string{"name", "description"}) ||
+		got.Items[1].Type != "category" || got.Items[1].Locale != "en" {
+		t.Fatalf("missing = %+v", got)
+	}
+
+	// pages run across the product and category lists
+	for page, want := range []string{"Banc", "Tables", ""} {
+		w := e.do(e.jsonRequest(http.MethodGet, fmt.Sprintf("/admin/translations/missing?limit=1&page=%d", page+1), nil, token))
+		expectStatus(t, w, http.StatusOK)
+		got := decodeJSON[missingPage](t, w)
+		if got.Total != 2 || (want == "" && len(got.Items) != 0) || (want != "" && (len(got.Items) != 1 || got.Items[0].Name != want)) {
+			t.Fatalf("page %d = %+v, want %q", page+1, got, want)
+		}
+	}
+
+	w = e.do(e.jsonRequest(http.MethodGet, "/admin/translations/missing?type=category&locale=en", nil, token))
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeJSON[missingPage](t, w); got.Total != 1 || got.Items[0].Name != "Tables" {
+		t.Fatalf("missing categories = %+v", got)
+	}
+	for _, query := range []string{"locale=fr", "locale=de", "type=user"} {
+		expectStatus(t, e.do(e.jsonRequest(http.MethodGet, "/admin/translations/missing?"+query, nil, token)), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,158 @@
+// Package upload checks the files clients send before anything is stored.
+// The type of a file is sniffed from its content, never taken from its name
+// or declared Content-Type; its size is bounded; a picture must decode and
+// fit the allowed dimensions, and a PDF must be complete. Each kind of
+// upload has its own Policy.
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/princinho/sahobackend/imaging"
+)
+
+// Kind is what an upload is for.
+type Kind string
+
+const (
+	ProductImage      Kind = "productImage"
+	CategoryImage     Kind = "categoryImage"
+	QuotePDF          Kind = "quotePdf"
+	RequestAttachment Kind = "requestAttachment"
+)
+
+// The content types a policy may accept.
+const (
+	PNG  = "image/png"
+	JPEG = "image/jpeg"
+	WebP = "image/webp"
+	PDF  = "application/pdf"
+)
+
+// Extensions maps the accepted content types to the extension files of
+// that type are stored with.
+var Extensions = map[string]string{PNG: ".png", JPEG: ".jpg", WebP: ".webp", PDF: ".pdf"}
+
+// labels name the content types in error messages.
+var labels = map[string]string{PNG: "PNG", JPEG: "JPEG", WebP: "WebP", PDF: "PDF"}
+
+// Policy is what one kind of upload accepts.
+type Policy struct {
+	// Types lists the accepted content types, among the keys of Extensions.
+	Types []string
+	// MaxBytes bounds the size of a file.
+	MaxBytes int64
+	// MaxWidth and MaxHeight bound pictures, in pixels.
+	MaxWidth, MaxHeight int
+}
+
+// Rules of the Errors, reported with each rejected file.
+const (
+	RuleType       = "type"
+	RuleMaxBytes   = "max_bytes"
+	RuleDimensions = "max_dimensions"
+	RuleDecodable  = "decodable"
+)
+
+// Error is the rejection of one file. Message is English and printf-style,
+// its first argument the name of the file.
+type Error struct {
+	Rule    string
+	Message string
+	Args    []any
+}
+
+func (e *Error) Error() string { return fmt.Sprintf(e.Message, e.Args...) }
+
+func reject(rule, message string, args ...any) *Error {
+	return &Error{Rule: rule, Message: message, Args: args}
+}
+
+// File is an upload its policy accepted.
+type File struct {
+	// Name is the name the client gave the file, for display only.
+	Name string
+	// ContentType is the sniffed type and Extension the one that goes with it.
+	ContentType string
+	Extension   string
+	Data        []byte
+}
+
+// Open reads a multipart file and checks it. A file larger than the policy
+// allows is rejected before it is read.
+func (p Policy) Open(fh *multipart.FileHeader) (*File, error) {
+	if p.MaxBytes > 0 && fh.Size > p.MaxBytes {
+		return nil, p.tooLarge(fh.Filename)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", fh.Filename, err)
+	}
+	defer f.Close()
+	data, err := p.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", fh.Filename, err)
+	}
+	return p.Check(fh.Filename, data)
+}
+
+// Read reads r up to one byte past MaxBytes, enough for Check to tell the
+// file is too large without reading all of it.
+func (p Policy) Read(r io.Reader) ([]byte, error) {
+	if p.MaxBytes > 0 {
+		r = io.LimitReader(r, p.MaxBytes+1)
+	}
+	return io.ReadAll(r)
+}
+
+// Check checks the content of a file named name.
+func (p Policy) Check(name string, data []byte) (*File, error) {
+	if p.MaxBytes > 0 && int64(len(data)) > p.MaxBytes {
+		return nil, p.tooLarge(name)
+	}
+	ct := http.DetectContentType(data)
+	if !slices.Contains(p.Types, ct) {
+		accepted := make([]string, len(p.Types))
+		for i, t := range p.Types {
+			accepted[i] = labels[t]
+		}
+		return nil, reject(RuleType, "%s is not an accepted file type (accepted: %s)", name, strings.Join(accepted, ", "))
+	}
+	f := &File{Name: name, ContentType: ct, Extension: Extensions[ct], Data: data}
+
+	if ct == PDF {
+		// a truncated upload loses the end-of-file marker
+		if !bytes.Contains(data[max(0, len(data)-1024):], []byte("%%EOF")) {
+			return nil, reject(RuleDecodable, "%s is damaged or incomplete", name)
+		}
+		return f, nil
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, reject(RuleDecodable, "%s is damaged or incomplete", name)
+	}
+	if (p.MaxWidth > 0 && cfg.Width > p.MaxWidth) || (p.MaxHeight > 0 && cfg.Height > p.MaxHeight) {
+		return nil, reject(RuleDimensions, "%s is larger than %d × %d pixels", name, p.MaxWidth, p.MaxHeight)
+	}
+	err = imaging.Validate(data)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		return nil, reject(RuleDimensions, "%s has more than %d megapixels", name, imaging.MaxPixels/1_000_000)
+	}
+	if err != nil {
+		return nil, reject(RuleDecodable, "%s is damaged or incomplete", name)
+	}
+	return f, nil
+}
+
+func (p Policy) tooLarge(name string) *Error {
+	return reject(RuleMaxBytes, "%s is larger than %d MB", name, p.MaxBytes>>20)
+}
@@ -0,0 +1,66 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func pngOf(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCheckAcceptsWhatThePolicyAllows(t *testing.T) {
+	images := Policy{Types: []string{PNG, JPEG, WebP}, MaxBytes: 1 << 20, MaxWidth: 64, MaxHeight: 64}
+	f, err := images.Check("photo.jpg", pngOf(t, 64, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the sniffed type wins over the name
+	if f.ContentType != PNG || f.Extension != ".png" {
+		t.Fatalf("file = %+v", f)
+	}
+
+	pdfs := Policy{Types: []string{PDF}, MaxBytes: 1 << 20}
+	if f, err := pdfs.Check("devis.pdf", []byte("%PDF-1.4\n1 0 obj<<>>endobj\n%%EOF\n")); err != nil || f.ContentType != PDF {
+		t.Fatalf("pdf = %+v, %v", f, err)
+	}
+}
+
+func TestCheckRejects(t *testing.T) {
+	images := Policy{Types: []string{PNG, JPEG, WebP}, MaxBytes: 1 << 20, MaxWidth: 64, MaxHeight: 64}
+	pdfs := Policy{Types: []string{PDF}, MaxBytes: 1 << 20}
+	truncated := pngOf(t, 32, 32)
+	truncated = truncated[:len(truncated)-20]
+
+	tests := []struct {
+		name   string
+		policy Policy
+		data   []byte
+		rule   string
+	}{
+		{"pdf as image", images, []byte("%PDF-1.4\n%%EOF\n"), RuleType},
+		{"executable", images, []byte("MZ\x90\x00 not an image"), RuleType},
+		{"too many bytes", images, make([]byte, 1<<20+1), RuleMaxBytes},
+		{"too wide", images, pngOf(t, 65, 10), RuleDimensions},
+		{"too tall", images, pngOf(t, 10, 65), RuleDimensions},
+		{"truncated picture", images, truncated, RuleDecodable},
+		{"bad header", images, []byte("\x89PNG\r\n\x1a\nnot really"), RuleDecodable},
+		{"image as pdf", pdfs, pngOf(t, 4, 4), RuleType},
+		{"truncated pdf", pdfs, []byte("%PDF-1.4\n1 0 obj<<"), RuleDecodable},
+	}
+	for _, tt := range tests {
+		_, err := tt.policy.Check("file", tt.data)
+		var rejection *Error
+		if !errors.As(err, &rejection) || rejection.Rule != tt.rule {
+			t.Errorf("%s: %v, want rule %s", tt.name, err, tt.rule)
+		}
+	}
+}
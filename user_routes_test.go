@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateUser(t *testing.T) {
+	e := newTestEnv(t)
+	token := e.adminToken()
+
+	w := e.do(e.jsonRequest(http.MethodPost, "/admin/users", gin.H{"email": "New.Admin@Saho.test", "password": "long-enough"}, token))
+	expectStatus(t, w, http.StatusCreated)
+	created := decodeJSON[map[string]any](t, w)
+	if created["email"] != "new.admin@saho.test" || created["role"] != "ADMIN" {
+		t.Fatalf("created = %v", created)
+	}
+	if _, leaked := created["passwordHash"]; leaked {
+		t.Fatal("password hash leaked in response")
+	}
+
+	// the new account can log in
+	e.login("new.admin@saho.test", "long-enough")
+
+	w = e.do(e.jsonRequest(http.MethodPost, "/admin/users", gin.H{"email": "short@saho.test", "password": "short"}, token))
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestChangeMyPasswordRevokesSessions(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedAdmin(testAdminEmail, testAdminPassword)
+	token, cookie := e.login(testAdminEmail, testAdminPassword)
+
+	w := e.do(e.jsonRequest(http.MethodPost, "/admin/users/me/password",
+		gin.H{"currentPassword": "wrong", "newPassword": "brand-new-secret"}, token))
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	w = e.do(e.jsonRequest(http.MethodPost, "/admin/users/me/password",
+		gin.H{"currentPassword": testAdminPassword, "newPassword": "brand-new-secret"}, token))
+	expectStatus(t, w, http.StatusOK)
+
+	// every refresh token was revoked
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	req.AddCookie(cookie)
+	w = e.do(req)
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	w = e.do(e.jsonRequest(http.MethodPost, "/auth/login", gin.H{"email": testAdminEmail, "password": testAdminPassword}, ""))
+	expectStatus(t, w, http.StatusUnauthorized)
+	e.login(testAdminEmail, "brand-new-secret")
+}
@@ -3,7 +3,7 @@ package utils
 import (
 	"context"
 	"fmt"
-	"os"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -13,12 +13,11 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-func SeedAdminUser(ctx context.Context, usersCol *mongo.Collection) error {
-	email := strings.ToLower(strings.TrimSpace(os.Getenv("ADMIN_EMAIL")))
-	pass := os.Getenv("ADMIN_PASSWORD")
+func SeedAdminUser(ctx context.Context, usersCol *mongo.Collection, email, pass string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
 
 	if email == "" || pass == "" {
-		return fmt.Errorf("missing ADMIN_EMAIL or ADMIN_PASSWORD env vars")
+		return fmt.Errorf("missing admin email or password")
 	}
 
 	hash, err := HashPassword(pass)
@@ -49,9 +48,9 @@ func SeedAdminUser(ctx context.Context, usersCol *mongo.Collection) error {
 	}
 
 	if res.UpsertedCount == 1 {
-		fmt.Println("Admin user seeded:", email)
+		slog.Info("admin user seeded", "email", email)
 	} else {
-		fmt.Println("Admin user already exists:", email)
+		slog.Debug("admin user already exists", "email", email)
 	}
 
 	return nil
@@ -1,110 +1,22 @@
 package utils
 
 import (
-	"context"
 	"errors"
-	"fmt"
-	"io"
-	"log"
-	"maps"
-	"mime"
-	"net/http"
-	"net/url"
-	"os"
 	"regexp"
-	"slices"
 	"strconv"
 	"strings"
 	"time"
 	"unicode"
 
-	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"github.com/princinho/sahobackend/models"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/text/unicode/norm"
 
-	"mime/multipart"
-	"path/filepath"
-
-	"cloud.google.com/go/storage"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
-	"google.golang.org/api/option"
 )
 
-//	func NewGCSClient(ctx context.Context, serviceKeyPath string) (*storage.Client, error) {
-//		return storage.NewClient(ctx, option.WithAuthCredentialsFile(option.ServiceAccount, serviceKeyPath))
-//	}
-func NewGCSClient1(c *gin.Context) (*storage.Client, string, error) {
-	GCSBucket := os.Getenv("GCS_BUCKET")
-	credentialsPath := os.Getenv("CREDENTIALS_FILE_LOCATION")
-	wd, err := os.Getwd()
-	if err != nil {
-		return nil, "", err
-	}
-	client, err := storage.NewClient(c, option.WithAuthCredentialsFile(option.ServiceAccount, filepath.Join(wd, credentialsPath)))
-
-	if err != nil {
-		return nil, "", err
-	}
-	return client, GCSBucket, err
-}
-
-func UploadImagesToGCSAndGetPublicURLs1(
-	ctx context.Context,
-	gcs *storage.Client,
-	bucketName string,
-	productSlug string,
-	files []*multipart.FileHeader,
-) ([]string, error) {
-
-	if len(files) < 1 || len(files) > 4 {
-		return nil, fmt.Errorf("images must be 1 to 4")
-	}
-
-	urls := make([]string, 0, len(files))
-
-	for _, fh := range files {
-		// Build a safe unique object name
-		ext := strings.ToLower(filepath.Ext(fh.Filename))
-		if ext == "" {
-			ext = ".bin"
-		}
-		objectName := fmt.Sprintf("products/%s/%d%s", productSlug, time.Now().UnixNano(), ext)
-
-		f, err := fh.Open()
-		if err != nil {
-			return nil, fmt.Errorf("open file: %w", err)
-		}
-
-		w := gcs.Bucket(bucketName).Object(objectName).NewWriter(ctx)
-
-		// Keep content-type if provided
-		if ct := fh.Header.Get("Content-Type"); ct != "" {
-			w.ContentType = ct
-		}
-
-		if _, err := io.Copy(w, f); err != nil {
-			_ = f.Close()
-			_ = w.Close()
-			return nil, fmt.Errorf("upload copy: %w", err)
-		}
-
-		_ = f.Close()
-
-		if err := w.Close(); err != nil {
-			return nil, fmt.Errorf("upload close: %w", err)
-		}
-
-		publicURL := fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucketName, objectName)
-		urls = append(urls, publicURL)
-	}
-
-	return urls, nil
-}
-
 func StringsToObjectIDs(ids []string) ([]bson.ObjectID, error) {
 	objectIDs := make([]bson.ObjectID, 0, len(ids))
 
@@ -124,7 +36,6 @@ func IsDuplicateKey(err error) bool {
 	var we mongo.WriteException
 	if errors.As(err, &we) {
 		for _, e := range we.WriteErrors {
-			log.Println("Error code", e.Code)
 			if e.Code == 11000 || e.Code == 11001 {
 				return true
 			}
@@ -146,9 +57,10 @@ func IsDuplicateKey(err error) bool {
 	return strings.Contains(msg, "E11000 duplicate key error")
 }
 
-func GenerateSlug(name string) string {
-	// Normalize accents
-	t := norm.NFD.String(name)
+// StripAccents decomposes s (NFD) and drops the combining marks: "Lomé"
+// becomes "Lome".
+func StripAccents(s string) string {
+	t := norm.NFD.String(s)
 	var b strings.Builder
 	for _, r := range t {
 		if unicode.Is(unicode.Mn, r) {
@@ -156,8 +68,11 @@ func GenerateSlug(name string) string {
 		}
 		b.WriteRune(r)
 	}
+	return b.String()
+}
 
-	s := strings.ToLower(b.String())
+func GenerateSlug(name string) string {
+	s := strings.ToLower(StripAccents(name))
 
 	// Replace non-alphanumeric with hyphen
 	reg := regexp.MustCompile(`[^a-z0-9]+`)
@@ -169,159 +84,6 @@ func GenerateSlug(name string) string {
 	return s
 }
 
-func IntersectStrings(a, b []string) []string {
-	set := make(map[string]struct{}, len(b))
-	for _, x := range b {
-		set[x] = struct{}{}
-	}
-	out := make([]string, 0)
-	for _, x := range a {
-		if _, ok := set[x]; ok {
-			out = append(out, x)
-		}
-	}
-	return out
-}
-
-func ObjectNameFromGCSPublicURL1(bucket string, raw string) (string, error) {
-	u, err := url.Parse(raw)
-	if err != nil {
-		return "", fmt.Errorf("invalid url: %w", err)
-	}
-
-	host := strings.ToLower(u.Host)
-	path := strings.TrimPrefix(u.Path, "/")
-
-	// style 1: storage.googleapis.com/<bucket>/<object>
-	if host == "storage.googleapis.com" {
-		prefix := bucket + "/"
-		if !strings.HasPrefix(path, prefix) {
-			return "", fmt.Errorf("url bucket mismatch")
-		}
-		return strings.TrimPrefix(path, prefix), nil
-	}
-
-	// style 2: <bucket>.storage.googleapis.com/<object>
-	if host == strings.ToLower(bucket)+".storage.googleapis.com" {
-		if path == "" {
-			return "", fmt.Errorf("missing object path")
-		}
-		return path, nil
-	}
-
-	return "", fmt.Errorf("not a gcs public url")
-}
-
-func DeleteGCSObjects1(ctx context.Context, client *storage.Client, bucket string, objectNames []string) error {
-	var firstErr error
-
-	for _, obj := range objectNames {
-		if obj == "" {
-			continue
-		}
-		err := client.Bucket(bucket).Object(obj).Delete(ctx)
-		if err != nil && firstErr == nil {
-			firstErr = fmt.Errorf("delete %s: %w", obj, err)
-		}
-	}
-
-	return firstErr
-}
-
-func UploadQuotePDFToGCS1(
-	ctx context.Context,
-	client *storage.Client,
-	bucketName string,
-	quoteID string,
-	fileHeader *multipart.FileHeader,
-) (*models.QuoteAttachment, error) {
-
-	// Only allow PDF
-	if !strings.HasSuffix(strings.ToLower(fileHeader.Filename), ".pdf") {
-		return nil, fmt.Errorf("only PDF files are allowed")
-	}
-
-	file, err := fileHeader.Open()
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	// Generate unique object name
-	timestamp := time.Now().UTC().Unix()
-	random := uuid.New().String()
-
-	objectName := fmt.Sprintf(
-		"quotes/%s/%d-%s.pdf",
-		quoteID,
-		timestamp,
-		random,
-	)
-
-	bucket := client.Bucket(bucketName)
-	obj := bucket.Object(objectName)
-
-	writer := obj.NewWriter(ctx)
-	writer.ContentType = "application/pdf"
-	writer.CacheControl = "no-cache"
-
-	if _, err := io.Copy(writer, file); err != nil {
-		_ = writer.Close()
-		return nil, err
-	}
-
-	if err := writer.Close(); err != nil {
-		return nil, err
-	}
-
-	publicURL := fmt.Sprintf(
-		"https://storage.googleapis.com/%s/%s",
-		bucketName,
-		objectName,
-	)
-
-	return &models.QuoteAttachment{
-		PublicURL:  publicURL,
-		ObjectName: objectName,
-		MimeType:   "application/pdf",
-		SizeBytes:  fileHeader.Size,
-	}, nil
-}
-
-func MergeImageUrlsArrays(
-	oldUrls []string,
-	toRemove []string,
-	toAdd []string,
-) []string {
-
-	// Step 1: build a set of urls to remove
-	removeSet := make(map[string]struct{}, len(toRemove))
-	for _, u := range toRemove {
-		removeSet[u] = struct{}{}
-	}
-
-	// Step 2: keep old urls that are NOT removed
-	final := make([]string, 0, len(oldUrls)+len(toAdd))
-	exists := make(map[string]struct{}) // prevent duplicates
-
-	for _, u := range oldUrls {
-		if _, shouldRemove := removeSet[u]; !shouldRemove {
-			final = append(final, u)
-			exists[u] = struct{}{}
-		}
-	}
-
-	// Step 3: append new urls (avoid duplicates)
-	for _, u := range toAdd {
-		if _, already := exists[u]; !already {
-			final = append(final, u)
-			exists[u] = struct{}{}
-		}
-	}
-
-	return final
-}
-
 func ParseBoolQuery(value string) (*bool, error) {
 	if value == "" {
 		return nil, nil // not provided
@@ -361,7 +123,7 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func GenerateAccessToken(userID, email, role string, accessTTL time.Duration) (string, error) {
+func GenerateAccessToken(userID, email, role string, accessTTL time.Duration, secret string) (string, error) {
 	claims := Claims{
 		UserID: userID,
 		Email:  email,
@@ -371,18 +133,21 @@ func GenerateAccessToken(userID, email, role string, accessTTL time.Duration) (s
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	return token.SignedString([]byte(secret))
 }
 
-func GenerateRefreshToken(userID string) (string, error) {
+func GenerateRefreshToken(userID string, refreshTTL time.Duration, secret string) (string, error) {
 	claims := Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
+			// a random jti keeps two tokens issued in the same second distinct
+			// (refresh_tokens.tokenHash is unique)
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(refreshTTL)),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_REFRESH_SECRET")))
+	return token.SignedString([]byte(secret))
 }
 
 func ValidateToken(tokenStr string, secret string) (*Claims, error) {
@@ -394,184 +159,3 @@ func ValidateToken(tokenStr string, secret string) (*Claims, error) {
 	}
 	return token.Claims.(*Claims), nil
 }
-func ClearRefreshCookie(c *gin.Context) {
-	secure := os.Getenv("COOKIE_SECURE") == "true"
-	domain := os.Getenv("COOKIE_DOMAIN")
-	path := "/auth"
-
-	c.SetCookie("refreshToken", "", -1, path, domain, secure, true)
-}
-func AccessTTL() time.Duration {
-	minStr := os.Getenv("ACCESS_TOKEN_TTL_MINUTES")
-	min, _ := strconv.Atoi(minStr)
-	if min <= 0 {
-		min = 15
-	}
-	return time.Duration(min) * time.Minute
-}
-
-func RefreshTTL() time.Duration {
-	dStr := os.Getenv("REFRESH_TOKEN_TTL_DAYS")
-	days, _ := strconv.Atoi(dStr)
-	if days <= 0 {
-		days = 14
-	}
-	return time.Duration(days) * 24 * time.Hour
-}
-
-func UploadProductRequestFileToGCS1(
-	ctx context.Context,
-	client *storage.Client,
-	bucketName string,
-	requestID string,
-	fileHeader *multipart.FileHeader,
-) (*models.ProductRequestAttachment, error) {
-
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-
-	allowed := map[string]bool{
-		".pdf":  true,
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".webp": true,
-	}
-
-	if !allowed[ext] {
-		return nil, fmt.Errorf("file type not allowed (allowed: pdf, jpg, jpeg, png, webp)")
-	}
-
-	file, err := fileHeader.Open()
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	timestamp := time.Now().UTC().Unix()
-	random := uuid.New().String()
-
-	objectName := fmt.Sprintf(
-		"product-requests/%s/%d-%s%s",
-		requestID,
-		timestamp,
-		random,
-		ext,
-	)
-
-	obj := client.Bucket(bucketName).Object(objectName)
-	writer := obj.NewWriter(ctx)
-
-	ct := fileHeader.Header.Get("Content-Type")
-	if ct == "" {
-		ct = mime.TypeByExtension(ext)
-		if ct == "" {
-			ct = "application/octet-stream"
-		}
-	}
-	writer.ContentType = ct
-	writer.CacheControl = "no-cache"
-
-	if _, err := io.Copy(writer, file); err != nil {
-		_ = writer.Close()
-		return nil, err
-	}
-	if err := writer.Close(); err != nil {
-		return nil, err
-	}
-
-	publicURL := fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucketName, objectName)
-
-	return &models.ProductRequestAttachment{
-		ImageURL:   publicURL,
-		ObjectName: objectName,
-		MimeType:   ct,
-		SizeBytes:  fileHeader.Size,
-		FileName:   fileHeader.Filename,
-		UploadedAt: time.Now().UTC(),
-	}, nil
-}
-
-type FileValidator struct {
-	allowedExt  map[string]bool
-	allowedMime map[string]bool
-	maxSize     int64
-}
-
-func NewPDFOrImageValidator() *FileValidator {
-	allowedExt := make(map[string]bool)
-	for _, ext := range strings.Split(os.Getenv("ALLOWED_FILE_EXTENSIONS"), ",") {
-		if ext = strings.TrimSpace(strings.ToLower(ext)); ext != "" {
-			allowedExt[ext] = true
-		}
-	}
-
-	allowedMime := make(map[string]bool)
-	for _, m := range strings.Split(os.Getenv("ALLOWED_FILE_MIME_TYPES"), ",") {
-		if m = strings.TrimSpace(strings.ToLower(m)); m != "" {
-			allowedMime[m] = true
-		}
-	}
-
-	sizeMB := 5
-	if v := os.Getenv("MAX_UPLOAD_SIZE_MB"); v != "" {
-		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
-			sizeMB = parsed
-		}
-	}
-
-	return &FileValidator{
-		allowedExt:  allowedExt,
-		allowedMime: allowedMime,
-		maxSize:     int64(sizeMB) * 1024 * 1024,
-	}
-}
-
-func (v *FileValidator) ValidateFile(fileHeader *multipart.FileHeader) (string, error) {
-	if fileHeader.Size > v.maxSize {
-		return "", fmt.Errorf("file too large (max %d MB)", v.maxSize/(1024*1024))
-	}
-
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	if !v.allowedExt[ext] {
-		log.Printf("invalid file extension: %s. Allowed: %s\n", ext, strings.Join(slices.Collect(maps.Keys(v.allowedExt)), ", "))
-		return "", fmt.Errorf("invalid file extension: %s. Allowed: %s", ext, strings.Join(slices.Collect(maps.Keys(v.allowedExt)), ", "))
-	}
-
-	file, err := fileHeader.Open()
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-
-	buffer := make([]byte, 512)
-	if _, err = file.Read(buffer); err != nil {
-		return "", fmt.Errorf("failed to read file header")
-	}
-	if _, err = file.Seek(0, 0); err != nil {
-		return "", fmt.Errorf("failed to reset file reader")
-	}
-
-	detectedMime := strings.ToLower(http.DetectContentType(buffer))
-	if !v.allowedMime[detectedMime] {
-		log.Printf("invalid file type: %s. Allowed: %s\n", detectedMime, strings.Join(slices.Collect(maps.Keys(v.allowedMime)), ", "))
-		return "", fmt.Errorf("invalid file type")
-	}
-
-	return detectedMime, nil
-}
-
-func GetDefaultQueryLimits() (int, int) {
-	maxLimitStr := os.Getenv("READ_QUERY_MAX_LIMIT")
-	defaultLimitStr := os.Getenv("DEFAULT_READ_QUERY_LIMIT")
-	maxLimit, err := strconv.Atoi(maxLimitStr)
-	if err != nil {
-		// handle error or fall back to a default
-		maxLimit = 100
-	}
-	defaultLimit, err := strconv.Atoi(defaultLimitStr)
-	if err != nil {
-		// handle error or fall back to a default
-		defaultLimit = 20
-	}
-	return maxLimit, defaultLimit
-}